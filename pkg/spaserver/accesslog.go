@@ -0,0 +1,155 @@
+package spaserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// access log formats
+const (
+	accessLogJson     = "json"
+	accessLogCombined = "combined"
+)
+
+var accessLogFormats = []string{accessLogJson, accessLogCombined}
+
+// accessLogFiles are the open access log files shared by the sites and kept
+// across the configuration reloads
+var accessLogFiles sync.Map
+
+// accessLog writes the line of each response to its sink, separately from the
+// application log
+type accessLog struct {
+	format  string
+	out     io.Writer
+	samples []accessLogSample
+}
+
+type accessLogSample struct {
+	regex *regexp.Regexp
+	ratio float64
+}
+
+// accessLogEntry is the line of the JSON access log
+type accessLogEntry struct {
+	Time       string  `json:"time"`
+	ClientIp   string  `json:"client_ip"`
+	Host       string  `json:"host"`
+	Method     string  `json:"method"`
+	Uri        string  `json:"uri"`
+	Protocol   string  `json:"protocol"`
+	Status     int     `json:"status"`
+	Bytes      int64   `json:"bytes"`
+	DurationMs float64 `json:"duration_ms"`
+	Encoding   string  `json:"encoding,omitempty"`
+	Referer    string  `json:"referer,omitempty"`
+	UserAgent  string  `json:"user_agent,omitempty"`
+	TraceId    string  `json:"trace_id,omitempty"`
+}
+
+// lockedWriter serializes the lines written by the concurrent requests
+type lockedWriter struct {
+	mutex sync.Mutex
+	out   io.Writer
+}
+
+func (this *lockedWriter) Write(p []byte) (int, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.out.Write(p)
+}
+
+// newAccessLog opens the sink of the access log, nil if disabled
+func newAccessLog(cfg Config) (*accessLog, error) {
+	var out io.Writer
+	switch cfg.AccessLog {
+	case "":
+		return nil, nil
+	case "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	default:
+		if file, ok := accessLogFiles.Load(cfg.AccessLog); ok {
+			out = file.(io.Writer)
+			break
+		}
+		file, err := os.OpenFile(cfg.AccessLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		writer, loaded := accessLogFiles.LoadOrStore(cfg.AccessLog, &lockedWriter{out: file})
+		if loaded {
+			file.Close()
+		}
+		out = writer.(io.Writer)
+	}
+
+	samples := make([]accessLogSample, 0, len(cfg.AccessLogSampling))
+	for _, sample := range cfg.AccessLogSampling {
+		regex, err := regexp.Compile(sample.Regexp)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, accessLogSample{regex: regex, ratio: sample.Ratio})
+	}
+	format := cfg.AccessLogFormat
+	if format == "" {
+		format = accessLogJson
+	}
+	return &accessLog{format: format, out: out, samples: samples}, nil
+}
+
+// sampled reports whether the response is logged, the unsuccessful responses
+// are logged always, the others with the ratio of the first matching sample
+func (this *accessLog) sampled(requestPath string, status int) bool {
+	if status >= http.StatusBadRequest {
+		return true
+	}
+	for _, sample := range this.samples {
+		if sample.regex.MatchString(requestPath) {
+			return rand.Float64() < sample.ratio
+		}
+	}
+	return true
+}
+
+// log writes the line of the response
+func (this *accessLog) log(entry accessLogEntry, started time.Time) {
+	line := bytes.Buffer{}
+	if this.format == accessLogCombined {
+		size := "-"
+		if entry.Bytes > 0 {
+			size = strconv.FormatInt(entry.Bytes, 10)
+		}
+		fmt.Fprintf(&line, "%v - - [%v] \"%v %v %v\" %v %v \"%v\" \"%v\"\n",
+			entry.ClientIp, started.Format("02/Jan/2006:15:04:05 -0700"),
+			entry.Method, combinedEscape(entry.Uri), entry.Protocol, entry.Status, size,
+			combinedEscape(entry.Referer), combinedEscape(entry.UserAgent))
+	} else {
+		entry.Time = started.UTC().Format(time.RFC3339Nano)
+		if err := json.NewEncoder(&line).Encode(entry); err != nil {
+			return
+		}
+	}
+	// the single write keeps the lines of the concurrent requests whole
+	this.out.Write(line.Bytes())
+}
+
+// combinedEscape escapes the quotes and the control characters of the quoted fields, as Apache does
+func combinedEscape(value string) string {
+	if value == "" {
+		return "-"
+	}
+	quoted := strconv.Quote(value)
+	return quoted[1 : len(quoted)-1]
+}
@@ -0,0 +1,106 @@
+package spaserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type AccessLogTestSuite struct {
+	suite.Suite
+	rootDir string
+	logFile string
+}
+
+func TestAccessLogTestSuite(t *testing.T) {
+	suite.Run(t, new(AccessLogTestSuite))
+}
+
+func (suite *AccessLogTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.logFile = path.Join(suite.T().TempDir(), "access.log")
+	for name, content := range map[string]string{
+		"index.html":        "app",
+		"assets/main.a1.js": "main",
+	} {
+		suite.Require().Nil(os.MkdirAll(path.Dir(path.Join(suite.rootDir, name)), 0755))
+		suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, name), []byte(content), 0644))
+	}
+}
+
+func (suite *AccessLogTestSuite) serve(cfg Config, targets ...string) []string {
+	cfg.RootDirs = []string{suite.rootDir}
+	cfg.AccessLog = suite.logFile
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	for _, target := range targets {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("User-Agent", `curl "8"`)
+		sut.handler(context.Background(), httptest.NewRecorder(), req)
+	}
+	content, err := os.ReadFile(suite.logFile)
+	suite.Require().Nil(err)
+	return strings.Split(strings.TrimSpace(string(content)), "\n")
+}
+
+func (suite *AccessLogTestSuite) Test_Json_format_Then_entry_per_response() {
+
+	// when
+	lines := suite.serve(Config{AccessLogFormat: accessLogJson}, "/assets/main.a1.js?v=1")
+
+	// then
+	suite.Require().Len(lines, 1)
+	entry := accessLogEntry{}
+	suite.Require().Nil(json.Unmarshal([]byte(lines[0]), &entry))
+	suite.Equal("192.0.2.1", entry.ClientIp)
+	suite.Equal("GET", entry.Method)
+	suite.Equal("/assets/main.a1.js?v=1", entry.Uri)
+	suite.Equal(200, entry.Status)
+	suite.Equal(int64(4), entry.Bytes)
+	suite.Equal(`curl "8"`, entry.UserAgent)
+	suite.NotEmpty(entry.Time)
+}
+
+func (suite *AccessLogTestSuite) Test_Combined_format_Then_apache_line() {
+
+	// when
+	lines := suite.serve(Config{AccessLogFormat: accessLogCombined}, "/")
+
+	// then
+	suite.Require().Len(lines, 1)
+	suite.Regexp(`^192\.0\.2\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET / HTTP/1\.1" 200 3 "-" "curl \\"8\\""$`, lines[0])
+}
+
+func (suite *AccessLogTestSuite) Test_Sampling_Then_successful_assets_skipped() {
+
+	// when
+	lines := suite.serve(Config{
+		AccessLogFormat:   accessLogCombined,
+		AccessLogSampling: []AccessLogSample{{Regexp: `^/assets/`, Ratio: 0}},
+		NotFoundRegexs:    []string{`^/assets/`},
+	}, "/assets/main.a1.js", "/assets/missing.js", "/")
+
+	// then
+	suite.Require().Len(lines, 2)
+	suite.Contains(lines[0], `"GET /assets/missing.js HTTP/1.1" 404`)
+	suite.Contains(lines[1], `"GET / HTTP/1.1" 200`)
+}
+
+func (suite *AccessLogTestSuite) Test_Invalid_access_log_Then_invalid() {
+
+	// when
+	_, err := newServer(Config{AccessLogFormat: "common", AccessLogSampling: []AccessLogSample{{Regexp: "^/", Ratio: 2}}}, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "access-log-format: unknown format common")
+	suite.ErrorContains(err, "access-log-sampling[0].ratio: the ratio must be from 0 to 1")
+}
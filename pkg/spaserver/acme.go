@@ -0,0 +1,35 @@
+package spaserver
+
+import (
+	"net/http"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// acmeManager creates the manager of the certificates obtained automatically from
+// the ACME directory, e.g. Let's Encrypt, for the allowed hosts, nil if not configured
+func acmeManager(cfg Config) *autocert.Manager {
+	if len(cfg.AcmeHosts) == 0 {
+		return nil
+	}
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.AcmeHosts...),
+		Email:      cfg.AcmeEmail,
+	}
+	if cfg.AcmeCacheDir != "" {
+		manager.Cache = autocert.DirCache(cfg.AcmeCacheDir)
+	}
+	if cfg.AcmeDirectoryUrl != "" {
+		manager.Client = &acme.Client{DirectoryURL: cfg.AcmeDirectoryUrl}
+	}
+	return manager
+}
+
+// acmeHandler serves the HTTP-01 challenges of the ACME directory on the plain
+// HTTP port before any routing, so that they do not fall back to the index,
+// the other requests are redirected to HTTPS
+func (this *server) acmeHandler() http.Handler {
+	return this.acme.HTTPHandler(nil)
+}
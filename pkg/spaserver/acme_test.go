@@ -0,0 +1,126 @@
+package spaserver
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/acme"
+)
+
+type AcmeTestSuite struct {
+	suite.Suite
+	cfg Config
+}
+
+func TestAcmeTestSuite(t *testing.T) {
+	suite.Run(t, new(AcmeTestSuite))
+}
+
+func (suite *AcmeTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	_, filename, _, _ := runtime.Caller(0)
+	suite.cfg = Config{
+		RootDirs:     []string{path.Join(path.Dir(filename), "test/data")},
+		BaseURL:      "/",
+		AcmeHosts:    []string{"spa.example.com"},
+		AcmeCacheDir: suite.T().TempDir(),
+	}
+}
+
+func (suite *AcmeTestSuite) Test_Hosts_configured_Then_certificates_obtained_on_handshake() {
+
+	// given
+	sut, err := newServer(suite.cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+
+	// when
+	config, certificates, err := tlsConfig(suite.cfg, sut.acme)
+
+	// then
+	suite.Require().Nil(err)
+	suite.Nil(certificates)
+	suite.NotNil(config.GetCertificate)
+	suite.Contains(config.NextProtos, acme.ALPNProto)
+}
+
+func (suite *AcmeTestSuite) Test_Host_not_allowed_Then_no_certificate() {
+
+	// given
+	sut, err := newServer(suite.cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	config, _, err := tlsConfig(suite.cfg, sut.acme)
+	suite.Require().Nil(err)
+
+	// when
+	_, err = config.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.example.com"})
+
+	// then
+	suite.NotNil(err)
+}
+
+func (suite *AcmeTestSuite) Test_Challenge_request_Then_not_served_by_fallback() {
+
+	// given
+	sut, err := newServer(suite.cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", "http://spa.example.com/.well-known/acme-challenge/unknown-token", nil)
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.acmeHandler().ServeHTTP(rr, req)
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+	suite.NotContains(rr.Body.String(), "<html")
+}
+
+func (suite *AcmeTestSuite) Test_Plain_request_Then_redirected_to_https() {
+
+	// given
+	sut, err := newServer(suite.cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", "http://spa.example.com/users/42?tab=1", nil)
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.acmeHandler().ServeHTTP(rr, req)
+
+	// then
+	suite.Equal(http.StatusFound, rr.Code)
+	suite.Equal("https://spa.example.com/users/42?tab=1", rr.Header().Get("Location"))
+}
+
+func (suite *AcmeTestSuite) Test_Not_configured_Then_no_manager() {
+
+	// given
+	suite.cfg.AcmeHosts = nil
+
+	// when
+	sut, err := newServer(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.Require().Nil(err)
+	suite.Nil(sut.acme)
+}
+
+func (suite *AcmeTestSuite) Test_Combined_with_certificate_files_Then_invalid() {
+
+	// given
+	suite.cfg.TlsCertFile = "/spa/tls/tls.crt"
+	suite.cfg.TlsKeyFile = "/spa/tls/tls.key"
+	suite.cfg.AcmeDirectoryUrl = "staging"
+
+	// when
+	_, err := newServer(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "acme-hosts")
+	suite.ErrorContains(err, "acme-directory-url")
+}
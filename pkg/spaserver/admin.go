@@ -0,0 +1,94 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// adminHandler serves the operational endpoints on the admin port,
+// which shall not be exposed to the public
+func (this *server) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", this.serveStatus)
+	mux.HandleFunc("/config", this.serveConfig)
+	mux.HandleFunc("/har", this.requireAdminToken(this.serveHar))
+	mux.HandleFunc("/diff", this.serveDiff)
+	mux.HandleFunc("/maintenance", this.requireAdminToken(this.serveMaintenance))
+	mux.HandleFunc("/drain", this.requireAdminToken(this.serveDrain))
+	mux.HandleFunc("/sign", this.requireAdminToken(this.serveSign))
+	mux.HandleFunc("/cache", this.requireAdminToken(this.servePurge))
+	if this.metricsHandler != nil {
+		mux.Handle("/metrics", this.metricsHandler)
+	}
+	this.handleDebugEndpoints(mux)
+
+	// the probes are called without the token
+	root := http.NewServeMux()
+	root.HandleFunc("/ready", this.serveReady)
+	root.HandleFunc("/readyz", this.serveReady)
+	root.HandleFunc("/livez", this.serveLive)
+	root.HandleFunc("/healthz", this.serveLive)
+	root.Handle("/", this.adminAuthorized(mux))
+	return root
+}
+
+// writeJSON writes the value as the JSON response
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+// serveHar controls the HAR capture: POST starts the capture of the paths matching
+// the `path` regexp for the `duration`, GET downloads the captured archive and
+// DELETE stops the capture and discards it
+func (this *server) serveHar(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodPost:
+		pathRegex, err := regexp.Compile(req.URL.Query().Get("path"))
+		if err != nil {
+			http.Error(w, "Invalid path regexp: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		duration := this.cfg.HarMaxDuration
+		if value := req.URL.Query().Get("duration"); value != "" {
+			duration, err = time.ParseDuration(value)
+			if err != nil || duration <= 0 {
+				http.Error(w, "Invalid duration", http.StatusBadRequest)
+				return
+			}
+			// captures expire at latest after the configured maximum
+			duration = min(duration, this.cfg.HarMaxDuration)
+		}
+
+		capture := &harCapture{
+			pathRegex:   pathRegex,
+			expires:     time.Now().Add(duration),
+			maxEntries:  this.cfg.HarMaxEntries,
+			maxBodySize: this.cfg.HarMaxBodySize,
+		}
+		this.har.start(capture)
+		this.logger.Warn().Str("path", pathRegex.String()).Time("expires", capture.expires).Msg("HAR capture started")
+		writeJSON(w, http.StatusCreated, map[string]any{"path": pathRegex.String(), "expires": capture.expires})
+	case http.MethodGet:
+		capture := this.har.get()
+		if capture == nil {
+			http.Error(w, "No HAR capture", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="spa_d.har"`)
+		writeJSON(w, http.StatusOK, capture.archive())
+	case http.MethodDelete:
+		this.har.stop()
+		this.logger.Warn().Msg("HAR capture stopped")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
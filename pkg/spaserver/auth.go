@@ -0,0 +1,187 @@
+package spaserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// authentication modes
+const (
+	authBasic = "basic"
+	authOidc  = "oidc"
+	authJwt   = "jwt"
+)
+
+var errHashUnsupported = errors.New("unsupported password hash, use bcrypt, e.g. htpasswd -B")
+
+// basicAuth verifies the credentials of the basic auth against the htpasswd users
+type basicAuth struct {
+	realm string
+	users map[string]string
+	// verified caches the digests of the verified credentials, bcrypt is too slow
+	// to be checked on each request of the assets
+	verified sync.Map
+}
+
+// newBasicAuth loads the users of the configuration and the htpasswd file
+func newBasicAuth(cfg Config) (*basicAuth, error) {
+	users := map[string]string{}
+	if cfg.BasicAuthFile != "" {
+		content, err := os.ReadFile(cfg.BasicAuthFile)
+		if err != nil {
+			return nil, err
+		}
+		if users, err = parseHtpasswd(content); err != nil {
+			return nil, fmt.Errorf("%v: %w", cfg.BasicAuthFile, err)
+		}
+	}
+	for user, hash := range cfg.BasicAuthUsers {
+		users[user] = hash
+	}
+	return &basicAuth{realm: cfg.BasicAuthRealm, users: users}, nil
+}
+
+// parseHtpasswd parses the `user:hash` lines of the htpasswd file
+func parseHtpasswd(content []byte) (map[string]string, error) {
+	users := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(entry, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("line %v: expected user:hash", line)
+		}
+		if err := checkPasswordHash(hash); err != nil {
+			return nil, fmt.Errorf("line %v: %w", line, err)
+		}
+		users[user] = hash
+	}
+	return users, nil
+}
+
+// checkPasswordHash checks the hash is the bcrypt or the `{SHA}` hash of htpasswd
+func checkPasswordHash(hash string) error {
+	if strings.HasPrefix(hash, "{SHA}") {
+		return nil
+	}
+	if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+		return errHashUnsupported
+	}
+	return nil
+}
+
+// verify reports whether the password matches the hash of the user
+func (this *basicAuth) verify(user, password string) bool {
+	hash, ok := this.users[user]
+	if !ok {
+		return false
+	}
+	digest := sha256.Sum256([]byte(user + ":" + password + ":" + hash))
+	if _, ok := this.verified.Load(digest); ok {
+		return true
+	}
+	if sha, ok := strings.CutPrefix(hash, "{SHA}"); ok {
+		sum := sha1.Sum([]byte(password))
+		ok = subtle.ConstantTimeCompare([]byte(base64.StdEncoding.EncodeToString(sum[:])), []byte(sha)) == 1
+		if !ok {
+			return false
+		}
+	} else if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return false
+	}
+	this.verified.Store(digest, true)
+	return true
+}
+
+// authenticate challenges the request without the valid credentials
+func (this *basicAuth) authenticate(w http.ResponseWriter, req *http.Request) (string, bool) {
+	user, password, ok := req.BasicAuth()
+	if ok && this.verify(user, password) {
+		return user, true
+	}
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", this.realm))
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	return "", false
+}
+
+// newAuth creates the authentication of the configured mode
+func (this *server) newAuth() error {
+	var err error
+	switch this.cfg.AuthMode {
+	case authBasic:
+		this.basicAuth, err = newBasicAuth(this.cfg)
+	case authOidc:
+		this.oidc, err = newOidcLogin(this.cfg, this.logger)
+	case authJwt:
+		this.bearerAuth = newBearerAuth(this.cfg)
+	}
+	return err
+}
+
+// authRequired reports whether the request path is protected by the authentication
+func (this *server) authRequired(requestPath string) bool {
+	if this.cfg.AuthMode == "" {
+		return false
+	}
+	return !this.regexes().authExclude.matches(requestPath)
+}
+
+// authenticatedKey marks the context of the authenticated request
+type authenticatedKey struct{}
+
+// authenticate returns the user of the request and the context with its claims,
+// or responds with the challenge, the login redirect or the login callback and
+// returns false
+func (this *server) authenticate(ctx context.Context, w http.ResponseWriter, req *http.Request) (context.Context, string, bool) {
+	var user string
+	var ok bool
+	switch {
+	case this.oidc != nil:
+		user, ok = this.oidc.authenticate(w, req)
+	case this.bearerAuth != nil:
+		ctx, user, ok = this.bearerAuth.authenticate(ctx, w, req)
+	default:
+		user, ok = this.basicAuth.authenticate(w, req)
+	}
+	if ok {
+		ctx = context.WithValue(ctx, authenticatedKey{}, true)
+	}
+	return ctx, user, ok
+}
+
+// authenticated reports whether the request passed the authentication
+func authenticated(ctx context.Context) bool {
+	authenticated, _ := ctx.Value(authenticatedKey{}).(bool)
+	return authenticated
+}
+
+// privateCacheControl restricts the cache control to the browser cache, so that
+// the shared caches do not serve the protected content to the other users
+func privateCacheControl(cacheControl string) string {
+	directives := []string{"private"}
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.TrimSpace(directive)
+		name := strings.ToLower(directive)
+		if directive == "" || name == "public" || name == "private" || strings.HasPrefix(name, "s-maxage") {
+			continue
+		}
+		directives = append(directives, directive)
+	}
+	return strings.Join(directives, ", ")
+}
@@ -0,0 +1,163 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type AuthTestSuite struct {
+	suite.Suite
+	rootDir string
+}
+
+func TestAuthTestSuite(t *testing.T) {
+	suite.Run(t, new(AuthTestSuite))
+}
+
+func (suite *AuthTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	for name, content := range map[string]string{
+		"index.html":           "app",
+		"manifest.webmanifest": "{}",
+		"app.js":               "init()",
+	} {
+		suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, name), []byte(content), 0644))
+	}
+}
+
+func (suite *AuthTestSuite) server(cfg Config) *server {
+	cfg.RootDirs = []string{suite.rootDir}
+	cfg.BasicAuthRealm = "Preview"
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	return sut
+}
+
+func (suite *AuthTestSuite) get(sut *server, target string, user, password string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	if user != "" {
+		req.SetBasicAuth(user, password)
+	}
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *AuthTestSuite) Test_Basic_auth_without_credentials_Then_challenged() {
+
+	// given
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	sut := suite.server(Config{AuthMode: authBasic, BasicAuthUsers: map[string]string{"alice": string(hash)}})
+
+	// when
+	rr := suite.get(sut, "/", "", "")
+
+	// then
+	suite.Equal(http.StatusUnauthorized, rr.Code)
+	suite.Equal(`Basic realm="Preview", charset="UTF-8"`, rr.Header().Get("WWW-Authenticate"))
+}
+
+func (suite *AuthTestSuite) Test_Basic_auth_valid_credentials_Then_served() {
+
+	// given
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	sut := suite.server(Config{AuthMode: authBasic, BasicAuthUsers: map[string]string{"alice": string(hash)}})
+
+	// when
+	rr := suite.get(sut, "/", "alice", "secret")
+	again := suite.get(sut, "/", "alice", "secret")
+	wrong := suite.get(sut, "/", "alice", "wrong")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("app", rr.Body.String())
+	suite.Equal(http.StatusOK, again.Code)
+	suite.Equal(http.StatusUnauthorized, wrong.Code)
+}
+
+func (suite *AuthTestSuite) Test_Htpasswd_file_Then_users_loaded() {
+
+	// given
+	file := path.Join(suite.T().TempDir(), ".htpasswd")
+	// htpasswd -s -b .htpasswd bob secret
+	suite.Require().Nil(os.WriteFile(file, []byte("# preview users\nbob:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n"), 0600))
+	sut := suite.server(Config{AuthMode: authBasic, BasicAuthFile: file})
+
+	// when
+	rr := suite.get(sut, "/", "bob", "secret")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+}
+
+func (suite *AuthTestSuite) Test_Excluded_path_Then_served_without_credentials() {
+
+	// given
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	sut := suite.server(Config{
+		AuthMode:          authBasic,
+		AuthExcludeRegexs: []string{`^/manifest\.webmanifest$`},
+		BasicAuthUsers:    map[string]string{"alice": string(hash)},
+	})
+
+	// when
+	rr := suite.get(sut, "/manifest.webmanifest", "", "")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+}
+
+func (suite *AuthTestSuite) Test_Invalid_auth_configuration_Then_invalid() {
+
+	// when
+	_, basic := newServer(Config{AuthMode: authBasic, BasicAuthUsers: map[string]string{"alice": "$apr1$x$y"}}, zerolog.New(io.Discard))
+	_, oidc := newServer(Config{AuthMode: authOidc, OidcIssuer: "idp"}, zerolog.New(io.Discard))
+	_, mode := newServer(Config{AuthMode: "digest"}, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(basic, "basic-auth-users.alice: unsupported password hash")
+	suite.ErrorContains(oidc, `oidc-issuer: "idp" is not an absolute url`)
+	suite.ErrorContains(oidc, "oidc-client-id: the client id is required")
+	suite.ErrorContains(oidc, "oidc-redirect-url")
+	suite.ErrorContains(mode, "auth-mode: unknown mode digest")
+}
+
+func (suite *AuthTestSuite) Test_Authenticated_Then_private_cache_control() {
+
+	// given
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	sut := suite.server(Config{
+		AuthMode:            authBasic,
+		BasicAuthUsers:      map[string]string{"alice": string(hash)},
+		AuthExcludeRegexs:   []string{"^/manifest"},
+		DefaultCacheControl: "public, max-age=3600, s-maxage=86400",
+	})
+
+	// when
+	document := suite.get(sut, "/", "alice", "secret")
+	asset := suite.get(sut, "/app.js", "alice", "secret")
+	excluded := suite.get(sut, "/manifest.webmanifest", "", "")
+
+	// then
+	suite.Equal(http.StatusOK, document.Code)
+	suite.Equal("private, no-cache", document.Header().Get("Cache-Control"))
+	suite.Equal("private, max-age=3600", asset.Header().Get("Cache-Control"))
+	suite.Equal("public, max-age=3600, s-maxage=86400", excluded.Header().Get("Cache-Control"))
+}
+
+func (suite *AuthTestSuite) Test_Private_cache_control() {
+	suite.Equal("private, max-age=3600", privateCacheControl("public, max-age=3600, s-maxage=86400"))
+	suite.Equal("private, no-cache", privateCacheControl("no-cache"))
+	suite.Equal("private, max-age=60", privateCacheControl("Private, max-age=60"))
+	suite.Equal("private", privateCacheControl(""))
+}
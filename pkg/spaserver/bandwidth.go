@@ -0,0 +1,128 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// throttleSlicesPerSecond splits the throttled writes into the slices of the
+// rate, so that the bytes are paced evenly instead of in one-second bursts
+const throttleSlicesPerSecond = 10
+
+// bandwidthLimit returns the bandwidth limit of the resource in bytes per
+// second, the first matching regexp overrides the global limit, 0 if unlimited
+func (this *server) bandwidthLimit(resourcePath string) int64 {
+	for _, matcher := range this.regexes().bandwidthPerPath {
+		if matcher.regex.MatchString(resourcePath) {
+			return matcher.limit
+		}
+	}
+	return this.cfg.BandwidthLimit
+}
+
+// throttle limits the bandwidth of the response of the resource, so that a few
+// large downloads, e.g. of the wasm modules or the videos, do not starve the
+// small critical assets. The burst is sent unthrottled, the small responses
+// are not delayed at all.
+func (this *server) throttle(ctx context.Context, w http.ResponseWriter, req *http.Request, resourcePath string) http.ResponseWriter {
+	limit := this.bandwidthLimit(resourcePath)
+	if limit <= 0 || req.Method == http.MethodHead {
+		return w
+	}
+	debugLookup(ctx, "bandwidth limited to %v B/s", limit)
+	return &throttledWriter{responseWriterPassthrough: responseWriterPassthrough{w}, ctx: req.Context(), rate: limit, burst: this.cfg.BandwidthLimitBurst}
+}
+
+// throttledWriter writes the bytes beyond the burst at the rate in bytes per second
+type throttledWriter struct {
+	responseWriterPassthrough
+	ctx     context.Context
+	rate    int64
+	burst   int64
+	started time.Time
+	written int64
+}
+
+func (this *throttledWriter) Write(content []byte) (int, error) {
+	total := 0
+	slice := int(max(this.rate/throttleSlicesPerSecond, 1))
+	for len(content) > 0 {
+		chunk := content[:min(len(content), slice)]
+		if err := this.wait(len(chunk)); err != nil {
+			return total, err
+		}
+		n, err := this.ResponseWriter.Write(chunk)
+		total += n
+		this.written += int64(n)
+		if err != nil {
+			return total, err
+		}
+		content = content[n:]
+	}
+	return total, nil
+}
+
+// ReadFrom paces the zero-copy path of the underlying writer, each slice of the
+// body is sent with sendfile once it is due
+func (this *throttledWriter) ReadFrom(src io.Reader) (int64, error) {
+	readerFrom, ok := this.ResponseWriter.(io.ReaderFrom)
+	if !ok {
+		return copyWrites(this, src)
+	}
+	slice := max(this.rate/throttleSlicesPerSecond, 1)
+	var total int64
+	for {
+		chunk := limitChunk(src, slice)
+		if chunk.N == 0 {
+			return total, nil
+		}
+		if err := this.wait(int(chunk.N)); err != nil {
+			return total, err
+		}
+		n, err := readerFrom.ReadFrom(chunk)
+		total += n
+		this.written += n
+		if limited, ok := src.(*io.LimitedReader); ok {
+			limited.N -= n
+		}
+		if err != nil || n == 0 {
+			return total, err
+		}
+	}
+}
+
+// limitChunk limits the reader to the chunk, the limited reader of the range is
+// flattened, so that its file is still sent with sendfile
+func limitChunk(src io.Reader, size int64) *io.LimitedReader {
+	if limited, ok := src.(*io.LimitedReader); ok {
+		return &io.LimitedReader{R: limited.R, N: min(size, limited.N)}
+	}
+	return &io.LimitedReader{R: src, N: size}
+}
+
+// wait delays the write of the chunk until its bytes beyond the burst are due
+// at the rate, or the client is gone
+func (this *throttledWriter) wait(chunk int) error {
+	if this.started.IsZero() {
+		this.started = time.Now()
+	}
+	beyond := this.written + int64(chunk) - this.burst
+	if beyond <= 0 {
+		return nil
+	}
+	due := this.started.Add(time.Duration(float64(beyond) / float64(this.rate) * float64(time.Second)))
+	delay := time.Until(due)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-this.ctx.Done():
+		return this.ctx.Err()
+	}
+}
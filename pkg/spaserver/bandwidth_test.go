@@ -0,0 +1,137 @@
+package spaserver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type BandwidthTestSuite struct {
+	suite.Suite
+	rootDir string
+	large   []byte
+}
+
+func TestBandwidthTestSuite(t *testing.T) {
+	suite.Run(t, new(BandwidthTestSuite))
+}
+
+func (suite *BandwidthTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.large = bytes.Repeat([]byte("wasm"), 30*1024/4)
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "index.html"), []byte("app"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "module.wasm"), suite.large, 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "main.js"), suite.large, 0644))
+}
+
+func (suite *BandwidthTestSuite) get(cfg Config, target string) (*httptest.ResponseRecorder, time.Duration) {
+	cfg.RootDirs = []string{suite.rootDir}
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+	started := time.Now()
+	sut.handler(context.Background(), rr, httptest.NewRequest("GET", target, nil))
+	return rr, time.Since(started)
+}
+
+func (suite *BandwidthTestSuite) Test_Bandwidth_limit_Then_bytes_beyond_burst_paced() {
+
+	// when
+	rr, elapsed := suite.get(Config{BandwidthLimit: 100 * 1024, BandwidthLimitBurst: 10 * 1024}, "/module.wasm")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(suite.large, rr.Body.Bytes())
+	// 20 KiB beyond the burst at 100 KiB/s
+	suite.GreaterOrEqual(elapsed, 180*time.Millisecond)
+}
+
+func (suite *BandwidthTestSuite) Test_Response_within_burst_Then_not_delayed() {
+
+	// when
+	rr, elapsed := suite.get(Config{BandwidthLimit: 1024, BandwidthLimitBurst: 64 * 1024}, "/module.wasm")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(suite.large, rr.Body.Bytes())
+	suite.Less(elapsed, 500*time.Millisecond)
+}
+
+func (suite *BandwidthTestSuite) Test_Regexp_limit_Then_overrides_global_limit() {
+
+	// given
+	cfg := Config{
+		BandwidthLimit:         1024,
+		BandwidthLimitPerRegex: map[string]int64{`\.js$`: 0, `\.wasm$`: 100 * 1024},
+	}
+
+	// when
+	script, scriptElapsed := suite.get(cfg, "/main.js")
+	module, moduleElapsed := suite.get(cfg, "/module.wasm")
+
+	// then
+	suite.Equal(suite.large, script.Body.Bytes())
+	suite.Less(scriptElapsed, 500*time.Millisecond, "unlimited by the regexp")
+	suite.Equal(suite.large, module.Body.Bytes())
+	suite.Less(moduleElapsed, time.Second, "limited by the regexp instead of the global limit")
+	suite.GreaterOrEqual(moduleElapsed, 200*time.Millisecond)
+}
+
+func (suite *BandwidthTestSuite) Test_File_range_Then_underlying_ReadFrom_paced() {
+
+	// given
+	file, err := os.Open(path.Join(suite.rootDir, "module.wasm"))
+	suite.Require().Nil(err)
+	defer file.Close()
+	underlying := &readerFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+	writer := &throttledWriter{responseWriterPassthrough: responseWriterPassthrough{underlying}, ctx: context.Background(), rate: 100 * 1024, burst: 10 * 1024}
+	started := time.Now()
+
+	// when
+	n, err := writer.ReadFrom(io.LimitReader(file, 20*1024))
+
+	// then
+	suite.Nil(err)
+	suite.Equal(int64(20*1024), n)
+	suite.Equal(suite.large[:20*1024], underlying.Body.Bytes())
+	// the file is passed to the underlying writer in the slices of the rate
+	suite.Len(underlying.sources, 2)
+	suite.Equal(file, underlying.sources[0].(*io.LimitedReader).R)
+	// 10 KiB beyond the burst at 100 KiB/s
+	suite.GreaterOrEqual(time.Since(started), 90*time.Millisecond)
+}
+
+func (suite *BandwidthTestSuite) Test_Client_gone_Then_write_aborted() {
+
+	// given
+	ctx, cancel := context.WithCancel(context.Background())
+	writer := &throttledWriter{responseWriterPassthrough: responseWriterPassthrough{httptest.NewRecorder()}, ctx: ctx, rate: 1024}
+	cancel()
+
+	// when
+	n, err := writer.Write(suite.large)
+
+	// then
+	suite.ErrorIs(err, context.Canceled)
+	suite.Less(n, len(suite.large))
+}
+
+func (suite *BandwidthTestSuite) Test_Negative_limit_Then_validation_error() {
+
+	// when
+	err := validateConfig(Config{BandwidthLimitPerRegex: map[string]int64{`\.wasm$`: -1}, BandwidthLimit: -1})
+
+	// then
+	suite.ErrorContains(err, "bandwidth-limit: the limit must not be negative")
+	suite.ErrorContains(err, `bandwidth-limit-per-regexp[\.wasm$]: the limit must not be negative`)
+}
@@ -0,0 +1,120 @@
+package spaserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// jwtClaimsMeta is the name of the meta tag with the claims injected into the html documents
+const jwtClaimsMeta = "jwt-claims"
+
+type documentClaimsKey struct{}
+
+// bearerAuth protects the requests with the JWT bearer tokens, e.g. issued by the
+// identity-aware proxy of the mesh
+type bearerAuth struct {
+	cfg  Config
+	keys *jwtKeys
+}
+
+func newBearerAuth(cfg Config) *bearerAuth {
+	return &bearerAuth{cfg: cfg, keys: newJwtKeys(&http.Client{Timeout: oidcTimeout}, cfg.JwtJwksUrl)}
+}
+
+// authenticate verifies the bearer token, sets the claim headers and returns the
+// context with the document claims, the requests without the valid token are refused
+func (this *bearerAuth) authenticate(ctx context.Context, w http.ResponseWriter, req *http.Request) (context.Context, string, bool) {
+	scheme, token, _ := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return ctx, "", false
+	}
+
+	payload := json.RawMessage{}
+	claims := jwtClaims{}
+	err := this.keys.verify(ctx, strings.TrimSpace(token), &payload)
+	if err == nil {
+		err = json.Unmarshal(payload, &claims)
+	}
+	if err == nil {
+		err = claims.check(this.cfg.JwtIssuer, this.cfg.JwtAudience)
+	}
+	if err != nil {
+		debugLookup(ctx, "bearer %v", err)
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return ctx, "", false
+	}
+
+	if len(this.cfg.JwtClaimHeaders) > 0 || len(this.cfg.JwtDocumentClaims) > 0 {
+		values := map[string]any{}
+		if err := json.Unmarshal(payload, &values); err != nil {
+			return ctx, claims.Subject, true
+		}
+		for claim, header := range this.cfg.JwtClaimHeaders {
+			if value, ok := claimHeaderValue(values[claim]); ok {
+				w.Header().Set(header, value)
+			}
+		}
+		selected := map[string]any{}
+		for _, claim := range this.cfg.JwtDocumentClaims {
+			if value, ok := values[claim]; ok {
+				selected[claim] = value
+			}
+		}
+		if len(selected) > 0 {
+			content, _ := json.Marshal(selected)
+			ctx = context.WithValue(ctx, documentClaimsKey{}, string(content))
+		}
+	}
+	return ctx, claims.Subject, true
+}
+
+// claimHeaderValue formats the claim as the header value, the strings as they
+// are and the other values as JSON, false if missing or not a valid header value
+func claimHeaderValue(claim any) (string, bool) {
+	if claim == nil {
+		return "", false
+	}
+	value, ok := claim.(string)
+	if !ok {
+		content, err := json.Marshal(claim)
+		if err != nil {
+			return "", false
+		}
+		value = string(content)
+	}
+	return value, httpguts.ValidHeaderFieldValue(value)
+}
+
+// documentClaims returns the JSON of the claims injected into the documents, empty if none
+func documentClaims(ctx context.Context) string {
+	claims, _ := ctx.Value(documentClaimsKey{}).(string)
+	return claims
+}
+
+// injectsClaims returns true if the claims of the request are injected into the document
+func injectsClaims(ctx context.Context, name string) bool {
+	return isHtml(name) && documentClaims(ctx) != ""
+}
+
+// injectClaims inserts the meta tag with the claims at the start of the head,
+// the application reads them with
+// `JSON.parse(document.querySelector('meta[name="jwt-claims"]').content)`
+func injectClaims(content []byte, claims string) []byte {
+	location := headTagRegex.FindIndex(content)
+	if location == nil {
+		return content
+	}
+	meta := fmt.Sprintf(`<meta name="%v" content="%v">`, jwtClaimsMeta, html.EscapeString(claims))
+	injected := append([]byte{}, content[:location[1]]...)
+	injected = append(injected, meta...)
+	return append(injected, content[location[1]:]...)
+}
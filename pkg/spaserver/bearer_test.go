@@ -0,0 +1,153 @@
+package spaserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type BearerTestSuite struct {
+	suite.Suite
+	jwks *httptest.Server
+	key  *ecdsa.PrivateKey
+	sut  *server
+}
+
+func TestBearerTestSuite(t *testing.T) {
+	suite.Run(t, new(BearerTestSuite))
+}
+
+func (suite *BearerTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().Nil(err)
+	suite.key = key
+	suite.jwks = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "EC",
+			"kid": "mesh",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		}}})
+	}))
+	suite.T().Cleanup(suite.jwks.Close)
+
+	rootDir := suite.T().TempDir()
+	for name, content := range map[string]string{
+		"index.html": "<html><head><title>app</title></head></html>",
+		"main.js":    "main",
+	} {
+		suite.Require().Nil(os.WriteFile(path.Join(rootDir, name), []byte(content), 0644))
+	}
+	sut, err := newServer(Config{
+		RootDirs:          []string{rootDir},
+		AuthMode:          authJwt,
+		JwtJwksUrl:        suite.jwks.URL,
+		JwtIssuer:         "https://mesh.example.com",
+		JwtAudience:       "spa",
+		JwtClaimHeaders:   map[string]string{"email": "X-User-Email", "roles": "X-User-Roles"},
+		JwtDocumentClaims: []string{"name", "roles"},
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *BearerTestSuite) token(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": "mesh"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, suite.key, digest[:])
+	suite.Require().Nil(err)
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (suite *BearerTestSuite) validClaims() map[string]any {
+	return map[string]any{
+		"iss":   "https://mesh.example.com",
+		"aud":   []string{"spa", "api"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"sub":   "u-1",
+		"email": "alice@example.com",
+		"name":  "Alice <Admin>",
+		"roles": []string{"admin"},
+	}
+}
+
+func (suite *BearerTestSuite) get(target string, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *BearerTestSuite) Test_Valid_token_Then_claims_forwarded() {
+
+	// when
+	rr := suite.get("/main.js", "Bearer "+suite.token(suite.validClaims()))
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("alice@example.com", rr.Header().Get("X-User-Email"))
+	suite.Equal(`["admin"]`, rr.Header().Get("X-User-Roles"))
+}
+
+func (suite *BearerTestSuite) Test_Document_claims_Then_injected_into_head() {
+
+	// when
+	rr := suite.get("/", "bearer "+suite.token(suite.validClaims()))
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(`<html><head><meta name="jwt-claims" content="{&#34;name&#34;:&#34;Alice \u003cAdmin\u003e&#34;,&#34;roles&#34;:[&#34;admin&#34;]}"><title>app</title></head></html>`, rr.Body.String())
+	suite.Empty(rr.Header().Get("ETag"))
+}
+
+func (suite *BearerTestSuite) Test_Missing_token_Then_unauthorized() {
+
+	// when
+	rr := suite.get("/", "")
+
+	// then
+	suite.Equal(http.StatusUnauthorized, rr.Code)
+	suite.Equal("Bearer", rr.Header().Get("WWW-Authenticate"))
+}
+
+func (suite *BearerTestSuite) Test_Invalid_token_Then_unauthorized() {
+
+	// given
+	expired := suite.validClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	audience := suite.validClaims()
+	audience["aud"] = "other"
+	tampered := suite.token(suite.validClaims())
+	tampered = tampered[:len(tampered)-4] + "AAAA"
+
+	for _, token := range []string{suite.token(expired), suite.token(audience), tampered, "not-a-jwt"} {
+		// when
+		rr := suite.get("/", "Bearer "+token)
+
+		// then
+		suite.Equal(http.StatusUnauthorized, rr.Code)
+		suite.Equal(`Bearer error="invalid_token"`, rr.Header().Get("WWW-Authenticate"))
+	}
+}
@@ -0,0 +1,124 @@
+package spaserver
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// bundleScheme is the root of a single archive file, e.g. `bundle:///srv/app.zip`
+const bundleScheme = "bundle://"
+
+// bundleExtensions are the extensions of the archives served as the bundle roots
+var bundleExtensions = []string{".zip", ".tar", ".tar.gz", ".tgz", ".squashfs", ".sqsh"}
+
+// bundleArchive returns the path of the archive of the bundle root
+func bundleArchive(rootDir string) (string, error) {
+	archive := strings.TrimPrefix(rootDir, bundleScheme)
+	ext := strings.ToLower(archive)
+	for _, suffix := range bundleExtensions {
+		if strings.HasSuffix(ext, suffix) {
+			return archive, nil
+		}
+	}
+	return "", fmt.Errorf("bundle %v is not a zip or tar archive or a squashfs image", archive)
+}
+
+// zipFS serves the regular files of a zip archive directly from the archive
+type zipFS struct {
+	data    io.ReaderAt
+	entries map[string]*zipEntry
+}
+
+type zipEntry struct {
+	info fs.FileInfo
+	// file is the compressed entry, nil for the stored entries and the directories
+	file   *zip.File
+	offset int64
+}
+
+// openZip indexes the zip archive, the stored entries are read from the
+// archive on demand, the compressed entries are inflated to memory when opened
+func openZip(archive string) (fs.FS, error) {
+	file, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	reader, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	fsys := &zipFS{data: file, entries: map[string]*zipEntry{}}
+	for _, entry := range reader.File {
+		name := rootName(entry.Name)
+		mode := entry.Mode()
+		switch {
+		case mode.IsDir():
+			fsys.entries[name] = &zipEntry{info: entry.FileInfo()}
+		case mode.IsRegular() && entry.Method == zip.Store:
+			offset, err := entry.DataOffset()
+			if err != nil {
+				file.Close()
+				return nil, err
+			}
+			fsys.entries[name] = &zipEntry{info: entry.FileInfo(), offset: offset}
+		case mode.IsRegular():
+			fsys.entries[name] = &zipEntry{info: entry.FileInfo(), file: entry}
+		default:
+			// links and special files are not served
+			continue
+		}
+
+		// implicit parent directories
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if _, ok := fsys.entries[dir]; !ok {
+				fsys.entries[dir] = &zipEntry{info: dirInfo(path.Base(dir))}
+			}
+		}
+	}
+	fsys.entries["."] = &zipEntry{info: dirInfo(".")}
+	return fsys, nil
+}
+
+func (this *zipFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	entry, ok := this.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	switch {
+	case entry.info.IsDir():
+		return newDirFile(entry.info, dirEntries(this.entries, name, func(entry *zipEntry) fs.FileInfo { return entry.info })), nil
+	case entry.file != nil:
+		// the compressed streams cannot be read at random offsets
+		reader, err := entry.file.Open()
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		defer reader.Close()
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+		}
+		return &tarFile{SectionReader: io.NewSectionReader(bytes.NewReader(content), 0, int64(len(content))), info: entry.info}, nil
+	default:
+		return &tarFile{
+			SectionReader: io.NewSectionReader(this.data, entry.offset, entry.info.Size()),
+			info:          entry.info,
+		}, nil
+	}
+}
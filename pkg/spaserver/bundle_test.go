@@ -0,0 +1,172 @@
+package spaserver
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type BundleTestSuite struct {
+	suite.Suite
+	dataDir string
+}
+
+func TestBundleTestSuite(t *testing.T) {
+	suite.Run(t, new(BundleTestSuite))
+}
+
+func (suite *BundleTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	_, filename, _, _ := runtime.Caller(0)
+	suite.dataDir = path.Join(path.Dir(filename), "test/data")
+}
+
+// writeZip packs the test data files into the zip archive, the precompressed
+// files are stored and the others deflated
+func (suite *BundleTestSuite) writeZip(name string) string {
+	archive := path.Join(suite.T().TempDir(), name)
+	file, err := os.Create(archive)
+	suite.Require().Nil(err)
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+	defer zw.Close()
+	_, err = zw.Create("assets/")
+	suite.Require().Nil(err)
+	for _, name := range []string{"index.html", "testfile.json", "prebr.js", "prebr.js.br"} {
+		content, err := os.ReadFile(path.Join(suite.dataDir, name))
+		suite.Require().Nil(err)
+		method := zip.Deflate
+		if path.Ext(name) == ".br" {
+			method = zip.Store
+		}
+		writer, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		suite.Require().Nil(err)
+		_, err = writer.Write(content)
+		suite.Require().Nil(err)
+	}
+	return archive
+}
+
+func (suite *BundleTestSuite) serve(roots []string, requestPath string, acceptEncoding string) *httptest.ResponseRecorder {
+	sut, err := newServer(Config{RootDirs: roots, BaseURL: "/"}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", requestPath, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *BundleTestSuite) Test_Zip_root_deflated_Then_OK_With_Content() {
+
+	// given
+	archive := suite.writeZip("app.zip")
+
+	// when
+	rr := suite.serve([]string{archive}, "/testfile.json", "")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(testfile_json, rr.Body.String())
+}
+
+func (suite *BundleTestSuite) Test_Zip_root_precompressed_Then_stored_sibling_served() {
+
+	// given
+	archive := suite.writeZip("app.zip")
+
+	// when
+	rr := suite.serve([]string{bundleScheme + archive}, "/prebr.js", "br")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("br", rr.Header().Get("Content-Encoding"))
+	suite.Equal(prebr_js_br, rr.Body.String())
+}
+
+func (suite *BundleTestSuite) Test_Zip_root_range_Then_partial_content() {
+
+	// given
+	archive := suite.writeZip("app.zip")
+	sut, err := newServer(Config{RootDirs: []string{bundleScheme + archive}, BaseURL: "/"}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", "/index.html", nil)
+	req.Header.Set("Range", "bytes=1-3")
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusPartialContent, rr.Code)
+	suite.Equal(index_html[1:4], rr.Body.String())
+}
+
+func (suite *BundleTestSuite) Test_Bundle_fallback_Then_OK_With_Index() {
+
+	// given
+	archive := suite.writeZip("app.zip")
+
+	// when
+	rr := suite.serve([]string{bundleScheme + archive}, "/some/route", "")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(index_html, rr.Body.String())
+}
+
+func (suite *BundleTestSuite) Test_Zip_directories_Then_listed() {
+
+	// given
+	fsys, err := openZip(suite.writeZip("app.zip"))
+	suite.Require().Nil(err)
+
+	// when
+	info, err := fs.Stat(fsys, "assets")
+	entries, readErr := fs.ReadDir(fsys, ".")
+
+	// then
+	suite.Nil(err)
+	suite.True(info.IsDir())
+	suite.Nil(readErr)
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	suite.Equal([]string{"assets", "index.html", "prebr.js", "prebr.js.br", "testfile.json"}, names)
+}
+
+func (suite *BundleTestSuite) Test_Bundle_not_archive_Then_invalid() {
+
+	// when
+	_, err := bundleArchive(bundleScheme + suite.dataDir)
+
+	// then
+	suite.ErrorContains(err, "is not a zip or tar archive or a squashfs image")
+}
+
+func (suite *BundleTestSuite) Test_Bundle_missing_Then_roots_check_fails() {
+
+	// given
+	sut := &server{cfg: Config{RootDirs: []string{bundleScheme + "/missing/app.zip"}}, logger: zerolog.New(io.Discard)}
+
+	// when
+	errs := checkRoots(context.Background(), sut)
+
+	// then
+	suite.NotEmpty(errs)
+	suite.ErrorContains(errs[0], "roots[0]")
+}
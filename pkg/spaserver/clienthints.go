@@ -0,0 +1,122 @@
+package spaserver
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// client hints selecting the variants, the Sec-CH- prefixed hints take precedence
+const (
+	hintSaveData           = "Save-Data"
+	hintDpr                = "Sec-CH-DPR"
+	hintLegacyDpr          = "DPR"
+	hintDeviceMemory       = "Sec-CH-Device-Memory"
+	hintLegacyDeviceMemory = "Device-Memory"
+)
+
+// clientHints are the hints used by the configured variants, Save-Data is
+// sent by the browsers without asking
+func (this *server) clientHints() []string {
+	hints := []string{}
+	for _, variant := range this.cfg.ClientHintsVariants {
+		if variant.SaveData && !slices.Contains(hints, hintSaveData) {
+			hints = append(hints, hintSaveData)
+		}
+		if variant.MaxDpr > 0 && !slices.Contains(hints, hintDpr) {
+			hints = append(hints, hintDpr, hintLegacyDpr)
+		}
+		if variant.MaxDeviceMemory > 0 && !slices.Contains(hints, hintDeviceMemory) {
+			hints = append(hints, hintDeviceMemory, hintLegacyDeviceMemory)
+		}
+	}
+	return hints
+}
+
+// applyAcceptClientHints asks the browsers to send the hints used by the variants
+// with the subsequent requests of the document
+func (this *server) applyAcceptClientHints(w http.ResponseWriter, name string) {
+	if len(this.cfg.ClientHintsVariants) == 0 || !isHtml(name) {
+		return
+	}
+	hints := slices.DeleteFunc(this.clientHints(), func(hint string) bool { return hint == hintSaveData })
+	if len(hints) > 0 {
+		w.Header().Set("Accept-CH", strings.Join(hints, ", "))
+	}
+}
+
+// hintValue reads the numeric hint, preferring the Sec-CH- prefixed header
+func hintValue(req *http.Request, hint string, legacy string) (float64, bool) {
+	value := req.Header.Get(hint)
+	if value == "" {
+		value = req.Header.Get(legacy)
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	return number, err == nil
+}
+
+// matches reports whether any of the conditions of the variant is satisfied by the hints
+func (this ClientHintsVariant) matches(req *http.Request) bool {
+	if this.SaveData && strings.EqualFold(strings.TrimSpace(req.Header.Get(hintSaveData)), "on") {
+		return true
+	}
+	if dpr, ok := hintValue(req, hintDpr, hintLegacyDpr); ok && this.MaxDpr > 0 && dpr <= this.MaxDpr {
+		return true
+	}
+	if memory, ok := hintValue(req, hintDeviceMemory, hintLegacyDeviceMemory); ok && this.MaxDeviceMemory > 0 && memory <= this.MaxDeviceMemory {
+		return true
+	}
+	return false
+}
+
+// variantName inserts the suffix of the variant before the extension, e.g.
+// `hero.lite.jpg` for `hero.jpg`
+func variantName(resourcePath string, suffix string) string {
+	ext := path.Ext(resourcePath)
+	return strings.TrimSuffix(resourcePath, ext) + suffix + ext
+}
+
+// indexDocument reports whether the path is the index document, the fallback
+// document or any of their variants
+func (this *server) indexDocument(resourcePath string) bool {
+	for _, document := range append([]string{"/index.html"}, this.fallbackDocuments()...) {
+		if resourcePath == document {
+			return true
+		}
+		for _, variant := range this.cfg.ClientHintsVariants {
+			if resourcePath == variantName(document, variant.Suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findAndServeHinted serves the first existing variant of the file matching the
+// client hints, or the file itself
+func (this *server) findAndServeHinted(ctx context.Context, resourcePath string, w http.ResponseWriter, req *http.Request) (bool, error) {
+	varied := false
+	for i, variant := range this.cfg.ClientHintsVariants {
+		if regex := this.regexes().clientHints[i]; regex != nil && !regex.MatchString(resourcePath) {
+			continue
+		}
+		if !varied {
+			// the response of the path depends on the hints
+			w.Header().Add("Vary", strings.Join(this.clientHints(), ", "))
+			varied = true
+		}
+		if !variant.matches(req) {
+			continue
+		}
+		name := variantName(resourcePath, variant.Suffix)
+		debugLookup(ctx, "client hints variant %v", name)
+		found, err := this.findAndServeEncoded(ctx, name, w, req)
+		if found || err != nil {
+			return found, err
+		}
+	}
+	return this.findAndServeEncoded(ctx, resourcePath, w, req)
+}
@@ -0,0 +1,90 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClientHintsTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestClientHintsTestSuite(t *testing.T) {
+	suite.Run(t, new(ClientHintsTestSuite))
+}
+
+func (suite *ClientHintsTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	for name, content := range map[string]string{
+		"index.html":      "full",
+		"index.lite.html": "lite",
+		"hero.jpg":        "hero",
+		"hero.1x.jpg":     "hero 1x",
+	} {
+		suite.Require().Nil(os.WriteFile(path.Join(rootDir, name), []byte(content), 0644))
+	}
+	sut, err := newServer(Config{
+		RootDirs: []string{rootDir},
+		BaseURL:  "/",
+		ClientHintsVariants: []ClientHintsVariant{
+			{Suffix: ".lite", Regexp: `\.html$`, SaveData: true, MaxDeviceMemory: 1},
+			{Suffix: ".1x", Regexp: `\.jpg$`, MaxDpr: 1},
+		},
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *ClientHintsTestSuite) get(target string, hints map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	for hint, value := range hints {
+		req.Header.Set(hint, value)
+	}
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+	return rr
+}
+
+func (suite *ClientHintsTestSuite) Test_Save_data_Then_lite_document_served() {
+
+	// when
+	rr := suite.get("/some/route", map[string]string{"Save-Data": "on"})
+
+	// then
+	suite.Equal("lite", rr.Body.String())
+	suite.Equal("no-cache", rr.Header().Get("Cache-Control"))
+	suite.Equal("Sec-CH-Device-Memory, Device-Memory, Sec-CH-DPR, DPR", rr.Header().Get("Accept-CH"))
+	suite.Contains(rr.Header().Values("Vary"), "Save-Data, Sec-CH-Device-Memory, Device-Memory, Sec-CH-DPR, DPR")
+}
+
+func (suite *ClientHintsTestSuite) Test_Without_hints_Then_full_document_served() {
+
+	// when
+	rr := suite.get("/", nil)
+
+	// then
+	suite.Equal("full", rr.Body.String())
+}
+
+func (suite *ClientHintsTestSuite) Test_Low_dpr_Then_image_variant_served() {
+
+	// when
+	lowDpr := suite.get("/hero.jpg", map[string]string{"Sec-CH-DPR": "1"})
+	highDpr := suite.get("/hero.jpg", map[string]string{"DPR": "2.5"})
+
+	// then
+	suite.Equal("hero 1x", lowDpr.Body.String())
+	suite.Equal("image/jpeg", lowDpr.Header().Get("Content-Type"))
+	suite.Equal("hero", highDpr.Body.String())
+}
@@ -0,0 +1,75 @@
+package spaserver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+)
+
+// coalescedFile is the result of the lookup shared by the concurrent requests
+type coalescedFile struct {
+	found bool
+	info  fs.FileInfo
+	// root is the index of the root containing the file
+	root int
+	// content is the loaded content of the small files, nil for large files
+	content []byte
+}
+
+// coalescedLookup shares a single lookup among the concurrent requests of the
+// same file, e.g. the burst of requests for the cold files right after a deploy.
+// Files up to the coalesce size are read once and served to all the requests
+// from memory, larger files are opened by each request in the resolved root.
+// The returned file is nil if none of the roots contains the file.
+func (this *server) coalescedLookup(ctx context.Context, roots []assetRoot, name string) (asset, int, error) {
+	// large file opened by the request executing the lookup
+	var opened asset
+	// the variants and versions are served from different roots
+	key := rootSetKey(ctx) + "|" + name
+	result, err, shared := this.lookups.Do(key, func() (any, error) {
+		file, info, root, err := this.lookupFile(ctx, roots, name)
+		if err != nil || file == nil {
+			return coalescedFile{}, err
+		}
+		// the files are not buffered under the critical memory pressure
+		if info.Size() > this.cfg.CoalesceMaxSize || this.memoryLevel.Load() >= memoryCritical {
+			opened = file
+			return coalescedFile{found: true, info: info, root: root}, nil
+		}
+
+		defer file.Close()
+		content, err := io.ReadAll(file)
+		if err != nil {
+			return coalescedFile{}, err
+		}
+		return coalescedFile{found: true, info: info, root: root, content: content}, nil
+	})
+	this.coalesceStats.lookups.Add(1)
+	if shared {
+		this.coalesceStats.coalesced.Add(1)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	coalesced := result.(coalescedFile)
+	switch {
+	case opened != nil:
+		return opened, coalesced.root, nil
+	case !coalesced.found:
+		return nil, 0, nil
+	case coalesced.content != nil:
+		// each request reads the shared content independently
+		return &tarFile{
+			SectionReader: io.NewSectionReader(bytes.NewReader(coalesced.content), 0, int64(len(coalesced.content))),
+			info:          coalesced.info,
+		}, coalesced.root, nil
+	default:
+		file, _, err := this.openFile(ctx, roots[coalesced.root].fsys, name)
+		if err != nil {
+			return nil, 0, err
+		}
+		return file, coalesced.root, nil
+	}
+}
@@ -0,0 +1,93 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type CoalesceTestSuite struct {
+	suite.Suite
+	original func(fs.FS, string) (asset, fs.FileInfo, error)
+}
+
+func TestCoalesceTestSuite(t *testing.T) {
+	suite.Run(t, new(CoalesceTestSuite))
+}
+
+func (suite *CoalesceTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.original = openAndStat
+}
+
+func (suite *CoalesceTestSuite) TearDownTest() {
+	openAndStat = suite.original
+}
+
+// lookupConcurrently looks up the file by the concurrent requests, the first
+// open is blocked for a while so that the other requests join the lookup
+func (suite *CoalesceTestSuite) lookupConcurrently(sut *server, requests int) ([]string, int32) {
+	opens := atomic.Int32{}
+	openAndStat = func(fsys fs.FS, name string) (asset, fs.FileInfo, error) {
+		if opens.Add(1) == 1 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		return suite.original(fsys, name)
+	}
+
+	contents := make([]string, requests)
+	done := sync.WaitGroup{}
+	for i := 0; i < requests; i++ {
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			file, ok, err := sut.findFile(context.Background(), "/testfile.json")
+			if err != nil || !ok {
+				return
+			}
+			defer file.Close()
+			content, _ := io.ReadAll(file)
+			contents[i] = string(content)
+		}(i)
+	}
+	done.Wait()
+	return contents, opens.Load()
+}
+
+func (suite *CoalesceTestSuite) Test_Concurrent_lookups_Then_file_opened_once() {
+
+	// given
+	sut := &server{cfg: Config{RootDirs: []string{"test/data"}, CoalesceMaxSize: 1 << 20}, logger: zerolog.New(os.Stdout)}
+
+	// when
+	contents, opens := suite.lookupConcurrently(sut, 10)
+
+	// then
+	suite.Equal(int32(1), opens)
+	for _, content := range contents {
+		suite.Equal(testfile_json, content)
+	}
+}
+
+func (suite *CoalesceTestSuite) Test_Concurrent_lookups_of_large_file_Then_opened_by_each_request() {
+
+	// given
+	sut := &server{cfg: Config{RootDirs: []string{"test/data"}, CoalesceMaxSize: 1}, logger: zerolog.New(os.Stdout)}
+
+	// when
+	contents, opens := suite.lookupConcurrently(sut, 10)
+
+	// then
+	suite.Equal(int32(10), opens)
+	for _, content := range contents {
+		suite.Equal(testfile_json, content)
+	}
+}
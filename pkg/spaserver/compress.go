@@ -0,0 +1,159 @@
+package spaserver
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// defaultCompressibleTypes are the media types compressed on the fly if not configured
+var defaultCompressibleTypes = []string{
+	"text/html", "text/css", "text/plain", "text/xml", "text/javascript",
+	"application/javascript", "application/json", "application/manifest+json",
+	"application/xml", "application/wasm", "image/svg+xml",
+}
+
+type compressedKey struct{}
+
+// compressedOnTheFly reports whether the response is compressed on the fly
+func compressedOnTheFly(ctx context.Context) bool {
+	compressed, _ := ctx.Value(compressedKey{}).(bool)
+	return compressed
+}
+
+// compressible reports whether the media type of the resource, taken from its extension, is compressible
+func (this *server) compressible(resourcePath string) bool {
+	ctype, _, _ := strings.Cut(mime.TypeByExtension(path.Ext(resourcePath)), ";")
+	types := this.cfg.DynamicCompressionTypes
+	if len(types) == 0 {
+		types = defaultCompressibleTypes
+	}
+	for _, media := range types {
+		if strings.EqualFold(strings.TrimSpace(ctype), media) {
+			return true
+		}
+	}
+	return false
+}
+
+// dynamicEncoding selects the encoding compressing the resource on the fly, empty
+// if the compression is disabled, no enabled encoding is accepted or the media
+// type of the resource is not compressible
+func (this *server) dynamicEncoding(req *http.Request, resourcePath string) string {
+	if !this.cfg.DynamicCompression || !this.compressible(resourcePath) {
+		return ""
+	}
+	encodings := []string{}
+	if !this.cfg.BrotliDisabled {
+		encodings = append(encodings, "br")
+	}
+	if !this.cfg.GzipDisabled {
+		encodings = append(encodings, "gzip")
+	}
+	if accepted := acceptedEncodings(req, encodings); len(accepted) > 0 {
+		return accepted[0]
+	}
+	return ""
+}
+
+// findAndServeCompressed serves the resource compressed on the fly with the encoding.
+// The compressed representation has its own ETag, but no byte ranges.
+func (this *server) findAndServeCompressed(ctx context.Context, resourcePath string, encoding string, w http.ResponseWriter, req *http.Request) (bool, error) {
+	debugLookup(ctx, "compressed on the fly %v", encoding)
+	ctx = context.WithValue(ctx, compressedKey{}, true)
+	w.Header().Set("Content-Encoding", encoding)
+	writer := &compressWriter{ResponseWriter: w, encoding: encoding, cfg: this.cfg}
+	full := req
+	if req.Header.Get("Range") != "" {
+		full = req.Clone(req.Context())
+		full.Header.Del("Range")
+	}
+	found, err := this.findAndServe(ctx, resourcePath, writer, full)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if !found {
+		w.Header().Del("Content-Encoding")
+	}
+	if writer.compressing {
+		telemetry().dynamically_compressed.Add(ctx, 1,
+			metric.WithAttributes(append(this.metricPathAttributes(req.URL.Path), attribute.String("encoding", encoding))...))
+	}
+	return found, err
+}
+
+// compressWriter compresses the successful responses not smaller than the
+// minimal size, the other responses are written as is
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	cfg         Config
+	compressor  io.WriteCloser
+	compressing bool
+	started     bool
+}
+
+func (this *compressWriter) WriteHeader(status int) {
+	if informational(status) {
+		this.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if this.started {
+		return
+	}
+	this.started = true
+	if status == http.StatusOK {
+		length, err := strconv.ParseInt(this.Header().Get("Content-Length"), 10, 64)
+		if err == nil && length < this.cfg.DynamicCompressionMinSize {
+			// not worth the compression overhead
+			this.Header().Del("Content-Encoding")
+		} else {
+			this.Header().Del("Content-Length")
+			this.compressing = true
+		}
+	}
+	this.ResponseWriter.WriteHeader(status)
+}
+
+func (this *compressWriter) newCompressor() io.WriteCloser {
+	if this.encoding == "br" {
+		return brotli.NewWriterLevel(this.ResponseWriter, this.cfg.DynamicBrotliLevel)
+	}
+	level := this.cfg.DynamicGzipLevel
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	compressor, _ := gzip.NewWriterLevel(this.ResponseWriter, level)
+	return compressor
+}
+
+func (this *compressWriter) Write(content []byte) (int, error) {
+	if !this.started {
+		this.WriteHeader(http.StatusOK)
+	}
+	if !this.compressing {
+		return this.ResponseWriter.Write(content)
+	}
+	if this.compressor == nil {
+		// the compressor is created by the first write, the HEAD responses have no body
+		this.compressor = this.newCompressor()
+	}
+	return this.compressor.Write(content)
+}
+
+// Close flushes the compressed response
+func (this *compressWriter) Close() error {
+	if this.compressor == nil {
+		return nil
+	}
+	return this.compressor.Close()
+}
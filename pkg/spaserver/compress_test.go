@@ -0,0 +1,177 @@
+package spaserver
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type CompressTestSuite struct {
+	suite.Suite
+	rootDir string
+	cfg     Config
+	content string
+}
+
+func TestCompressTestSuite(t *testing.T) {
+	suite.Run(t, new(CompressTestSuite))
+}
+
+func (suite *CompressTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.content = strings.Repeat("console.log('spa');\n", 100)
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "main.js"), []byte(suite.content), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "small.js"), []byte("small"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "logo.png"), []byte(suite.content), 0644))
+	suite.cfg = Config{
+		RootDirs:                  []string{suite.rootDir},
+		BaseURL:                   "/",
+		Etag:                      etagStrong,
+		DynamicCompression:        true,
+		DynamicCompressionMinSize: 1024,
+		DynamicBrotliLevel:        5,
+	}
+}
+
+func (suite *CompressTestSuite) get(target string, headers map[string]string) *httptest.ResponseRecorder {
+	sut, err := newServer(suite.cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", target, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *CompressTestSuite) Test_Gzip_accepted_Then_compressed_on_the_fly() {
+
+	// when
+	rr := suite.get("/main.js", map[string]string{"Accept-Encoding": "gzip"})
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("gzip", rr.Header().Get("Content-Encoding"))
+	suite.Empty(rr.Header().Get("Content-Length"))
+	suite.Contains(rr.Header().Values("Vary"), "Accept-Encoding")
+	suite.Regexp(`^"[0-9a-f]+-gzip"$`, rr.Header().Get("ETag"))
+	reader, err := gzip.NewReader(rr.Body)
+	suite.Require().Nil(err)
+	content, err := io.ReadAll(reader)
+	suite.Nil(err)
+	suite.Equal(suite.content, string(content))
+}
+
+func (suite *CompressTestSuite) Test_Brotli_accepted_Then_brotli_preferred() {
+
+	// when
+	rr := suite.get("/main.js", map[string]string{"Accept-Encoding": "gzip, br"})
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("br", rr.Header().Get("Content-Encoding"))
+	content, err := io.ReadAll(brotli.NewReader(rr.Body))
+	suite.Nil(err)
+	suite.Equal(suite.content, string(content))
+}
+
+func (suite *CompressTestSuite) Test_Small_file_Then_not_compressed() {
+
+	// when
+	rr := suite.get("/small.js", map[string]string{"Accept-Encoding": "gzip"})
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Empty(rr.Header().Get("Content-Encoding"))
+	suite.Equal("5", rr.Header().Get("Content-Length"))
+	suite.Equal("small", rr.Body.String())
+}
+
+func (suite *CompressTestSuite) Test_Type_not_compressible_Then_not_compressed() {
+
+	// when
+	rr := suite.get("/logo.png", map[string]string{"Accept-Encoding": "gzip"})
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Empty(rr.Header().Get("Content-Encoding"))
+	suite.Equal(suite.content, rr.Body.String())
+}
+
+func (suite *CompressTestSuite) Test_Disabled_Then_not_compressed() {
+
+	// given
+	suite.cfg.DynamicCompression = false
+
+	// when
+	rr := suite.get("/main.js", map[string]string{"Accept-Encoding": "gzip"})
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Empty(rr.Header().Get("Content-Encoding"))
+	suite.Equal(suite.content, rr.Body.String())
+}
+
+func (suite *CompressTestSuite) Test_Precompressed_variant_Then_served_instead() {
+
+	// given
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "main.js.gz"), []byte("precompressed"), 0644))
+
+	// when
+	rr := suite.get("/main.js", map[string]string{"Accept-Encoding": "gzip"})
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("gzip", rr.Header().Get("Content-Encoding"))
+	suite.Equal("precompressed", rr.Body.String())
+}
+
+func (suite *CompressTestSuite) Test_Matching_etag_Then_not_modified() {
+
+	// given
+	etag := suite.get("/main.js", map[string]string{"Accept-Encoding": "gzip"}).Header().Get("ETag")
+
+	// when
+	rr := suite.get("/main.js", map[string]string{"Accept-Encoding": "gzip", "If-None-Match": etag})
+
+	// then
+	suite.Equal(http.StatusNotModified, rr.Code)
+	suite.Empty(rr.Body.String())
+}
+
+func (suite *CompressTestSuite) Test_Range_Then_full_compressed_content() {
+
+	// when
+	rr := suite.get("/main.js", map[string]string{"Accept-Encoding": "gzip", "Range": "bytes=0-9"})
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("gzip", rr.Header().Get("Content-Encoding"))
+	suite.Empty(rr.Header().Get("Content-Range"))
+}
+
+func (suite *CompressTestSuite) Test_Invalid_level_Then_invalid() {
+
+	// given
+	suite.cfg.DynamicGzipLevel = 10
+	suite.cfg.DynamicBrotliLevel = 12
+
+	// when
+	_, err := newServer(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "dynamic-gzip-level")
+	suite.ErrorContains(err, "dynamic-brotli-level")
+}
@@ -0,0 +1,1184 @@
+package spaserver
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// defaultCrawlerUserAgents matches the user agents of the common search engine and link preview crawlers
+const defaultCrawlerUserAgents = `(?i)(googlebot|bingbot|yandex|baiduspider|duckduckbot|slurp|applebot|facebookexternalhit|twitterbot|linkedinbot|slackbot|discordbot|telegrambot|whatsapp|pinterest|embedly)`
+
+type Config struct {
+	// Port is the port to listen on.
+	Port int `mapstructure:"port"`
+
+	// TlsCertFile is the PEM certificate chain of the server, TLS disabled if empty.
+	TlsCertFile string `mapstructure:"tls-cert-file"`
+
+	// TlsKeyFile is the PEM private key of the certificate.
+	TlsKeyFile string `mapstructure:"tls-key-file"`
+
+	// TlsReloadInterval is the interval of checking the certificate files for the renewed certificate, disabled if zero.
+	TlsReloadInterval time.Duration `mapstructure:"tls-reload-interval"`
+
+	// AcmeHosts are the hosts of the certificates obtained automatically from the ACME directory, disabled if empty.
+	AcmeHosts []string `mapstructure:"acme-hosts"`
+
+	// AcmeCacheDir is the directory keeping the obtained certificates across restarts, in memory only if empty.
+	AcmeCacheDir string `mapstructure:"acme-cache-dir"`
+
+	// AcmeEmail is the contact email of the ACME account.
+	AcmeEmail string `mapstructure:"acme-email"`
+
+	// AcmeDirectoryUrl is the url of the ACME directory, Let's Encrypt if empty.
+	AcmeDirectoryUrl string `mapstructure:"acme-directory-url"`
+
+	// AcmeHttpPort is the plain HTTP port serving the HTTP-01 challenges and redirecting to HTTPS, disabled if zero.
+	AcmeHttpPort int `mapstructure:"acme-http-port"`
+
+	// TlsMinVersion is the minimal TLS version, e.g. 1.2.
+	TlsMinVersion string `mapstructure:"tls-min-version"`
+
+	// TlsMaxVersion is the maximal TLS version, the latest supported if empty.
+	TlsMaxVersion string `mapstructure:"tls-max-version"`
+
+	// TlsCipherSuites are the IANA names of the enabled TLS 1.2 cipher suites, the Go defaults if empty.
+	TlsCipherSuites []string `mapstructure:"tls-cipher-suites"`
+
+	// TlsCurvePreferences are the elliptic curves of the key exchange in the order of preference.
+	TlsCurvePreferences []string `mapstructure:"tls-curve-preferences"`
+
+	// H2C accepts the cleartext HTTP/2 on the main listener, e.g. behind a service mesh sidecar terminating mTLS.
+	H2C bool `mapstructure:"h2c"`
+
+	// Http3 serves the experimental HTTP/3 on the UDP port of the same number, requires TLS.
+	Http3 bool `mapstructure:"http3"`
+
+	// Http3AdvertisedPort is the UDP port advertised with Alt-Svc, e.g. when the port is mapped, the listening port if zero.
+	Http3AdvertisedPort int `mapstructure:"http3-advertised-port"`
+
+	// ReadHeaderTimeout is the time to read the request headers, unbounded if zero.
+	ReadHeaderTimeout time.Duration `mapstructure:"read-header-timeout"`
+
+	// ReadTimeout is the time to read the whole request including the body, unbounded if zero.
+	ReadTimeout time.Duration `mapstructure:"read-timeout"`
+
+	// WriteTimeout is the time to write the response, unbounded if zero, e.g. for the long downloads.
+	WriteTimeout time.Duration `mapstructure:"write-timeout"`
+
+	// IdleTimeout is the time the keep-alive connections wait for the next request, the read timeout if zero.
+	IdleTimeout time.Duration `mapstructure:"idle-timeout"`
+
+	// MaxHeaderBytes is the maximal size of the request headers.
+	MaxHeaderBytes int `mapstructure:"max-header-bytes"`
+
+	// ReusePortListeners is the number of listeners sharing the port with SO_REUSEPORT, disabled if zero, one per CPU if negative.
+	ReusePortListeners int `mapstructure:"reuse-port-listeners"`
+
+	// AdminPort is the port of the admin endpoints, e.g. /status, disabled if zero.
+	AdminPort int `mapstructure:"admin-port"`
+
+	// AdminToken is the bearer token required by the admin endpoints, the maintenance and drain endpoints are disabled if empty.
+	AdminToken string `mapstructure:"admin-token" secret:"true"`
+
+	// MaintenancePage is the path of the page within the root served in the maintenance mode.
+	MaintenancePage string `mapstructure:"maintenance-page"`
+
+	// HarMaxDuration is the maximal duration of the HAR capture started through the admin API.
+	HarMaxDuration time.Duration `mapstructure:"har-max-duration"`
+
+	// HarMaxEntries is the maximal count of the requests recorded by the HAR capture.
+	HarMaxEntries int `mapstructure:"har-max-entries"`
+
+	// HarMaxBodySize is the maximal size of the response body recorded by the HAR capture.
+	HarMaxBodySize int `mapstructure:"har-max-body-size"`
+
+	// ShutdownTimeout is the grace period of the requests in flight on shutdown, unbounded if zero.
+	ShutdownTimeout time.Duration `mapstructure:"shutdown-timeout"`
+
+	// ShutdownDrainDelay is the period of the failing readiness before the shutdown, so that the load balancers stop the traffic.
+	ShutdownDrainDelay time.Duration `mapstructure:"shutdown-drain-delay"`
+
+	// MaxInflightRequests is the limit of the requests served concurrently, the requests beyond it are refused, unlimited if zero.
+	MaxInflightRequests int `mapstructure:"max-inflight-requests"`
+
+	// OverloadRetryAfter is the delay announced to the requests refused beyond the in-flight limit.
+	OverloadRetryAfter time.Duration `mapstructure:"overload-retry-after"`
+
+	// OverloadQueueTimeout is the time the requests beyond the in-flight limit wait for a slot, refused right away if zero.
+	OverloadQueueTimeout time.Duration `mapstructure:"overload-queue-timeout"`
+
+	// RateLimit is the rate of the requests of each client per second, the requests beyond it are refused, unlimited if zero.
+	RateLimit float64 `mapstructure:"rate-limit"`
+
+	// RateLimitBurst is the count of the requests of each client allowed at once above the rate.
+	RateLimitBurst int `mapstructure:"rate-limit-burst"`
+
+	// RateLimitHeader is the request header of the trusted proxies identifying the client instead of its address, e.g. the API key.
+	RateLimitHeader string `mapstructure:"rate-limit-header"`
+
+	// OfflinePage is the html file served while none of the roots is readable, a built-in page if empty.
+	OfflinePage string `mapstructure:"offline-page"`
+
+	// OfflineRetryAfter is the delay announced by the offline page before the retry.
+	OfflineRetryAfter time.Duration `mapstructure:"offline-retry-after"`
+
+	// OfflineCheckInterval is the interval of checking the availability of the roots.
+	OfflineCheckInterval time.Duration `mapstructure:"offline-check-interval"`
+
+	// DebugHeader enables the X-Debug-Lookup response header describing the lookup decisions
+	// to the requests with the X-Debug header, matching the admin token if configured.
+	DebugHeader bool `mapstructure:"debug-header"`
+
+	// DebugEndpoints serves the pprof, expvar and logging level endpoints on the admin port.
+	DebugEndpoints bool `mapstructure:"debug-endpoints"`
+
+	// DebugEndpointsInsecure serves the pprof and expvar endpoints without the admin token.
+	DebugEndpointsInsecure bool `mapstructure:"debug-endpoints-insecure"`
+
+	// LoggingLevel is the logging level.
+	LoggingLevel string `mapstructure:"logging-level"`
+
+	// JsonLogging is whether to log in json format.
+	JsonLogging bool `mapstructure:"json-logging"`
+
+	// Sites are the sites selected by the host of the request, keyed by the hostname with the `*` wildcards,
+	// each overriding the configuration keys of the default site serving the other hosts.
+	Sites map[string]map[string]any `mapstructure:"sites"`
+
+	// ConfigFiles are the configuration files, each merged over the previous ones, config/spa-base.* if empty.
+	ConfigFiles []string `mapstructure:"config-files"`
+
+	// ConfigWatch reloads the configuration when the configuration file changes.
+	ConfigWatch bool `mapstructure:"config-watch"`
+
+	// BaseURL is the base url to use for the server.
+	// All file paths will be resolved as if relative to BaseURL.
+	BaseURL string `mapstructure:"base-url"`
+
+	// If enabled and request path does not starts with base url
+	// then the file will be searched in using the request path as is.
+	AllowSkipBaseUrl bool `mapstructure:"allow-skip-base-url"`
+
+	// TrustedProxies are the addresses or CIDR ranges of the proxies whose X-Forwarded-Prefix, X-Forwarded-For and X-Real-IP are honored.
+	TrustedProxies []string `mapstructure:"trusted-proxies"`
+
+	// AllowedCidrs are the addresses or CIDR ranges of the clients allowed, all the clients if empty.
+	AllowedCidrs []string `mapstructure:"allowed-cidrs"`
+
+	// DeniedCidrs are the addresses or CIDR ranges of the clients denied, even if allowed.
+	DeniedCidrs []string `mapstructure:"denied-cidrs"`
+
+	// StripPrefixes are the additional prefixes stripped from the request path like the base url,
+	// the longest matching prefix is stripped.
+	StripPrefixes []string `mapstructure:"strip-prefixes"`
+
+	// BaseHref sets the href of the base tag of the html documents to the base url, injecting the tag if missing.
+	BaseHref bool `mapstructure:"base-href"`
+
+	// RewriteAbsoluteUrls prefixes the root-absolute urls in the html and css files with the base url.
+	RewriteAbsoluteUrls bool `mapstructure:"rewrite-absolute-urls"`
+
+	// DefaultCacheControl is the Cache-Control of the responses without one, except the index documents,
+	// empty to cache the hashed assets as immutable and the unhashed ones with the UnhashedCacheControl.
+	DefaultCacheControl string `mapstructure:"default-cache-control"`
+
+	// HashedFileRegex is the regexp of the content hashed file names cached as immutable.
+	HashedFileRegex string `mapstructure:"hashed-file-regexp"`
+
+	// UnhashedCacheControl is the Cache-Control of the assets without the content hash in their names.
+	UnhashedCacheControl string `mapstructure:"unhashed-cache-control"`
+
+	// IndexCacheControl is the Cache-Control of the index documents without one.
+	IndexCacheControl string `mapstructure:"index-cache-control"`
+
+	// DefaultCacheControlDisabled sends no Cache-Control unless configured by the headers.
+	DefaultCacheControlDisabled bool `mapstructure:"default-cache-control-disabled"`
+
+	// CacheBustParams are the query parameters marking the versioned urls cached as immutable.
+	CacheBustParams []string `mapstructure:"cache-bust-params"`
+
+	// SecurityHeaders is the preset of the security headers: strict, basic or off, overridden by the configured headers.
+	SecurityHeaders string `mapstructure:"security-headers"`
+
+	// Etag is the ETag strategy of the responses: strong content hash, weak mtime-size, or none.
+	Etag string `mapstructure:"etag"`
+
+	// BandwidthLimit is the bandwidth of each response in bytes per second, unlimited if zero.
+	BandwidthLimit int64 `mapstructure:"bandwidth-limit"`
+
+	// BandwidthLimitPerRegex overrides the bandwidth limit of the paths matching the regexp, unlimited if zero.
+	BandwidthLimitPerRegex map[string]int64 `mapstructure:"bandwidth-limit-per-regexp"`
+
+	// BandwidthLimitBurst is the size of the beginning of the responses sent without the bandwidth limit.
+	BandwidthLimitBurst int64 `mapstructure:"bandwidth-limit-burst"`
+
+	// EtagPerPathRegex overrides the ETag strategy of the paths matching the regexp.
+	EtagPerPathRegex map[string]string `mapstructure:"etag-per-regexp"`
+
+	// LastModified is the build timestamp overriding the modification times of the files, RFC 3339 or unix seconds.
+	LastModified string `mapstructure:"last-modified"`
+
+	// LastModifiedFile is the path of the file within the root holding the build timestamp.
+	LastModifiedFile string `mapstructure:"last-modified-file"`
+
+	// ContentSecurityPolicy is the Content-Security-Policy header of the html responses, disabled if empty.
+	ContentSecurityPolicy string `mapstructure:"content-security-policy"`
+
+	// ContentSecurityPolicyReportOnly is the Content-Security-Policy-Report-Only header
+	// of the html responses, disabled if empty.
+	ContentSecurityPolicyReportOnly string `mapstructure:"content-security-policy-report-only"`
+
+	// CspInlineHashes adds the hashes of the inline scripts and styles of the documents to the policy.
+	CspInlineHashes bool `mapstructure:"csp-inline-hashes"`
+
+	// CspNonce injects the nonce generated for each response into the scripts and styles of the html documents
+	// and adds it to the policy, a strict policy if none is configured.
+	CspNonce bool `mapstructure:"csp-nonce"`
+
+	// SriEnabled injects the subresource integrity of the scripts and stylesheets into the html documents.
+	SriEnabled bool `mapstructure:"sri-enabled"`
+
+	// SriCrossOrigin is the crossorigin attribute added to the scripts and stylesheets with the injected integrity.
+	SriCrossOrigin string `mapstructure:"sri-crossorigin"`
+
+	// RedirectToBaseUrl redirects the requests not matching the base url to the base url instead of not found.
+	RedirectToBaseUrl bool `mapstructure:"redirect-to-base-url"`
+
+	// RootDirs is the list of root directories to search for resources.
+	RootDirs []string `mapstructure:"roots"`
+
+	// Headers is the map of headers to add to responses.
+	Headers map[string]string `mapstructure:"headers"`
+
+	// HeadersPerPathRegex is the map of headers per path regex to add to responses.
+	HeadersPerPathRegex map[string]map[string]string `mapstructure:"headers-per-regexp"`
+
+	// NotFoundRegexs is the list of path regexs to return 404 instead of fallback html.
+	NotFoundRegexs []string `mapstructure:"no-fallback-regexp"`
+
+	// wheter to disable fallback to index.html
+	FallbackDisabled bool `mapstructure:"fallback-disabled"`
+
+	// ErrorPages are the paths of the pages within the root served instead of the plain text errors by the status, e.g. `404: /404.html`.
+	ErrorPages map[string]string `mapstructure:"error-pages"`
+
+	// FallbackAcceptTypes are the media ranges of the Accept header qualifying for the fallback, text/html if empty.
+	FallbackAcceptTypes []string `mapstructure:"fallback-accept-types"`
+
+	// FallbackDocument is the path of the document served as the fallback, index.html if empty.
+	FallbackDocument string `mapstructure:"fallback-document"`
+
+	// FallbackRoutes are the fallback documents of the applications mounted under the path prefixes.
+	FallbackRoutes []FallbackRoute `mapstructure:"fallback-routes"`
+
+	// FallbackLanguages are the fallback documents of the languages selected by the Accept-Language, e.g. `de: de/index.html`.
+	FallbackLanguages map[string]string `mapstructure:"fallback-languages"`
+
+	// gzip encoding disabled
+	GzipDisabled bool `mapstructure:"gzip-disabled"`
+
+	// brotli encoding disabled
+	BrotliDisabled bool `mapstructure:"brotli-disabled"`
+
+	// zstd encoding disabled
+	ZstdDisabled bool `mapstructure:"zstd-disabled"`
+
+	// PrecompressOnStart generates the missing precompressed variants of the directory roots when opened.
+	PrecompressOnStart bool `mapstructure:"precompress-on-start"`
+
+	// PrecompressCacheDir is the directory of the generated variants, next to the files if empty.
+	PrecompressCacheDir string `mapstructure:"precompress-cache-dir"`
+
+	// DynamicCompression compresses the resources without the precompressed variant on the fly.
+	DynamicCompression bool `mapstructure:"dynamic-compression"`
+
+	// DynamicCompressionMinSize is the minimal size of the resources compressed on the fly in bytes.
+	DynamicCompressionMinSize int64 `mapstructure:"dynamic-compression-min-size"`
+
+	// DynamicCompressionTypes are the media types compressed on the fly, the common text types if empty.
+	DynamicCompressionTypes []string `mapstructure:"dynamic-compression-types"`
+
+	// DynamicGzipLevel is the gzip level of the compression on the fly, 1 to 9, the default level if zero.
+	DynamicGzipLevel int `mapstructure:"dynamic-gzip-level"`
+
+	// DynamicBrotliLevel is the brotli level of the compression on the fly, 0 to 11.
+	DynamicBrotliLevel int `mapstructure:"dynamic-brotli-level"`
+
+	// telemetry disabled
+	TelemetryDisabled bool `mapstructure:"telemetry-disabled"`
+
+	// MetricsPathLabel is the mode of the path attribute attached to metrics,
+	// one of raw, template, prefix or none.
+	MetricsPathLabel string `mapstructure:"metrics-path-label"`
+
+	// MetricsPathPrefixDepth is the number of leading path segments kept by the prefix mode.
+	MetricsPathPrefixDepth int `mapstructure:"metrics-path-prefix-depth"`
+
+	// MetricsPathTemplates is the list of path templates applied by the template mode.
+	MetricsPathTemplates []PathTemplate `mapstructure:"metrics-path-templates"`
+
+	// MetricViews is the list of views adapting the metric instruments.
+	MetricViews []MetricView `mapstructure:"metric-views"`
+
+	// TraceSampler is the sampler of the traces, SDK default is used if empty.
+	TraceSampler string `mapstructure:"trace-sampler"`
+
+	// TraceSamplerRatio is the ratio of sampled traces for the ratio based samplers.
+	TraceSamplerRatio float64 `mapstructure:"trace-sampler-ratio"`
+
+	// TraceBatchQueueSize is the maximum number of spans queued for the export.
+	TraceBatchQueueSize int `mapstructure:"trace-batch-queue-size"`
+
+	// TraceBatchSize is the maximum number of spans exported in one batch.
+	TraceBatchSize int `mapstructure:"trace-batch-size"`
+
+	// TraceBatchTimeout is the maximum delay between two consecutive exports.
+	TraceBatchTimeout time.Duration `mapstructure:"trace-batch-timeout"`
+
+	// TraceExportTimeout is the maximum duration of one export.
+	TraceExportTimeout time.Duration `mapstructure:"trace-export-timeout"`
+
+	// TraceExcludeRegexs is the list of path regexs excluded from tracing.
+	TraceExcludeRegexs []string `mapstructure:"trace-exclude-regexp"`
+
+	// TraceResponseHeader is the header of the trace id of the response: x-trace-id or traceresponse, none if empty.
+	TraceResponseHeader string `mapstructure:"trace-response-header"`
+
+	// ServerTiming enables the Server-Timing header with the durations of the request phases.
+	ServerTiming bool `mapstructure:"server-timing"`
+
+	// KubernetesDetectionDisabled disables the detection of kubernetes resource attributes.
+	KubernetesDetectionDisabled bool `mapstructure:"kubernetes-detection-disabled"`
+
+	// LogExcludeRegexs is the list of path regexs excluded from access and info logging.
+	LogExcludeRegexs []string `mapstructure:"log-exclude-regexp"`
+
+	// AccessLog is the sink of the access log: stdout, stderr or the file path, disabled if empty.
+	AccessLog string `mapstructure:"access-log"`
+
+	// AccessLogFormat is the format of the access log: json or combined.
+	AccessLogFormat string `mapstructure:"access-log-format"`
+
+	// AccessLogSampling are the ratios of the successful requests logged by the path regexps, e.g. of the hashed assets.
+	AccessLogSampling []AccessLogSample `mapstructure:"access-log-sampling"`
+
+	// SymlinkPolicy is the policy of the symlinks within the directory roots: follow, within-root or deny.
+	SymlinkPolicy string `mapstructure:"symlink-policy"`
+
+	// FsRetryAttempts is the number of retries of transient filesystem errors.
+	FsRetryAttempts int `mapstructure:"fs-retry-attempts"`
+
+	// FsRetryBackoff is the delay before the first retry, doubled with each attempt.
+	FsRetryBackoff time.Duration `mapstructure:"fs-retry-backoff"`
+
+	// FileCacheTtl is the time the infos of the files and the missing files are cached, disabled if zero.
+	FileCacheTtl time.Duration `mapstructure:"file-cache-ttl"`
+
+	// FileCacheMaxEntries is the number of the cached file infos, the cache is cleared when full.
+	FileCacheMaxEntries int `mapstructure:"file-cache-max-entries"`
+
+	// OciCacheDir is the directory of the pulled OCI bundles.
+	OciCacheDir string `mapstructure:"oci-cache-dir"`
+
+	// OciUsername is the username of the OCI registry.
+	OciUsername string `mapstructure:"oci-username"`
+
+	// OciPassword is the password or token of the OCI registry.
+	OciPassword string `mapstructure:"oci-password" secret:"true"`
+
+	// OciInsecure enables plain http access to the OCI registry.
+	OciInsecure bool `mapstructure:"oci-insecure"`
+
+	// OciPullOnDemand defers pulling the OCI bundles to the first request.
+	OciPullOnDemand bool `mapstructure:"oci-pull-on-demand"`
+
+	// GitCacheDir is the directory of the git repositories and their checkouts.
+	GitCacheDir string `mapstructure:"git-cache-dir"`
+
+	// GitBinary is the git executable.
+	GitBinary string `mapstructure:"git-binary"`
+
+	// S3Endpoint is the url of the S3 compatible object storage, the AWS endpoint of the region if empty.
+	S3Endpoint string `mapstructure:"s3-endpoint"`
+
+	// S3Region is the region of the buckets.
+	S3Region string `mapstructure:"s3-region"`
+
+	// S3AccessKeyId is the access key of the object storage, AWS_ACCESS_KEY_ID if empty, anonymous access if none.
+	S3AccessKeyId string `mapstructure:"s3-access-key-id"`
+
+	// S3SecretAccessKey is the secret key of the object storage, AWS_SECRET_ACCESS_KEY if empty.
+	S3SecretAccessKey string `mapstructure:"s3-secret-access-key" secret:"true"`
+
+	// S3SessionToken is the session token of the temporary credentials, AWS_SESSION_TOKEN if empty.
+	S3SessionToken string `mapstructure:"s3-session-token" secret:"true"`
+
+	// S3PathStyle addresses the buckets in the path instead of the host name, e.g. for MinIO.
+	S3PathStyle bool `mapstructure:"s3-path-style"`
+
+	// S3CacheDir is the directory of the objects downloaded from the buckets.
+	S3CacheDir string `mapstructure:"s3-cache-dir"`
+
+	// ReadyChecks are the readiness checks in addition to the drain mode and the roots availability.
+	ReadyChecks []string `mapstructure:"ready-checks"`
+
+	// ReadyMaxSyncAge is the maximal time since the last sync of the remote roots checked by the readiness, three sync intervals if zero.
+	ReadyMaxSyncAge time.Duration `mapstructure:"ready-max-sync-age"`
+
+	// SyncInterval is the interval of refreshing the remote roots, disabled if zero.
+	SyncInterval time.Duration `mapstructure:"sync-interval"`
+
+	// SyncTimeout bounds each request of the remote roots, including the download of the archive, unbounded if zero.
+	SyncTimeout time.Duration `mapstructure:"sync-timeout"`
+
+	// SyncChecksums verifies the archives of the http(s) roots against the SHA-256 checksums of the `<url>.sha256` files.
+	SyncChecksums bool `mapstructure:"sync-checksums"`
+
+	// RevisionHeader is the response header with the revision of the remote root serving the file, none if empty.
+	RevisionHeader string `mapstructure:"revision-header"`
+
+	// ReleasePointerInterval is the interval of checking the local roots being symlinks for a flipped release, disabled if zero.
+	ReleasePointerInterval time.Duration `mapstructure:"release-pointer-interval"`
+
+	// SyncCacheDir is the directory of the archives downloaded from http(s) roots.
+	SyncCacheDir string `mapstructure:"sync-cache-dir"`
+
+	// IntegrityManifest is the name of the checksums manifest within the roots, disabled if empty.
+	IntegrityManifest string `mapstructure:"integrity-manifest"`
+
+	// IntegrityMode is either enforce to refuse serving roots failing the verification, or warn.
+	IntegrityMode string `mapstructure:"integrity-mode"`
+
+	// SignaturePublicKeys are the PEM files of the public keys trusted to sign the remote bundles, disabled if empty.
+	SignaturePublicKeys []string `mapstructure:"signature-public-keys"`
+
+	// SignatureIdentities are the certificate identities, i.e. the emails or the URIs, trusted to sign the remote bundles keyless, disabled if empty.
+	SignatureIdentities []string `mapstructure:"signature-identities"`
+
+	// SignatureOidcIssuer is the OIDC issuer that authenticated the signature identities.
+	SignatureOidcIssuer string `mapstructure:"signature-oidc-issuer"`
+
+	// SignatureCertificateRoots are the PEM files of the root and the intermediate certificates of the CA issuing the identity certificates.
+	SignatureCertificateRoots []string `mapstructure:"signature-certificate-roots"`
+
+	// SignatureTlogPublicKeys are the PEM files of the public keys of the transparency logs recording the keyless signatures.
+	SignatureTlogPublicKeys []string `mapstructure:"signature-tlog-public-keys"`
+
+	// SnapshotEnabled loads the roots to memory, the requests are served without filesystem access.
+	SnapshotEnabled bool `mapstructure:"snapshot-enabled"`
+
+	// CoalesceMaxSize is the maximal size of the files read once for the concurrent requests, coalescing disabled if zero.
+	CoalesceMaxSize int64 `mapstructure:"coalesce-max-size"`
+
+	// ProfilingUrl is the url of the Pyroscope compatible server receiving the profiles, profiling disabled if empty.
+	ProfilingUrl string `mapstructure:"profiling-url"`
+
+	// ProfilingAppName is the application name of the pushed profiles.
+	ProfilingAppName string `mapstructure:"profiling-app-name"`
+
+	// ProfilingLabels are the labels attached to the pushed profiles.
+	ProfilingLabels map[string]string `mapstructure:"profiling-labels"`
+
+	// ProfilingInterval is the period covered by each pushed profile.
+	ProfilingInterval time.Duration `mapstructure:"profiling-interval"`
+
+	// ProfilingTypes are the pushed profiles, cpu or any of the runtime/pprof profiles.
+	ProfilingTypes []string `mapstructure:"profiling-types"`
+
+	// PrometheusMetrics serves the metrics as the Prometheus scrape endpoint /metrics on the admin port.
+	PrometheusMetrics bool `mapstructure:"prometheus-metrics"`
+
+	// RuntimeMetricsDisabled disables the export of the Go runtime and process metrics.
+	RuntimeMetricsDisabled bool `mapstructure:"runtime-metrics-disabled"`
+
+	// RuntimeMetricsInterval is the minimal interval of reading the memory statistics.
+	RuntimeMetricsInterval time.Duration `mapstructure:"runtime-metrics-interval"`
+
+	// ScheduledRoots replace the roots at the scheduled activation time.
+	ScheduledRoots []string `mapstructure:"scheduled-roots"`
+
+	// ScheduledActivation is the RFC 3339 time of the switch to the scheduled roots.
+	ScheduledActivation string `mapstructure:"scheduled-activation"`
+
+	// RolloutRoots serve the percentage of the sessions, the rollout is disabled if empty.
+	RolloutRoots []string `mapstructure:"rollout-roots"`
+
+	// RolloutPercentage is the percentage of the new sessions assigned to the rollout roots.
+	RolloutPercentage int `mapstructure:"rollout-percentage"`
+
+	// RolloutCookie is the name of the cookie keeping the session on its variant.
+	RolloutCookie string `mapstructure:"rollout-cookie"`
+
+	// RolloutHeader is the request header selecting the variant, e.g. for the testers, disabled if empty.
+	RolloutHeader string `mapstructure:"rollout-header"`
+
+	// VersionsDir contains the directories of the versions served under `/v/<version>/`, disabled if empty.
+	VersionsDir string `mapstructure:"versions-dir"`
+
+	// PreloadManifest is the path of the Vite build manifest within the root, preload hints disabled if empty.
+	PreloadManifest string `mapstructure:"preload-manifest"`
+
+	// PreloadAssets are the paths of the critical assets preloaded by the html responses, e.g. `assets/main.js`.
+	PreloadAssets []string `mapstructure:"preload-assets"`
+
+	// EarlyHints sends the preload links in the 103 Early Hints response before the html document.
+	EarlyHints bool `mapstructure:"early-hints"`
+
+	// SignedUrlKey is the HMAC key of the signed urls, disabled if empty.
+	SignedUrlKey string `mapstructure:"signed-url-key" secret:"true"`
+
+	// SignedUrlRegexs are the path regexps served only with a valid signed url.
+	SignedUrlRegexs []string `mapstructure:"signed-url-regexp"`
+
+	// SignedUrlTtl is the default validity of the urls signed through the admin API.
+	SignedUrlTtl time.Duration `mapstructure:"signed-url-ttl"`
+
+	// AuthMode protects the served requests with the basic auth, the OIDC login or the bearer tokens: basic, oidc or jwt, disabled if empty.
+	AuthMode string `mapstructure:"auth-mode"`
+
+	// AuthExcludeRegexs are the path regexps served without the authentication, e.g. the web manifest.
+	AuthExcludeRegexs []string `mapstructure:"auth-exclude-regexp"`
+
+	// BasicAuthUsers are the bcrypt or `{SHA}` password hashes of the users, as in the htpasswd file.
+	BasicAuthUsers map[string]string `mapstructure:"basic-auth-users" secret:"true"`
+
+	// BasicAuthFile is the htpasswd file of the users, e.g. the mounted secret, merged with the users above.
+	BasicAuthFile string `mapstructure:"basic-auth-file"`
+
+	// BasicAuthRealm is the realm of the basic auth challenge.
+	BasicAuthRealm string `mapstructure:"basic-auth-realm"`
+
+	// OidcIssuer is the issuer url of the identity provider, discovered through its `/.well-known/openid-configuration`.
+	OidcIssuer string `mapstructure:"oidc-issuer"`
+
+	// OidcClientId is the client id registered at the identity provider.
+	OidcClientId string `mapstructure:"oidc-client-id"`
+
+	// OidcClientSecret is the client secret registered at the identity provider.
+	OidcClientSecret string `mapstructure:"oidc-client-secret" secret:"true"`
+
+	// OidcRedirectUrl is the absolute url of the callback registered at the identity provider, e.g. `https://preview.example.com/oauth2/callback`.
+	OidcRedirectUrl string `mapstructure:"oidc-redirect-url"`
+
+	// OidcScopes are the scopes requested from the identity provider.
+	OidcScopes []string `mapstructure:"oidc-scopes"`
+
+	// OidcCookie is the name of the session cookie.
+	OidcCookie string `mapstructure:"oidc-cookie"`
+
+	// OidcSessionTtl is the validity of the session after the login.
+	OidcSessionTtl time.Duration `mapstructure:"oidc-session-ttl"`
+
+	// OidcCookieKey is the HMAC key of the session cookie shared by the replicas, random on each start if empty, kept across the configuration reloads.
+	OidcCookieKey string `mapstructure:"oidc-cookie-key" secret:"true"`
+
+	// JwtJwksUrl is the url of the JWKS verifying the signatures of the bearer tokens.
+	JwtJwksUrl string `mapstructure:"jwt-jwks-url"`
+
+	// JwtIssuer is the required issuer of the bearer tokens, not checked if empty.
+	JwtIssuer string `mapstructure:"jwt-issuer"`
+
+	// JwtAudience is the required audience of the bearer tokens, not checked if empty.
+	JwtAudience string `mapstructure:"jwt-audience"`
+
+	// JwtClaimHeaders are the response headers set to the claims of the bearer token, e.g. `email: X-User-Email`.
+	JwtClaimHeaders map[string]string `mapstructure:"jwt-claim-headers"`
+
+	// JwtDocumentClaims are the claims of the bearer token injected into the html documents.
+	JwtDocumentClaims []string `mapstructure:"jwt-document-claims"`
+
+	// TenantRoot is the template of the tenant root directory with the `{tenant}` placeholder, disabled if empty.
+	TenantRoot string `mapstructure:"tenant-root"`
+
+	// TenantSource is either host to take the tenant from the first label of the host, or path from the first path segment.
+	TenantSource string `mapstructure:"tenant-source"`
+
+	// TenantRegex is the regexp of the valid tenants.
+	TenantRegex string `mapstructure:"tenant-regexp"`
+
+	// DirectoryHeadersFile is the name of the header override files of the directories, disabled if empty.
+	DirectoryHeadersFile string `mapstructure:"directory-headers-file"`
+
+	// DirectoryIndex serves the index.html of the directories requested by their path.
+	DirectoryIndex bool `mapstructure:"directory-index"`
+
+	// DirectoryListing lists the directories without the index document.
+	DirectoryListing bool `mapstructure:"directory-listing"`
+
+	// DirectoryListingRegexs are the regexps of the request paths of the listed directories, all if empty.
+	DirectoryListingRegexs []string `mapstructure:"directory-listing-regexp"`
+
+	// DotfilesBlocked refuses the hidden files and directories, e.g. `/.git` or `/.env`, except `/.well-known`.
+	DotfilesBlocked bool `mapstructure:"dotfiles-blocked"`
+
+	// ExtensionsAllowed are the only extensions of the served files, e.g. `.js`, all if empty.
+	ExtensionsAllowed []string `mapstructure:"extensions-allowed"`
+
+	// ExtensionsDenied are the extensions of the files not served, e.g. `.map`.
+	ExtensionsDenied []string `mapstructure:"extensions-denied"`
+
+	// RedirectsFile is the path of the Netlify-style redirects file within the root, disabled if empty.
+	RedirectsFile string `mapstructure:"redirects-file"`
+
+	// Redirects are the redirects of the matching requests applied before the lookup, e.g. of the legacy urls.
+	Redirects []Redirect `mapstructure:"redirects"`
+
+	// Rewrites are the internal rewrites of the request paths applied before the lookup, not visible to the client.
+	Rewrites []Rewrite `mapstructure:"rewrites"`
+
+	// SitemapRoutesFile is the path of the JSON routes file within the root generating the sitemap.xml, disabled if empty.
+	SitemapRoutesFile string `mapstructure:"sitemap-routes-file"`
+
+	// SitemapRoutes are the routes of the sitemap.xml if the routes file is not configured or not found.
+	SitemapRoutes []string `mapstructure:"sitemap-routes"`
+
+	// SitemapOrigin is the scheme and host of the sitemap urls, taken from the request if empty.
+	SitemapOrigin string `mapstructure:"sitemap-origin"`
+
+	// ClientHintsVariants are the variants of the files served to the clients matching the client hints.
+	ClientHintsVariants []ClientHintsVariant `mapstructure:"client-hints-variants"`
+
+	// Proxies are the routes passed to the upstreams instead of being served from the roots, e.g. the API.
+	Proxies []Proxy `mapstructure:"proxies"`
+
+	// PrerenderUrl is the url of the prerender service receiving the crawler requests, disabled if empty.
+	PrerenderUrl string `mapstructure:"prerender-url"`
+
+	// PrerenderDir is the directory of the pre-generated page snapshots served to the crawlers, disabled if empty.
+	PrerenderDir string `mapstructure:"prerender-dir"`
+
+	// PrerenderUserAgentRegex is the regexp of the crawler user agents.
+	PrerenderUserAgentRegex string `mapstructure:"prerender-user-agent-regexp"`
+
+	// PrerenderTimeout is the timeout of the prerender service, the application is served if exceeded.
+	PrerenderTimeout time.Duration `mapstructure:"prerender-timeout"`
+
+	// UpstreamCacheTtl is the time the responses of the prerender service and the proxied upstreams are cached, disabled if zero.
+	UpstreamCacheTtl time.Duration `mapstructure:"upstream-cache-ttl"`
+
+	// UpstreamCacheStaleIfError is the time after the ttl the cached response is served while its upstream fails.
+	UpstreamCacheStaleIfError time.Duration `mapstructure:"upstream-cache-stale-if-error"`
+
+	// UpstreamCacheMaxEntries is the number of the cached upstream responses, the cache is cleared when full.
+	UpstreamCacheMaxEntries int `mapstructure:"upstream-cache-max-entries"`
+
+	// UpstreamCacheMaxSize is the max size in bytes of the cached upstream response body.
+	UpstreamCacheMaxSize int64 `mapstructure:"upstream-cache-max-size"`
+
+	// MaxProcs is GOMAXPROCS, the CPU quota of the container if zero, the runtime default if negative.
+	MaxProcs int `mapstructure:"max-procs"`
+
+	// ContainerMemoryLimitRatio is the ratio of the container memory limit set as GOMEMLIMIT, disabled if zero.
+	ContainerMemoryLimitRatio float64 `mapstructure:"container-memory-limit-ratio"`
+
+	// MemoryLimit is the memory limit of the process in bytes degrading the caches, GOMEMLIMIT if zero.
+	MemoryLimit int64 `mapstructure:"memory-limit"`
+
+	// MemoryPressureRatio is the ratio of the memory limit at which the caches start to degrade.
+	MemoryPressureRatio float64 `mapstructure:"memory-pressure-ratio"`
+
+	// MemoryCheckInterval is the interval of checking the memory usage against the limit.
+	MemoryCheckInterval time.Duration `mapstructure:"memory-check-interval"`
+}
+
+// AccessLogSample is the ratio of the successful requests of the matching paths logged.
+type AccessLogSample struct {
+	// Regexp matches the request path.
+	Regexp string `mapstructure:"regexp"`
+
+	// Ratio is the ratio of the requests logged, from 0 to 1.
+	Ratio float64 `mapstructure:"ratio"`
+}
+
+// PathTemplate replaces the parts of the path matching the regexp with the replacement.
+type PathTemplate struct {
+	Regexp      string `mapstructure:"regexp"`
+	Replacement string `mapstructure:"replacement"`
+}
+
+// ClientHintsVariant serves the variant of the files to the clients matching any of its conditions.
+type ClientHintsVariant struct {
+	// Suffix is inserted before the extension of the file name, e.g. `.lite` for `main.lite.js`.
+	Suffix string `mapstructure:"suffix"`
+
+	// Regexp restricts the variant to the matching paths, all paths if empty.
+	Regexp string `mapstructure:"regexp"`
+
+	// SaveData matches the clients requesting the reduced data usage.
+	SaveData bool `mapstructure:"save-data"`
+
+	// MaxDpr matches the clients with the device pixel ratio up to the value, ignored if zero.
+	MaxDpr float64 `mapstructure:"max-dpr"`
+
+	// MaxDeviceMemory matches the clients with the device memory in GiB up to the value, ignored if zero.
+	MaxDeviceMemory float64 `mapstructure:"max-device-memory"`
+}
+
+// FallbackRoute serves its document as the fallback of the paths under the prefix.
+type FallbackRoute struct {
+	// Prefix is the path prefix relative to the base url, e.g. `/admin`.
+	Prefix string `mapstructure:"prefix"`
+
+	// Document is the path of the fallback document, e.g. `admin/index.html`.
+	Document string `mapstructure:"document"`
+}
+
+// Redirect redirects the requests matching the regexp to the target.
+type Redirect struct {
+	// Regexp matches the request path, or the host and the path, e.g. `^www\.example\.com(/.*)$`, if Host is set.
+	Regexp string `mapstructure:"regexp"`
+
+	// Host matches the regexp against the host followed by the path.
+	Host bool `mapstructure:"host"`
+
+	// Target is the location with the `$1` or `${name}` references of the capture groups.
+	Target string `mapstructure:"target"`
+
+	// Status is the redirect status, 301 if zero.
+	Status int `mapstructure:"status"`
+}
+
+// Rewrite serves the requests matching the regexp from the target path.
+type Rewrite struct {
+	// Regexp matches the request path, e.g. `^/v2/app/(.*)$`.
+	Regexp string `mapstructure:"regexp"`
+
+	// Target is the path with the `$1` or `${name}` references of the capture groups, e.g. `/app/$1`.
+	Target string `mapstructure:"target"`
+}
+
+// Proxy passes the requests matching the path prefix or the regexp to the upstream.
+type Proxy struct {
+	// Prefix matches the request paths starting with it, e.g. `/api/`.
+	Prefix string `mapstructure:"prefix"`
+
+	// Regexp matches the request paths, used instead of the prefix.
+	Regexp string `mapstructure:"regexp"`
+
+	// Upstream is the url of the upstream, its path is prepended to the request path.
+	Upstream string `mapstructure:"upstream"`
+
+	// StripPrefix removes the prefix from the path passed to the upstream.
+	StripPrefix bool `mapstructure:"strip-prefix"`
+}
+
+// MetricView adapts the metric stream of the matching instruments.
+type MetricView struct {
+	// Instrument is the name of the instrument, `*` and `?` wildcards are supported.
+	Instrument string `mapstructure:"instrument"`
+
+	// Name renames the metric stream, not allowed with wildcards.
+	Name string `mapstructure:"name"`
+
+	// Description overrides the description of the metric stream.
+	Description string `mapstructure:"description"`
+
+	// Drop drops the metric stream entirely.
+	Drop bool `mapstructure:"drop"`
+
+	// DropAttributes is the list of attributes removed from the measurements.
+	DropAttributes []string `mapstructure:"drop-attributes"`
+
+	// AllowedAttributes is the list of attributes kept in the measurements, all if empty.
+	AllowedAttributes []string `mapstructure:"allowed-attributes"`
+
+	// HistogramBuckets are the explicit bucket boundaries of histogram instruments.
+	HistogramBuckets []float64 `mapstructure:"histogram-buckets"`
+}
+
+func loadConfiguration() (cfg Config) {
+	if err := configureViper(); err != nil {
+		log.Fatalf("Cannot read configuration: %v", err)
+	}
+	cfg = Config{}
+	err := viper.Unmarshal(&cfg)
+	if err != nil {
+		log.Fatal("Cannot read configuration")
+	}
+	return
+}
+
+func configureViper() error {
+	viper.AddConfigPath("config")
+	if executable, err := os.Executable(); err == nil {
+		// services do not start in the installation directory, e.g. on Windows
+		viper.AddConfigPath(filepath.Join(filepath.Dir(executable), "config"))
+	}
+	// the format is detected by the extension, e.g. spa-base.yaml or spa-base.toml
+	viper.SetConfigName("spa-base")
+	setDefaults(viper.GetViper())
+
+	viper.SetEnvKeyReplacer(strings.NewReplacer(`.`, `_`, `-`, `_`))
+	viper.SetEnvPrefix("SPA_BASE")
+	viper.AutomaticEnv()
+	return readConfig()
+}
+
+// readConfig reads the configuration files and merges the configuration document of the environment
+func readConfig() error {
+	var err error
+	if files := viper.GetStringSlice("config-files"); len(files) > 0 {
+		err = readConfigFiles(viper.GetViper(), files)
+	} else {
+		err = viper.ReadInConfig()
+	}
+	switch err.(type) {
+	case viper.ConfigFileNotFoundError:
+		log.Println("No configuration file found, using defaults")
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := mergeEnvConfig(viper.GetViper()); err != nil {
+		return err
+	}
+	migrateDeprecatedKeys(viper.GetViper())
+	return nil
+}
+
+// readConfigFiles reads the configuration files, each merged over the previous
+// ones, e.g. the base and the overlay of the environment. The format of each
+// file is detected by its extension.
+func readConfigFiles(v *viper.Viper, files []string) error {
+	for i, file := range files {
+		v.SetConfigFile(file)
+		read := v.MergeInConfig
+		if i == 0 {
+			read = v.ReadInConfig
+		}
+		if err := read(); err != nil {
+			return fmt.Errorf("%v: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// reloadConfiguration reads the configuration file and the environment again,
+// the errors are returned instead of failing
+func reloadConfiguration() (cfg Config, err error) {
+	if err := readConfig(); err != nil {
+		return cfg, err
+	}
+	err = viper.Unmarshal(&cfg)
+	return cfg, err
+}
+
+// configEnv holds the whole configuration as a single JSON or YAML document
+const configEnv = "SPA_BASE_CONFIG"
+
+// mergeEnvConfig merges the configuration document of the environment variable
+// over the configuration file, e.g. templated by Helm with the maps the single
+// variables cannot express. The single variables still take precedence.
+func mergeEnvConfig(v *viper.Viper) error {
+	document := os.Getenv(configEnv)
+	if strings.TrimSpace(document) == "" {
+		return nil
+	}
+	// JSON is a subset of YAML, whatever the format of the configuration files
+	parsed := viper.New()
+	parsed.SetConfigType("yaml")
+	if err := parsed.ReadConfig(strings.NewReader(document)); err != nil {
+		return fmt.Errorf("invalid %v: %w", configEnv, err)
+	}
+	return v.MergeConfigMap(parsed.AllSettings())
+}
+
+// deprecatedKeys maps the renamed configuration keys to their current names
+var deprecatedKeys = map[string]string{
+	"not-found-regexp": "no-fallback-regexp",
+}
+
+// migrateDeprecatedKeys moves the values of the deprecated keys, set in the
+// configuration file or the environment, to their current keys with a warning.
+// The current key wins if both are set.
+func migrateDeprecatedKeys(v *viper.Viper) {
+	for deprecated, current := range deprecatedKeys {
+		if !v.IsSet(deprecated) {
+			continue
+		}
+		if v.InConfig(current) || isEnvSet(current) {
+			log.Printf("Configuration key %v is deprecated and ignored, %v is set", deprecated, current)
+			continue
+		}
+		log.Printf("Configuration key %v is deprecated, use %v instead", deprecated, current)
+		v.Set(current, v.Get(deprecated))
+	}
+}
+
+// isEnvSet checks the environment variable of the configuration key
+func isEnvSet(key string) bool {
+	_, ok := os.LookupEnv("SPA_BASE_" + strings.ToUpper(strings.NewReplacer(`.`, `_`, `-`, `_`).Replace(key)))
+	return ok
+}
+
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("port", 7105)
+	v.SetDefault("tls-cert-file", "")
+	v.SetDefault("tls-key-file", "")
+	v.SetDefault("tls-reload-interval", 10*time.Second)
+	v.SetDefault("acme-hosts", []string{})
+	v.SetDefault("acme-cache-dir", "")
+	v.SetDefault("acme-email", "")
+	v.SetDefault("acme-directory-url", "")
+	v.SetDefault("acme-http-port", 80)
+	v.SetDefault("tls-min-version", "1.2")
+	v.SetDefault("tls-max-version", "")
+	v.SetDefault("tls-cipher-suites", []string{})
+	v.SetDefault("tls-curve-preferences", []string{})
+	v.SetDefault("h2c", false)
+	v.SetDefault("read-header-timeout", 10*time.Second)
+	v.SetDefault("read-timeout", time.Minute)
+	v.SetDefault("write-timeout", time.Duration(0))
+	v.SetDefault("idle-timeout", 2*time.Minute)
+	v.SetDefault("max-header-bytes", http.DefaultMaxHeaderBytes)
+	v.SetDefault("http3", false)
+	v.SetDefault("http3-advertised-port", 0)
+	v.SetDefault("reuse-port-listeners", 0)
+	v.SetDefault("admin-port", 0)
+	v.SetDefault("admin-token", "")
+	v.SetDefault("maintenance-page", "")
+	v.SetDefault("har-max-duration", 15*time.Minute)
+	v.SetDefault("har-max-entries", 1000)
+	v.SetDefault("har-max-body-size", 64*1024)
+	v.SetDefault("shutdown-timeout", 30*time.Second)
+	v.SetDefault("shutdown-drain-delay", time.Duration(0))
+	v.SetDefault("max-inflight-requests", 0)
+	v.SetDefault("overload-retry-after", time.Second)
+	v.SetDefault("overload-queue-timeout", time.Duration(0))
+	v.SetDefault("rate-limit", 0.0)
+	v.SetDefault("rate-limit-burst", 20)
+	v.SetDefault("rate-limit-header", "")
+	v.SetDefault("offline-page", "")
+	v.SetDefault("offline-retry-after", 5*time.Second)
+	v.SetDefault("offline-check-interval", time.Second)
+	v.SetDefault("debug-header", false)
+	v.SetDefault("debug-endpoints", false)
+	v.SetDefault("debug-endpoints-insecure", false)
+	v.SetDefault("base-url", "/")
+	v.SetDefault("allow-skip-base-url", false)
+	v.SetDefault("strip-prefixes", []string{})
+	v.SetDefault("trusted-proxies", []string{})
+	v.SetDefault("allowed-cidrs", []string{})
+	v.SetDefault("denied-cidrs", []string{})
+	v.SetDefault("rewrite-absolute-urls", false)
+	v.SetDefault("default-cache-control", "")
+	v.SetDefault("hashed-file-regexp", `\.[0-9a-f]{8,}\.`)
+	v.SetDefault("unhashed-cache-control", unhashedCacheControl)
+	v.SetDefault("index-cache-control", indexCacheControl)
+	v.SetDefault("default-cache-control-disabled", false)
+	v.SetDefault("cache-bust-params", []string{})
+	v.SetDefault("etag", "none")
+	v.SetDefault("etag-per-regexp", map[string]string{})
+	v.SetDefault("bandwidth-limit", 0)
+	v.SetDefault("bandwidth-limit-per-regexp", map[string]int64{})
+	v.SetDefault("bandwidth-limit-burst", 256*1024)
+	v.SetDefault("last-modified", "")
+	v.SetDefault("last-modified-file", "")
+	v.SetDefault("content-security-policy", "")
+	v.SetDefault("content-security-policy-report-only", "")
+	v.SetDefault("csp-inline-hashes", false)
+	v.SetDefault("csp-nonce", false)
+	v.SetDefault("base-href", false)
+	v.SetDefault("security-headers", securityHeadersOff)
+	v.SetDefault("sri-enabled", false)
+	v.SetDefault("sri-crossorigin", "anonymous")
+	v.SetDefault("redirect-to-base-url", false)
+	v.SetDefault("logging-level", "info")
+	v.SetDefault("json-logging", true)
+	if _, ok := embeddedRoot(); ok {
+		// the binary built by `spa_d embed` serves the embedded SPA
+		v.SetDefault("roots", []string{embedScheme})
+	} else {
+		v.SetDefault("roots", []string{"./public"})
+	}
+	v.SetDefault("headers", map[string]string{})
+	v.SetDefault("headers-per-regexp", map[string]map[string]string{})
+	v.SetDefault("fallback-document", "index.html")
+	v.SetDefault("precompress-on-start", false)
+	v.SetDefault("precompress-cache-dir", "")
+	v.SetDefault("dynamic-compression", false)
+	v.SetDefault("dynamic-compression-min-size", 1024)
+	v.SetDefault("dynamic-compression-types", defaultCompressibleTypes)
+	v.SetDefault("dynamic-gzip-level", 6)
+	v.SetDefault("dynamic-brotli-level", 5)
+	v.SetDefault("fallback-accept-types", []string{"text/html"})
+	v.SetDefault("error-pages", map[string]string{})
+	v.SetDefault("no-fallback-regexp", []string{"(\\.js|\\.json|\\.mjs|\\.png|\\.jpe?g|\\.woff2)"})
+	v.SetDefault("metrics-path-label", "raw")
+	v.SetDefault("metrics-path-prefix-depth", 1)
+	v.SetDefault("metrics-path-templates", []PathTemplate{})
+	v.SetDefault("metric-views", []MetricView{})
+	v.SetDefault("trace-sampler", "")
+	v.SetDefault("trace-sampler-ratio", 1.0)
+	v.SetDefault("trace-batch-queue-size", tracesdk.DefaultMaxQueueSize)
+	v.SetDefault("trace-batch-size", tracesdk.DefaultMaxExportBatchSize)
+	v.SetDefault("trace-batch-timeout", tracesdk.DefaultScheduleDelay*time.Millisecond)
+	v.SetDefault("trace-export-timeout", tracesdk.DefaultExportTimeout*time.Millisecond)
+	v.SetDefault("trace-exclude-regexp", []string{})
+	v.SetDefault("trace-response-header", "")
+	v.SetDefault("server-timing", false)
+	v.SetDefault("kubernetes-detection-disabled", false)
+	v.SetDefault("log-exclude-regexp", []string{})
+	v.SetDefault("access-log", "")
+	v.SetDefault("access-log-format", "json")
+	v.SetDefault("access-log-sampling", []AccessLogSample{})
+	v.SetDefault("symlink-policy", symlinksFollow)
+	v.SetDefault("fs-retry-attempts", 3)
+	v.SetDefault("fs-retry-backoff", 50*time.Millisecond)
+	v.SetDefault("file-cache-ttl", 2*time.Second)
+	v.SetDefault("file-cache-max-entries", 10000)
+	v.SetDefault("oci-cache-dir", filepath.Join(os.TempDir(), "spa_d", "oci"))
+	v.SetDefault("oci-username", "")
+	v.SetDefault("oci-password", "")
+	v.SetDefault("oci-insecure", false)
+	v.SetDefault("oci-pull-on-demand", false)
+	v.SetDefault("git-cache-dir", filepath.Join(os.TempDir(), "spa_d", "git"))
+	v.SetDefault("git-binary", "git")
+	v.SetDefault("s3-endpoint", "")
+	v.SetDefault("s3-region", "us-east-1")
+	v.SetDefault("s3-access-key-id", "")
+	v.SetDefault("s3-secret-access-key", "")
+	v.SetDefault("s3-session-token", "")
+	v.SetDefault("s3-path-style", false)
+	v.SetDefault("s3-cache-dir", filepath.Join(os.TempDir(), "spa_d", "s3"))
+	v.SetDefault("ready-checks", []string{readyFallbackDocument})
+	v.SetDefault("ready-max-sync-age", time.Duration(0))
+	v.SetDefault("sync-interval", time.Duration(0))
+	v.SetDefault("sync-timeout", 5*time.Minute)
+	v.SetDefault("sync-checksums", false)
+	v.SetDefault("revision-header", "")
+	v.SetDefault("release-pointer-interval", time.Second)
+	v.SetDefault("sync-cache-dir", filepath.Join(os.TempDir(), "spa_d", "http"))
+	v.SetDefault("integrity-manifest", "")
+	v.SetDefault("integrity-mode", "enforce")
+	v.SetDefault("signature-public-keys", []string{})
+	v.SetDefault("signature-identities", []string{})
+	v.SetDefault("signature-oidc-issuer", "")
+	v.SetDefault("signature-certificate-roots", []string{})
+	v.SetDefault("signature-tlog-public-keys", []string{})
+	v.SetDefault("snapshot-enabled", false)
+	v.SetDefault("coalesce-max-size", 0)
+	v.SetDefault("profiling-url", "")
+	v.SetDefault("profiling-app-name", "spa_d")
+	v.SetDefault("profiling-labels", map[string]string{})
+	v.SetDefault("profiling-interval", 15*time.Second)
+	v.SetDefault("profiling-types", []string{"cpu", "heap", "goroutine"})
+	v.SetDefault("prometheus-metrics", false)
+	v.SetDefault("runtime-metrics-disabled", false)
+	v.SetDefault("runtime-metrics-interval", 15*time.Second)
+	v.SetDefault("scheduled-roots", []string{})
+	v.SetDefault("scheduled-activation", "")
+	v.SetDefault("rollout-roots", []string{})
+	v.SetDefault("rollout-percentage", 0)
+	v.SetDefault("rollout-cookie", "spa_d_variant")
+	v.SetDefault("rollout-header", "")
+	v.SetDefault("versions-dir", "")
+	v.SetDefault("preload-manifest", "")
+	v.SetDefault("preload-assets", []string{})
+	v.SetDefault("early-hints", false)
+	v.SetDefault("signed-url-key", "")
+	v.SetDefault("signed-url-regexp", []string{})
+	v.SetDefault("signed-url-ttl", time.Hour)
+	v.SetDefault("auth-mode", "")
+	v.SetDefault("auth-exclude-regexp", []string{})
+	v.SetDefault("basic-auth-users", map[string]string{})
+	v.SetDefault("basic-auth-file", "")
+	v.SetDefault("basic-auth-realm", "Restricted")
+	v.SetDefault("oidc-issuer", "")
+	v.SetDefault("oidc-client-id", "")
+	v.SetDefault("oidc-client-secret", "")
+	v.SetDefault("oidc-redirect-url", "")
+	v.SetDefault("oidc-scopes", []string{"openid", "email", "profile"})
+	v.SetDefault("oidc-cookie", "spa_session")
+	v.SetDefault("oidc-session-ttl", 8*time.Hour)
+	v.SetDefault("oidc-cookie-key", "")
+	v.SetDefault("jwt-jwks-url", "")
+	v.SetDefault("jwt-issuer", "")
+	v.SetDefault("jwt-audience", "")
+	v.SetDefault("jwt-claim-headers", map[string]string{})
+	v.SetDefault("jwt-document-claims", []string{})
+	v.SetDefault("tenant-root", "")
+	v.SetDefault("tenant-source", tenantSourceHost)
+	v.SetDefault("tenant-regexp", "^[a-z0-9][a-z0-9-]*$")
+	v.SetDefault("directory-headers-file", "")
+	v.SetDefault("directory-index", false)
+	v.SetDefault("directory-listing", false)
+	v.SetDefault("directory-listing-regexp", []string{})
+	v.SetDefault("dotfiles-blocked", true)
+	v.SetDefault("extensions-allowed", []string{})
+	v.SetDefault("extensions-denied", []string{})
+	v.SetDefault("redirects-file", "")
+	v.SetDefault("sitemap-routes-file", "")
+	v.SetDefault("sitemap-routes", []string{})
+	v.SetDefault("sitemap-origin", "")
+	v.SetDefault("client-hints-variants", []ClientHintsVariant{})
+	v.SetDefault("proxies", []Proxy{})
+	v.SetDefault("redirects", []Redirect{})
+	v.SetDefault("rewrites", []Rewrite{})
+	v.SetDefault("fallback-routes", []FallbackRoute{})
+	v.SetDefault("fallback-languages", map[string]string{})
+	v.SetDefault("sites", map[string]map[string]any{})
+	v.SetDefault("config-watch", false)
+	v.SetDefault("config-files", []string{})
+	v.SetDefault("prerender-url", "")
+	v.SetDefault("prerender-dir", "")
+	v.SetDefault("prerender-user-agent-regexp", defaultCrawlerUserAgents)
+	v.SetDefault("prerender-timeout", 10*time.Second)
+	v.SetDefault("upstream-cache-ttl", 0)
+	v.SetDefault("upstream-cache-stale-if-error", time.Hour)
+	v.SetDefault("upstream-cache-max-entries", 1000)
+	v.SetDefault("upstream-cache-max-size", 1<<20)
+	v.SetDefault("max-procs", 0)
+	v.SetDefault("container-memory-limit-ratio", 0.9)
+	v.SetDefault("memory-limit", 0)
+	v.SetDefault("memory-pressure-ratio", 0.8)
+	v.SetDefault("memory-check-interval", time.Second)
+}
+
+// loggingLevel parses the name of the logging level, e.g. debug, warn or warning
+func loggingLevel(name string) (zerolog.Level, bool) {
+	switch strings.ToLower(name) {
+	case "trace":
+		return zerolog.TraceLevel, true
+	case "debug":
+		return zerolog.DebugLevel, true
+	case "info", "information":
+		return zerolog.InfoLevel, true
+	case "warn", "warning":
+		return zerolog.WarnLevel, true
+	case "error":
+		return zerolog.ErrorLevel, true
+	default:
+		return zerolog.InfoLevel, false
+	}
+}
+
+func configureLogger(cfg Config) zerolog.Logger {
+	loglevel, _ := loggingLevel(cfg.LoggingLevel)
+	zerolog.SetGlobalLevel(loglevel)
+	l := zerolog.New(os.Stderr).With().Timestamp().Logger()
+	if !cfg.JsonLogging {
+		l = l.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	}
+	l.Info().
+		Str("logging-level", cfg.LoggingLevel).
+		Str("port", strconv.Itoa(cfg.Port)).
+		Str("roots", strings.Join(cfg.RootDirs, ", ")).
+		Str("base-url", cfg.BaseURL).
+		Bool("allow-skip-base-url", cfg.AllowSkipBaseUrl).
+		Msg("Configuration")
+
+	return l
+}
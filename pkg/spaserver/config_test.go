@@ -0,0 +1,204 @@
+package spaserver
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfigTestSuite struct {
+	suite.Suite
+}
+
+func TestConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(ConfigTestSuite))
+}
+
+func (suite *ConfigTestSuite) read(yaml string) *viper.Viper {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	v.SetDefault("no-fallback-regexp", []string{"default"})
+	suite.Require().Nil(v.ReadConfig(strings.NewReader(yaml)))
+	return v
+}
+
+func (suite *ConfigTestSuite) Test_Deprecated_key_Then_migrated() {
+
+	// given
+	v := suite.read("not-found-regexp: [ '\\.js$' ]")
+
+	// when
+	migrateDeprecatedKeys(v)
+
+	// then
+	suite.Equal([]string{`\.js$`}, v.GetStringSlice("no-fallback-regexp"))
+}
+
+func (suite *ConfigTestSuite) Test_Both_keys_Then_current_wins() {
+
+	// given
+	v := suite.read("not-found-regexp: [ old ]\nno-fallback-regexp: [ current ]")
+
+	// when
+	migrateDeprecatedKeys(v)
+
+	// then
+	suite.Equal([]string{"current"}, v.GetStringSlice("no-fallback-regexp"))
+}
+
+func (suite *ConfigTestSuite) Test_Deprecated_env_Then_migrated() {
+
+	// given
+	suite.T().Setenv("SPA_BASE_NOT_FOUND_REGEXP", `\.css$`)
+	v := suite.read("")
+	v.SetEnvKeyReplacer(strings.NewReplacer(`.`, `_`, `-`, `_`))
+	v.SetEnvPrefix("SPA_BASE")
+	v.AutomaticEnv()
+
+	// when
+	migrateDeprecatedKeys(v)
+
+	// then
+	suite.Equal([]string{`\.css$`}, v.GetStringSlice("no-fallback-regexp"))
+}
+
+func (suite *ConfigTestSuite) Test_No_deprecated_key_Then_default_kept() {
+
+	// given
+	v := suite.read("port: 7000")
+
+	// when
+	migrateDeprecatedKeys(v)
+
+	// then
+	suite.Equal([]string{"default"}, v.GetStringSlice("no-fallback-regexp"))
+}
+
+func (suite *ConfigTestSuite) Test_Env_config_Then_merged_over_file() {
+
+	// given
+	suite.T().Setenv("SPA_BASE_CONFIG", `{"port": 8080, "headers": {"X-Frame-Options": "DENY"}}`)
+	v := suite.read("port: 7000\nbase-url: /app/")
+
+	// when
+	err := mergeEnvConfig(v)
+
+	// then
+	suite.Require().Nil(err)
+	suite.Equal(8080, v.GetInt("port"))
+	suite.Equal("/app/", v.GetString("base-url"))
+	suite.Equal(map[string]string{"x-frame-options": "DENY"}, v.GetStringMapString("headers"))
+}
+
+func (suite *ConfigTestSuite) Test_Env_config_yaml_Then_single_env_wins() {
+
+	// given
+	suite.T().Setenv("SPA_BASE_CONFIG", "port: 8080\nbase-url: /blob/")
+	suite.T().Setenv("SPA_BASE_PORT", "9090")
+	v := suite.read("")
+	v.SetEnvKeyReplacer(strings.NewReplacer(`.`, `_`, `-`, `_`))
+	v.SetEnvPrefix("SPA_BASE")
+	v.AutomaticEnv()
+
+	// when
+	err := mergeEnvConfig(v)
+
+	// then
+	suite.Require().Nil(err)
+	suite.Equal(9090, v.GetInt("port"))
+	suite.Equal("/blob/", v.GetString("base-url"))
+}
+
+func (suite *ConfigTestSuite) Test_Env_config_invalid_Then_error() {
+
+	// given
+	suite.T().Setenv("SPA_BASE_CONFIG", "{port")
+	v := suite.read("")
+
+	// when
+	err := mergeEnvConfig(v)
+
+	// then
+	suite.ErrorContains(err, "SPA_BASE_CONFIG")
+}
+
+func (suite *ConfigTestSuite) Test_Flags_Then_override_file_and_merge_maps() {
+
+	// given
+	v := suite.read("port: 7000\nbase-url: /app/\nheaders: { X-File: file }")
+
+	// when
+	err := parseFlags(v, []string{"--port", "8080", "--root", "dist", "--root", "public",
+		"--header", "X-Foo: bar", "--header", "Cache-Control: public, max-age=60", "--shutdown-timeout", "5s"})
+
+	// then
+	suite.Require().Nil(err)
+	cfg := Config{}
+	suite.Require().Nil(v.Unmarshal(&cfg))
+	suite.Equal(8080, cfg.Port)
+	suite.Equal("/app/", cfg.BaseURL)
+	suite.Equal([]string{"dist", "public"}, cfg.RootDirs)
+	suite.Equal(map[string]string{"x-file": "file", "X-Foo": "bar", "Cache-Control": "public, max-age=60"}, cfg.Headers)
+	suite.Equal(5*time.Second, cfg.ShutdownTimeout)
+}
+
+func (suite *ConfigTestSuite) Test_Invalid_header_flag_Then_error() {
+
+	// when
+	err := parseFlags(viper.New(), []string{"--header", "X-Foo"})
+
+	// then
+	suite.ErrorContains(err, "expected name: value")
+}
+
+func (suite *ConfigTestSuite) Test_Config_files_Then_overlay_merged_over_base() {
+
+	// given
+	dir := suite.T().TempDir()
+	base, overlay := path.Join(dir, "base.yaml"), path.Join(dir, "production.toml")
+	suite.Require().Nil(os.WriteFile(base, []byte("port: 7000\nbase-url: /app/\nheaders: { X-Base: base }"), 0644))
+	suite.Require().Nil(os.WriteFile(overlay, []byte("port = 8080\n[headers]\nX-Overlay = \"overlay\"\n"), 0644))
+	v := viper.New()
+
+	// when
+	err := readConfigFiles(v, []string{base, overlay})
+
+	// then
+	suite.Require().Nil(err)
+	suite.Equal(8080, v.GetInt("port"))
+	suite.Equal("/app/", v.GetString("base-url"))
+	suite.Equal(map[string]string{"x-base": "base", "x-overlay": "overlay"}, v.GetStringMapString("headers"))
+}
+
+func (suite *ConfigTestSuite) Test_Missing_config_file_Then_error() {
+
+	// when
+	err := readConfigFiles(viper.New(), []string{path.Join(suite.T().TempDir(), "missing.json")})
+
+	// then
+	suite.ErrorContains(err, "missing.json")
+}
+
+func (suite *ConfigTestSuite) Test_Env_config_with_json_file_Then_parsed_as_yaml() {
+
+	// given
+	dir := suite.T().TempDir()
+	file := path.Join(dir, "spa-base.json")
+	suite.Require().Nil(os.WriteFile(file, []byte(`{"port": 7000}`), 0644))
+	suite.T().Setenv(configEnv, "base-url: /env/")
+	v := viper.New()
+	suite.Require().Nil(readConfigFiles(v, []string{file}))
+
+	// when
+	err := mergeEnvConfig(v)
+
+	// then
+	suite.Require().Nil(err)
+	suite.Equal(7000, v.GetInt("port"))
+	suite.Equal("/env/", v.GetString("base-url"))
+}
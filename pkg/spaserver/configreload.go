@@ -0,0 +1,146 @@
+package spaserver
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// restartKeys are the configuration keys of the listeners and the telemetry,
+// applied only on restart
+var restartKeys = []string{
+	"port", "admin-port", "reuse-port-listeners", "h2c",
+	"read-header-timeout", "read-timeout", "write-timeout", "idle-timeout", "max-header-bytes", "http3", "http3-advertised-port",
+	"tls-cert-file", "tls-key-file", "tls-reload-interval", "tls-min-version", "tls-max-version",
+	"tls-cipher-suites", "tls-curve-preferences",
+	"acme-hosts", "acme-cache-dir", "acme-email", "acme-directory-url", "acme-http-port",
+	"telemetry-disabled", "prometheus-metrics", "runtime-metrics-disabled", "runtime-metrics-interval",
+	"metric-views", "trace-sampler", "trace-sampler-ratio",
+	"profiling-url", "profiling-app-name", "profiling-labels", "profiling-interval", "profiling-types",
+	"shutdown-timeout", "shutdown-drain-delay",
+}
+
+// serverSwitch serves the requests by the current server, swapped atomically
+// when the configuration is reloaded. The connections are kept, the requests
+// in flight complete on the previous server.
+type serverSwitch struct {
+	ctx     context.Context
+	current atomic.Pointer[server]
+	// mutex serializes the reloads
+	mutex sync.Mutex
+	// stop stops the background tasks of the current server
+	stop context.CancelFunc
+}
+
+// newServerSwitch serves the server and runs its background tasks
+func newServerSwitch(ctx context.Context, spa *server) *serverSwitch {
+	this := &serverSwitch{ctx: ctx}
+	this.activate(spa)
+	return this
+}
+
+func (this *serverSwitch) activate(spa *server) {
+	ctx, stop := context.WithCancel(this.ctx)
+	spa.admin = spa.adminHandler()
+	this.current.Store(spa)
+	this.stop = stop
+	spa.runBackground(ctx)
+}
+
+func (this *serverSwitch) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	this.current.Load().ServeHTTP(w, req)
+}
+
+// adminHandler serves the admin endpoints of the current server
+func (this *serverSwitch) adminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		this.current.Load().admin.ServeHTTP(w, req)
+	})
+}
+
+// traceExcluded reports whether the path is excluded from tracing by the current server
+func (this *serverSwitch) traceExcluded(requestPath string) bool {
+	return this.current.Load().traceExcluded(requestPath)
+}
+
+// reloadConfig opens the server of the configuration and swaps it for the current
+// one, the current server keeps serving if the configuration is invalid or its
+// roots cannot be opened. The operational state, i.e. the maintenance and drain
+// modes, survives the reload. The changed keys applied only on restart are logged.
+func (this *serverSwitch) reloadConfig(cfg Config, logger zerolog.Logger) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	previous := this.current.Load()
+	next, err := newServer(cfg, logger)
+	if err != nil {
+		return err
+	}
+	next.metricsHandler = previous.metricsHandler
+	next.acme = previous.acme
+	next.maintenance.Store(previous.maintenance.Load())
+	next.draining.Store(previous.draining.Load())
+
+	if changed := restartRequired(previous.cfg, cfg); len(changed) > 0 {
+		logger.Warn().Strs("keys", changed).Msg("Configuration keys changed, applied on restart")
+	}
+	this.stop()
+	this.activate(next)
+	logger.Info().Msg("Configuration reloaded")
+	return nil
+}
+
+// shutdown stops the background tasks of the current server, e.g. its scheduled switch
+func (this *serverSwitch) shutdown() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.stop()
+}
+
+// restartRequired returns the changed keys applied only on restart
+func restartRequired(previous Config, next Config) []string {
+	changed := []string{}
+	previousValue, nextValue := reflect.ValueOf(previous), reflect.ValueOf(next)
+	for i := 0; i < previousValue.NumField(); i++ {
+		key := previousValue.Type().Field(i).Tag.Get("mapstructure")
+		if !slices.Contains(restartKeys, key) {
+			continue
+		}
+		if !reflect.DeepEqual(previousValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// runBackground runs the periodic tasks of the server and its sites until the context is done
+func (this *server) runBackground(ctx context.Context) {
+	go this.observeRequests(ctx)
+
+	for _, site := range this.siteServers() {
+		if timer := site.scheduled.timer; timer != nil {
+			// the replaced server does not switch its roots
+			go func() {
+				<-ctx.Done()
+				timer.Stop()
+			}()
+		}
+
+		if site.cfg.SyncInterval > 0 {
+			go site.syncRoots(ctx, site.cfg.SyncInterval)
+		}
+
+		if site.cfg.ReleasePointerInterval > 0 {
+			go site.watchReleasePointers(ctx, site.cfg.ReleasePointerInterval)
+		}
+
+		if limit := memoryLimit(site.cfg); limit > 0 {
+			go site.monitorMemory(ctx, limit)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package spaserver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfigReloadTestSuite struct {
+	suite.Suite
+	cfg Config
+	sut *serverSwitch
+}
+
+func TestConfigReloadTestSuite(t *testing.T) {
+	suite.Run(t, new(ConfigReloadTestSuite))
+}
+
+func (suite *ConfigReloadTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	dir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(dir, "index.html"), []byte("index"), 0644))
+	suite.cfg = Config{
+		RootDirs: []string{dir},
+		BaseURL:  "/",
+		Port:     7000,
+		Headers:  map[string]string{"X-Version": "1"},
+	}
+	spa, err := newServer(suite.cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	suite.T().Cleanup(cancel)
+	suite.sut = newServerSwitch(ctx, spa)
+}
+
+func (suite *ConfigReloadTestSuite) get() *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/users", nil)
+	req.Header.Set("Accept", "text/html")
+	suite.sut.ServeHTTP(rr, req)
+	return rr
+}
+
+func (suite *ConfigReloadTestSuite) Test_Changed_headers_Then_served_by_new_configuration() {
+
+	// given
+	suite.cfg.Headers = map[string]string{"X-Version": "2"}
+
+	// when
+	err := suite.sut.reloadConfig(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.Nil(err)
+	suite.Equal("2", suite.get().Header().Get("X-Version"))
+}
+
+func (suite *ConfigReloadTestSuite) Test_Invalid_configuration_Then_previous_kept() {
+
+	// given
+	suite.cfg.Headers = map[string]string{"X-Version": "2"}
+	suite.cfg.NotFoundRegexs = []string{"("}
+
+	// when
+	err := suite.sut.reloadConfig(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.NotNil(err)
+	rr := suite.get()
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("1", rr.Header().Get("X-Version"))
+}
+
+func (suite *ConfigReloadTestSuite) Test_Maintenance_Then_kept_after_reload() {
+
+	// given
+	suite.sut.current.Load().maintenance.Store(&maintenanceState{Since: time.Now(), RetryAfter: time.Minute})
+
+	// when
+	err := suite.sut.reloadConfig(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.Nil(err)
+	suite.Equal(http.StatusServiceUnavailable, suite.get().Code)
+}
+
+func (suite *ConfigReloadTestSuite) Test_Changed_port_Then_restart_required() {
+
+	// given
+	next := suite.cfg
+	next.Port = 8080
+	next.Headers = map[string]string{"X-Version": "2"}
+
+	// when
+	changed := restartRequired(suite.cfg, next)
+
+	// then
+	suite.Equal([]string{"port"}, changed)
+}
+
+func (suite *ConfigReloadTestSuite) Test_Admin_requests_Then_handler_built_once_per_server() {
+
+	// given
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	defer zerolog.SetGlobalLevel(zerolog.Disabled)
+	logs := &bytes.Buffer{}
+	suite.cfg.DebugEndpoints = true
+	suite.Require().Nil(suite.sut.reloadConfig(suite.cfg, zerolog.New(logs)))
+	handler := suite.sut.adminHandler()
+
+	// when
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/readyz", nil))
+		suite.Equal(http.StatusOK, rr.Code)
+	}
+
+	// then
+	suite.Equal(1, strings.Count(logs.String(), "pprof and expvar endpoints are not served"))
+}
@@ -0,0 +1,106 @@
+package spaserver
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// containerLimits are the CPU and memory limits of the container, zero if unlimited
+type containerLimits struct {
+	// cpus is the CPU quota in cores
+	cpus float64
+	// memory is the memory limit in bytes
+	memory int64
+}
+
+// tuneRuntime aligns GOMAXPROCS and GOMEMLIMIT with the limits of the container,
+// unless set explicitly by the configuration or the environment
+func tuneRuntime(cfg Config, logger zerolog.Logger) {
+	limits, err := readContainerLimits()
+	if err != nil {
+		logger.Warn().Err(err).Msg("Cannot read container limits")
+	}
+
+	if procs := maxProcs(cfg.MaxProcs, limits.cpus, runtime.NumCPU()); procs > 0 && os.Getenv("GOMAXPROCS") == "" {
+		runtime.GOMAXPROCS(procs)
+		logger.Info().Int("gomaxprocs", procs).Float64("cpu_quota", limits.cpus).Msg("GOMAXPROCS set")
+	}
+
+	if cfg.ContainerMemoryLimitRatio > 0 && limits.memory > 0 && os.Getenv("GOMEMLIMIT") == "" {
+		limit := int64(float64(limits.memory) * cfg.ContainerMemoryLimitRatio)
+		debug.SetMemoryLimit(limit)
+		logger.Info().Int64("gomemlimit", limit).Int64("container_memory", limits.memory).Msg("GOMEMLIMIT set")
+	}
+}
+
+// maxProcs is the configured GOMAXPROCS, or the CPU quota rounded up when
+// zero, at most the count of CPUs. Zero keeps the runtime default.
+func maxProcs(configured int, cpus float64, numCPU int) int {
+	switch {
+	case configured > 0:
+		return configured
+	case configured < 0 || cpus <= 0:
+		return 0
+	default:
+		return max(1, min(numCPU, int(math.Ceil(cpus))))
+	}
+}
+
+// parseCgroupV2Cpu parses the cpu.max of the cgroup v2, e.g. `200000 100000` or `max 100000`
+func parseCgroupV2Cpu(content string) (float64, error) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 || fields[0] == "max" {
+		return 0, nil
+	}
+	quota, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cpu.max %q: %w", content, err)
+	}
+	period := 100000.0
+	if len(fields) > 1 {
+		if period, err = strconv.ParseFloat(fields[1], 64); err != nil || period <= 0 {
+			return 0, fmt.Errorf("invalid cpu.max %q", content)
+		}
+	}
+	return quota / period, nil
+}
+
+// parseCgroupV1Cpu parses the cpu.cfs_quota_us and cpu.cfs_period_us of the cgroup v1, unlimited if the quota is negative
+func parseCgroupV1Cpu(quota string, period string) (float64, error) {
+	q, err := strconv.ParseFloat(strings.TrimSpace(quota), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cpu.cfs_quota_us %q: %w", quota, err)
+	}
+	p, err := strconv.ParseFloat(strings.TrimSpace(period), 64)
+	if err != nil || p <= 0 {
+		return 0, fmt.Errorf("invalid cpu.cfs_period_us %q", period)
+	}
+	if q <= 0 {
+		return 0, nil
+	}
+	return q / p, nil
+}
+
+// parseCgroupMemory parses the memory.max of the cgroup v2 or the memory.limit_in_bytes
+// of the cgroup v1, the latter reports the unlimited memory as a huge number
+func parseCgroupMemory(content string) (int64, error) {
+	content = strings.TrimSpace(content)
+	if content == "max" || content == "" {
+		return 0, nil
+	}
+	limit, err := strconv.ParseInt(content, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory limit %q: %w", content, err)
+	}
+	if limit <= 0 || limit >= math.MaxInt64/2 {
+		return 0, nil
+	}
+	return limit, nil
+}
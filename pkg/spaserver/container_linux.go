@@ -0,0 +1,43 @@
+package spaserver
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// cgroupRoot is the mount point of the cgroup filesystem
+const cgroupRoot = "/sys/fs/cgroup"
+
+// readContainerLimits reads the limits of the cgroup v2, or the cgroup v1,
+// of the process, zero if the process is not limited
+func readContainerLimits() (containerLimits, error) {
+	limits := containerLimits{}
+	if cpuMax, err := os.ReadFile(filepath.Join(cgroupRoot, "cpu.max")); err == nil {
+		if limits.cpus, err = parseCgroupV2Cpu(string(cpuMax)); err != nil {
+			return limits, err
+		}
+		memoryMax, err := os.ReadFile(filepath.Join(cgroupRoot, "memory.max"))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return limits, err
+		}
+		limits.memory, err = parseCgroupMemory(string(memoryMax))
+		return limits, err
+	}
+
+	quota, quotaErr := os.ReadFile(filepath.Join(cgroupRoot, "cpu", "cpu.cfs_quota_us"))
+	period, periodErr := os.ReadFile(filepath.Join(cgroupRoot, "cpu", "cpu.cfs_period_us"))
+	if quotaErr == nil && periodErr == nil {
+		cpus, err := parseCgroupV1Cpu(string(quota), string(period))
+		if err != nil {
+			return limits, err
+		}
+		limits.cpus = cpus
+	}
+	if memory, err := os.ReadFile(filepath.Join(cgroupRoot, "memory", "memory.limit_in_bytes")); err == nil {
+		limits.memory, err = parseCgroupMemory(string(memory))
+		return limits, err
+	}
+	return limits, nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package spaserver
+
+// readContainerLimits reports no limits, the cgroups are specific to linux
+func readContainerLimits() (containerLimits, error) {
+	return containerLimits{}, nil
+}
@@ -0,0 +1,67 @@
+package spaserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ContainerTestSuite struct {
+	suite.Suite
+}
+
+func TestContainerTestSuite(t *testing.T) {
+	suite.Run(t, new(ContainerTestSuite))
+}
+
+func (suite *ContainerTestSuite) Test_Cgroup_v2_cpu_quota_Then_cores() {
+
+	// when
+	limited, limitedErr := parseCgroupV2Cpu("150000 100000\n")
+	unlimited, unlimitedErr := parseCgroupV2Cpu("max 100000\n")
+
+	// then
+	suite.Nil(limitedErr)
+	suite.Equal(1.5, limited)
+	suite.Nil(unlimitedErr)
+	suite.Zero(unlimited)
+}
+
+func (suite *ContainerTestSuite) Test_Cgroup_v1_cpu_quota_Then_cores() {
+
+	// when
+	limited, limitedErr := parseCgroupV1Cpu("50000\n", "100000\n")
+	unlimited, unlimitedErr := parseCgroupV1Cpu("-1\n", "100000\n")
+
+	// then
+	suite.Nil(limitedErr)
+	suite.Equal(0.5, limited)
+	suite.Nil(unlimitedErr)
+	suite.Zero(unlimited)
+}
+
+func (suite *ContainerTestSuite) Test_Cgroup_memory_Then_limit() {
+
+	// when
+	v2, _ := parseCgroupMemory("536870912\n")
+	v2Unlimited, _ := parseCgroupMemory("max\n")
+	v1Unlimited, _ := parseCgroupMemory("9223372036854771712\n")
+	_, err := parseCgroupMemory("lots")
+
+	// then
+	suite.Equal(int64(536870912), v2)
+	suite.Zero(v2Unlimited)
+	suite.Zero(v1Unlimited)
+	suite.NotNil(err)
+}
+
+func (suite *ContainerTestSuite) Test_Max_procs_Then_quota_rounded_up_within_cpus() {
+
+	// then
+	suite.Equal(2, maxProcs(0, 1.5, 8))
+	suite.Equal(1, maxProcs(0, 0.2, 8))
+	suite.Equal(8, maxProcs(0, 16, 8))
+	suite.Equal(0, maxProcs(0, 0, 8))
+	suite.Equal(3, maxProcs(3, 1.5, 8))
+	suite.Equal(0, maxProcs(-1, 1.5, 8))
+}
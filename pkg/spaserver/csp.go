@@ -0,0 +1,214 @@
+package spaserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// inline scripts, the scripts loaded by the src attribute are not hashed
+	inlineScriptRegex = regexp.MustCompile(`(?is)<script((?:\s[^>]*)?)>(.*?)</script\s*>`)
+	inlineStyleRegex  = regexp.MustCompile(`(?is)<style(?:\s[^>]*)?>(.*?)</style\s*>`)
+	srcAttributeRegex = regexp.MustCompile(`(?i)\ssrc\s*=`)
+)
+
+// applyContentSecurityPolicy sets the configured policies on the html responses. The
+// report-only policy is sent alongside the enforced one, so that a stricter policy can
+// be staged before it is enforced. With the inline hashes enabled, the hashes of the
+// inline scripts and styles of the document are added to the script-src and style-src
+// directives of both policies.
+func (this *server) applyContentSecurityPolicy(ctx context.Context, w http.ResponseWriter, name string) error {
+	if !isHtml(name) {
+		return nil
+	}
+	policies := map[string]string{}
+	if this.cfg.ContentSecurityPolicy != "" {
+		policies["Content-Security-Policy"] = this.cfg.ContentSecurityPolicy
+	}
+	if this.cfg.ContentSecurityPolicyReportOnly != "" {
+		policies["Content-Security-Policy-Report-Only"] = this.cfg.ContentSecurityPolicyReportOnly
+	}
+	if this.injectsNonce(ctx, name) {
+		if len(policies) == 0 {
+			policies["Content-Security-Policy"] = strictNoncePolicy
+		}
+		nonce := []string{"'nonce-" + cspNonce(ctx) + "'"}
+		for header, policy := range policies {
+			policies[header] = addCspSources(addCspSources(policy, "script-src", nonce), "style-src", nonce)
+		}
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+	if !this.cfg.CspInlineHashes {
+		for header, policy := range policies {
+			w.Header().Set(header, policy)
+		}
+		return nil
+	}
+
+	hashes, err := this.documentHashes(ctx, name)
+	if err != nil || hashes == nil {
+		return err
+	}
+	for header, policy := range policies {
+		policy = addCspSources(policy, "script-src", hashes.scripts)
+		policy = addCspSources(policy, "style-src", hashes.styles)
+		w.Header().Set(header, policy)
+	}
+	return nil
+}
+
+// cspHashes are the CSP sources of the inline scripts and styles of the document
+type cspHashes struct {
+	scripts []string
+	styles  []string
+}
+
+// documentHashes gets the hashes of the inline scripts and styles of the document,
+// nil if the document does not exist
+func (this *server) documentHashes(ctx context.Context, name string) (*cspHashes, error) {
+	// the hashes are computed from the unencoded document
+	file, ok, err := this.findFile(ctx, name)
+	if err != nil || !ok {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	key := fmt.Sprintf("%v|%v|%v|%v", rootSetKey(ctx), name, info.ModTime().UnixNano(), info.Size())
+	if hashes, ok := this.cspHashes.Load(key); ok {
+		recordCacheLookup(ctx, "csp_hashes", cacheHit)
+		file.Close()
+		return hashes.(*cspHashes), nil
+	}
+	recordCacheLookup(ctx, "csp_hashes", cacheMiss)
+
+	if this.transformsContent(ctx, name) {
+		// the hashes are computed from the served content
+		if file, err = this.transformContent(ctx, name, file); err != nil {
+			return nil, err
+		}
+	}
+	content, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := &cspHashes{}
+	hashes.scripts, hashes.styles = inlineHashes(content)
+	this.cache(&this.cspHashes, key, hashes, memoryCritical)
+	return hashes, nil
+}
+
+// inlineHashes computes the CSP sources of the inline scripts and styles of the document
+func inlineHashes(content []byte) (scripts []string, styles []string) {
+	hash := func(inline []byte) string {
+		digest := sha256.Sum256(inline)
+		return "'sha256-" + base64.StdEncoding.EncodeToString(digest[:]) + "'"
+	}
+	for _, match := range inlineScriptRegex.FindAllSubmatch(content, -1) {
+		if srcAttributeRegex.Match(match[1]) {
+			continue
+		}
+		scripts = append(scripts, hash(match[2]))
+	}
+	for _, match := range inlineStyleRegex.FindAllSubmatch(content, -1) {
+		styles = append(styles, hash(match[1]))
+	}
+	return scripts, styles
+}
+
+// addCspSources adds the sources to the directive of the policy. A missing directive
+// is created from default-src, without default-src the directive is not restricted
+// and the sources are not needed.
+func addCspSources(policy string, directive string, sources []string) string {
+	if len(sources) == 0 {
+		return policy
+	}
+
+	directives := strings.Split(policy, ";")
+	defaultSources := ""
+	for i, entry := range directives {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToLower(fields[0]) {
+		case directive:
+			directives[i] = " " + strings.Join(append(fields, sources...), " ")
+			return strings.TrimSpace(strings.Join(directives, ";"))
+		case "default-src":
+			defaultSources = strings.Join(fields[1:], " ")
+		}
+	}
+	if defaultSources == "" {
+		return policy
+	}
+	if defaultSources == "'none'" {
+		defaultSources = ""
+	}
+	created := strings.Join(append(strings.Fields(directive+" "+defaultSources), sources...), " ")
+	return strings.TrimSuffix(strings.TrimSpace(policy), ";") + "; " + created
+}
+
+// strictNoncePolicy is the policy of the nonces if none is configured, the
+// scripts loaded by the trusted scripts are allowed by strict-dynamic
+const strictNoncePolicy = "script-src 'strict-dynamic'; object-src 'none'; base-uri 'self'"
+
+var (
+	// opening script and style tags with their attributes
+	openingTagRegex = regexp.MustCompile(`(?is)<(script|style)(\s[^>]*)?>`)
+	nonceAttrRegex  = regexp.MustCompile(`(?is)\snonce\s*=\s*("[^"]*"|'[^']*'|[^\s>]*)`)
+)
+
+type cspNonceKey struct{}
+
+// withCspNonce generates the nonce of the response
+func withCspNonce(ctx context.Context) (context.Context, error) {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, cspNonceKey{}, base64.StdEncoding.EncodeToString(nonce[:])), nil
+}
+
+// cspNonce returns the nonce of the response, empty if not generated
+func cspNonce(ctx context.Context) string {
+	nonce, _ := ctx.Value(cspNonceKey{}).(string)
+	return nonce
+}
+
+// injectsNonce returns true if the nonce of the response is injected into the document
+func (this *server) injectsNonce(ctx context.Context, name string) bool {
+	return this.cfg.CspNonce && isHtml(name) && cspNonce(ctx) != ""
+}
+
+// injectNonce sets the nonce attribute of the script and style tags, replacing
+// the nonces of the document, e.g. the placeholders of the build
+func injectNonce(content []byte, nonce string) []byte {
+	return openingTagRegex.ReplaceAllFunc(content, func(tag []byte) []byte {
+		match := openingTagRegex.FindSubmatch(tag)
+		attributes := nonceAttrRegex.ReplaceAll(match[2], nil)
+		injected := append([]byte("<"), match[1]...)
+		injected = append(injected, ` nonce="`+nonce+`"`...)
+		injected = append(injected, attributes...)
+		return append(injected, '>')
+	})
+}
+
+func isHtml(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".html" || ext == ".htm"
+}
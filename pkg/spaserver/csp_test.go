@@ -0,0 +1,175 @@
+package spaserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"regexp"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type CspTestSuite struct {
+	suite.Suite
+	rootDir string
+}
+
+func TestCspTestSuite(t *testing.T) {
+	suite.Run(t, new(CspTestSuite))
+}
+
+func (suite *CspTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "index.html"), []byte(
+		`<script src="/main.js"></script><script>init()</script><style>body{margin:0}</style>`), 0644))
+}
+
+func (suite *CspTestSuite) hash(inline string) string {
+	digest := sha256.Sum256([]byte(inline))
+	return "'sha256-" + base64.StdEncoding.EncodeToString(digest[:]) + "'"
+}
+
+func (suite *CspTestSuite) Test_Inline_hashes_Then_added_to_policy() {
+
+	// given
+	sut := &server{
+		cfg: Config{
+			RootDirs:              []string{suite.rootDir},
+			BaseURL:               "/",
+			ContentSecurityPolicy: "default-src 'self'; script-src 'self'",
+			CspInlineHashes:       true,
+		},
+		logger: zerolog.New(os.Stdout),
+	}
+	req, err := http.NewRequest("GET", "/some/route", nil)
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(
+		"default-src 'self'; script-src 'self' "+suite.hash("init()")+"; style-src 'self' "+suite.hash("body{margin:0}"),
+		rr.Header().Get("Content-Security-Policy"))
+}
+
+func (suite *CspTestSuite) Test_Policy_without_default_src_Then_directive_not_created() {
+
+	// when
+	policy := addCspSources("object-src 'none'", "script-src", []string{"'sha256-x'"})
+
+	// then
+	suite.Equal("object-src 'none'", policy)
+}
+
+func (suite *CspTestSuite) Test_Non_html_response_Then_no_policy() {
+
+	// given
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "main.js"), []byte("init()"), 0644))
+	sut := &server{
+		cfg: Config{
+			RootDirs:              []string{suite.rootDir},
+			BaseURL:               "/",
+			ContentSecurityPolicy: "default-src 'self'",
+		},
+		logger: zerolog.New(os.Stdout),
+	}
+	req, err := http.NewRequest("GET", "/main.js", nil)
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Empty(rr.Header().Get("Content-Security-Policy"))
+}
+
+func (suite *CspTestSuite) Test_Report_only_policy_Then_sent_alongside_enforced_policy() {
+
+	// given
+	sut := &server{
+		cfg: Config{
+			RootDirs:                        []string{suite.rootDir},
+			BaseURL:                         "/",
+			ContentSecurityPolicy:           "object-src 'none'",
+			ContentSecurityPolicyReportOnly: "default-src 'self'; report-uri /csp-reports",
+			CspInlineHashes:                 true,
+		},
+		logger: zerolog.New(os.Stdout),
+	}
+	req, err := http.NewRequest("GET", "/", nil)
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("object-src 'none'", rr.Header().Get("Content-Security-Policy"))
+	suite.Equal(
+		"default-src 'self'; report-uri /csp-reports; script-src 'self' "+suite.hash("init()")+"; style-src 'self' "+suite.hash("body{margin:0}"),
+		rr.Header().Get("Content-Security-Policy-Report-Only"))
+}
+
+func (suite *CspTestSuite) nonceResponse(cfg Config) *httptest.ResponseRecorder {
+	cfg.RootDirs = []string{suite.rootDir}
+	cfg.BaseURL = "/"
+	cfg.CspNonce = true
+	sut, err := newServer(cfg, zerolog.New(os.Stdout))
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", "/some/route", nil)
+	req.Header.Set("Accept", "text/html")
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *CspTestSuite) Test_Nonce_Then_injected_into_tags_and_policy() {
+
+	// when
+	rr := suite.nonceResponse(Config{ContentSecurityPolicy: "default-src 'self'; script-src 'self'", Etag: "weak"})
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	match := regexp.MustCompile(`<script nonce="([^"]+)" src="/main.js"></script>`).FindStringSubmatch(rr.Body.String())
+	suite.Require().Len(match, 2)
+	nonce := match[1]
+	suite.Contains(rr.Body.String(), `<script nonce="`+nonce+`">init()</script><style nonce="`+nonce+`">`)
+	suite.Equal("default-src 'self'; script-src 'self' 'nonce-"+nonce+"'; style-src 'self' 'nonce-"+nonce+"'",
+		rr.Header().Get("Content-Security-Policy"))
+	suite.Empty(rr.Header().Get("ETag"))
+	suite.Empty(rr.Header().Get("Last-Modified"))
+}
+
+func (suite *CspTestSuite) Test_Nonce_Then_different_per_response() {
+
+	// when
+	first := suite.nonceResponse(Config{})
+	second := suite.nonceResponse(Config{})
+
+	// then
+	suite.Contains(first.Header().Get("Content-Security-Policy"), "'strict-dynamic'")
+	suite.NotEqual(first.Header().Get("Content-Security-Policy"), second.Header().Get("Content-Security-Policy"))
+	suite.NotEqual(first.Body.String(), second.Body.String())
+}
+
+func (suite *CspTestSuite) Test_Nonce_placeholder_Then_replaced() {
+
+	// when
+	content := injectNonce([]byte(`<script nonce="__NONCE__" type="module">x()</script><scripts></scripts>`), "abc")
+
+	// then
+	suite.Equal(`<script nonce="abc" type="module">x()</script><scripts></scripts>`, string(content))
+}
@@ -0,0 +1,102 @@
+package spaserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	// debugRequestHeader requests the description of the lookup decisions
+	debugRequestHeader = "X-Debug"
+	// debugResponseHeader describes the lookup decisions of the response
+	debugResponseHeader = "X-Debug-Lookup"
+)
+
+type lookupDebugKey struct{}
+
+// lookupDebug collects the decisions taken while resolving the request
+type lookupDebug struct {
+	mutex sync.Mutex
+	steps []string
+}
+
+// debugRequested reports whether the request asks for the debug header, the
+// value of the request header must match the admin token if configured
+func (this *server) debugRequested(req *http.Request) bool {
+	if !this.cfg.DebugHeader {
+		return false
+	}
+	value := req.Header.Get(debugRequestHeader)
+	if value == "" {
+		return false
+	}
+	return this.cfg.AdminToken == "" ||
+		subtle.ConstantTimeCompare([]byte(value), []byte(this.cfg.AdminToken)) == 1
+}
+
+// withLookupDebug attaches the collector of the lookup decisions to the context
+func withLookupDebug(ctx context.Context) (context.Context, *lookupDebug) {
+	debug := &lookupDebug{}
+	return context.WithValue(ctx, lookupDebugKey{}, debug), debug
+}
+
+// debugging reports whether the lookup decisions of the request are collected
+func debugging(ctx context.Context) bool {
+	_, ok := ctx.Value(lookupDebugKey{}).(*lookupDebug)
+	return ok
+}
+
+// debugLookup records the lookup decision if the request is debugged
+func debugLookup(ctx context.Context, format string, args ...any) {
+	debug, ok := ctx.Value(lookupDebugKey{}).(*lookupDebug)
+	if !ok {
+		return
+	}
+	debug.mutex.Lock()
+	defer debug.mutex.Unlock()
+	debug.steps = append(debug.steps, fmt.Sprintf(format, args...))
+}
+
+// String joins the decisions to the single header value
+func (this *lookupDebug) String() string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return strings.Join(this.steps, "; ")
+}
+
+// debugWriter sets the debug header with the decisions taken until the response is started
+type debugWriter struct {
+	responseWriterPassthrough
+	debug   *lookupDebug
+	started bool
+}
+
+func (this *debugWriter) start() {
+	if !this.started {
+		this.started = true
+		this.Header().Set(debugResponseHeader, this.debug.String())
+	}
+}
+
+func (this *debugWriter) WriteHeader(status int) {
+	if !informational(status) {
+		this.start()
+	}
+	this.ResponseWriter.WriteHeader(status)
+}
+
+func (this *debugWriter) Write(b []byte) (int, error) {
+	this.start()
+	return this.ResponseWriter.Write(b)
+}
+
+// ReadFrom keeps the zero-copy path of the underlying writer
+func (this *debugWriter) ReadFrom(src io.Reader) (int64, error) {
+	this.start()
+	return this.readFrom(src)
+}
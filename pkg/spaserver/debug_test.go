@@ -0,0 +1,89 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type DebugTestSuite struct {
+	suite.Suite
+	rootDir string
+}
+
+func TestDebugTestSuite(t *testing.T) {
+	suite.Run(t, new(DebugTestSuite))
+}
+
+func (suite *DebugTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "index.html"), []byte("<html></html>"), 0644))
+}
+
+func (suite *DebugTestSuite) serve(cfg Config, target string, debug string) *httptest.ResponseRecorder {
+	cfg.RootDirs = []string{suite.rootDir}
+	cfg.BaseURL = "/"
+	cfg.NotFoundRegexs = []string{`\.js$`}
+	cfg.HeadersPerPathRegex = map[string]map[string]string{`\.html$`: {"X-Frame-Options": "DENY"}}
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", target, nil)
+	if debug != "" {
+		req.Header.Set("X-Debug", debug)
+	}
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *DebugTestSuite) Test_Fallback_Then_decisions_described() {
+
+	// when
+	rr := suite.serve(Config{DebugHeader: true}, "/about", "1")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(
+		suite.rootDir+":about missing; fallback /index.html; "+
+			suite.rootDir+":index.html found; "+`headers-per-regexp \.html$; default cache-control`,
+		rr.Header().Get("X-Debug-Lookup"))
+}
+
+func (suite *DebugTestSuite) Test_Not_found_Then_fallback_decision_described() {
+
+	// when
+	rr := suite.serve(Config{DebugHeader: true}, "/main.js", "1")
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+	suite.Equal(
+		suite.rootDir+`:main.js missing; fallback skipped: not-found-regexp \.js$; not found`,
+		rr.Header().Get("X-Debug-Lookup"))
+}
+
+func (suite *DebugTestSuite) Test_Disabled_Then_no_header() {
+
+	// when
+	rr := suite.serve(Config{}, "/about", "1")
+
+	// then
+	suite.Empty(rr.Header().Get("X-Debug-Lookup"))
+}
+
+func (suite *DebugTestSuite) Test_Admin_token_mismatch_Then_no_header() {
+
+	// when
+	rr := suite.serve(Config{DebugHeader: true, AdminToken: "secret"}, "/about", "1")
+
+	// then
+	suite.Empty(rr.Header().Get("X-Debug-Lookup"))
+	suite.NotEmpty(suite.serve(Config{DebugHeader: true, AdminToken: "secret"}, "/about", "secret").Header().Get("X-Debug-Lookup"))
+}
@@ -0,0 +1,77 @@
+package spaserver
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// publishRuntimeOnce publishes the runtime variables once, expvar panics on
+// the variables published again, e.g. by the reloaded configuration
+var publishRuntimeOnce sync.Once
+
+// handleDebugEndpoints registers the diagnostic endpoints on the admin mux: the
+// pprof profiles, the expvar variables with the runtime stats, and the live
+// change of the logging level, so that the memory and the CPU issues are
+// diagnosed in production without rebuilding the image. The profiles and the
+// variables expose the command line and the memory, they are not served without
+// the admin token unless explicitly allowed.
+func (this *server) handleDebugEndpoints(mux *http.ServeMux) {
+	if !this.cfg.DebugEndpoints {
+		return
+	}
+	mux.HandleFunc("/debug/log-level", this.serveLogLevel)
+	if this.cfg.AdminToken == "" && !this.cfg.DebugEndpointsInsecure {
+		this.logger.Warn().Msg("The pprof and expvar endpoints are not served without the admin token")
+		return
+	}
+	publishRuntimeOnce.Do(func() {
+		started := time.Now()
+		expvar.Publish("runtime", expvar.Func(func() any {
+			return map[string]any{
+				"goroutines": runtime.NumGoroutine(),
+				"gomaxprocs": runtime.GOMAXPROCS(0),
+				"cpus":       runtime.NumCPU(),
+				"version":    runtime.Version(),
+				"uptime":     time.Since(started).String(),
+			}
+		}))
+	})
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+}
+
+// serveLogLevel reports the logging level, PUT changes it to the `level` until
+// the restart or the reload of the configuration and requires the admin token
+func (this *server) serveLogLevel(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodPut:
+		if this.cfg.AdminToken == "" {
+			http.Error(w, "Admin token not configured", http.StatusForbidden)
+			return
+		}
+		level, ok := loggingLevel(req.URL.Query().Get("level"))
+		if !ok {
+			http.Error(w, "Invalid logging level", http.StatusBadRequest)
+			return
+		}
+		previous := zerolog.GlobalLevel()
+		zerolog.SetGlobalLevel(level)
+		this.logger.Warn().Stringer("previous", previous).Stringer("level", level).Msg("Logging level changed")
+	case http.MethodGet:
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"level": zerolog.GlobalLevel().String()})
+}
@@ -0,0 +1,158 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type DebugEndpointsTestSuite struct {
+	suite.Suite
+}
+
+func TestDebugEndpointsTestSuite(t *testing.T) {
+	suite.Run(t, new(DebugEndpointsTestSuite))
+}
+
+func (suite *DebugEndpointsTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+}
+
+func (suite *DebugEndpointsTestSuite) TearDownTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+}
+
+func (suite *DebugEndpointsTestSuite) admin(cfg Config, method string, target string, token string) *httptest.ResponseRecorder {
+	cfg.RootDirs = []string{suite.T().TempDir()}
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	req := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	sut.adminHandler().ServeHTTP(rr, req)
+	return rr
+}
+
+func (suite *DebugEndpointsTestSuite) Test_Enabled_Then_pprof_and_vars_served() {
+
+	// given
+	cfg := Config{DebugEndpoints: true, AdminToken: "secret"}
+
+	// when
+	index := suite.admin(cfg, "GET", "/debug/pprof/", "secret")
+	heap := suite.admin(cfg, "GET", "/debug/pprof/heap?debug=1", "secret")
+	vars := suite.admin(cfg, "GET", "/debug/vars", "secret")
+
+	// then
+	suite.Equal(http.StatusOK, index.Code)
+	suite.Contains(index.Body.String(), "goroutine")
+	suite.Equal(http.StatusOK, heap.Code)
+	suite.Equal(http.StatusOK, vars.Code)
+	published := map[string]json.RawMessage{}
+	suite.Require().Nil(json.Unmarshal(vars.Body.Bytes(), &published))
+	suite.Contains(published, "memstats")
+	suite.Contains(string(published["runtime"]), "goroutines")
+}
+
+func (suite *DebugEndpointsTestSuite) Test_Disabled_Then_not_found() {
+
+	// when
+	rr := suite.admin(Config{}, "GET", "/debug/pprof/", "")
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+}
+
+func (suite *DebugEndpointsTestSuite) Test_Admin_token_Then_required_by_pprof() {
+
+	// when
+	rr := suite.admin(Config{DebugEndpoints: true, AdminToken: "secret"}, "GET", "/debug/pprof/", "")
+
+	// then
+	suite.Equal(http.StatusUnauthorized, rr.Code)
+}
+
+func (suite *DebugEndpointsTestSuite) Test_Without_admin_token_Then_pprof_and_vars_not_served() {
+
+	// when
+	pprof := suite.admin(Config{DebugEndpoints: true}, "GET", "/debug/pprof/", "")
+	vars := suite.admin(Config{DebugEndpoints: true}, "GET", "/debug/vars", "")
+
+	// then
+	suite.Equal(http.StatusNotFound, pprof.Code)
+	suite.Equal(http.StatusNotFound, vars.Code)
+}
+
+func (suite *DebugEndpointsTestSuite) Test_Insecure_without_admin_token_Then_pprof_served() {
+
+	// when
+	rr := suite.admin(Config{DebugEndpoints: true, DebugEndpointsInsecure: true}, "GET", "/debug/pprof/", "")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+}
+
+func (suite *DebugEndpointsTestSuite) Test_Log_level_without_admin_token_Then_reported() {
+
+	// when
+	rr := suite.admin(Config{DebugEndpoints: true}, "GET", "/debug/log-level", "")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.JSONEq(`{"level": "disabled"}`, rr.Body.String())
+}
+
+func (suite *DebugEndpointsTestSuite) Test_Log_level_changed_Then_applied() {
+
+	// given
+	cfg := Config{DebugEndpoints: true, AdminToken: "secret"}
+
+	// when
+	rr := suite.admin(cfg, "PUT", "/debug/log-level?level=debug", "secret")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.JSONEq(`{"level": "debug"}`, rr.Body.String())
+	suite.Equal(zerolog.DebugLevel, zerolog.GlobalLevel())
+}
+
+func (suite *DebugEndpointsTestSuite) Test_Invalid_log_level_Then_bad_request() {
+
+	// when
+	rr := suite.admin(Config{DebugEndpoints: true, AdminToken: "secret"}, "PUT", "/debug/log-level?level=verbose", "secret")
+
+	// then
+	suite.Equal(http.StatusBadRequest, rr.Code)
+	suite.Equal(zerolog.Disabled, zerolog.GlobalLevel())
+}
+
+func (suite *DebugEndpointsTestSuite) Test_Log_level_without_admin_token_Then_forbidden() {
+
+	// when
+	rr := suite.admin(Config{DebugEndpoints: true}, "PUT", "/debug/log-level?level=debug", "")
+
+	// then
+	suite.Equal(http.StatusForbidden, rr.Code)
+	suite.Equal(zerolog.Disabled, zerolog.GlobalLevel())
+}
+
+func (suite *DebugEndpointsTestSuite) Test_Logging_level_names_Then_parsed() {
+
+	for name, expected := range map[string]zerolog.Level{
+		"warn": zerolog.WarnLevel, "Warning": zerolog.WarnLevel, "info": zerolog.InfoLevel, "information": zerolog.InfoLevel,
+	} {
+		// when
+		level, ok := loggingLevel(name)
+
+		// then
+		suite.True(ok, name)
+		suite.Equal(expected, level, name)
+	}
+}
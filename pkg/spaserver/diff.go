@@ -0,0 +1,155 @@
+package spaserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"sort"
+)
+
+// manifestEntry describes the served file in the asset manifest
+type manifestEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+type manifestChange struct {
+	Path string        `json:"path"`
+	From manifestEntry `json:"from"`
+	To   manifestEntry `json:"to"`
+}
+
+type manifestDiff struct {
+	From      string           `json:"from"`
+	To        string           `json:"to"`
+	Added     []manifestEntry  `json:"added"`
+	Removed   []manifestEntry  `json:"removed"`
+	Changed   []manifestChange `json:"changed"`
+	Unchanged int              `json:"unchanged"`
+}
+
+// serveDiff compares the files served by the `from` and `to` roots, e.g. before
+// flipping the rollout or the scheduled switchover. The roots are `current`,
+// `rollout`, `scheduled` or the version of the versions directory.
+func (this *server) serveDiff(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	from, to := req.URL.Query().Get("from"), req.URL.Query().Get("to")
+	if from == "" || to == "" {
+		http.Error(w, "Both from and to roots are required", http.StatusBadRequest)
+		return
+	}
+
+	manifests := make([]map[string]manifestEntry, 2)
+	for i, name := range []string{from, to} {
+		roots, err := this.namedRoots(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if manifests[i], err = assetManifest(roots); err != nil {
+			this.logger.Err(err).Str("roots", name).Msg("Cannot compute asset manifest")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, diffManifests(from, to, manifests[0], manifests[1]))
+}
+
+// namedRoots resolves the roots compared by the diff
+func (this *server) namedRoots(name string) ([]assetRoot, error) {
+	roots, err := this.assetRoots()
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "current":
+		return roots, nil
+	case variantRollout:
+		if this.rolloutEnabled() {
+			return this.rolloutRoots, nil
+		}
+	case "scheduled":
+		if len(this.scheduled.roots) > 0 {
+			return this.scheduled.roots, nil
+		}
+	default:
+		if version, _, ok := this.versionedPath(versionsPrefix + name); ok && version == name {
+			roots, err := this.versionRoots(name)
+			if err != nil || roots != nil {
+				return roots, err
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown roots %v", name)
+}
+
+// assetManifest hashes the files served from the roots, the file of the first
+// root containing it is served
+func assetManifest(roots []assetRoot) (map[string]manifestEntry, error) {
+	manifest := map[string]manifestEntry{}
+	for _, root := range roots {
+		err := fs.WalkDir(root.fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			if _, ok := manifest[name]; ok || !entry.Type().IsRegular() {
+				return nil
+			}
+			file, err := root.fsys.Open(name)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			digest := sha256.New()
+			size, err := io.Copy(digest, file)
+			if err != nil {
+				return err
+			}
+			manifest[name] = manifestEntry{Path: name, Size: size, Sha256: hex.EncodeToString(digest.Sum(nil))}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot read root %v: %w", rootLabel(root.name), err)
+		}
+	}
+	return manifest, nil
+}
+
+func diffManifests(from string, to string, fromManifest map[string]manifestEntry, toManifest map[string]manifestEntry) manifestDiff {
+	diff := manifestDiff{
+		From:    from,
+		To:      to,
+		Added:   []manifestEntry{},
+		Removed: []manifestEntry{},
+		Changed: []manifestChange{},
+	}
+	for name, entry := range toManifest {
+		previous, ok := fromManifest[name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, entry)
+		case previous != entry:
+			diff.Changed = append(diff.Changed, manifestChange{Path: name, From: previous, To: entry})
+		default:
+			diff.Unchanged++
+		}
+	}
+	for name, entry := range fromManifest {
+		if _, ok := toManifest[name]; !ok {
+			diff.Removed = append(diff.Removed, entry)
+		}
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Path < diff.Added[j].Path })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Path < diff.Removed[j].Path })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Path < diff.Changed[j].Path })
+	return diff
+}
@@ -0,0 +1,88 @@
+package spaserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type DiffTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestDiffTestSuite(t *testing.T) {
+	suite.Run(t, new(DiffTestSuite))
+}
+
+func (suite *DiffTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	versionsDir := suite.T().TempDir()
+	files := map[string]map[string]string{
+		"1.0.0": {"index.html": "index", "main-1.js": "one", "logo.svg": "<svg/>"},
+		"2.0.0": {"index.html": "index v2", "main-2.js": "two", "logo.svg": "<svg/>"},
+	}
+	for version, contents := range files {
+		suite.Require().Nil(os.MkdirAll(path.Join(versionsDir, version), 0755))
+		for name, content := range contents {
+			suite.Require().Nil(os.WriteFile(path.Join(versionsDir, version, name), []byte(content), 0644))
+		}
+	}
+
+	sut, err := newServer(Config{
+		RootDirs:    []string{path.Join(versionsDir, "1.0.0")},
+		VersionsDir: versionsDir,
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *DiffTestSuite) entry(name string, content string) manifestEntry {
+	digest := sha256.Sum256([]byte(content))
+	return manifestEntry{Path: name, Size: int64(len(content)), Sha256: hex.EncodeToString(digest[:])}
+}
+
+func (suite *DiffTestSuite) Test_Diff_Then_changes_listed() {
+
+	// given
+	req := httptest.NewRequest("GET", "/diff?from=current&to=2.0.0", nil)
+	rr := httptest.NewRecorder()
+
+	// when
+	suite.sut.adminHandler().ServeHTTP(rr, req)
+
+	// then
+	suite.Require().Equal(http.StatusOK, rr.Code)
+	diff := manifestDiff{}
+	suite.Require().Nil(json.Unmarshal(rr.Body.Bytes(), &diff))
+	suite.Equal(manifestDiff{
+		From:      "current",
+		To:        "2.0.0",
+		Added:     []manifestEntry{suite.entry("main-2.js", "two")},
+		Removed:   []manifestEntry{suite.entry("main-1.js", "one")},
+		Changed:   []manifestChange{{Path: "index.html", From: suite.entry("index.html", "index"), To: suite.entry("index.html", "index v2")}},
+		Unchanged: 1,
+	}, diff)
+}
+
+func (suite *DiffTestSuite) Test_Unknown_roots_Then_not_found() {
+
+	// given
+	req := httptest.NewRequest("GET", "/diff?from=current&to=../1.0.0", nil)
+	rr := httptest.NewRecorder()
+
+	// when
+	suite.sut.adminHandler().ServeHTTP(rr, req)
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+}
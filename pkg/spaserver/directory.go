@@ -0,0 +1,157 @@
+package spaserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// directoryEntry is an entry of the JSON directory listing
+type directoryEntry struct {
+	Name    string    `json:"name"`
+	Dir     bool      `json:"dir"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// directoryEnabled reports whether the directory paths are resolved
+func (this *server) directoryEnabled() bool {
+	return this.cfg.DirectoryIndex || this.cfg.DirectoryListing
+}
+
+// serveDirectory serves the directory found in the roots with its index document
+// or its listing. The directory paths without the trailing slash are redirected
+// to the path with the slash, so that the relative urls of the documents resolve
+// within the directory.
+func (this *server) serveDirectory(ctx context.Context, w http.ResponseWriter, req *http.Request, resourcePath string) (bool, error) {
+	name := rootName(resourcePath)
+	if name == "." || !this.directoryEnabled() {
+		return false, nil
+	}
+	roots, err := this.requestRoots(ctx)
+	if err != nil {
+		return false, err
+	}
+	dirs := []fs.FS{}
+	for _, root := range roots {
+		info, err := fs.Stat(root.fsys, name)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, fs.ErrInvalid) {
+			return false, err
+		}
+		if err == nil && info.IsDir() {
+			dirs = append(dirs, root.fsys)
+		}
+	}
+	if len(dirs) == 0 {
+		return false, nil
+	}
+
+	index := ""
+	if this.cfg.DirectoryIndex {
+		file, ok, err := this.findFile(ctx, path.Join(name, "index.html"))
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			file.Close()
+			index = path.Join(name, "index.html")
+		}
+	}
+	listed := index == "" && this.directoryListed(req.URL.Path)
+	if index == "" && !listed {
+		debugLookup(ctx, "directory %v not served", name)
+		return false, nil
+	}
+
+	if !strings.HasSuffix(req.URL.Path, "/") {
+		// relative to the requested path, which may be prefixed by the proxies
+		location := path.Base(req.URL.Path) + "/"
+		if req.URL.RawQuery != "" {
+			location += "?" + req.URL.RawQuery
+		}
+		debugLookup(ctx, "directory %v redirected", name)
+		w.Header().Set("Location", location)
+		w.WriteHeader(http.StatusMovedPermanently)
+		return true, nil
+	}
+
+	if index != "" {
+		debugLookup(ctx, "directory index %v", index)
+		return this.findAndServeHinted(ctx, index, w, req)
+	}
+	debugLookup(ctx, "directory listing %v", name)
+	return true, this.serveListing(w, req, name, dirs)
+}
+
+// directoryListed reports whether the listing of the request path is enabled,
+// all the directories are listed if no regexp is configured
+func (this *server) directoryListed(requestPath string) bool {
+	if !this.cfg.DirectoryListing {
+		return false
+	}
+	return len(this.cfg.DirectoryListingRegexs) == 0 || this.regexes().directoryListing.matches(requestPath)
+}
+
+// serveListing lists the entries of the directory merged over the roots, the
+// entries of the first roots override the later ones. The hidden files and the
+// configuration files are not listed. The listing is JSON if the client
+// accepts it, HTML otherwise.
+func (this *server) serveListing(w http.ResponseWriter, req *http.Request, name string, dirs []fs.FS) error {
+	entries := map[string]directoryEntry{}
+	for _, fsys := range dirs {
+		children, err := fs.ReadDir(fsys, name)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			if _, ok := entries[child.Name()]; ok || strings.HasPrefix(child.Name(), ".") ||
+				this.configFileRequested(path.Join(name, child.Name())) {
+				continue
+			}
+			info, err := child.Info()
+			if err != nil {
+				return err
+			}
+			entry := directoryEntry{Name: child.Name(), Dir: child.IsDir(), ModTime: info.ModTime().UTC()}
+			if !entry.Dir {
+				entry.Size = info.Size()
+			}
+			entries[child.Name()] = entry
+		}
+	}
+	listing := make([]directoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		listing = append(listing, entry)
+	}
+	sort.Slice(listing, func(i, j int) bool { return listing[i].Name < listing[j].Name })
+
+	w.Header().Add("Vary", "Accept")
+	if acceptsAny(req, []string{"application/json"}) {
+		writeJSON(w, http.StatusOK, listing)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	title := html.EscapeString(req.URL.Path)
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>%v</title></head>\n<body>\n<h1>%v</h1>\n<ul>\n", title, title)
+	fmt.Fprintf(w, "<li><a href=\"../\">../</a></li>\n")
+	for _, entry := range listing {
+		label := entry.Name
+		if entry.Dir {
+			label += "/"
+		}
+		link := (&url.URL{Path: "./" + label}).String()
+		fmt.Fprintf(w, "<li><a href=\"%v\">%v</a></li>\n", html.EscapeString(link), html.EscapeString(label))
+	}
+	fmt.Fprintf(w, "</ul>\n</body>\n</html>\n")
+	return nil
+}
@@ -0,0 +1,140 @@
+package spaserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type DirectoryTestSuite struct {
+	suite.Suite
+	rootDir string
+}
+
+func TestDirectoryTestSuite(t *testing.T) {
+	suite.Run(t, new(DirectoryTestSuite))
+}
+
+func (suite *DirectoryTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	for name, content := range map[string]string{
+		"index.html":          "shell",
+		"docs/index.html":     "docs",
+		"assets/main.js":      "main",
+		"assets/img/logo.svg": "<svg/>",
+		"assets/.secret":      "secret",
+	} {
+		suite.Require().Nil(os.MkdirAll(path.Dir(path.Join(suite.rootDir, name)), 0755))
+		suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, name), []byte(content), 0644))
+	}
+}
+
+func (suite *DirectoryTestSuite) serve(cfg Config, target string, accept string) *httptest.ResponseRecorder {
+	cfg.RootDirs = []string{suite.rootDir}
+	cfg.BaseURL = "/"
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", target, nil)
+	req.Header.Set("Accept", accept)
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *DirectoryTestSuite) Test_Directory_without_slash_Then_redirected() {
+
+	// when
+	rr := suite.serve(Config{DirectoryIndex: true}, "/docs?lang=en", "text/html")
+
+	// then
+	suite.Equal(http.StatusMovedPermanently, rr.Code)
+	suite.Equal("docs/?lang=en", rr.Header().Get("Location"))
+}
+
+func (suite *DirectoryTestSuite) Test_Directory_with_slash_Then_index_served() {
+
+	// when
+	rr := suite.serve(Config{DirectoryIndex: true}, "/docs/", "text/html")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("docs", rr.Body.String())
+}
+
+func (suite *DirectoryTestSuite) Test_Directory_disabled_Then_fallback() {
+
+	// when
+	rr := suite.serve(Config{}, "/docs/", "text/html")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("shell", rr.Body.String())
+}
+
+func (suite *DirectoryTestSuite) Test_Directory_without_index_and_listing_Then_fallback() {
+
+	// when
+	rr := suite.serve(Config{DirectoryIndex: true}, "/assets", "text/html")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("shell", rr.Body.String())
+}
+
+func (suite *DirectoryTestSuite) Test_Listing_Then_html_entries() {
+
+	// when
+	rr := suite.serve(Config{DirectoryListing: true}, "/assets/", "text/html")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	suite.Contains(rr.Body.String(), `<a href="./img/">img/</a>`)
+	suite.Contains(rr.Body.String(), `<a href="./main.js">main.js</a>`)
+	suite.NotContains(rr.Body.String(), ".secret")
+}
+
+func (suite *DirectoryTestSuite) Test_Listing_accepts_json_Then_json_entries() {
+
+	// when
+	rr := suite.serve(Config{DirectoryListing: true}, "/assets/", "application/json")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("application/json", rr.Header().Get("Content-Type"))
+	listing := []directoryEntry{}
+	suite.Require().Nil(json.Unmarshal(rr.Body.Bytes(), &listing))
+	suite.Require().Len(listing, 2)
+	suite.Equal("img", listing[0].Name)
+	suite.True(listing[0].Dir)
+	suite.Equal("main.js", listing[1].Name)
+	suite.Equal(int64(4), listing[1].Size)
+}
+
+func (suite *DirectoryTestSuite) Test_Listing_path_not_matching_Then_fallback() {
+
+	// when
+	rr := suite.serve(Config{DirectoryListing: true, DirectoryListingRegexs: []string{`^/assets/img/`}}, "/assets/", "text/html")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("shell", rr.Body.String())
+}
+
+func (suite *DirectoryTestSuite) Test_Index_and_listing_Then_index_preferred() {
+
+	// when
+	rr := suite.serve(Config{DirectoryIndex: true, DirectoryListing: true}, "/docs/", "text/html")
+
+	// then
+	suite.Equal("docs", rr.Body.String())
+}
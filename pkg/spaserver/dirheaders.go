@@ -0,0 +1,99 @@
+package spaserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// directoryHeaders are the header overrides declared by the directory for its subtree
+type directoryHeaders struct {
+	// Headers are set on all the files of the subtree.
+	Headers map[string]string `yaml:"headers"`
+	// HeadersPerPathRegex are set on the files whose path relative to the directory matches the regexp.
+	HeadersPerPathRegex map[string]map[string]string `yaml:"headers-per-regexp"`
+
+	// headersPerPath are the regexps compiled on the load of the file
+	headersPerPath []headersMatcher
+}
+
+// applyDirectoryHeaders sets the headers declared by the override files of the directories
+// containing the file, the deeper directories override the outer ones, and the overrides
+// take precedence over the configured headers and policies
+func (this *server) applyDirectoryHeaders(ctx context.Context, w http.ResponseWriter, name string) error {
+	if this.cfg.DirectoryHeadersFile == "" {
+		return nil
+	}
+	file := rootName(name)
+	segments := strings.Split(file, "/")
+	for depth := 0; depth < len(segments); depth++ {
+		dir := path.Join(append([]string{"."}, segments[:depth]...)...)
+		overrides, err := this.directoryHeaders(ctx, dir)
+		if err != nil {
+			return err
+		}
+		if overrides == nil {
+			continue
+		}
+		relative := path.Join(segments[depth:]...)
+		for header, value := range overrides.Headers {
+			w.Header().Set(header, value)
+		}
+		for _, matcher := range overrides.headersPerPath {
+			if matcher.regex.MatchString(relative) {
+				debugLookup(ctx, "%v headers-per-regexp %v", path.Join(dir, this.cfg.DirectoryHeadersFile), matcher.regex)
+				for header, value := range matcher.headers {
+					w.Header().Set(header, value)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// directoryHeaders loads the override file of the directory, nil if the directory has none
+func (this *server) directoryHeaders(ctx context.Context, dir string) (*directoryHeaders, error) {
+	key := rootSetKey(ctx) + "|" + dir
+	if overrides, ok := this.dirHeaders.Load(key); ok {
+		if overrides.(*directoryHeaders) == nil {
+			recordCacheLookup(ctx, "dir_headers", cacheNegativeHit)
+		} else {
+			recordCacheLookup(ctx, "dir_headers", cacheHit)
+		}
+		return overrides.(*directoryHeaders), nil
+	}
+	recordCacheLookup(ctx, "dir_headers", cacheMiss)
+
+	var overrides *directoryHeaders
+	file, ok, err := this.findFile(ctx, path.Join(dir, this.cfg.DirectoryHeadersFile))
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		defer file.Close()
+		content, err := io.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+		overrides = &directoryHeaders{}
+		if err := yaml.Unmarshal(content, overrides); err != nil {
+			return nil, fmt.Errorf("cannot decode header overrides of %v: %w", dir, err)
+		}
+		for _, pattern := range sortedKeys(overrides.HeadersPerPathRegex) {
+			regex, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regexp of the header overrides of %v: %w", dir, err)
+			}
+			overrides.headersPerPath = append(overrides.headersPerPath, headersMatcher{regex: regex, headers: overrides.HeadersPerPathRegex[pattern]})
+		}
+		debugLookup(ctx, "header overrides %v", path.Join(dir, this.cfg.DirectoryHeadersFile))
+	}
+	this.cache(&this.dirHeaders, key, overrides, memoryCritical)
+	return overrides, nil
+}
@@ -0,0 +1,106 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type DirectoryHeadersTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestDirectoryHeadersTestSuite(t *testing.T) {
+	suite.Run(t, new(DirectoryHeadersTestSuite))
+}
+
+func (suite *DirectoryHeadersTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.MkdirAll(path.Join(rootDir, "mfe", "cart", "assets"), 0755))
+	suite.Require().Nil(os.MkdirAll(path.Join(rootDir, "broken"), 0755))
+	for name, content := range map[string]string{
+		"index.html":                 "shell",
+		"main.js":                    "shell()",
+		"mfe/cart/.spa-headers.yaml": "headers:\n  X-Frame-Options: SAMEORIGIN\nheaders-per-regexp:\n  \"^assets/\":\n    Cache-Control: no-cache\n",
+		"mfe/.spa-headers.yaml":      "headers:\n  X-Frame-Options: DENY\n  X-Team: frontends\n",
+		"mfe/cart/assets/cart.js":    "cart()",
+		"mfe/cart/entry.js":          "entry()",
+		"broken/.spa-headers.yaml":   "headers-per-regexp:\n  \"(\":\n    Cache-Control: no-cache\n",
+		"broken/app.js":              "app()",
+	} {
+		suite.Require().Nil(os.WriteFile(path.Join(rootDir, name), []byte(content), 0644))
+	}
+	sut, err := newServer(Config{
+		RootDirs:             []string{rootDir},
+		BaseURL:              "/",
+		NotFoundRegexs:       []string{`\.(js|yaml)$`},
+		DirectoryHeadersFile: ".spa-headers.yaml",
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *DirectoryHeadersTestSuite) get(target string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, httptest.NewRequest("GET", target, nil))
+	return rr
+}
+
+func (suite *DirectoryHeadersTestSuite) Test_Nested_overrides_Then_deeper_directory_wins() {
+
+	// when
+	rr := suite.get("/mfe/cart/assets/cart.js")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("SAMEORIGIN", rr.Header().Get("X-Frame-Options"))
+	suite.Equal("frontends", rr.Header().Get("X-Team"))
+	suite.Equal("no-cache", rr.Header().Get("Cache-Control"))
+}
+
+func (suite *DirectoryHeadersTestSuite) Test_Regexp_relative_to_directory_Then_other_files_unaffected() {
+
+	// when
+	rr := suite.get("/mfe/cart/entry.js")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("public, max-age=300", rr.Header().Get("Cache-Control"))
+}
+
+func (suite *DirectoryHeadersTestSuite) Test_Outside_subtree_Then_no_overrides() {
+
+	// when
+	rr := suite.get("/main.js")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Empty(rr.Header().Get("X-Frame-Options"))
+}
+
+func (suite *DirectoryHeadersTestSuite) Test_Override_file_Then_not_served() {
+
+	// when
+	rr := suite.get("/mfe/.spa-headers.yaml")
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+}
+
+func (suite *DirectoryHeadersTestSuite) Test_Invalid_regexp_Then_internal_error() {
+
+	// when
+	rr := suite.get("/broken/app.js")
+
+	// then
+	suite.Equal(http.StatusInternalServerError, rr.Code)
+}
@@ -0,0 +1,71 @@
+package spaserver
+
+import (
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// redacted replaces the values of the secrets in the effective configuration
+const redacted = "[redacted]"
+
+// serveConfig reports the effective configuration of the server merged from the
+// defaults, the configuration file and the environment, with the secrets redacted
+func (this *server) serveConfig(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	effective := effectiveValue(reflect.ValueOf(this.cfg)).(map[string]any)
+	// the overrides of the sites may contain the secrets, the configurations are reported instead
+	sites := map[string]any{}
+	for _, site := range this.sites {
+		sites[site.pattern] = effectiveValue(reflect.ValueOf(site.server.cfg))
+	}
+	effective["sites"] = sites
+	writeJSON(w, http.StatusOK, effective)
+}
+
+// effectiveValue converts the configuration value to its JSON form keyed as in the
+// configuration file, the fields tagged as secret are redacted and the credentials
+// are removed from the urls
+func effectiveValue(value reflect.Value) any {
+	if value.Type() == reflect.TypeOf(time.Duration(0)) {
+		return time.Duration(value.Int()).String()
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		fields := map[string]any{}
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			key := field.Tag.Get("mapstructure")
+			if key == "" || !field.IsExported() {
+				continue
+			}
+			if field.Tag.Get("secret") == "true" && !value.Field(i).IsZero() {
+				fields[key] = redacted
+				continue
+			}
+			fields[key] = effectiveValue(value.Field(i))
+		}
+		return fields
+	case reflect.Slice:
+		items := make([]any, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			items = append(items, effectiveValue(value.Index(i)))
+		}
+		return items
+	case reflect.Map:
+		entries := map[string]any{}
+		iter := value.MapRange()
+		for iter.Next() {
+			entries[iter.Key().String()] = effectiveValue(iter.Value())
+		}
+		return entries
+	case reflect.String:
+		return rootLabel(value.String())
+	default:
+		return value.Interface()
+	}
+}
@@ -0,0 +1,102 @@
+package spaserver
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// embedScheme is the root of the SPA compiled into the binary
+const embedScheme = "embed:"
+
+// embedCommand builds the binary serving the SPA directory compiled into it, e.g.
+// `go run ./cmd/spa_d embed -o my-app ./dist` in the spa_d source tree. The SPA
+// is copied to the embedded directory of this package and the command is built
+// with the embed tag.
+func embedCommand(args []string) error {
+	flags := flag.NewFlagSet("embed", flag.ContinueOnError)
+	output := flags.String("o", "spa_d-embedded", "path of the built binary")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: spa_d embed [-o output] <spa-directory>")
+	}
+
+	module, err := sourceModuleDir()
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(module, "pkg", "spaserver", "embedded")
+	if _, err := os.Stat(target); err == nil {
+		return fmt.Errorf("%v already exists, remove it or wait for the running build", target)
+	}
+	if err := copyDir(flags.Arg(0), target); err != nil {
+		os.RemoveAll(target)
+		return err
+	}
+	defer os.RemoveAll(target)
+
+	binary, err := filepath.Abs(*output)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("go", "build", "-tags", "embed", "-o", binary, "./cmd/spa_d")
+	cmd.Dir = module
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go build failed: %w", err)
+	}
+	fmt.Println("Built", binary)
+	return nil
+}
+
+// sourceModuleDir finds the source of the spa_d module in the working directory or its parents
+func sourceModuleDir() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "pkg", "spaserver", "embedded_bundle.go")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("spa_d source not found, run the command in the spa_d source tree")
+		}
+		dir = parent
+	}
+}
+
+// copyDir copies the regular files of the directory, the symlinks are followed
+func copyDir(source string, target string) error {
+	return fs.WalkDir(os.DirFS(source), ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		destination := filepath.Join(target, filepath.FromSlash(name))
+		if entry.IsDir() {
+			return os.MkdirAll(destination, 0755)
+		}
+		in, err := os.Open(filepath.Join(source, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.Create(destination)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
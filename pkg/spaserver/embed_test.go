@@ -0,0 +1,59 @@
+package spaserver
+
+import (
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type EmbedTestSuite struct {
+	suite.Suite
+}
+
+func TestEmbedTestSuite(t *testing.T) {
+	suite.Run(t, new(EmbedTestSuite))
+}
+
+func (suite *EmbedTestSuite) Test_Copy_dir_Then_files_and_dot_dirs_copied() {
+
+	// given
+	source := suite.T().TempDir()
+	target := path.Join(suite.T().TempDir(), "embedded")
+	suite.Require().Nil(os.MkdirAll(path.Join(source, ".vite"), 0755))
+	suite.Require().Nil(os.WriteFile(path.Join(source, "index.html"), []byte("index"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(source, ".vite/manifest.json"), []byte("{}"), 0644))
+
+	// when
+	err := copyDir(source, target)
+
+	// then
+	suite.Nil(err)
+	content, err := os.ReadFile(path.Join(target, "index.html"))
+	suite.Nil(err)
+	suite.Equal("index", string(content))
+	content, err = os.ReadFile(path.Join(target, ".vite/manifest.json"))
+	suite.Nil(err)
+	suite.Equal("{}", string(content))
+}
+
+func (suite *EmbedTestSuite) Test_Embed_root_without_bundle_Then_error() {
+
+	// when
+	_, err := newServer(Config{RootDirs: []string{embedScheme}}, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "no SPA is embedded")
+}
+
+func (suite *EmbedTestSuite) Test_Embed_without_directory_Then_usage_error() {
+
+	// when
+	err := embedCommand([]string{"-o", "app"})
+
+	// then
+	suite.ErrorContains(err, "usage")
+}
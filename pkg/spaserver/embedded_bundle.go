@@ -0,0 +1,19 @@
+//go:build embed
+
+package spaserver
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// embeddedBundle is the SPA compiled into the binary by `spa_d embed`
+//
+//go:embed all:embedded
+var embeddedBundle embed.FS
+
+// embeddedRoot returns the SPA compiled into the binary
+func embeddedRoot() (fs.FS, bool) {
+	bundle, err := fs.Sub(embeddedBundle, "embedded")
+	return bundle, err == nil
+}
@@ -0,0 +1,10 @@
+//go:build !embed
+
+package spaserver
+
+import "io/fs"
+
+// embeddedRoot returns false, no SPA is compiled into the binary
+func embeddedRoot() (fs.FS, bool) {
+	return nil, false
+}
@@ -0,0 +1,79 @@
+package spaserver
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// acceptedEncodings orders the available content codings by the preference of
+// the client, the order of the available codings breaks the ties. The codings
+// with zero quality, not listed without the wildcard, or less preferred than
+// the identity are not acceptable.
+func acceptedEncodings(req *http.Request, available []string) []string {
+	qualities := map[string]float64{}
+	for _, value := range req.Header.Values("Accept-Encoding") {
+		for _, item := range strings.Split(value, ",") {
+			coding, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding != "" {
+				qualities[coding] = quality(params)
+			}
+		}
+	}
+	wildcard, hasWildcard := qualities["*"]
+	qualityOf := func(coding string) (float64, bool) {
+		if q, ok := qualities[coding]; ok {
+			return q, true
+		}
+		return wildcard, hasWildcard
+	}
+	// the identity is acceptable unless refused, but least preferred if not listed
+	identity, ok := qualityOf("identity")
+	if !ok {
+		identity = 0
+	}
+
+	accepted := []string{}
+	for _, coding := range available {
+		if q, ok := qualityOf(coding); ok && q > 0 && q >= identity {
+			accepted = append(accepted, coding)
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool {
+		qi, _ := qualityOf(accepted[i])
+		qj, _ := qualityOf(accepted[j])
+		return qi > qj
+	})
+	return accepted
+}
+
+// quality is the q parameter of the item of the content negotiation header, 1 if missing
+func quality(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		if value, ok := strings.CutPrefix(strings.ToLower(strings.TrimSpace(param)), "q="); ok {
+			quality, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return 0
+			}
+			return quality
+		}
+	}
+	return 1
+}
+
+// encodedRangeRequest prepares the range request of the encoded representation.
+// The encoded and identity representations share the modification time, so a
+// date in If-Range cannot tell them apart and the range would splice the bytes
+// of different representations: the full representation is served instead.
+func encodedRangeRequest(req *http.Request) *http.Request {
+	ifRange := req.Header.Get("If-Range")
+	if req.Header.Get("Range") == "" || ifRange == "" ||
+		strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, `W/"`) {
+		return req
+	}
+	full := req.Clone(req.Context())
+	full.Header.Del("Range")
+	return full
+}
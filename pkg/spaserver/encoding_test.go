@@ -0,0 +1,58 @@
+package spaserver
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type EncodingTestSuite struct {
+	suite.Suite
+}
+
+func TestEncodingTestSuite(t *testing.T) {
+	suite.Run(t, new(EncodingTestSuite))
+}
+
+func (suite *EncodingTestSuite) accepted(headers ...string) []string {
+	req := httptest.NewRequest("GET", "/main.js", nil)
+	for _, header := range headers {
+		req.Header.Add("Accept-Encoding", header)
+	}
+	return acceptedEncodings(req, []string{"br", "zstd", "gzip"})
+}
+
+func (suite *EncodingTestSuite) Test_Equal_qualities_Then_server_order() {
+	suite.Equal([]string{"br", "gzip"}, suite.accepted("gzip, deflate, br"))
+}
+
+func (suite *EncodingTestSuite) Test_Weighted_Then_client_preference() {
+	suite.Equal([]string{"gzip", "br"}, suite.accepted("gzip;q=0.8, br;q=0.5"))
+	suite.Equal([]string{"zstd", "br", "gzip"}, suite.accepted("br;q=0.9,gzip;q=0.1,zstd"))
+}
+
+func (suite *EncodingTestSuite) Test_Zero_quality_Then_refused() {
+	suite.Equal([]string{"br"}, suite.accepted("gzip;q=0, br;q=0.5"))
+	suite.Equal([]string{"br"}, suite.accepted("gzip;q=0.0", "br;Q=0.5"))
+}
+
+func (suite *EncodingTestSuite) Test_Multiple_headers_Then_combined() {
+	suite.Equal([]string{"br", "gzip"}, suite.accepted("gzip", "br"))
+}
+
+func (suite *EncodingTestSuite) Test_Wildcard_Then_unlisted_accepted() {
+	suite.Equal([]string{"gzip", "br", "zstd"}, suite.accepted("gzip, *;q=0.5"))
+	suite.Equal([]string{"zstd", "gzip"}, suite.accepted("*, br;q=0"))
+	suite.Empty(suite.accepted("*;q=0"))
+}
+
+func (suite *EncodingTestSuite) Test_Identity_preferred_Then_less_preferred_refused() {
+	suite.Equal([]string{"br"}, suite.accepted("identity;q=0.5, gzip;q=0.2, br"))
+	suite.Equal([]string{"gzip"}, suite.accepted("identity, gzip"))
+}
+
+func (suite *EncodingTestSuite) Test_No_header_or_invalid_quality_Then_identity() {
+	suite.Empty(suite.accepted())
+	suite.Empty(suite.accepted("gzip;q=high"))
+}
@@ -0,0 +1,53 @@
+package spaserver
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+)
+
+// errorPageStatuses are the statuses of the configurable error pages
+var errorPageStatuses = []int{http.StatusNotFound, http.StatusInternalServerError}
+
+// errorPageAcceptTypes are the media ranges of the Accept header qualifying for the error page
+var errorPageAcceptTypes = []string{"text/html", "text/*", "*/*"}
+
+// serveErrorPage responds with the configured error page of the status, false if
+// the page is not configured, not found or not acceptable by the client, e.g.
+// the scripts fetching json get the plain text error
+func (this *server) serveErrorPage(w http.ResponseWriter, req *http.Request, status int) bool {
+	page, ok := this.cfg.ErrorPages[strconv.Itoa(status)]
+	if !ok {
+		return false
+	}
+	w.Header().Add("Vary", "Accept")
+	if len(req.Header.Get("Accept")) != 0 && !acceptsAny(req, errorPageAcceptTypes) {
+		return false
+	}
+	ctx := req.Context()
+	file, found, err := this.findFile(ctx, page)
+	if err != nil || !found {
+		this.logger.Warn().Err(err).Str("page", page).Msg("Error page not found")
+		return false
+	}
+	defer file.Close()
+	debugLookup(ctx, "error page %v", page)
+
+	ctype := mime.TypeByExtension(path.Ext(page))
+	if ctype == "" {
+		ctype = "text/html; charset=utf-8"
+	}
+	// the headers of the failed lookup do not describe the page
+	for _, name := range []string{"Content-Length", "Content-Encoding", "ETag", "Last-Modified"} {
+		w.Header().Del(name)
+	}
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if req.Method != http.MethodHead {
+		io.Copy(w, file)
+	}
+	return true
+}
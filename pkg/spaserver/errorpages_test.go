@@ -0,0 +1,111 @@
+package spaserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type ErrorPagesTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestErrorPagesTestSuite(t *testing.T) {
+	suite.Run(t, new(ErrorPagesTestSuite))
+}
+
+func (suite *ErrorPagesTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	for name, content := range map[string]string{
+		"index.html": "app",
+		"404.html":   "missing",
+		"50x.html":   "failed",
+	} {
+		suite.Require().Nil(os.WriteFile(path.Join(rootDir, name), []byte(content), 0644))
+	}
+	sut, err := newServer(Config{
+		RootDirs:         []string{rootDir},
+		FallbackDisabled: true,
+		ErrorPages:       map[string]string{"404": "/404.html", "500": "/50x.html"},
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *ErrorPagesTestSuite) get(target string, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *ErrorPagesTestSuite) Test_Not_found_Then_error_page_with_status() {
+
+	// when
+	rr := suite.get("/missing", "text/html,application/xhtml+xml;q=0.9")
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+	suite.Equal("missing", rr.Body.String())
+	suite.Equal("text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	suite.Contains(rr.Header().Values("Vary"), "Accept")
+}
+
+func (suite *ErrorPagesTestSuite) Test_Html_not_accepted_Then_plain_text() {
+
+	// when
+	rr := suite.get("/missing", "application/json")
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+	suite.Equal("Not Found\n", rr.Body.String())
+}
+
+func (suite *ErrorPagesTestSuite) Test_Internal_error_Then_error_page_with_status() {
+
+	// given
+	rr := httptest.NewRecorder()
+
+	// when
+	suite.sut.internalError(rr, httptest.NewRequest("GET", "/", nil), errors.New("failed"))
+
+	// then
+	suite.Equal(http.StatusInternalServerError, rr.Code)
+	suite.Equal("failed", rr.Body.String())
+}
+
+func (suite *ErrorPagesTestSuite) Test_Page_missing_Then_plain_text() {
+
+	// given
+	suite.sut.cfg.ErrorPages["404"] = "/none.html"
+
+	// when
+	rr := suite.get("/missing", "")
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+	suite.Equal("Not Found\n", rr.Body.String())
+}
+
+func (suite *ErrorPagesTestSuite) Test_Invalid_status_Then_invalid() {
+
+	// when
+	_, err := newServer(Config{ErrorPages: map[string]string{"200": "/ok.html", "404": ""}}, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "error-pages.200: the status must be one of [404 500]")
+	suite.ErrorContains(err, "error-pages.404: the page is required")
+}
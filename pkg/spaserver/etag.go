@@ -0,0 +1,79 @@
+package spaserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+// ETag strategies
+const (
+	etagNone   = "none"
+	etagWeak   = "weak"
+	etagStat   = "stat"
+	etagStrong = "strong"
+)
+
+// etagModes are the valid ETag strategies
+var etagModes = []string{etagNone, etagWeak, etagStat, etagStrong}
+
+// etagMode returns the ETag strategy of the resource, a matching path regexp
+// overrides the global strategy
+func (this *server) etagMode(resourcePath string) string {
+	for _, matcher := range this.regexes().etagPerPath {
+		if matcher.regex.MatchString(resourcePath) {
+			return matcher.mode
+		}
+	}
+	return this.cfg.Etag
+}
+
+// applyEtag sets the ETag of the served file, so that the conditional requests
+// are answered with 304 Not Modified. The strong ETag is the hash of the served
+// content, the weak and the stat ETags are derived from the presented modification
+// time and size, the stat one is strong without hashing, e.g. for the CDNs dropping
+// the weak validators.
+func (this *server) applyEtag(ctx context.Context, w http.ResponseWriter, name string, file asset, info fs.FileInfo, modTime time.Time) error {
+	// the encoded variants are distinct representations
+	encoding := w.Header().Get("Content-Encoding")
+	switch mode := this.etagMode(name); mode {
+	case etagWeak, etagStat:
+		etag := fmt.Sprintf(`"%x-%x`, modTime.UnixNano(), info.Size())
+		if encoding != "" {
+			etag += "-" + encoding
+		}
+		if mode == etagWeak {
+			etag = "W/" + etag
+		}
+		w.Header().Set("ETag", etag+`"`)
+	case etagStrong:
+		key := fmt.Sprintf("%v|%v|%v|%v|%v", rootSetKey(ctx), name, encoding, info.ModTime().UnixNano(), info.Size())
+		if etag, ok := this.etags.Load(key); ok {
+			recordCacheLookup(ctx, "etags", cacheHit)
+			w.Header().Set("ETag", etag.(string))
+			return nil
+		}
+		recordCacheLookup(ctx, "etags", cacheMiss)
+		digest := sha256.New()
+		if _, err := io.Copy(digest, file); err != nil {
+			return err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		etag := `"` + hex.EncodeToString(digest.Sum(nil)[:16])
+		if compressedOnTheFly(ctx) {
+			// the content hashed is the identity representation
+			etag += "-" + encoding
+		}
+		etag += `"`
+		this.cache(&this.etags, key, etag, memoryCritical)
+		w.Header().Set("ETag", etag)
+	}
+	return nil
+}
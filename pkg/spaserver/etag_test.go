@@ -0,0 +1,163 @@
+package spaserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type EtagTestSuite struct {
+	suite.Suite
+	rootDir string
+}
+
+func TestEtagTestSuite(t *testing.T) {
+	suite.Run(t, new(EtagTestSuite))
+}
+
+func (suite *EtagTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.Require().Nil(os.MkdirAll(path.Join(suite.rootDir, "assets"), 0755))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "index.html"), []byte("index"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "assets/main.js"), []byte("main"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "assets/main.js.gz"), []byte("gzipped"), 0644))
+}
+
+func (suite *EtagTestSuite) server(etag string, perPath map[string]string) *server {
+	sut, err := newServer(Config{
+		RootDirs:         []string{suite.rootDir},
+		BaseURL:          "/",
+		Etag:             etag,
+		EtagPerPathRegex: perPath,
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	return sut
+}
+
+func (suite *EtagTestSuite) get(sut *server, requestPath string, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", requestPath, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *EtagTestSuite) Test_Strong_etag_Then_content_hash() {
+
+	// given
+	sut := suite.server(etagStrong, nil)
+	digest := sha256.Sum256([]byte("main"))
+
+	// when
+	rr := suite.get(sut, "/assets/main.js", "")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(`"`+hex.EncodeToString(digest[:16])+`"`, rr.Header().Get("ETag"))
+	suite.Equal("main", rr.Body.String())
+}
+
+func (suite *EtagTestSuite) Test_Matching_etag_Then_not_modified() {
+
+	// given
+	sut := suite.server(etagWeak, nil)
+	etag := suite.get(sut, "/assets/main.js", "").Header().Get("ETag")
+
+	// when
+	rr := suite.get(sut, "/assets/main.js", etag)
+
+	// then
+	suite.Regexp(`^W/"[0-9a-f]+-4"$`, etag)
+	suite.Equal(http.StatusNotModified, rr.Code)
+}
+
+func (suite *EtagTestSuite) Test_Path_override_Then_strategy_of_path() {
+
+	// given
+	sut := suite.server(etagWeak, map[string]string{"^/?index.html$": etagNone})
+
+	// when
+	rr := suite.get(sut, "/", "")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Empty(rr.Header().Get("ETag"))
+}
+
+func (suite *EtagTestSuite) Test_Stat_etag_Then_strong_without_hashing() {
+
+	// given
+	sut := suite.server(etagStat, nil)
+
+	// when
+	rr := suite.get(sut, "/assets/main.js", "")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Regexp(`^"[0-9a-f]+-4"$`, rr.Header().Get("ETag"))
+}
+
+func (suite *EtagTestSuite) Test_Precompressed_matching_etag_Then_not_modified() {
+
+	// given
+	sut := suite.server(etagStat, nil)
+	req := httptest.NewRequest("GET", "/assets/main.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	etag := rr.Header().Get("ETag")
+
+	// when
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Regexp(`^"[0-9a-f]+-7-gzip"$`, etag)
+	suite.Equal(http.StatusNotModified, rr.Code)
+	suite.Empty(rr.Body.String())
+}
+
+func (suite *EtagTestSuite) Test_Precompressed_not_modified_since_Then_not_modified() {
+
+	// given
+	sut := suite.server(etagNone, nil)
+	req := httptest.NewRequest("GET", "/assets/main.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusNotModified, rr.Code)
+	suite.Empty(rr.Body.String())
+}
+
+func (suite *EtagTestSuite) Test_Unknown_strategy_Then_invalid() {
+
+	// when
+	_, err := newServer(Config{
+		RootDirs:         []string{suite.rootDir},
+		Etag:             "sha1",
+		EtagPerPathRegex: map[string]string{"^/assets/": "hash"},
+	}, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "etag: unknown ETag strategy sha1")
+	suite.ErrorContains(err, "etag-per-regexp[^/assets/]: unknown ETag strategy hash")
+}
@@ -0,0 +1,71 @@
+package spaserver
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// explainCommand resolves the request path with the current configuration and
+// prints the served file, the lookup decisions and the response headers, e.g.
+// `spa_d explain -accept-encoding br /about`, without starting the server.
+func explainCommand(args []string) error {
+	flags := flag.NewFlagSet("explain", flag.ContinueOnError)
+	method := flags.String("method", http.MethodGet, "method of the request")
+	accept := flags.String("accept", "text/html", "Accept header of the request")
+	acceptEncoding := flags.String("accept-encoding", "", "Accept-Encoding header of the request")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: spa_d explain [-method GET] [-accept types] [-accept-encoding encodings] <path>")
+	}
+
+	req := httptest.NewRequest(*method, flags.Arg(0), nil)
+	if *accept != "" {
+		req.Header.Set("Accept", *accept)
+	}
+	if *acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", *acceptEncoding)
+	}
+	return explain(loadConfiguration(), req, os.Stdout)
+}
+
+// explain serves the request without a listener and prints how it was resolved
+func explain(cfg Config, req *http.Request, out io.Writer) error {
+	spa, err := newServer(cfg, zerolog.Nop())
+	if err != nil {
+		return err
+	}
+	ctx, debug := withLookupDebug(context.Background())
+	rr := httptest.NewRecorder()
+	spa.handler(ctx, rr, req)
+
+	fmt.Fprintf(out, "%v %v\n", req.Method, req.URL.RequestURI())
+	fmt.Fprintf(out, "Status: %v %v\n", rr.Code, http.StatusText(rr.Code))
+	fmt.Fprintln(out, "Lookup:")
+	debug.mutex.Lock()
+	for _, step := range debug.steps {
+		fmt.Fprintf(out, "  %v\n", step)
+	}
+	debug.mutex.Unlock()
+	fmt.Fprintln(out, "Headers:")
+	names := make([]string, 0, len(rr.Header()))
+	for name := range rr.Header() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %v: %v\n", name, strings.Join(rr.Header().Values(name), ", "))
+	}
+	fmt.Fprintf(out, "Body: %v bytes\n", rr.Body.Len())
+	return nil
+}
@@ -0,0 +1,85 @@
+package spaserver
+
+import (
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExplainTestSuite struct {
+	suite.Suite
+	cfg Config
+}
+
+func TestExplainTestSuite(t *testing.T) {
+	suite.Run(t, new(ExplainTestSuite))
+}
+
+func (suite *ExplainTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "index.html"), []byte("<html></html>"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "app.js"), []byte("app"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "app.js.gz"), []byte("gz"), 0644))
+	suite.cfg = Config{
+		RootDirs:            []string{rootDir},
+		BaseURL:             "/",
+		FallbackDocument:    "index.html",
+		FallbackAcceptTypes: []string{"text/html"},
+		HeadersPerPathRegex: map[string]map[string]string{`\.html$`: {"X-Frame-Options": "DENY"}},
+	}
+}
+
+func (suite *ExplainTestSuite) Test_Fallback_Then_file_and_header_rules_printed() {
+
+	// given
+	req := httptest.NewRequest("GET", "/about", nil)
+	req.Header.Set("Accept", "text/html")
+	out := &strings.Builder{}
+
+	// when
+	err := explain(suite.cfg, req, out)
+
+	// then
+	suite.Require().Nil(err)
+	suite.Contains(out.String(), "GET /about\nStatus: 200 OK\n")
+	suite.Contains(out.String(), "  fallback /index.html\n")
+	suite.Contains(out.String(), ":index.html found\n")
+	suite.Contains(out.String(), "  headers-per-regexp \\.html$\n")
+	suite.Contains(out.String(), "  X-Frame-Options: DENY\n")
+}
+
+func (suite *ExplainTestSuite) Test_Accept_encoding_Then_encoded_variant_printed() {
+
+	// given
+	req := httptest.NewRequest("GET", "/app.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	out := &strings.Builder{}
+
+	// when
+	err := explain(suite.cfg, req, out)
+
+	// then
+	suite.Require().Nil(err)
+	suite.Contains(out.String(), "  Content-Encoding: gzip\n")
+	suite.Contains(out.String(), "Body: 2 bytes\n")
+}
+
+func (suite *ExplainTestSuite) Test_Invalid_configuration_Then_error() {
+
+	// given
+	suite.cfg.NotFoundRegexs = []string{"("}
+	out := &strings.Builder{}
+
+	// when
+	err := explain(suite.cfg, httptest.NewRequest("GET", "/", nil), out)
+
+	// then
+	suite.NotNil(err)
+	suite.Empty(out.String())
+}
@@ -0,0 +1,102 @@
+package spaserver
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// fileStat is the cached info of the file of the root, nil info for the missing file
+type fileStat struct {
+	info    fs.FileInfo
+	expires time.Time
+}
+
+// fileStatsEnabled reports whether the file infos and the missing files are cached
+func (this *server) fileStatsEnabled() bool {
+	return this.cfg.FileCacheTtl > 0
+}
+
+func fileStatKey(rootName string, name string) string {
+	return rootName + "|" + name
+}
+
+// cachedFileStat returns the unexpired info of the file of the root, nil info
+// if the file is missing
+func (this *server) cachedFileStat(rootName string, name string) (fs.FileInfo, bool) {
+	if !this.fileStatsEnabled() {
+		return nil, false
+	}
+	key := fileStatKey(rootName, name)
+	cached, ok := this.fileStats.Load(key)
+	if !ok {
+		return nil, false
+	}
+	stat := cached.(fileStat)
+	if time.Now().After(stat.expires) {
+		if this.fileStats.CompareAndDelete(key, cached) {
+			this.fileStatsCount.Add(-1)
+		}
+		return nil, false
+	}
+	return stat.info, true
+}
+
+// cacheFileStat stores the info of the file of the root, nil for the missing file,
+// unless the memory is under pressure. The full cache is cleared, so that the
+// misses of the random paths do not keep the hot files out.
+func (this *server) cacheFileStat(rootName string, name string, info fs.FileInfo) {
+	if !this.fileStatsEnabled() || this.memoryLevel.Load() >= memoryElevated {
+		return
+	}
+	if this.fileStatsCount.Load() >= int64(this.cfg.FileCacheMaxEntries) {
+		this.clearFileStats()
+	}
+	stat := fileStat{info: info, expires: time.Now().Add(this.cfg.FileCacheTtl)}
+	if _, replaced := this.fileStats.Swap(fileStatKey(rootName, name), stat); !replaced {
+		this.fileStatsCount.Add(1)
+	}
+}
+
+// clearFileStats drops all the cached file infos
+func (this *server) clearFileStats() {
+	clearMap(&this.fileStats)
+	this.fileStatsCount.Store(0)
+}
+
+// purgeFileStats drops the cached file infos of the root, e.g. after its content was synced
+func (this *server) purgeFileStats(rootName string) {
+	purged := purgeMap(&this.fileStats, func(key string) bool {
+		return strings.HasPrefix(key, rootName+"|")
+	})
+	this.fileStatsCount.Add(-int64(purged))
+}
+
+// statFile returns the info of the file in the first root containing it, the
+// cached infos spare opening the file, e.g. to read the size of the original
+// of the precompressed file
+func (this *server) statFile(ctx context.Context, name string) (fs.FileInfo, bool, error) {
+	roots, err := this.requestRoots(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	name = rootName(name)
+	for _, root := range roots {
+		if info, cached := this.cachedFileStat(root.name, name); cached {
+			if info == nil || info.IsDir() {
+				continue
+			}
+			return info, true, nil
+		}
+		file, info, _, err := this.lookupFile(ctx, []assetRoot{root}, name)
+		if err != nil {
+			return nil, false, err
+		}
+		if file != nil {
+			file.Close()
+			return info, true, nil
+		}
+	}
+	return nil, false, nil
+}
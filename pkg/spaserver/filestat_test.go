@@ -0,0 +1,189 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type FileStatTestSuite struct {
+	suite.Suite
+	rootDir  string
+	original func(fs.FS, string) (asset, fs.FileInfo, error)
+	opened   []string
+}
+
+func TestFileStatTestSuite(t *testing.T) {
+	suite.Run(t, new(FileStatTestSuite))
+}
+
+func (suite *FileStatTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "index.html"), []byte("shell"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "app.js"), []byte("app()"), 0644))
+	suite.original = openAndStat
+	suite.opened = nil
+	openAndStat = func(fsys fs.FS, name string) (asset, fs.FileInfo, error) {
+		suite.opened = append(suite.opened, name)
+		return suite.original(fsys, name)
+	}
+}
+
+func (suite *FileStatTestSuite) TearDownTest() {
+	openAndStat = suite.original
+}
+
+func (suite *FileStatTestSuite) server(ttl time.Duration, maxEntries int) *server {
+	sut, err := newServer(Config{
+		RootDirs:            []string{suite.rootDir},
+		BaseURL:             "/",
+		FileCacheTtl:        ttl,
+		FileCacheMaxEntries: maxEntries,
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	return sut
+}
+
+func (suite *FileStatTestSuite) get(sut *server, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *FileStatTestSuite) Test_Repeated_request_Then_misses_not_opened_again() {
+
+	// given
+	sut := suite.server(time.Minute, 100)
+	suite.get(sut, "/app.js")
+	first := len(suite.opened)
+	suite.opened = nil
+
+	// when
+	rr := suite.get(sut, "/app.js")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("app()", rr.Body.String())
+	suite.Equal([]string{"app.js"}, suite.opened, "the precompressed variants are known missing")
+	suite.Greater(first, len(suite.opened))
+}
+
+func (suite *FileStatTestSuite) Test_Fallback_route_Then_miss_cached() {
+
+	// given
+	sut := suite.server(time.Minute, 100)
+	suite.get(sut, "/users/1")
+	suite.opened = nil
+
+	// when
+	rr := suite.get(sut, "/users/1")
+
+	// then
+	suite.Equal("shell", rr.Body.String())
+	suite.NotContains(suite.opened, "users/1")
+}
+
+func (suite *FileStatTestSuite) Test_Cached_miss_Then_new_file_found_after_ttl() {
+
+	// given
+	sut := suite.server(50*time.Millisecond, 100)
+	suite.Equal("shell", suite.get(sut, "/late.js").Body.String())
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "late.js"), []byte("late()"), 0644))
+	cached := suite.get(sut, "/late.js")
+
+	// when
+	time.Sleep(60 * time.Millisecond)
+	expired := suite.get(sut, "/late.js")
+
+	// then
+	suite.Equal("shell", cached.Body.String())
+	suite.Equal("late()", expired.Body.String())
+}
+
+func (suite *FileStatTestSuite) Test_Cache_disabled_Then_new_file_found() {
+
+	// given
+	sut := suite.server(0, 100)
+	suite.get(sut, "/late.js")
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "late.js"), []byte("late()"), 0644))
+
+	// when
+	rr := suite.get(sut, "/late.js")
+
+	// then
+	suite.Equal("late()", rr.Body.String())
+	suite.Zero(sut.fileStatsCount.Load())
+}
+
+func (suite *FileStatTestSuite) Test_Purge_Then_miss_dropped() {
+
+	// given
+	sut := suite.server(time.Minute, 100)
+	suite.get(sut, "/late.js")
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "late.js"), []byte("late()"), 0644))
+
+	// when
+	sut.purgeFileStats(suite.rootDir)
+	rr := suite.get(sut, "/late.js")
+
+	// then
+	suite.Equal("late()", rr.Body.String())
+}
+
+func (suite *FileStatTestSuite) Test_Cache_full_Then_cleared() {
+
+	// given
+	sut := suite.server(time.Minute, 3)
+
+	// when
+	for _, target := range []string{"/a.js", "/b.js", "/c.js", "/d.js"} {
+		sut.lookupFile(context.Background(), sut.roots, rootName(target))
+	}
+
+	// then
+	suite.Equal(int64(1), sut.fileStatsCount.Load())
+	_, cached := sut.cachedFileStat(suite.rootDir, "d.js")
+	suite.True(cached)
+	_, cached = sut.cachedFileStat(suite.rootDir, "a.js")
+	suite.False(cached)
+}
+
+func (suite *FileStatTestSuite) Test_Stat_cached_Then_file_not_opened() {
+
+	// given
+	sut := suite.server(time.Minute, 100)
+	_, _, err := sut.statFile(context.Background(), "/app.js")
+	suite.Require().Nil(err)
+	suite.opened = nil
+
+	// when
+	info, ok, err := sut.statFile(context.Background(), "/app.js")
+
+	// then
+	suite.Nil(err)
+	suite.True(ok)
+	suite.Equal(int64(5), info.Size())
+	suite.Empty(suite.opened)
+}
+
+func (suite *FileStatTestSuite) Test_No_entries_Then_validation_error() {
+
+	// when
+	err := validateConfig(Config{FileCacheTtl: time.Second})
+
+	// then
+	suite.ErrorContains(err, "file-cache-max-entries: the cache must hold at least 1 entry")
+}
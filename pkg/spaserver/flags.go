@@ -0,0 +1,108 @@
+package spaserver
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// flagAliases are the singular names of the repeated flags, e.g. `--root dist --header 'X-Foo: bar'`
+var flagAliases = map[string]string{
+	"root":   "roots",
+	"header": "headers",
+	"config": "config-files",
+}
+
+// parseFlags binds the command-line flags of the configuration options to viper,
+// the flags override the configuration file and the environment. The lists and
+// maps of structures, e.g. `proxies` or `sites`, are configured in the file only.
+func parseFlags(v *viper.Viper, args []string) error {
+	flags := pflag.NewFlagSet("spa_d", pflag.ContinueOnError)
+	flags.SortFlags = false
+	flags.SetNormalizeFunc(func(flags *pflag.FlagSet, name string) pflag.NormalizedName {
+		if alias, ok := flagAliases[name]; ok {
+			name = alias
+		}
+		return pflag.NormalizedName(name)
+	})
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: spa_d [flags]\n       spa_d embed|explain [flags] ...\n\n")
+		fmt.Fprintf(os.Stderr, "The flags override the configuration files, config/spa-base.* or --config, and the\nSPA_BASE_* environment variables. The lists are repeated, e.g. --root dist\n--root public, and the map entries, e.g. --header 'X-Foo: bar', are merged\nover the entries of the file.\n\n")
+		flags.PrintDefaults()
+	}
+
+	fields := reflect.TypeOf(Config{})
+	for i := 0; i < fields.NumField(); i++ {
+		key := fields.Field(i).Tag.Get("mapstructure")
+		usage := "env SPA_BASE_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		switch fields.Field(i).Type {
+		case reflect.TypeOf(""):
+			flags.String(key, v.GetString(key), usage)
+		case reflect.TypeOf(false):
+			flags.Bool(key, v.GetBool(key), usage)
+		case reflect.TypeOf(0), reflect.TypeOf(int64(0)):
+			flags.Int64(key, v.GetInt64(key), usage)
+		case reflect.TypeOf(0.0):
+			flags.Float64(key, v.GetFloat64(key), usage)
+		case reflect.TypeOf(time.Duration(0)):
+			flags.Duration(key, v.GetDuration(key), usage)
+		case reflect.TypeOf([]string{}):
+			// the items are not split by commas, the regexps contain them
+			flags.StringArray(key, v.GetStringSlice(key), usage)
+		case reflect.TypeOf(map[string]string{}):
+			entries := mapFlag(v.GetStringMapString(key))
+			flags.Var(&entries, key, "`name: value` entries, "+usage)
+		default:
+			continue
+		}
+		if err := v.BindPFlag(key, flags.Lookup(key)); err != nil {
+			return err
+		}
+	}
+	return flags.Parse(args)
+}
+
+// mapFlag collects the `name: value` or `name=value` entries of the repeated map flag
+type mapFlag map[string]string
+
+func (this *mapFlag) Set(entry string) error {
+	separator := strings.IndexAny(entry, ":=")
+	if separator < 1 {
+		return fmt.Errorf("expected name: value, got %q", entry)
+	}
+	if *this == nil {
+		*this = mapFlag{}
+	}
+	(*this)[strings.TrimSpace(entry[:separator])] = strings.TrimSpace(entry[separator+1:])
+	return nil
+}
+
+// Type is the type of the maps viper converts from the flags
+func (this *mapFlag) Type() string {
+	return "stringToString"
+}
+
+// String formats the entries as the csv of `name=value` read by viper
+func (this *mapFlag) String() string {
+	entries := make([]string, 0, len(*this))
+	for name, value := range *this {
+		entries = append(entries, name+"="+value)
+	}
+	if len(entries) == 0 {
+		return "[]"
+	}
+	sort.Strings(entries)
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.Write(entries)
+	writer.Flush()
+	return "[" + strings.TrimSuffix(buffer.String(), "\n") + "]"
+}
@@ -0,0 +1,71 @@
+package spaserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// forwardedPrefixRegex accepts only the url path characters, the prefix is written
+// into the html attributes and the redirect locations
+var forwardedPrefixRegex = regexp.MustCompile(`^/[A-Za-z0-9/_.~-]*$`)
+
+type forwardedPrefixKey struct{}
+
+// forwardedPrefix reads the X-Forwarded-Prefix of the ingress stripping the prefix
+// from the request path, the header is honored only from the trusted proxies
+func (this *server) forwardedPrefix(req *http.Request) (string, bool) {
+	value := req.Header.Get("X-Forwarded-Prefix")
+	if value == "" || !this.trustedProxy(req.RemoteAddr) {
+		return "", false
+	}
+	// the first proxy is the closest to the client
+	value, _, _ = strings.Cut(value, ",")
+	value = strings.TrimSpace(value)
+	if !forwardedPrefixRegex.MatchString(value) {
+		return "", false
+	}
+	prefix := strings.TrimSuffix(path.Clean(value), "/")
+	return prefix, prefix != ""
+}
+
+// trustedProxy reports whether the remote address is any of the trusted proxies
+func (this *server) trustedProxy(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range this.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// withForwardedPrefix stores the forwarded prefix of the request in the context
+func withForwardedPrefix(ctx context.Context, prefix string) context.Context {
+	return context.WithValue(ctx, forwardedPrefixKey{}, prefix)
+}
+
+// requestForwardedPrefix returns the forwarded prefix of the request, empty if not forwarded
+func requestForwardedPrefix(ctx context.Context) string {
+	prefix, _ := ctx.Value(forwardedPrefixKey{}).(string)
+	return prefix
+}
+
+// publicBaseUrl is the base url as seen by the client, prefixed with the forwarded prefix
+func (this *server) publicBaseUrl(ctx context.Context) string {
+	baseUrl := this.cfg.BaseURL
+	if baseUrl == "" {
+		baseUrl = "/"
+	}
+	return requestForwardedPrefix(ctx) + baseUrl
+}
@@ -0,0 +1,111 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type ForwardedPrefixTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestForwardedPrefixTestSuite(t *testing.T) {
+	suite.Run(t, new(ForwardedPrefixTestSuite))
+}
+
+func (suite *ForwardedPrefixTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "index.html"), []byte(`<script src="/main.js"></script>`), 0644))
+	sut, err := newServer(Config{
+		RootDirs:            []string{rootDir},
+		BaseURL:             "/app/",
+		RedirectToBaseUrl:   true,
+		RewriteAbsoluteUrls: true,
+		TrustedProxies:      []string{"10.0.0.0/8", "192.168.1.1"},
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *ForwardedPrefixTestSuite) get(target string, remoteAddr string, prefix string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-Prefix", prefix)
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *ForwardedPrefixTestSuite) Test_Trusted_proxy_Then_urls_rewritten_with_prefix() {
+
+	// when
+	forwarded := suite.get("/app/", "10.1.2.3:4567", "/shop/")
+	direct := suite.get("/app/", "10.1.2.3:4567", "")
+
+	// then
+	suite.Equal(http.StatusOK, forwarded.Code)
+	suite.Equal(`<script src="/shop/app/main.js"></script>`, forwarded.Body.String())
+	suite.Equal(`<script src="/app/main.js"></script>`, direct.Body.String())
+}
+
+func (suite *ForwardedPrefixTestSuite) Test_Trusted_proxy_Then_redirect_to_prefixed_base_url() {
+
+	// when
+	rr := suite.get("/other", "192.168.1.1:4567", "/shop")
+
+	// then
+	suite.Equal(http.StatusFound, rr.Code)
+	suite.Equal("/shop/app/", rr.Header().Get("Location"))
+}
+
+func (suite *ForwardedPrefixTestSuite) Test_Untrusted_client_Then_prefix_ignored() {
+
+	// when
+	rr := suite.get("/other", "203.0.113.7:4567", "//evil.example.com")
+
+	// then
+	suite.Equal(http.StatusFound, rr.Code)
+	suite.Equal("/app/", rr.Header().Get("Location"))
+}
+
+func (suite *ForwardedPrefixTestSuite) Test_Protocol_relative_prefix_Then_cleaned() {
+
+	// when
+	prefix, ok := suite.sut.forwardedPrefix(&http.Request{
+		RemoteAddr: "10.0.0.1:80",
+		Header:     http.Header{"X-Forwarded-Prefix": {"//evil.example.com/"}},
+	})
+
+	// then
+	suite.True(ok)
+	suite.Equal("/evil.example.com", prefix)
+}
+
+func (suite *ForwardedPrefixTestSuite) Test_Prefix_with_markup_Then_ignored() {
+
+	// when
+	rr := suite.get("/app/", "10.1.2.3:4567", `/shop"><script>alert(1)</script>`)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(`<script src="/app/main.js"></script>`, rr.Body.String())
+}
+
+func (suite *ForwardedPrefixTestSuite) Test_Invalid_trusted_proxy_Then_invalid_config() {
+
+	// when
+	err := validateConfig(Config{TrustedProxies: []string{"10.0.0.0/8", "proxy.local"}})
+
+	// then
+	suite.ErrorContains(err, `trusted-proxies[1]: "proxy.local" is neither an address nor a CIDR range`)
+}
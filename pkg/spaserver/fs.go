@@ -0,0 +1,56 @@
+package spaserver
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// openFile opens the file of the root and reads its info. Transient errors of
+// network filesystems are retried with exponential backoff.
+func (this *server) openFile(ctx context.Context, fsys fs.FS, name string) (asset, fs.FileInfo, error) {
+	backoff := this.cfg.FsRetryBackoff
+	for attempt := 0; ; attempt++ {
+		file, info, err := openAndStat(fsys, name)
+		if err == nil || !isTransientError(err) || attempt >= this.cfg.FsRetryAttempts {
+			return file, info, err
+		}
+
+		this.logger.Warn().Err(err).Str("file", name).Int("attempt", attempt+1).Msg("Transient filesystem error, retrying")
+		select {
+		case <-ctx.Done():
+			return nil, nil, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// openAndStat opens the file and reads its info, replaceable in tests
+var openAndStat = func(fsys fs.FS, name string) (asset, fs.FileInfo, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	seekable, ok := file.(asset)
+	if !ok {
+		file.Close()
+		return nil, nil, &fs.PathError{Op: "seek", Path: name, Err: errors.ErrUnsupported}
+	}
+	return seekable, info, nil
+}
+
+// isTransientError returns true for errors which network filesystems
+// report temporarily, e.g. during node events
+func isTransientError(err error) bool {
+	return errors.Is(err, syscall.ESTALE) ||
+		errors.Is(err, syscall.EINTR) ||
+		errors.Is(err, syscall.EIO)
+}
@@ -0,0 +1,107 @@
+package spaserver
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type FsTestSuite struct {
+	suite.Suite
+	original func(fs.FS, string) (asset, fs.FileInfo, error)
+}
+
+func TestFsTestSuite(t *testing.T) {
+	suite.Run(t, new(FsTestSuite))
+}
+
+func (suite *FsTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.original = openAndStat
+}
+
+func (suite *FsTestSuite) TearDownTest() {
+	openAndStat = suite.original
+}
+
+func (suite *FsTestSuite) Test_Transient_error_Then_retried() {
+
+	// given
+	attempts := 0
+	openAndStat = func(fsys fs.FS, name string) (asset, fs.FileInfo, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, nil, &fs.PathError{Op: "open", Path: name, Err: syscall.ESTALE}
+		}
+		return suite.original(fsys, name)
+	}
+	sut := &server{cfg: Config{FsRetryAttempts: 3, FsRetryBackoff: time.Millisecond}}
+
+	// when
+	file, info, err := sut.openFile(context.Background(), os.DirFS("test/data"), "testfile.json")
+
+	// then
+	suite.Nil(err)
+	defer file.Close()
+	suite.Equal("testfile.json", info.Name())
+	suite.Equal(3, attempts)
+}
+
+func (suite *FsTestSuite) Test_Permanent_error_Then_not_retried() {
+
+	// given
+	attempts := 0
+	openAndStat = func(fsys fs.FS, name string) (asset, fs.FileInfo, error) {
+		attempts++
+		return nil, nil, &fs.PathError{Op: "open", Path: name, Err: syscall.ENOENT}
+	}
+	sut := &server{cfg: Config{FsRetryAttempts: 3, FsRetryBackoff: time.Millisecond}}
+
+	// when
+	_, _, err := sut.openFile(context.Background(), os.DirFS("test/data"), "missing.json")
+
+	// then
+	suite.True(os.IsNotExist(err))
+	suite.Equal(1, attempts)
+}
+
+func (suite *FsTestSuite) Test_Transient_error_persists_Then_error_after_attempts() {
+
+	// given
+	attempts := 0
+	openAndStat = func(fsys fs.FS, name string) (asset, fs.FileInfo, error) {
+		attempts++
+		return nil, nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EIO}
+	}
+	sut := &server{cfg: Config{FsRetryAttempts: 2, FsRetryBackoff: time.Millisecond}}
+
+	// when
+	_, _, err := sut.openFile(context.Background(), os.DirFS("test/data"), "testfile.json")
+
+	// then
+	suite.ErrorIs(err, syscall.EIO)
+	suite.Equal(3, attempts)
+}
+
+func (suite *FsTestSuite) Test_Name_invalid_on_platform_Then_not_found() {
+
+	// given
+	openAndStat = func(fsys fs.FS, name string) (asset, fs.FileInfo, error) {
+		// e.g. os.DirFS on Windows refuses the names with backslashes
+		return nil, nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	sut := &server{cfg: Config{FsRetryAttempts: 3, FsRetryBackoff: time.Millisecond}}
+
+	// when
+	file, _, _, err := sut.lookupFile(context.Background(), []assetRoot{{name: "test/data", fsys: os.DirFS("test/data")}}, `assets\main.js`)
+
+	// then
+	suite.Nil(err)
+	suite.Nil(file)
+}
@@ -0,0 +1,131 @@
+package spaserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const gitScheme = "git+"
+
+// gitSource checks out the ref of the git repository, e.g.
+// `git+https://github.com/org/docs.git#main`. Each revision is checked out to
+// its own directory so that the served tree can be swapped atomically.
+type gitSource struct {
+	url string
+	ref string
+	dir string
+	cfg Config
+
+	lock     sync.Mutex
+	revision string
+}
+
+func newGitSource(uri string, cfg Config) (*gitSource, error) {
+	repoUrl, ref, _ := strings.Cut(strings.TrimPrefix(uri, gitScheme), "#")
+	if repoUrl == "" {
+		return nil, fmt.Errorf("invalid git root %v", uri)
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	hash := sha256.Sum256([]byte(repoUrl + "#" + ref))
+	return &gitSource{
+		url: repoUrl,
+		ref: ref,
+		dir: filepath.Join(cfg.GitCacheDir, hex.EncodeToString(hash[:8])),
+		cfg: cfg,
+	}, nil
+}
+
+// checkout fetches the ref and checks out its revision, the returned
+// filesystem is nil if the revision did not change since the last checkout
+func (this *gitSource) checkout(ctx context.Context) (fs.FS, string, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	repo := filepath.Join(this.dir, "repo")
+	if _, err := os.Stat(repo); os.IsNotExist(err) {
+		if err := os.MkdirAll(this.dir, 0755); err != nil {
+			return nil, "", err
+		}
+		if _, err := this.git(ctx, "", "init", "--quiet", "--bare"); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if _, err := this.git(ctx, "", "fetch", "--quiet", "--depth", "1", this.url, this.ref); err != nil {
+		return nil, "", err
+	}
+	revision, err := this.git(ctx, "", "rev-parse", "FETCH_HEAD")
+	if err != nil {
+		return nil, "", err
+	}
+	if revision == this.revision {
+		return nil, "", nil
+	}
+
+	tree := filepath.Join(this.dir, "trees", revision)
+	if _, err := os.Stat(tree); os.IsNotExist(err) {
+		tmp := tree + ".checkout"
+		os.RemoveAll(tmp)
+		if err := os.MkdirAll(tmp, 0755); err != nil {
+			return nil, "", err
+		}
+		if _, err := this.git(ctx, tmp, "checkout", "--quiet", "--force", revision, "--", "."); err != nil {
+			os.RemoveAll(tmp)
+			return nil, "", err
+		}
+		if err := os.Rename(tmp, tree); err != nil {
+			return nil, "", err
+		}
+	}
+
+	previous := this.revision
+	this.revision = revision
+	this.cleanup(revision, previous)
+	// the symlinks of the repository are not trusted more than the local ones
+	fsys, err := dirFS(tree, this.cfg.SymlinkPolicy)
+	return fsys, revision, err
+}
+
+// cleanup removes the checked out trees except the current and previous
+// revision, which may still be used by in-flight requests
+func (this *gitSource) cleanup(current string, previous string) {
+	entries, err := os.ReadDir(filepath.Join(this.dir, "trees"))
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == current || name == previous {
+			continue
+		}
+		os.RemoveAll(filepath.Join(this.dir, "trees", name))
+	}
+}
+
+// git runs the git command on the bare repository of the source
+func (this *gitSource) git(ctx context.Context, workTree string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, this.cfg.GitBinary, args...)
+	cmd.Env = append(os.Environ(), "GIT_DIR="+filepath.Join(this.dir, "repo"))
+	if workTree != "" {
+		cmd.Env = append(cmd.Env, "GIT_WORK_TREE="+workTree)
+	}
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %v failed: %w: %v", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
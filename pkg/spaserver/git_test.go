@@ -0,0 +1,116 @@
+package spaserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type GitTestSuite struct {
+	suite.Suite
+	repo string
+}
+
+func TestGitTestSuite(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	suite.Run(t, new(GitTestSuite))
+}
+
+func (suite *GitTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.repo = suite.T().TempDir()
+	suite.git("init", "--quiet", "--initial-branch", "main")
+	suite.commit("index.html", index_html)
+}
+
+func (suite *GitTestSuite) git(args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = suite.repo
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	suite.Require().Nil(err, string(out))
+}
+
+func (suite *GitTestSuite) commit(name string, content string) {
+	suite.Require().Nil(os.WriteFile(filepath.Join(suite.repo, name), []byte(content), 0644))
+	suite.git("add", name)
+	suite.git("commit", "--quiet", "-m", "update "+name)
+}
+
+func (suite *GitTestSuite) get(sut *server, requestPath string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", requestPath, nil)
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *GitTestSuite) config() Config {
+	return Config{
+		RootDirs:         []string{"git+file://" + suite.repo + "#main"},
+		BaseURL:          "/",
+		FallbackDisabled: true,
+		GitCacheDir:      suite.T().TempDir(),
+		GitBinary:        "git",
+	}
+}
+
+func (suite *GitTestSuite) Test_Git_root_Then_working_tree_served() {
+
+	// given
+	sut, err := newServer(suite.config(), zerolog.New(os.Stdout))
+	suite.Require().Nil(err)
+
+	// when
+	rr := suite.get(sut, "/index.html")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(index_html, rr.Body.String())
+}
+
+func (suite *GitTestSuite) Test_Git_repository_not_served() {
+
+	// given
+	sut, err := newServer(suite.config(), zerolog.New(os.Stdout))
+	suite.Require().Nil(err)
+
+	// when
+	rr := suite.get(sut, "/.git/HEAD")
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+}
+
+func (suite *GitTestSuite) Test_Git_ref_moved_and_refreshed_Then_new_revision_served() {
+
+	// given
+	sut, err := newServer(suite.config(), zerolog.New(os.Stdout))
+	suite.Require().Nil(err)
+	suite.commit("testfile.json", testfile_json)
+	before := suite.get(sut, "/testfile.json")
+
+	// when
+	roots, _ := sut.assetRoots()
+	changed, err := roots[0].refresh(context.Background())
+	suite.Require().Nil(err)
+	suite.True(changed)
+	after := suite.get(sut, "/testfile.json")
+
+	// then
+	suite.Equal(http.StatusNotFound, before.Code)
+	suite.Equal(http.StatusOK, after.Code)
+	suite.Equal(testfile_json, after.Body.String())
+}
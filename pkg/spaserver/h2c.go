@@ -0,0 +1,25 @@
+package spaserver
+
+import (
+	"errors"
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// enableH2C accepts the cleartext HTTP/2 by the server, HTTP/1.1 is served as before
+func enableH2C(httpServer *http.Server) error {
+	if httpServer.TLSConfig != nil {
+		return errors.New("h2c cannot be combined with TLS")
+	}
+	h2s := &http2.Server{}
+	// the HTTP/2 connections are sent GOAWAY on shutdown
+	if err := http2.ConfigureServer(httpServer, h2s); err != nil {
+		return err
+	}
+	// the TLS configuration is created for the negotiated HTTP/2, not used in cleartext
+	httpServer.TLSConfig = nil
+	httpServer.Handler = h2c.NewHandler(httpServer.Handler, h2s)
+	return nil
+}
@@ -0,0 +1,85 @@
+package spaserver
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/http2"
+)
+
+type H2CTestSuite struct {
+	suite.Suite
+}
+
+func TestH2CTestSuite(t *testing.T) {
+	suite.Run(t, new(H2CTestSuite))
+}
+
+func (suite *H2CTestSuite) serve() (*http.Server, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().Nil(err)
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, req.Proto)
+	})}
+	suite.Require().Nil(enableH2C(httpServer))
+	go serve(httpServer, []net.Listener{listener})
+	suite.T().Cleanup(func() { httpServer.Close() })
+	return httpServer, "http://" + listener.Addr().String()
+}
+
+func (suite *H2CTestSuite) get(client *http.Client, url string) string {
+	resp, err := client.Get(url)
+	suite.Require().Nil(err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	suite.Require().Nil(err)
+	return string(body)
+}
+
+func (suite *H2CTestSuite) Test_Prior_knowledge_Then_http2() {
+
+	// given
+	httpServer, url := suite.serve()
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+
+	// when
+	proto := suite.get(client, url)
+
+	// then
+	suite.Equal("HTTP/2.0", proto)
+	suite.Nil(httpServer.TLSConfig)
+}
+
+func (suite *H2CTestSuite) Test_Http1_Then_served() {
+
+	// given
+	_, url := suite.serve()
+
+	// when
+	proto := suite.get(http.DefaultClient, url)
+
+	// then
+	suite.Equal("HTTP/1.1", proto)
+}
+
+func (suite *H2CTestSuite) Test_Tls_configured_Then_error() {
+
+	// given
+	httpServer := &http.Server{TLSConfig: &tls.Config{}}
+
+	// when
+	err := enableH2C(httpServer)
+
+	// then
+	suite.NotNil(err)
+}
@@ -0,0 +1,282 @@
+package spaserver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// harCapture records the request/response pairs of the matching paths
+// in the HTTP Archive format until it expires
+type harCapture struct {
+	pathRegex   *regexp.Regexp
+	expires     time.Time
+	maxEntries  int
+	maxBodySize int
+
+	lock    sync.Mutex
+	entries []harEntry
+}
+
+// harCaptures holds the current capture, started and stopped through the admin API
+type harCaptures struct {
+	lock    sync.Mutex
+	current *harCapture
+}
+
+func (this *harCaptures) start(capture *harCapture) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.current = capture
+}
+
+func (this *harCaptures) stop() *harCapture {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	capture := this.current
+	this.current = nil
+	return capture
+}
+
+func (this *harCaptures) get() *harCapture {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.current
+}
+
+// active returns the capture recording the path, nil if there is none or it expired
+func (this *harCaptures) active(requestPath string) *harCapture {
+	capture := this.get()
+	if capture == nil || time.Now().After(capture.expires) || !capture.pathRegex.MatchString(requestPath) {
+		return nil
+	}
+	return capture
+}
+
+// harWriter captures the response of the recorded request
+type harWriter struct {
+	responseWriterPassthrough
+	status      int
+	size        int64
+	body        bytes.Buffer
+	maxBodySize int
+}
+
+func (this *harWriter) WriteHeader(status int) {
+	if this.status == 0 && !informational(status) {
+		this.status = status
+	}
+	this.ResponseWriter.WriteHeader(status)
+}
+
+func (this *harWriter) Write(b []byte) (int, error) {
+	if this.status == 0 {
+		this.status = http.StatusOK
+	}
+	if remaining := this.maxBodySize - this.body.Len(); remaining > 0 {
+		this.body.Write(b[:min(remaining, len(b))])
+	}
+	n, err := this.ResponseWriter.Write(b)
+	this.size += int64(n)
+	return n, err
+}
+
+// ReadFrom captures the start of the body up to the max body size, the rest keeps
+// the zero-copy path of the underlying writer
+func (this *harWriter) ReadFrom(src io.Reader) (int64, error) {
+	var total int64
+	if remaining := int64(this.maxBodySize - this.body.Len()); remaining > 0 {
+		n, err := copyWrites(this, io.LimitReader(src, remaining))
+		total += n
+		if err != nil || n < remaining {
+			return total, err
+		}
+	}
+	if this.status == 0 {
+		this.status = http.StatusOK
+	}
+	n, err := this.readFrom(src)
+	this.size += n
+	return total + n, err
+}
+
+// record adds the entry of the served request, the entries over the limit are dropped
+func (this *harCapture) record(req *http.Request, w *harWriter, started time.Time) {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	content := harContent{Size: w.size, MimeType: w.Header().Get("Content-Type")}
+	if utf8.Valid(w.body.Bytes()) && w.Header().Get("Content-Encoding") == "" {
+		content.Text = w.body.String()
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(w.body.Bytes())
+		content.Encoding = "base64"
+	}
+	if int64(w.body.Len()) < w.size {
+		content.Comment = "content truncated"
+	}
+
+	elapsed := float64(time.Since(started).Microseconds()) / 1000
+	entry := harEntry{
+		StartedDateTime: started,
+		Time:            elapsed,
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         scheme + "://" + req.Host + req.URL.RequestURI(),
+			HTTPVersion: req.Proto,
+			Headers:     harHeaders(req.Header),
+			QueryString: harQuery(req),
+			Cookies:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: harResponse{
+			Status:      status,
+			StatusText:  http.StatusText(status),
+			HTTPVersion: req.Proto,
+			Headers:     harHeaders(w.Header()),
+			Cookies:     []harNameValue{},
+			Content:     content,
+			RedirectURL: w.Header().Get("Location"),
+			HeadersSize: -1,
+			BodySize:    w.size,
+		},
+		Cache:   struct{}{},
+		Timings: harTimings{Send: 0, Wait: elapsed, Receive: 0},
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if len(this.entries) < this.maxEntries {
+		this.entries = append(this.entries, entry)
+	}
+}
+
+// archive returns the HAR document of the recorded entries
+func (this *harCapture) archive() harArchive {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	creator := harCreator{Name: "spa_d", Version: "unknown"}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		creator.Version = info.Main.Version
+	}
+	return harArchive{Log: harLog{
+		Version: "1.2",
+		Creator: creator,
+		Entries: append([]harEntry{}, this.entries...),
+	}}
+}
+
+// harRedactedHeaders carry the credentials, e.g. of the basic, OIDC or bearer
+// authentication, which must not leak into the downloaded archive
+var harRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+const harRedacted = "[REDACTED]"
+
+func harHeaders(header http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for name, values := range header {
+		redacted := slices.ContainsFunc(harRedactedHeaders, func(redacted string) bool {
+			return strings.EqualFold(redacted, name)
+		})
+		for _, value := range values {
+			if redacted {
+				value = harRedacted
+			}
+			headers = append(headers, harNameValue{Name: name, Value: value})
+		}
+	}
+	return headers
+}
+
+func harQuery(req *http.Request) []harNameValue {
+	query := []harNameValue{}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			query = append(query, harNameValue{Name: name, Value: value})
+		}
+	}
+	return query
+}
+
+// HTTP Archive 1.2 format, see http://www.softwareishard.com/blog/har-12-spec/
+type harArchive struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	Cookies     []harNameValue `json:"cookies"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	Cookies     []harNameValue `json:"cookies"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
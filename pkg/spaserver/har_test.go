@@ -0,0 +1,177 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type HarTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestHarTestSuite(t *testing.T) {
+	suite.Run(t, new(HarTestSuite))
+}
+
+func (suite *HarTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	_, filename, _, _ := runtime.Caller(0)
+	suite.sut = &server{
+		cfg: Config{
+			RootDirs:       []string{path.Join(path.Dir(filename), "test/data")},
+			BaseURL:        "/",
+			HarMaxDuration: time.Minute,
+			HarMaxEntries:  10,
+			HarMaxBodySize: 1024,
+			AdminToken:     "secret",
+		},
+		logger: zerolog.New(os.Stdout),
+	}
+}
+
+func (suite *HarTestSuite) admin(method string, target string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, target, nil)
+	suite.Require().Nil(err)
+	req.Header.Set("Authorization", "Bearer secret")
+	rr := httptest.NewRecorder()
+	suite.sut.adminHandler().ServeHTTP(rr, req)
+	return rr
+}
+
+func (suite *HarTestSuite) get(requestPath string) {
+	suite.getWithHeaders(requestPath, nil)
+}
+
+func (suite *HarTestSuite) getWithHeaders(requestPath string, headers map[string]string) {
+	req, err := http.NewRequest("GET", requestPath, nil)
+	suite.Require().Nil(err)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	suite.sut.handler(req.Context(), httptest.NewRecorder(), req)
+}
+
+func (suite *HarTestSuite) Test_Capture_started_Then_matching_requests_recorded() {
+
+	// given
+	suite.Equal(http.StatusCreated, suite.admin("POST", "/har?path=json$").Code)
+
+	// when
+	suite.get("/testfile.json")
+	suite.get("/index.html")
+	rr := suite.admin("GET", "/har")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	archive := harArchive{}
+	suite.Require().Nil(json.Unmarshal(rr.Body.Bytes(), &archive))
+	suite.Equal("1.2", archive.Log.Version)
+	suite.Require().Len(archive.Log.Entries, 1)
+	entry := archive.Log.Entries[0]
+	suite.Equal("GET", entry.Request.Method)
+	suite.Equal(http.StatusOK, entry.Response.Status)
+	suite.Equal(testfile_json, entry.Response.Content.Text)
+}
+
+func (suite *HarTestSuite) Test_Capture_expired_Then_requests_not_recorded() {
+
+	// given
+	suite.Equal(http.StatusCreated, suite.admin("POST", "/har?path=.*&duration=1ms").Code)
+	time.Sleep(5 * time.Millisecond)
+
+	// when
+	suite.get("/testfile.json")
+	rr := suite.admin("GET", "/har")
+
+	// then
+	archive := harArchive{}
+	suite.Require().Nil(json.Unmarshal(rr.Body.Bytes(), &archive))
+	suite.Empty(archive.Log.Entries)
+}
+
+func (suite *HarTestSuite) Test_Capture_stopped_Then_not_found() {
+
+	// given
+	suite.admin("POST", "/har?path=.*")
+
+	// when
+	stopped := suite.admin("DELETE", "/har")
+	rr := suite.admin("GET", "/har")
+
+	// then
+	suite.Equal(http.StatusNoContent, stopped.Code)
+	suite.Equal(http.StatusNotFound, rr.Code)
+}
+
+func (suite *HarTestSuite) Test_Credentials_Then_redacted() {
+
+	// given
+	suite.Equal(http.StatusCreated, suite.admin("POST", "/har?path=json$").Code)
+
+	// when
+	suite.getWithHeaders("/testfile.json", map[string]string{
+		"Authorization": "Basic dXNlcjpwYXNz",
+		"Cookie":        "spa_d_session=token",
+		"Accept":        "application/json",
+	})
+	rr := suite.admin("GET", "/har")
+
+	// then
+	archive := harArchive{}
+	suite.Require().Nil(json.Unmarshal(rr.Body.Bytes(), &archive))
+	suite.Require().Len(archive.Log.Entries, 1)
+	headers := map[string]string{}
+	for _, header := range archive.Log.Entries[0].Request.Headers {
+		headers[header.Name] = header.Value
+	}
+	suite.Equal(harRedacted, headers["Authorization"])
+	suite.Equal(harRedacted, headers["Cookie"])
+	suite.Equal("application/json", headers["Accept"])
+	suite.NotContains(rr.Body.String(), "dXNlcjpwYXNz")
+}
+
+func (suite *HarTestSuite) Test_Admin_token_not_configured_Then_capture_forbidden() {
+
+	// given
+	suite.sut.cfg.AdminToken = ""
+
+	// when
+	rr := suite.admin("POST", "/har?path=.*")
+
+	// then
+	suite.Equal(http.StatusForbidden, rr.Code)
+	suite.Nil(suite.sut.har.get())
+}
+
+func (suite *HarTestSuite) Test_File_copied_Then_body_captured_and_underlying_ReadFrom_used() {
+
+	// given
+	file, err := os.Open("test/data/testfile.json")
+	suite.Require().Nil(err)
+	defer file.Close()
+	underlying := &readerFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+	writer := &harWriter{responseWriterPassthrough: responseWriterPassthrough{underlying}, maxBodySize: 4}
+
+	// when
+	n, err := writer.ReadFrom(file)
+
+	// then
+	suite.Nil(err)
+	suite.Equal(int64(len(testfile_json)), n)
+	suite.Equal(testfile_json, underlying.Body.String())
+	suite.Equal(testfile_json[:4], writer.body.String())
+	suite.Equal(int64(len(testfile_json)), writer.size)
+	suite.Equal(http.StatusOK, writer.status)
+	suite.Equal([]io.Reader{file}, underlying.sources)
+}
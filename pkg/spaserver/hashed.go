@@ -0,0 +1,90 @@
+package spaserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// assetCacheControl returns the built-in Cache-Control of the asset: the content
+// hashed assets are cached as immutable, since their urls change with their
+// content, the unhashed ones are revalidated after a shorter max-age, so that a
+// new release is not hidden behind the stale copies in the shared caches
+func (this *server) assetCacheControl(ctx context.Context, req *http.Request, resourcePath string) string {
+	hashed, err := this.hashedAsset(ctx, resourcePath)
+	if err != nil {
+		// unknown, the shorter caching is the safe one
+		logger := this.requestLogger(req)
+		logger.Warn().Err(err).Msg("Cannot read the build manifest of the hashed assets")
+	}
+	if hashed {
+		debugLookup(ctx, "hashed asset")
+		return immutableCacheControl
+	}
+	if this.cfg.UnhashedCacheControl != "" {
+		return this.cfg.UnhashedCacheControl
+	}
+	return unhashedCacheControl
+}
+
+// hashedAsset reports whether the name of the asset contains its content hash,
+// either matching the hashed file regexp or listed in the build manifest
+func (this *server) hashedAsset(ctx context.Context, resourcePath string) (bool, error) {
+	if regex := this.regexes().hashedFile; regex != nil && regex.MatchString(resourcePath) {
+		return true, nil
+	}
+	files, err := this.manifestFiles(ctx)
+	if err != nil {
+		return false, err
+	}
+	return files[rootName(resourcePath)], nil
+}
+
+// manifestFiles returns the files emitted by the build into the build manifest,
+// all of them are content hashed by the bundler, empty if no manifest is
+// configured. The files follow the changes of the manifest.
+func (this *server) manifestFiles(ctx context.Context) (map[string]bool, error) {
+	if this.cfg.PreloadManifest == "" {
+		return nil, nil
+	}
+	file, ok, err := this.findFile(ctx, this.cfg.PreloadManifest)
+	if err != nil || !ok {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%v|%v|%v|%v", rootSetKey(ctx), rootName(this.cfg.PreloadManifest), info.ModTime().UnixNano(), info.Size())
+	if files, ok := this.hashedFiles.Load(key); ok {
+		recordCacheLookup(ctx, "hashed_files", cacheHit)
+		return files.(map[string]bool), nil
+	}
+	recordCacheLookup(ctx, "hashed_files", cacheMiss)
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	manifest := map[string]buildManifestChunk{}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("cannot decode build manifest %v: %w", this.cfg.PreloadManifest, err)
+	}
+	files := map[string]bool{}
+	for _, chunk := range manifest {
+		if chunk.File != "" {
+			files[chunk.File] = true
+		}
+		for _, css := range chunk.Css {
+			files[css] = true
+		}
+		for _, asset := range chunk.Assets {
+			files[asset] = true
+		}
+	}
+	this.cache(&this.hashedFiles, key, files, memoryCritical)
+	return files, nil
+}
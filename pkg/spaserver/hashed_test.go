@@ -0,0 +1,138 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type HashedTestSuite struct {
+	suite.Suite
+	rootDir string
+}
+
+func TestHashedTestSuite(t *testing.T) {
+	suite.Run(t, new(HashedTestSuite))
+}
+
+func (suite *HashedTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.Require().Nil(os.MkdirAll(path.Join(suite.rootDir, ".vite"), 0755))
+	suite.Require().Nil(os.MkdirAll(path.Join(suite.rootDir, "assets"), 0755))
+	for _, name := range []string{"index.html", "assets/index-BwA3xY_1.js", "assets/logo-Dk2h7s9A.svg", "assets/main.3f2a1b9c.js", "robots.txt"} {
+		suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, name), []byte("content"), 0644))
+	}
+	suite.writeManifest(`{
+		"index.html": {"file": "assets/index-BwA3xY_1.js", "isEntry": true, "assets": ["assets/logo-Dk2h7s9A.svg"]}
+	}`)
+}
+
+func (suite *HashedTestSuite) writeManifest(content string) {
+	manifest := path.Join(suite.rootDir, ".vite/manifest.json")
+	suite.Require().Nil(os.WriteFile(manifest, []byte(content), 0644))
+	// the manifest changes are detected by the modification time
+	modTime := time.Now().Add(time.Duration(len(content)) * time.Second)
+	suite.Require().Nil(os.Chtimes(manifest, modTime, modTime))
+}
+
+func (suite *HashedTestSuite) server(cfg Config) *server {
+	cfg.RootDirs = []string{suite.rootDir}
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	return sut
+}
+
+func (suite *HashedTestSuite) cacheControl(sut *server, target string) string {
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, httptest.NewRequest("GET", target, nil))
+	suite.Require().Equal(200, rr.Code, target)
+	return rr.Header().Get("Cache-Control")
+}
+
+func (suite *HashedTestSuite) Test_Hashed_file_name_Then_immutable() {
+
+	// given
+	sut := suite.server(Config{HashedFileRegex: `\.[0-9a-f]{8,}\.`})
+
+	// when
+	hashed := suite.cacheControl(sut, "/assets/main.3f2a1b9c.js")
+	unhashed := suite.cacheControl(sut, "/robots.txt")
+
+	// then
+	suite.Equal(immutableCacheControl, hashed)
+	suite.Equal(unhashedCacheControl, unhashed)
+}
+
+func (suite *HashedTestSuite) Test_Manifest_files_Then_immutable() {
+
+	// given
+	sut := suite.server(Config{PreloadManifest: ".vite/manifest.json", UnhashedCacheControl: "no-cache"})
+
+	// when
+	chunk := suite.cacheControl(sut, "/assets/index-BwA3xY_1.js")
+	asset := suite.cacheControl(sut, "/assets/logo-Dk2h7s9A.svg")
+	other := suite.cacheControl(sut, "/assets/main.3f2a1b9c.js")
+
+	// then
+	suite.Equal(immutableCacheControl, chunk)
+	suite.Equal(immutableCacheControl, asset)
+	suite.Equal("no-cache", other)
+}
+
+func (suite *HashedTestSuite) Test_Manifest_changed_Then_files_reloaded() {
+
+	// given
+	sut := suite.server(Config{PreloadManifest: ".vite/manifest.json"})
+	suite.Equal(immutableCacheControl, suite.cacheControl(sut, "/assets/index-BwA3xY_1.js"))
+
+	// when
+	suite.writeManifest(`{"index.html": {"file": "assets/main.3f2a1b9c.js", "isEntry": true}}`)
+
+	// then
+	suite.Equal(unhashedCacheControl, suite.cacheControl(sut, "/assets/index-BwA3xY_1.js"))
+	suite.Equal(immutableCacheControl, suite.cacheControl(sut, "/assets/main.3f2a1b9c.js"))
+}
+
+func (suite *HashedTestSuite) Test_Default_cache_control_configured_Then_hashes_ignored() {
+
+	// given
+	sut := suite.server(Config{HashedFileRegex: `\.[0-9a-f]{8,}\.`, DefaultCacheControl: "public, max-age=60"})
+
+	// when
+	cacheControl := suite.cacheControl(sut, "/assets/main.3f2a1b9c.js")
+
+	// then
+	suite.Equal("public, max-age=60", cacheControl)
+}
+
+func (suite *HashedTestSuite) Test_Transformed_identity_response_Then_varies_by_encoding() {
+
+	// given
+	sut := suite.server(Config{BaseURL: "/app", BaseHref: true, DynamicCompression: true})
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, httptest.NewRequest("GET", "/app/index.html", nil))
+
+	// then
+	suite.Equal(200, rr.Code)
+	suite.Empty(rr.Header().Get("Content-Encoding"))
+	suite.Contains(rr.Header().Values("Vary"), "Accept-Encoding")
+}
+
+func (suite *HashedTestSuite) Test_Invalid_hashed_file_regexp_Then_validation_error() {
+
+	// when
+	err := validateConfig(Config{HashedFileRegex: "("})
+
+	// then
+	suite.ErrorContains(err, "hashed-file-regexp")
+}
@@ -0,0 +1,85 @@
+package spaserver
+
+import (
+	"net/http"
+)
+
+// Hooks are the extension points of the request handling, e.g. for the custom
+// authorization, audit logging or headers. The hooks of the several WithHooks
+// options are chained in the order of the options.
+type Hooks struct {
+	// BeforeLookup is called before the resource path, stripped of the base url,
+	// is looked up in the roots. The request is not served further if the hook
+	// returns false, the hook writes the response, e.g. 401.
+	BeforeLookup func(w http.ResponseWriter, req *http.Request, resourcePath string) bool
+
+	// AfterHeaders is called with the status before the response headers are
+	// written, the headers may be changed.
+	AfterHeaders func(header http.Header, req *http.Request, status int)
+
+	// OnNotFound serves the not found response instead of the plain 404, the
+	// next hook or the plain 404 is served if the hook returns false.
+	OnNotFound func(w http.ResponseWriter, req *http.Request) bool
+
+	// OnError is called with the error serving the request and serves the
+	// response instead of the plain 500, the next hook or the plain 500 is served
+	// if the hook returns false.
+	OnError func(w http.ResponseWriter, req *http.Request, err error) bool
+}
+
+// WithHooks adds the hooks to the request handling
+func WithHooks(hooks Hooks) Option {
+	return func(this *options) {
+		this.hooks = append(this.hooks, hooks)
+	}
+}
+
+// beforeLookup calls the before-lookup hooks, false if any of them served the request
+func (this *server) beforeLookup(w http.ResponseWriter, req *http.Request, resourcePath string) bool {
+	for _, hooks := range this.hooks {
+		if hooks.BeforeLookup != nil && !hooks.BeforeLookup(w, req, resourcePath) {
+			return false
+		}
+	}
+	return true
+}
+
+// afterHeaders returns the callback of the recorder calling the after-headers hooks, nil if none
+func (this *server) afterHeaders(w http.ResponseWriter, req *http.Request) func(status int) {
+	if len(this.hooks) == 0 {
+		return nil
+	}
+	return func(status int) {
+		for _, hooks := range this.hooks {
+			if hooks.AfterHeaders != nil {
+				hooks.AfterHeaders(w.Header(), req, status)
+			}
+		}
+	}
+}
+
+// notFound serves the not found response of the hooks, the error page or the plain 404
+func (this *server) notFound(w http.ResponseWriter, req *http.Request) {
+	for _, hooks := range this.hooks {
+		if hooks.OnNotFound != nil && hooks.OnNotFound(w, req) {
+			return
+		}
+	}
+	if this.serveErrorPage(w, req, http.StatusNotFound) {
+		return
+	}
+	http.Error(w, "Not Found", http.StatusNotFound)
+}
+
+// internalError serves the error response of the hooks, the error page or the plain 500
+func (this *server) internalError(w http.ResponseWriter, req *http.Request, err error) {
+	for _, hooks := range this.hooks {
+		if hooks.OnError != nil && hooks.OnError(w, req, err) {
+			return
+		}
+	}
+	if this.serveErrorPage(w, req, http.StatusInternalServerError) {
+		return
+	}
+	http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+}
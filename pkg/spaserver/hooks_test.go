@@ -0,0 +1,143 @@
+package spaserver
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type HooksTestSuite struct {
+	suite.Suite
+	cfg Config
+}
+
+func TestHooksTestSuite(t *testing.T) {
+	suite.Run(t, new(HooksTestSuite))
+}
+
+func (suite *HooksTestSuite) SetupTest() {
+	dir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(dir, "index.html"), []byte("index"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(dir, "app.js"), []byte("app"), 0644))
+	suite.cfg = DefaultConfig()
+	suite.cfg.RootDirs = []string{dir}
+}
+
+func (suite *HooksTestSuite) get(target string, hooks ...Hooks) *httptest.ResponseRecorder {
+	options := []Option{}
+	for _, hook := range hooks {
+		options = append(options, WithHooks(hook))
+	}
+	sut, err := New(suite.cfg, options...)
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", target, nil)
+	req.Header.Set("Accept", "text/html")
+	rr := httptest.NewRecorder()
+	sut.ServeHTTP(rr, req)
+	return rr
+}
+
+func (suite *HooksTestSuite) Test_Before_lookup_refusing_Then_not_served() {
+
+	// given
+	var resourcePath string
+	hooks := Hooks{BeforeLookup: func(w http.ResponseWriter, req *http.Request, path string) bool {
+		resourcePath = path
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}}
+
+	// when
+	rr := suite.get("/app.js", hooks)
+
+	// then
+	suite.Equal(http.StatusUnauthorized, rr.Code)
+	suite.Equal("app.js", resourcePath)
+}
+
+func (suite *HooksTestSuite) Test_Chained_hooks_Then_called_in_order() {
+
+	// given
+	calls := []string{}
+	hook := func(name string) Hooks {
+		return Hooks{
+			BeforeLookup: func(w http.ResponseWriter, req *http.Request, path string) bool {
+				calls = append(calls, name)
+				return true
+			},
+			AfterHeaders: func(header http.Header, req *http.Request, status int) {
+				header.Set("X-Hook", name)
+			},
+		}
+	}
+
+	// when
+	rr := suite.get("/app.js", hook("first"), hook("second"))
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("app", rr.Body.String())
+	suite.Equal([]string{"first", "second"}, calls)
+	suite.Equal("second", rr.Header().Get("X-Hook"))
+}
+
+func (suite *HooksTestSuite) Test_After_headers_Then_status_passed() {
+
+	// given
+	statuses := []int{}
+	hooks := Hooks{AfterHeaders: func(header http.Header, req *http.Request, status int) {
+		statuses = append(statuses, status)
+	}}
+	suite.cfg.FallbackDisabled = true
+
+	// when
+	suite.get("/missing", hooks)
+
+	// then
+	suite.Equal([]int{http.StatusNotFound}, statuses)
+}
+
+func (suite *HooksTestSuite) Test_On_not_found_Then_custom_response() {
+
+	// given
+	suite.cfg.FallbackDisabled = true
+	hooks := Hooks{OnNotFound: func(w http.ResponseWriter, req *http.Request) bool {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, "custom "+req.URL.Path)
+		return true
+	}}
+
+	// when
+	rr := suite.get("/missing", Hooks{OnNotFound: func(w http.ResponseWriter, req *http.Request) bool { return false }}, hooks)
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+	suite.Equal("custom /missing", rr.Body.String())
+}
+
+func (suite *HooksTestSuite) Test_On_error_Then_error_passed() {
+
+	// given
+	var served error
+	hooks := Hooks{OnError: func(w http.ResponseWriter, req *http.Request, err error) bool {
+		served = err
+		http.Error(w, "Sorry", http.StatusServiceUnavailable)
+		return true
+	}}
+	sut, err := New(suite.cfg, WithHooks(hooks))
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.(*server).internalError(rr, httptest.NewRequest("GET", "/", nil), errors.New("broken root"))
+
+	// then
+	suite.Equal(http.StatusServiceUnavailable, rr.Code)
+	suite.EqualError(served, "broken root")
+}
@@ -0,0 +1,37 @@
+package spaserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// enableHTTP3 creates the experimental HTTP/3 server sharing the handler and the
+// certificates of the server, listening on the UDP port of the address. The
+// HTTP/1.1 and HTTP/2 responses advertise it with Alt-Svc on the advertised
+// port, the listening port if zero, so that the browsers switch to QUIC.
+func enableHTTP3(httpServer *http.Server, addr string, advertisedPort int) (*http3.Server, error) {
+	if httpServer.TLSConfig == nil {
+		return nil, errors.New("HTTP/3 requires TLS")
+	}
+	h3s := &http3.Server{
+		Addr:           addr,
+		Port:           advertisedPort,
+		Handler:        httpServer.Handler,
+		TLSConfig:      http3.ConfigureTLSConfig(httpServer.TLSConfig),
+		MaxHeaderBytes: httpServer.MaxHeaderBytes,
+	}
+	if httpServer.IdleTimeout > 0 {
+		// the idle QUIC connections expire as the TCP ones
+		h3s.QuicConfig = &quic.Config{MaxIdleTimeout: httpServer.IdleTimeout}
+	}
+	handler := httpServer.Handler
+	httpServer.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// not advertised until listening
+		h3s.SetQuicHeaders(w.Header())
+		handler.ServeHTTP(w, req)
+	})
+	return h3s, nil
+}
@@ -0,0 +1,125 @@
+package spaserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/stretchr/testify/suite"
+)
+
+type Http3TestSuite struct {
+	suite.Suite
+	httpServer *http.Server
+}
+
+func TestHttp3TestSuite(t *testing.T) {
+	suite.Run(t, new(Http3TestSuite))
+}
+
+func (suite *Http3TestSuite) SetupTest() {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().Nil(err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	suite.Require().Nil(err)
+	suite.httpServer = &http.Server{
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{certificate}, PrivateKey: key}}},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			io.WriteString(w, req.Proto)
+		}),
+	}
+}
+
+// serve serves HTTP/3 on a dynamic UDP port and returns the port
+func (suite *Http3TestSuite) serve(advertisedPort int) int {
+	h3s, err := enableHTTP3(suite.httpServer, "127.0.0.1:0", advertisedPort)
+	suite.Require().Nil(err)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	suite.Require().Nil(err)
+	go h3s.Serve(conn)
+	suite.T().Cleanup(func() { h3s.Close() })
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// altSvc returns the Alt-Svc header of the response over TCP once advertised
+func (suite *Http3TestSuite) altSvc() string {
+	header := ""
+	suite.Eventually(func() bool {
+		rr := httptest.NewRecorder()
+		suite.httpServer.Handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+		header = rr.Header().Get("Alt-Svc")
+		return header != ""
+	}, time.Second, 10*time.Millisecond)
+	return header
+}
+
+func (suite *Http3TestSuite) Test_Quic_request_Then_served_over_http3() {
+
+	// given
+	port := suite.serve(0)
+	client := &http.Client{Transport: &http3.RoundTripper{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+
+	// when
+	resp, err := client.Get(fmt.Sprintf("https://127.0.0.1:%v/", port))
+
+	// then
+	suite.Require().Nil(err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	suite.Nil(err)
+	suite.Equal("HTTP/3.0", string(body))
+}
+
+func (suite *Http3TestSuite) Test_Tcp_response_Then_http3_advertised() {
+
+	// given
+	port := suite.serve(0)
+
+	// when
+	header := suite.altSvc()
+
+	// then
+	suite.Contains(header, fmt.Sprintf(`h3=":%v"`, port))
+}
+
+func (suite *Http3TestSuite) Test_Advertised_port_Then_advertised_instead_of_listening() {
+
+	// given
+	suite.serve(443)
+
+	// when
+	header := suite.altSvc()
+
+	// then
+	suite.Contains(header, `h3=":443"`)
+}
+
+func (suite *Http3TestSuite) Test_No_tls_Then_error() {
+
+	// given
+	suite.httpServer.TLSConfig = nil
+
+	// when
+	_, err := enableHTTP3(suite.httpServer, ":7105", 0)
+
+	// then
+	suite.NotNil(err)
+}
@@ -0,0 +1,19 @@
+package spaserver
+
+import (
+	"net/http"
+)
+
+// newHttpServer creates the server of the handler with the configured timeouts
+// and limits, so that the slow clients, e.g. sending the headers byte by byte,
+// cannot hold the connections open indefinitely
+func newHttpServer(cfg Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
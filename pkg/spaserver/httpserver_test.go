@@ -0,0 +1,78 @@
+package spaserver
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type HttpServerTestSuite struct {
+	suite.Suite
+}
+
+func TestHttpServerTestSuite(t *testing.T) {
+	suite.Run(t, new(HttpServerTestSuite))
+}
+
+func (suite *HttpServerTestSuite) serve(cfg Config) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().Nil(err)
+	httpServer := newHttpServer(cfg, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "done")
+	}))
+	go httpServer.Serve(listener)
+	suite.T().Cleanup(func() { httpServer.Close() })
+	return listener.Addr().String()
+}
+
+func (suite *HttpServerTestSuite) Test_Slow_headers_Then_connection_closed() {
+
+	// given
+	addr := suite.serve(Config{ReadHeaderTimeout: 100 * time.Millisecond})
+	conn, err := net.Dial("tcp", addr)
+	suite.Require().Nil(err)
+	defer conn.Close()
+	started := time.Now()
+
+	// when
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: localhost\r\n")
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = io.ReadAll(conn)
+
+	// then
+	suite.Nil(err, "closed by the server")
+	suite.Less(time.Since(started), time.Second)
+}
+
+func (suite *HttpServerTestSuite) Test_Headers_too_large_Then_rejected() {
+
+	// given
+	addr := suite.serve(Config{MaxHeaderBytes: 1024})
+	conn, err := net.Dial("tcp", addr)
+	suite.Require().Nil(err)
+	defer conn.Close()
+
+	// when
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: localhost\r\nX-Large: "+strings.Repeat("a", 8192)+"\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+
+	// then
+	suite.Require().Nil(err)
+	suite.Equal(http.StatusRequestHeaderFieldsTooLarge, resp.StatusCode)
+}
+
+func (suite *HttpServerTestSuite) Test_Negative_timeout_Then_validation_error() {
+
+	// when
+	err := validateConfig(Config{ReadHeaderTimeout: -time.Second, MaxHeaderBytes: -1})
+
+	// then
+	suite.ErrorContains(err, "read-header-timeout: the timeout must not be negative")
+	suite.ErrorContains(err, "max-header-bytes: the size must not be negative")
+}
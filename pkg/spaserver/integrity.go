@@ -0,0 +1,84 @@
+package spaserver
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// verifyIntegrity verifies the files of the root against the checksums manifest
+// of the root. In the warn mode the mismatch is only reported.
+func (this *server) verifyIntegrity(rootDir string, fsys fs.FS) error {
+	if this.cfg.IntegrityManifest == "" {
+		return nil
+	}
+
+	err := verifyChecksums(fsys, this.cfg.IntegrityManifest)
+	if err == nil {
+		return nil
+	}
+
+	telemetry().integrity_failures.Add(context.Background(), 1,
+		metric.WithAttributes(attribute.String("root", rootLabel(rootDir))))
+	this.recent.add("integrity_failure")
+	if this.cfg.IntegrityMode == "warn" {
+		this.logger.Warn().Err(err).Str("root", rootLabel(rootDir)).Msg("Integrity verification failed")
+		return nil
+	}
+	return err
+}
+
+// verifyChecksums verifies the files listed in the manifest in the sha256sum
+// format, i.e. lines of the hex encoded sha256 digest and the file name
+func verifyChecksums(fsys fs.FS, manifest string) error {
+	file, err := fsys.Open(rootName(manifest))
+	if err != nil {
+		return fmt.Errorf("cannot open checksums manifest: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		expected, name, ok := strings.Cut(entry, " ")
+		if !ok {
+			return fmt.Errorf("invalid checksums manifest entry on line %v", line)
+		}
+		// binary mode marker of sha256sum
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+
+		actual, err := fileChecksum(fsys, rootName(name))
+		if err != nil {
+			return fmt.Errorf("cannot verify %v: %w", name, err)
+		}
+		if !strings.EqualFold(actual, expected) {
+			return fmt.Errorf("checksum mismatch of %v", name)
+		}
+	}
+	return scanner.Err()
+}
+
+func fileChecksum(fsys fs.FS, name string) (string, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	digest := sha256.New()
+	if _, err := io.Copy(digest, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
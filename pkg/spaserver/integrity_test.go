@@ -0,0 +1,96 @@
+package spaserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type IntegrityTestSuite struct {
+	suite.Suite
+	root string
+}
+
+func TestIntegrityTestSuite(t *testing.T) {
+	suite.Run(t, new(IntegrityTestSuite))
+}
+
+func (suite *IntegrityTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.root = suite.T().TempDir()
+	suite.write("index.html", index_html)
+	suite.write("assets/testfile.json", testfile_json)
+
+	manifest := ""
+	for _, name := range []string{"index.html", "assets/testfile.json"} {
+		content, err := os.ReadFile(filepath.Join(suite.root, name))
+		suite.Require().Nil(err)
+		hash := sha256.Sum256(content)
+		manifest += hex.EncodeToString(hash[:]) + "  ./" + name + "\n"
+	}
+	suite.write("SHA256SUMS", manifest)
+}
+
+func (suite *IntegrityTestSuite) write(name string, content string) {
+	target := filepath.Join(suite.root, name)
+	suite.Require().Nil(os.MkdirAll(filepath.Dir(target), 0755))
+	suite.Require().Nil(os.WriteFile(target, []byte(content), 0644))
+}
+
+func (suite *IntegrityTestSuite) config(mode string) Config {
+	return Config{
+		RootDirs:          []string{suite.root},
+		IntegrityManifest: "SHA256SUMS",
+		IntegrityMode:     mode,
+	}
+}
+
+func (suite *IntegrityTestSuite) Test_Files_match_manifest_Then_served() {
+
+	// when
+	_, err := newServer(suite.config("enforce"), zerolog.New(os.Stdout))
+
+	// then
+	suite.Nil(err)
+}
+
+func (suite *IntegrityTestSuite) Test_File_tampered_Then_refused() {
+
+	// given
+	suite.write("assets/testfile.json", `{"tampered": true}`)
+
+	// when
+	_, err := newServer(suite.config("enforce"), zerolog.New(os.Stdout))
+
+	// then
+	suite.ErrorContains(err, "checksum mismatch of ./assets/testfile.json")
+}
+
+func (suite *IntegrityTestSuite) Test_File_tampered_in_warn_mode_Then_served() {
+
+	// given
+	suite.write("assets/testfile.json", `{"tampered": true}`)
+
+	// when
+	_, err := newServer(suite.config("warn"), zerolog.New(os.Stdout))
+
+	// then
+	suite.Nil(err)
+}
+
+func (suite *IntegrityTestSuite) Test_Manifest_missing_Then_refused() {
+
+	// given
+	suite.Require().Nil(os.Remove(filepath.Join(suite.root, "SHA256SUMS")))
+
+	// when
+	_, err := newServer(suite.config("enforce"), zerolog.New(os.Stdout))
+
+	// then
+	suite.ErrorContains(err, "cannot open checksums manifest")
+}
@@ -0,0 +1,115 @@
+package spaserver
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ipFilter allows the clients by their addresses
+type ipFilter struct {
+	allowed []*net.IPNet
+	denied  []*net.IPNet
+}
+
+// parseCidr parses the CIDR range or the single address
+func parseCidr(value string) (*net.IPNet, error) {
+	if _, network, err := net.ParseCIDR(value); err == nil {
+		return network, nil
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("%q is neither an address nor a CIDR range", value)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+func parseCidrs(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		network, err := parseCidr(value)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// newIpFilter parses the configured ranges, nil if no client is filtered
+func (this *server) newIpFilter() (*ipFilter, error) {
+	if len(this.cfg.AllowedCidrs) == 0 && len(this.cfg.DeniedCidrs) == 0 {
+		return nil, nil
+	}
+	allowed, err := parseCidrs(this.cfg.AllowedCidrs)
+	if err != nil {
+		return nil, err
+	}
+	denied, err := parseCidrs(this.cfg.DeniedCidrs)
+	if err != nil {
+		return nil, err
+	}
+	return &ipFilter{allowed: allowed, denied: denied}, nil
+}
+
+// allows reports whether the client is not denied and allowed, if any allowed
+func (this *ipFilter) allows(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	contains := func(networks []*net.IPNet) bool {
+		for _, network := range networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	if contains(this.denied) {
+		return false
+	}
+	return len(this.allowed) == 0 || contains(this.allowed)
+}
+
+// clientIp resolves the address of the client, the X-Forwarded-For and X-Real-IP
+// headers are honored only from the trusted proxies. The X-Forwarded-For is read
+// from the right skipping the trusted proxies, the addresses left of the first
+// untrusted one may be forged by the client.
+func (this *server) clientIp(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	peer := net.ParseIP(host)
+	if peer == nil || !this.trustedProxy(host) {
+		return peer
+	}
+
+	forwarded := []string{}
+	for _, value := range req.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(value, ",")...)
+	}
+	if len(forwarded) > 0 {
+		client := peer
+		for i := len(forwarded) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+			if ip == nil {
+				// the invalid entry is not trusted to name the client
+				break
+			}
+			client = ip
+			if !this.trustedProxy(ip.String()) {
+				break
+			}
+		}
+		return client
+	}
+	if ip := net.ParseIP(strings.TrimSpace(req.Header.Get("X-Real-IP"))); ip != nil {
+		return ip
+	}
+	return peer
+}
@@ -0,0 +1,108 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type IpFilterTestSuite struct {
+	suite.Suite
+	rootDir string
+}
+
+func TestIpFilterTestSuite(t *testing.T) {
+	suite.Run(t, new(IpFilterTestSuite))
+}
+
+func (suite *IpFilterTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "index.html"), []byte("app"), 0644))
+}
+
+func (suite *IpFilterTestSuite) server(cfg Config) *server {
+	cfg.RootDirs = []string{suite.rootDir}
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	return sut
+}
+
+func (suite *IpFilterTestSuite) get(sut *server, remoteAddr string, headers map[string]string) int {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = remoteAddr
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr.Code
+}
+
+func (suite *IpFilterTestSuite) Test_Allowed_cidrs_Then_others_forbidden() {
+
+	// given
+	sut := suite.server(Config{AllowedCidrs: []string{"10.1.0.0/16", "192.168.1.5"}, DeniedCidrs: []string{"10.1.2.0/24"}})
+
+	// then
+	suite.Equal(http.StatusOK, suite.get(sut, "10.1.1.1:4000", nil))
+	suite.Equal(http.StatusOK, suite.get(sut, "192.168.1.5:4000", nil))
+	suite.Equal(http.StatusForbidden, suite.get(sut, "10.1.2.3:4000", nil))
+	suite.Equal(http.StatusForbidden, suite.get(sut, "8.8.8.8:4000", nil))
+}
+
+func (suite *IpFilterTestSuite) Test_Trusted_proxy_Then_forwarded_client_filtered() {
+
+	// given
+	sut := suite.server(Config{TrustedProxies: []string{"10.0.0.0/8"}, DeniedCidrs: []string{"203.0.113.7"}})
+
+	// then
+	suite.Equal(http.StatusForbidden, suite.get(sut, "10.0.0.1:4000", map[string]string{"X-Forwarded-For": "203.0.113.7, 10.0.0.2"}))
+	suite.Equal(http.StatusOK, suite.get(sut, "10.0.0.1:4000", map[string]string{"X-Forwarded-For": "203.0.113.7, 198.51.100.1"}))
+	suite.Equal(http.StatusForbidden, suite.get(sut, "10.0.0.1:4000", map[string]string{"X-Real-IP": "203.0.113.7"}))
+}
+
+func (suite *IpFilterTestSuite) Test_Untrusted_peer_Then_forwarded_headers_ignored() {
+
+	// given
+	sut := suite.server(Config{AllowedCidrs: []string{"10.0.0.0/8"}})
+
+	// when
+	code := suite.get(sut, "203.0.113.7:4000", map[string]string{"X-Forwarded-For": "10.0.0.1", "X-Real-IP": "10.0.0.1"})
+
+	// then
+	suite.Equal(http.StatusForbidden, code)
+}
+
+func (suite *IpFilterTestSuite) Test_Forwarded_chain_of_trusted_proxies_Then_leftmost_client() {
+
+	// given
+	sut := suite.server(Config{TrustedProxies: []string{"10.0.0.0/8"}})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:4000"
+	req.Header.Add("X-Forwarded-For", "10.0.0.3")
+	req.Header.Add("X-Forwarded-For", "10.0.0.2")
+
+	// when
+	client := sut.clientIp(req)
+
+	// then
+	suite.Equal(net.ParseIP("10.0.0.3").String(), client.String())
+}
+
+func (suite *IpFilterTestSuite) Test_Invalid_cidr_Then_invalid() {
+
+	// when
+	_, err := newServer(Config{DeniedCidrs: []string{"10.0.0.0/33"}}, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, `denied-cidrs[0]: "10.0.0.0/33" is neither an address nor a CIDR range`)
+}
@@ -0,0 +1,249 @@
+package spaserver
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwtKeysInterval is the minimal interval of fetching the keys of the unknown key ids
+	jwtKeysInterval = time.Minute
+	// jwtLeeway tolerates the clock skew of the token issuer
+	jwtLeeway = time.Minute
+)
+
+var errTokenInvalid = errors.New("token invalid")
+
+// jwtClaims are the registered claims of the tokens and the id token claims
+type jwtClaims struct {
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	Expires   int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	Nonce     string   `json:"nonce"`
+	Subject   string   `json:"sub"`
+	Email     string   `json:"email"`
+}
+
+// check verifies the issuer, the audience, the expiration and the not before
+// time, the issuer and the audience are not checked if empty
+func (this jwtClaims) check(issuer, aud string) error {
+	now := time.Now()
+	switch {
+	case issuer != "" && this.Issuer != issuer:
+		return fmt.Errorf("%w: issuer %v", errTokenInvalid, this.Issuer)
+	case aud != "" && !this.Audience.contains(aud):
+		return fmt.Errorf("%w: audience %v", errTokenInvalid, this.Audience)
+	case this.Expires == 0 || now.Add(-jwtLeeway).Unix() >= this.Expires:
+		return fmt.Errorf("%w: expired", errTokenInvalid)
+	case this.NotBefore != 0 && now.Add(jwtLeeway).Unix() < this.NotBefore:
+		return fmt.Errorf("%w: not valid yet", errTokenInvalid)
+	}
+	return nil
+}
+
+// audience is the single or the list of audiences of the token
+type audience []string
+
+func (this *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*this = audience{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(this))
+}
+
+func (this audience) contains(aud string) bool {
+	for _, item := range this {
+		if item == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonWebKey is the public key of the JWKS of the token issuer
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes the RSA or the P-256 key
+func (this jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	decode := func(value string) (*big.Int, error) {
+		content, err := base64.RawURLEncoding.DecodeString(value)
+		return new(big.Int).SetBytes(content), err
+	}
+	switch {
+	case this.Kty == "RSA":
+		n, err := decode(this.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(this.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("invalid exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case this.Kty == "EC" && this.Crv == "P-256":
+		x, err := decode(this.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(this.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("invalid point")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %v", this.Kty)
+	}
+}
+
+// jwtKeys verifies the signatures of the tokens with the keys of the JWKS url
+type jwtKeys struct {
+	client  *http.Client
+	jwksUri string
+
+	mutex   sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+func newJwtKeys(client *http.Client, jwksUri string) *jwtKeys {
+	return &jwtKeys{client: client, jwksUri: jwksUri}
+}
+
+// verify verifies the RS256 or ES256 signature of the token and decodes its claims
+func (this *jwtKeys) verify(ctx context.Context, token string, claims any) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errTokenInvalid
+	}
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	if err := decodeJwtPart(parts[0], &header); err != nil {
+		return err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return errTokenInvalid
+	}
+	key, err := this.publicKey(ctx, header.Kid)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg != "RS256" || rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) != nil {
+			return fmt.Errorf("%w: signature", errTokenInvalid)
+		}
+	case *ecdsa.PublicKey:
+		if header.Alg != "ES256" || len(signature) != 64 ||
+			!ecdsa.Verify(key, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+			return fmt.Errorf("%w: signature", errTokenInvalid)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported key", errTokenInvalid)
+	}
+	return decodeJwtPart(parts[1], claims)
+}
+
+func decodeJwtPart(part string, value any) error {
+	content, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return errTokenInvalid
+	}
+	if err := json.Unmarshal(content, value); err != nil {
+		return fmt.Errorf("%w: %v", errTokenInvalid, err)
+	}
+	return nil
+}
+
+// publicKey returns the key of the id, the keys are fetched again for the
+// unknown id, e.g. after the rotation, at most once per the keys interval
+func (this *jwtKeys) publicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if key, ok := this.lookup(kid); ok {
+		return key, nil
+	}
+	if time.Since(this.fetched) < jwtKeysInterval {
+		return nil, fmt.Errorf("%w: unknown key %v", errTokenInvalid, kid)
+	}
+	this.fetched = time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, this.jwksUri, nil)
+	if err != nil {
+		return nil, err
+	}
+	jwks := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err := fetchJson(this.client, req, &jwks); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	this.keys = map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		// the keys of the unsupported types are skipped
+		if key, err := jwk.publicKey(); err == nil {
+			this.keys[jwk.Kid] = key
+		}
+	}
+	if key, ok := this.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key %v", errTokenInvalid, kid)
+}
+
+// lookup returns the key of the id, or the only key if the token has no id
+func (this *jwtKeys) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(this.keys) == 1 {
+		for _, key := range this.keys {
+			return key, true
+		}
+	}
+	key, ok := this.keys[kid]
+	return key, ok
+}
+
+// fetchJson decodes the JSON response of the identity provider or the token issuer
+func fetchJson(client *http.Client, req *http.Request, value any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status %v: %s", resp.StatusCode, body)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(value)
+}
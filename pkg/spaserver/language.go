@@ -0,0 +1,73 @@
+package spaserver
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// languageTagRegex matches the language tags of the fallback languages, e.g. `de` or `pt-BR`
+var languageTagRegex = regexp.MustCompile(`^[A-Za-z]{1,8}(-[A-Za-z0-9]{1,8})*$`)
+
+// languageFallbackDocument selects the fallback document of the language best
+// matching the Accept-Language of the request, the fallback document if none
+// matches. The Content-Language is set to the language of the selected document.
+func (this *server) languageFallbackDocument(w http.ResponseWriter, req *http.Request) string {
+	// the fallback responses differ by the accepted languages
+	w.Header().Add("Vary", "Accept-Language")
+	language, ok := matchLanguage(req, this.cfg.FallbackLanguages)
+	document := this.fallbackDocument()
+	if ok {
+		document = "/" + rootName(this.cfg.FallbackLanguages[language])
+	} else {
+		// the language of the fallback document, if listed
+		for tag, localized := range this.cfg.FallbackLanguages {
+			if "/"+rootName(localized) == document {
+				language, ok = tag, true
+				break
+			}
+		}
+	}
+	if ok {
+		w.Header().Set("Content-Language", language)
+	}
+	return document
+}
+
+// matchLanguage returns the configured language best matching the language
+// ranges of the Accept-Language header in the order of their quality. The range
+// matches the language of its tag or of its prefix, e.g. `de-AT` matches `de`.
+func matchLanguage(req *http.Request, languages map[string]string) (string, bool) {
+	type languageRange struct {
+		tag     string
+		quality float64
+	}
+	ranges := []languageRange{}
+	for _, value := range req.Header.Values("Accept-Language") {
+		for _, item := range strings.Split(value, ",") {
+			tag, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			if q := quality(params); tag != "" && tag != "*" && q > 0 {
+				ranges = append(ranges, languageRange{tag: tag, quality: q})
+			}
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].quality > ranges[j].quality })
+
+	for _, languageRange := range ranges {
+		for tag := languageRange.tag; tag != ""; {
+			for language := range languages {
+				if strings.EqualFold(language, tag) {
+					return language, true
+				}
+			}
+			cut := strings.LastIndex(tag, "-")
+			if cut < 0 {
+				break
+			}
+			tag = tag[:cut]
+		}
+	}
+	return "", false
+}
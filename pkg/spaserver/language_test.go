@@ -0,0 +1,111 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type LanguageTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestLanguageTestSuite(t *testing.T) {
+	suite.Run(t, new(LanguageTestSuite))
+}
+
+func (suite *LanguageTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.MkdirAll(path.Join(rootDir, "de"), 0755))
+	suite.Require().Nil(os.MkdirAll(path.Join(rootDir, "pt-br"), 0755))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "index.html"), []byte("en"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "de/index.html"), []byte("de"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "pt-br/index.html"), []byte("pt-BR"), 0644))
+	sut, err := newServer(Config{
+		RootDirs: []string{rootDir},
+		BaseURL:  "/",
+		FallbackLanguages: map[string]string{
+			"en":    "index.html",
+			"de":    "de/index.html",
+			"pt-BR": "pt-br/index.html",
+		},
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *LanguageTestSuite) serve(acceptLanguage string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/users/1", nil)
+	req.Header.Set("Accept", "text/html")
+	if acceptLanguage != "" {
+		req.Header.Set("Accept-Language", acceptLanguage)
+	}
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *LanguageTestSuite) Test_Accept_language_Then_language_document() {
+
+	for acceptLanguage, expected := range map[string]string{
+		"de":                    "de",
+		"de-AT,en;q=0.5":        "de",
+		"fr;q=0.9, pt-br;q=0.8": "pt-BR",
+		"en;q=0.4, de;q=0.7":    "de",
+		"de;q=0, en":            "en",
+		"*":                     "en",
+		"":                      "en",
+	} {
+		// when
+		rr := suite.serve(acceptLanguage)
+
+		// then
+		suite.Equal(http.StatusOK, rr.Code, acceptLanguage)
+		suite.Equal(expected, rr.Body.String(), acceptLanguage)
+		suite.Equal(expected, rr.Header().Get("Content-Language"), acceptLanguage)
+		suite.Contains(rr.Header().Values("Vary"), "Accept-Language", acceptLanguage)
+	}
+}
+
+func (suite *LanguageTestSuite) Test_Existing_file_Then_no_language_selection() {
+
+	// given
+	req := httptest.NewRequest("GET", "/de/index.html", nil)
+	req.Header.Set("Accept-Language", "pt-BR")
+	rr := httptest.NewRecorder()
+
+	// when
+	suite.sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal("de", rr.Body.String())
+	suite.Empty(rr.Header().Get("Content-Language"))
+}
+
+func (suite *LanguageTestSuite) Test_Language_documents_Then_ready_checked() {
+
+	// when
+	documents := suite.sut.fallbackDocuments()
+
+	// then
+	suite.Equal([]string{"/index.html", "/de/index.html", "/pt-br/index.html"}, documents)
+}
+
+func (suite *LanguageTestSuite) Test_Invalid_language_Then_validation_error() {
+
+	// when
+	err := validateConfig(Config{FallbackLanguages: map[string]string{"de_AT": "de/index.html", "fr": ""}})
+
+	// then
+	suite.ErrorContains(err, "fallback-languages[de_AT]: invalid language tag")
+	suite.ErrorContains(err, "fallback-languages[fr]: the document is required")
+}
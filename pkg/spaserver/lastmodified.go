@@ -0,0 +1,64 @@
+package spaserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseBuildTime parses the build timestamp, either RFC 3339 or unix seconds
+// like SOURCE_DATE_EPOCH
+func parseBuildTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid build timestamp %q, expected RFC 3339 or unix seconds", value)
+	}
+	return parsed, nil
+}
+
+// modTime returns the modification time presented in Last-Modified, the configured
+// build timestamp overrides the modification times of the files, so that the
+// replicas and the rebuilt images present the same value
+func (this *server) modTime(ctx context.Context, info fs.FileInfo) (time.Time, error) {
+	if !this.buildTime.IsZero() {
+		return this.buildTime, nil
+	}
+	if this.cfg.LastModifiedFile == "" {
+		return info.ModTime(), nil
+	}
+
+	file, ok, err := this.findFile(ctx, this.cfg.LastModifiedFile)
+	if err != nil || !ok {
+		// the roots without the build-info file keep the file times
+		return info.ModTime(), err
+	}
+	defer file.Close()
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return time.Time{}, err
+	}
+	key := fmt.Sprintf("%v|%v|%v", rootSetKey(ctx), fileInfo.ModTime().UnixNano(), fileInfo.Size())
+	if buildTime, ok := this.buildTimes.Load(key); ok {
+		recordCacheLookup(ctx, "build_times", cacheHit)
+		return buildTime.(time.Time), nil
+	}
+	recordCacheLookup(ctx, "build_times", cacheMiss)
+	content, err := io.ReadAll(io.LimitReader(file, 1024))
+	if err != nil {
+		return time.Time{}, err
+	}
+	buildTime, err := parseBuildTime(string(content))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot read %v: %w", this.cfg.LastModifiedFile, err)
+	}
+	this.buildTimes.Store(key, buildTime)
+	return buildTime, nil
+}
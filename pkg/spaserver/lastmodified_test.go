@@ -0,0 +1,72 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type LastModifiedTestSuite struct {
+	suite.Suite
+	rootDir string
+}
+
+func TestLastModifiedTestSuite(t *testing.T) {
+	suite.Run(t, new(LastModifiedTestSuite))
+}
+
+func (suite *LastModifiedTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "main.js"), []byte("main"), 0644))
+}
+
+func (suite *LastModifiedTestSuite) get(cfg Config) *httptest.ResponseRecorder {
+	cfg.RootDirs = []string{suite.rootDir}
+	cfg.BaseURL = "/"
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", "/main.js", nil)
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+	return rr
+}
+
+func (suite *LastModifiedTestSuite) Test_Configured_timestamp_Then_last_modified_overridden() {
+
+	// when
+	rr := suite.get(Config{LastModified: "1700000000"})
+
+	// then
+	suite.Equal(time.Unix(1700000000, 0).UTC().Format(http.TimeFormat), rr.Header().Get("Last-Modified"))
+}
+
+func (suite *LastModifiedTestSuite) Test_Build_info_file_Then_last_modified_overridden() {
+
+	// given
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "build-time"), []byte("2024-06-01T09:00:00Z\n"), 0644))
+
+	// when
+	rr := suite.get(Config{LastModifiedFile: "build-time"})
+
+	// then
+	suite.Equal("Sat, 01 Jun 2024 09:00:00 GMT", rr.Header().Get("Last-Modified"))
+}
+
+func (suite *LastModifiedTestSuite) Test_Invalid_timestamp_Then_error() {
+
+	// when
+	_, err := newServer(Config{RootDirs: []string{suite.rootDir}, LastModified: "yesterday"}, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "invalid build timestamp")
+}
@@ -0,0 +1,59 @@
+package spaserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"runtime"
+)
+
+// listen opens the listeners of the server. With SO_REUSEPORT enabled, the
+// kernel distributes the incoming connections across multiple listeners,
+// reducing the contention on a single accept queue.
+func listen(ctx context.Context, addr string, reusePortListeners int) ([]net.Listener, error) {
+	if reusePortListeners == 0 {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		return []net.Listener{listener}, nil
+	}
+
+	if reusePortListeners < 0 {
+		reusePortListeners = runtime.NumCPU()
+	}
+
+	config := net.ListenConfig{Control: reusePort}
+	listeners := make([]net.Listener, 0, reusePortListeners)
+	for i := 0; i < reusePortListeners; i++ {
+		listener, err := config.Listen(ctx, "tcp", addr)
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return nil, err
+		}
+		if i == 0 {
+			// the next listeners share the port, even if assigned dynamically
+			addr = listener.Addr().String()
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// serve serves the listeners until any of them fails or the server is closed
+func serve(httpServer *http.Server, listeners []net.Listener) error {
+	errs := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		go func(listener net.Listener) {
+			if httpServer.TLSConfig != nil {
+				// the certificates are in the TLS configuration
+				errs <- httpServer.ServeTLS(listener, "", "")
+			} else {
+				errs <- httpServer.Serve(listener)
+			}
+		}(listener)
+	}
+	return <-errs
+}
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package spaserver
+
+import (
+	"errors"
+	"syscall"
+)
+
+// reusePort fails, SO_REUSEPORT is not supported on this platform
+func reusePort(network, address string, conn syscall.RawConn) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,89 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ListenTestSuite struct {
+	suite.Suite
+}
+
+func TestListenTestSuite(t *testing.T) {
+	suite.Run(t, new(ListenTestSuite))
+}
+
+func (suite *ListenTestSuite) Test_Reuse_port_listeners_Then_all_share_the_port() {
+
+	// when
+	listeners, err := listen(context.Background(), "127.0.0.1:0", 4)
+	suite.Require().Nil(err)
+	defer func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}()
+
+	// then
+	suite.Len(listeners, 4)
+	for _, listener := range listeners {
+		suite.Equal(listeners[0].Addr().String(), listener.Addr().String())
+	}
+}
+
+func (suite *ListenTestSuite) Test_Reuse_port_disabled_Then_single_listener() {
+
+	// when
+	listeners, err := listen(context.Background(), "127.0.0.1:0", 0)
+	suite.Require().Nil(err)
+	defer listeners[0].Close()
+
+	// then
+	suite.Len(listeners, 1)
+}
+
+// benchmarkListeners requests the server over new connections, so that
+// each request passes through the accept queue
+func benchmarkListeners(b *testing.B, reusePortListeners int) {
+	listeners, err := listen(context.Background(), "127.0.0.1:0", reusePortListeners)
+	if err != nil {
+		b.Fatal(err)
+	}
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(index_html))
+	})}
+	go serve(httpServer, listeners)
+	defer httpServer.Close()
+
+	url := "http://" + listeners[0].Addr().String() + "/"
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			res, err := client.Get(url)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+	})
+}
+
+// BenchmarkSingleListener and BenchmarkReusePortListeners compare the throughput
+// of new connections, e.g. `go test -bench Listener -cpu 8 ./pkg/spaserver`
+func BenchmarkSingleListener(b *testing.B) {
+	benchmarkListeners(b, 0)
+}
+
+func BenchmarkReusePortListeners(b *testing.B) {
+	benchmarkListeners(b, -1)
+}
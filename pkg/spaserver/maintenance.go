@@ -0,0 +1,146 @@
+package spaserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maintenanceState is the maintenance mode enabled through the admin API
+type maintenanceState struct {
+	Since      time.Time     `json:"since"`
+	RetryAfter time.Duration `json:"-"`
+}
+
+// adminAuthorized checks the bearer token of the admin request, all requests
+// are authorized if no token is configured
+func (this *server) adminAuthorized(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if this.cfg.AdminToken != "" {
+			token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(this.cfg.AdminToken)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="spa_d"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// requireAdminToken refuses the state changing endpoints unless the admin token is configured
+func (this *server) requireAdminToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if this.cfg.AdminToken == "" {
+			http.Error(w, "Admin token not configured", http.StatusForbidden)
+			return
+		}
+		next(w, req)
+	}
+}
+
+// serveMaintenance controls the maintenance mode: POST enables it, optionally
+// with the `retry-after` duration announced to the clients, DELETE disables it
+// and GET reports it
+func (this *server) serveMaintenance(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodPost:
+		state := &maintenanceState{Since: time.Now()}
+		if value := req.URL.Query().Get("retry-after"); value != "" {
+			retryAfter, err := time.ParseDuration(value)
+			if err != nil || retryAfter < 0 {
+				http.Error(w, "Invalid retry-after duration", http.StatusBadRequest)
+				return
+			}
+			state.RetryAfter = retryAfter
+		}
+		this.maintenance.Store(state)
+		this.logger.Warn().Dur("retry_after", state.RetryAfter).Msg("Maintenance mode enabled")
+	case http.MethodDelete:
+		if this.maintenance.Swap(nil) != nil {
+			this.logger.Warn().Msg("Maintenance mode disabled")
+		}
+	case http.MethodGet:
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	state := this.maintenance.Load()
+	writeJSON(w, http.StatusOK, map[string]any{"enabled": state != nil, "state": state})
+}
+
+// serveDrain controls the drain mode, the drained instance fails the readiness
+// but keeps serving, so that the in-flight sessions complete while the load
+// balancer moves the traffic to the other instances
+func (this *server) serveDrain(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodPost:
+		if !this.draining.Swap(true) {
+			this.logger.Warn().Msg("Drain mode enabled")
+		}
+	case http.MethodDelete:
+		if this.draining.Swap(false) {
+			this.logger.Warn().Msg("Drain mode disabled")
+		}
+	case http.MethodGet:
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"draining": this.draining.Load()})
+}
+
+// serveReady reports the readiness of the instance to receive the traffic,
+// including the configured readiness checks
+func (this *server) serveReady(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if this.draining.Load() {
+		http.Error(w, "Draining", http.StatusServiceUnavailable)
+		return
+	}
+	if this.rootsOffline() {
+		http.Error(w, "Roots unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if failures := this.readinessFailures(req.Context()); len(failures) > 0 {
+		http.Error(w, strings.Join(failures, "\n"), http.StatusServiceUnavailable)
+		return
+	}
+	io.WriteString(w, "ready\n")
+}
+
+// serveLive reports the process is alive and handling requests, also while
+// draining, so that the replica is not restarted during the shutdown
+func (this *server) serveLive(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	io.WriteString(w, "ok\n")
+}
+
+// serveMaintenancePage responds with the maintenance page, or plain text if the page is not configured
+func (this *server) serveMaintenancePage(ctx context.Context, w http.ResponseWriter, state *maintenanceState) error {
+	w.Header().Set("Cache-Control", "no-store")
+	if state.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(state.RetryAfter.Seconds())))
+	}
+	if this.cfg.MaintenancePage != "" {
+		file, ok, err := this.findFile(ctx, this.cfg.MaintenancePage)
+		if err != nil {
+			return err
+		}
+		if ok {
+			defer file.Close()
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, err = io.Copy(w, file)
+			return err
+		}
+	}
+	http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	return nil
+}
@@ -0,0 +1,115 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type MaintenanceTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestMaintenanceTestSuite(t *testing.T) {
+	suite.Run(t, new(MaintenanceTestSuite))
+}
+
+func (suite *MaintenanceTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "index.html"), []byte("app"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "maintenance.html"), []byte("be right back"), 0644))
+	sut, err := newServer(Config{
+		RootDirs:        []string{rootDir},
+		BaseURL:         "/",
+		AdminToken:      "secret",
+		MaintenancePage: "maintenance.html",
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *MaintenanceTestSuite) admin(method string, target string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	suite.sut.adminHandler().ServeHTTP(rr, req)
+	return rr
+}
+
+func (suite *MaintenanceTestSuite) get() *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *MaintenanceTestSuite) Test_Maintenance_Then_maintenance_page_served() {
+
+	// when
+	enabled := suite.admin("POST", "/maintenance?retry-after=5m", "secret")
+	rr := suite.get()
+
+	// then
+	suite.Equal(http.StatusOK, enabled.Code)
+	suite.Equal(http.StatusServiceUnavailable, rr.Code)
+	suite.Equal("be right back", rr.Body.String())
+	suite.Equal("300", rr.Header().Get("Retry-After"))
+}
+
+func (suite *MaintenanceTestSuite) Test_Maintenance_disabled_Then_app_served() {
+
+	// given
+	suite.admin("POST", "/maintenance", "secret")
+
+	// when
+	suite.admin("DELETE", "/maintenance", "secret")
+	rr := suite.get()
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("app", rr.Body.String())
+}
+
+func (suite *MaintenanceTestSuite) Test_Drain_Then_not_ready_and_serving() {
+
+	// when
+	suite.admin("POST", "/drain", "secret")
+
+	// then
+	suite.Equal(http.StatusServiceUnavailable, suite.admin("GET", "/ready", "").Code)
+	suite.Equal(http.StatusOK, suite.get().Code)
+}
+
+func (suite *MaintenanceTestSuite) Test_Invalid_token_Then_unauthorized() {
+
+	// when
+	rr := suite.admin("POST", "/maintenance", "wrong")
+
+	// then
+	suite.Equal(http.StatusUnauthorized, rr.Code)
+	suite.Nil(suite.sut.maintenance.Load())
+}
+
+func (suite *MaintenanceTestSuite) Test_Token_not_configured_Then_forbidden() {
+
+	// given
+	suite.sut.cfg.AdminToken = ""
+
+	// when
+	rr := suite.admin("POST", "/drain", "")
+
+	// then
+	suite.Equal(http.StatusForbidden, rr.Code)
+	suite.False(suite.sut.draining.Load())
+}
@@ -0,0 +1,157 @@
+package spaserver
+
+import (
+	"context"
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// memory pressure levels, the caches degrade progressively with the level
+const (
+	// memoryNormal keeps all the caches
+	memoryNormal int32 = iota
+	// memoryElevated drops the cached transformed content and upstream responses and stops caching them
+	memoryElevated
+	// memoryCritical drops all the caches and stops buffering of the coalesced files
+	memoryCritical
+)
+
+var memoryLevelNames = []string{"normal", "elevated", "critical"}
+
+// memoryLimit is the configured memory limit, or GOMEMLIMIT if not configured,
+// zero if neither is set
+func memoryLimit(cfg Config) int64 {
+	if cfg.MemoryLimit > 0 {
+		return cfg.MemoryLimit
+	}
+	if limit := debug.SetMemoryLimit(-1); limit < math.MaxInt64 {
+		return limit
+	}
+	return 0
+}
+
+// pressureLevel maps the memory usage to the pressure level, the critical
+// level starts halfway between the pressure ratio and the limit
+func pressureLevel(usage uint64, limit int64, ratio float64) int32 {
+	used := float64(usage) / float64(limit)
+	switch {
+	case used >= (1+ratio)/2:
+		return memoryCritical
+	case used >= ratio:
+		return memoryElevated
+	default:
+		return memoryNormal
+	}
+}
+
+// memoryUsage reads the memory of the process accounted by the Go runtime,
+// the same as the one compared to GOMEMLIMIT
+func memoryUsage() uint64 {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+	return samples[0].Value.Uint64() - samples[1].Value.Uint64()
+}
+
+// monitorMemory periodically checks the memory usage against the limit and
+// degrades the caches under pressure until the context is done
+func (this *server) monitorMemory(ctx context.Context, limit int64) {
+	registration, err := telemetry().meters.RegisterCallback(
+		func(_ context.Context, observer metric.Observer) error {
+			observer.ObserveInt64(telemetry().memory_pressure, int64(this.memoryLevel.Load()))
+			return nil
+		},
+		telemetry().memory_pressure,
+	)
+	if err == nil {
+		defer registration.Unregister()
+	}
+
+	ticker := time.NewTicker(this.cfg.MemoryCheckInterval)
+	defer ticker.Stop()
+	for {
+		usage := memoryUsage()
+		this.setMemoryLevel(pressureLevel(usage, limit, this.cfg.MemoryPressureRatio), usage, limit)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (this *server) setMemoryLevel(level int32, usage uint64, limit int64) {
+	previous := this.memoryLevel.Swap(level)
+	if previous == level {
+		return
+	}
+	logger := this.logger.With().
+		Str("level", memoryLevelNames[level]).
+		Uint64("usage", usage).
+		Int64("limit", limit).
+		Logger()
+	if level > previous {
+		logger.Warn().Msg("Memory pressure increased, degrading caches")
+	} else {
+		logger.Info().Msg("Memory pressure decreased")
+	}
+
+	if level >= memoryElevated {
+		clearMap(&this.transforms)
+		this.clearUpstreamResponses()
+		this.clearFileStats()
+	}
+	if level >= memoryCritical {
+		clearMap(&this.cspHashes)
+		clearMap(&this.preloads)
+		clearMap(&this.hashedFiles)
+		clearMap(&this.redirects)
+		clearMap(&this.sitemaps)
+		clearMap(&this.dirHeaders)
+		clearMap(&this.etags)
+		clearMap(&this.buildTimes)
+	}
+}
+
+// cache stores the value in the cache unless the memory is under pressure
+func (this *server) cache(cache *sync.Map, key string, value any, level int32) {
+	if this.memoryLevel.Load() < level {
+		cache.Store(key, value)
+	}
+}
+
+// results of the cache lookups reported in the cache_lookups metric
+const (
+	cacheHit = "hit"
+	// cacheNegativeHit is the cached absence, e.g. of the header overrides of a directory
+	cacheNegativeHit = "negative_hit"
+	cacheMiss        = "miss"
+	// cacheStaleHit is the expired response served while its upstream fails
+	cacheStaleHit = "stale_hit"
+)
+
+// recordCacheLookup records the result of the lookup of the cache
+func recordCacheLookup(ctx context.Context, cache string, result string) {
+	telemetry().cache_lookups.Add(ctx, 1,
+		metric.WithAttributes(attribute.String("cache", cache), attribute.String("result", result)))
+}
+
+func clearMap(cache *sync.Map) {
+	cache.Range(func(key, _ any) bool {
+		cache.Delete(key)
+		return true
+	})
+}
+
+// memoryLevelName is the name of the current memory pressure level
+func (this *server) memoryLevelName() string {
+	return memoryLevelNames[this.memoryLevel.Load()]
+}
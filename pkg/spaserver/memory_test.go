@@ -0,0 +1,79 @@
+package spaserver
+
+import (
+	"io"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestMemoryTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryTestSuite))
+}
+
+func (suite *MemoryTestSuite) SetupTest() {
+	suite.sut = &server{cfg: Config{MemoryPressureRatio: 0.8}, logger: zerolog.New(io.Discard)}
+}
+
+func (suite *MemoryTestSuite) Test_Usage_Then_pressure_level() {
+	suite.Equal(memoryNormal, pressureLevel(700, 1000, 0.8))
+	suite.Equal(memoryElevated, pressureLevel(800, 1000, 0.8))
+	suite.Equal(memoryCritical, pressureLevel(900, 1000, 0.8))
+	suite.Equal(memoryCritical, pressureLevel(1200, 1000, 0.8))
+}
+
+func (suite *MemoryTestSuite) Test_Elevated_pressure_Then_transforms_dropped() {
+
+	// given
+	suite.sut.cache(&suite.sut.transforms, "index.html", []byte("content"), memoryElevated)
+	suite.sut.cache(&suite.sut.cspHashes, "index.html", &cspHashes{}, memoryCritical)
+
+	// when
+	suite.sut.setMemoryLevel(memoryElevated, 800, 1000)
+	suite.sut.cache(&suite.sut.transforms, "other.html", []byte("content"), memoryElevated)
+
+	// then
+	_, transformed := suite.sut.transforms.Load("index.html")
+	suite.False(transformed)
+	_, transformed = suite.sut.transforms.Load("other.html")
+	suite.False(transformed)
+	_, hashed := suite.sut.cspHashes.Load("index.html")
+	suite.True(hashed)
+	suite.Equal("elevated", suite.sut.memoryLevelName())
+}
+
+func (suite *MemoryTestSuite) Test_Critical_pressure_Then_all_caches_dropped() {
+
+	// given
+	suite.sut.cache(&suite.sut.cspHashes, "index.html", &cspHashes{}, memoryCritical)
+	suite.sut.cache(&suite.sut.preloads, "index.html", []string{}, memoryCritical)
+
+	// when
+	suite.sut.setMemoryLevel(memoryCritical, 950, 1000)
+
+	// then
+	_, hashed := suite.sut.cspHashes.Load("index.html")
+	suite.False(hashed)
+	_, preloaded := suite.sut.preloads.Load("index.html")
+	suite.False(preloaded)
+}
+
+func (suite *MemoryTestSuite) Test_Pressure_decreased_Then_caching_resumed() {
+
+	// given
+	suite.sut.setMemoryLevel(memoryCritical, 950, 1000)
+
+	// when
+	suite.sut.setMemoryLevel(memoryNormal, 100, 1000)
+	suite.sut.cache(&suite.sut.transforms, "index.html", []byte("content"), memoryElevated)
+
+	// then
+	_, transformed := suite.sut.transforms.Load("index.html")
+	suite.True(transformed)
+}
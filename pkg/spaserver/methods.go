@@ -0,0 +1,28 @@
+package spaserver
+
+import (
+	"net/http"
+)
+
+// allowedMethods are the methods of the served resources, the other methods
+// are passed only to the proxies and the hooks
+const allowedMethods = "GET, HEAD, OPTIONS"
+
+// serveMethod answers the OPTIONS requests with the allowed methods and refuses
+// the methods other than GET and HEAD, instead of looking up the resource and
+// falling back to the document, false if the request is not served
+func serveMethod(w http.ResponseWriter, req *http.Request) (bool, int) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return false, 0
+	case http.MethodOptions:
+		w.Header().Set("Allow", allowedMethods)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusNoContent)
+		return true, http.StatusNoContent
+	default:
+		w.Header().Set("Allow", allowedMethods)
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return true, http.StatusMethodNotAllowed
+	}
+}
@@ -0,0 +1,106 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type MethodsTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestMethodsTestSuite(t *testing.T) {
+	suite.Run(t, new(MethodsTestSuite))
+}
+
+func (suite *MethodsTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	_, filename, _, _ := runtime.Caller(0)
+	sut, err := newServer(Config{
+		RootDirs: []string{path.Join(path.Dir(filename), "test/data")},
+		BaseURL:  "/",
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *MethodsTestSuite) request(method string, target string, encoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if encoding != "" {
+		req.Header.Set("Accept-Encoding", encoding)
+	}
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *MethodsTestSuite) Test_Head_Then_headers_of_get_without_body() {
+
+	for _, tc := range []struct{ target, encoding string }{
+		{"/testfile.json", ""},
+		{"/prebr.js", "br"},
+		{"/some/route", ""},
+	} {
+		// when
+		get := suite.request("GET", tc.target, tc.encoding)
+		head := suite.request("HEAD", tc.target, tc.encoding)
+
+		// then
+		suite.Equal(http.StatusOK, head.Code, tc.target)
+		suite.Empty(head.Body.Bytes(), tc.target)
+		suite.Equal(strconv.Itoa(get.Body.Len()), head.Header().Get("Content-Length"), tc.target)
+		suite.Equal(get.Header().Get("Content-Encoding"), head.Header().Get("Content-Encoding"), tc.target)
+		suite.Equal(get.Header().Get("Content-Type"), head.Header().Get("Content-Type"), tc.target)
+	}
+}
+
+func (suite *MethodsTestSuite) Test_Options_Then_allowed_methods() {
+
+	// when
+	rr := suite.request("OPTIONS", "/testfile.json", "")
+
+	// then
+	suite.Equal(http.StatusNoContent, rr.Code)
+	suite.Equal("GET, HEAD, OPTIONS", rr.Header().Get("Allow"))
+	suite.Empty(rr.Body.Bytes())
+}
+
+func (suite *MethodsTestSuite) Test_Other_method_Then_not_allowed_instead_of_fallback() {
+
+	for _, method := range []string{"POST", "PUT", "DELETE", "PATCH"} {
+		// when
+		rr := suite.request(method, "/some/route", "")
+
+		// then
+		suite.Equal(http.StatusMethodNotAllowed, rr.Code, method)
+		suite.Equal("GET, HEAD, OPTIONS", rr.Header().Get("Allow"), method)
+	}
+}
+
+func (suite *MethodsTestSuite) Test_Other_method_and_hook_Then_served_by_hook() {
+
+	// given
+	suite.sut.hooks = []Hooks{{BeforeLookup: func(w http.ResponseWriter, req *http.Request, resourcePath string) bool {
+		if req.Method == http.MethodPost && req.URL.Path == "/api/feedback" {
+			w.WriteHeader(http.StatusAccepted)
+			return false
+		}
+		return true
+	}}}
+
+	// when
+	rr := suite.request("POST", "/api/feedback", "")
+
+	// then
+	suite.Equal(http.StatusAccepted, rr.Code)
+}
@@ -0,0 +1,351 @@
+package spaserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	ociScheme            = "oci://"
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociIndexMediaType    = "application/vnd.oci.image.index.v1+json"
+	dockerManifestType   = "application/vnd.docker.distribution.manifest.v2+json"
+	dockerListMediaType  = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// ociReference identifies an artifact in the registry,
+// e.g. `oci://ghcr.io/org/app:1.0.0` or `oci://ghcr.io/org/app@sha256:...`
+type ociReference struct {
+	registry   string
+	repository string
+	reference  string
+}
+
+func parseOCIReference(uri string) (ociReference, error) {
+	ref := ociReference{}
+	rest := strings.TrimPrefix(uri, ociScheme)
+	slash := strings.Index(rest, "/")
+	if slash <= 0 {
+		return ref, fmt.Errorf("invalid OCI reference %v, registry missing", uri)
+	}
+	ref.registry, rest = rest[:slash], rest[slash+1:]
+
+	if at := strings.Index(rest, "@"); at >= 0 {
+		ref.repository, ref.reference = rest[:at], rest[at+1:]
+	} else if colon := strings.LastIndex(rest, ":"); colon >= 0 {
+		ref.repository, ref.reference = rest[:colon], rest[colon+1:]
+	} else {
+		ref.repository, ref.reference = rest, "latest"
+	}
+
+	if ref.repository == "" || ref.reference == "" {
+		return ref, fmt.Errorf("invalid OCI reference %v", uri)
+	}
+	return ref, nil
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+// ociClient pulls artifacts from registries implementing the OCI distribution API
+type ociClient struct {
+	cfg    Config
+	client *http.Client
+	token  string
+}
+
+// pullOCIBundle pulls the bundle artifact to the cache directory and
+// returns the path of the tar archive holding the bundle
+func pullOCIBundle(ctx context.Context, uri string, cfg Config) (string, error) {
+	ref, err := parseOCIReference(uri)
+	if err != nil {
+		return "", err
+	}
+
+	client := &ociClient{cfg: cfg, client: remoteClient(cfg)}
+	manifest, digest, err := client.manifest(ctx, ref, ref.reference)
+	if err != nil {
+		return "", err
+	}
+
+	if signatureVerified(cfg) {
+		if err := client.verifyManifestSignature(ctx, ref, digest); err != nil {
+			return "", fmt.Errorf("signature verification of OCI artifact %v failed: %w", uri, err)
+		}
+	}
+
+	if len(manifest.Manifests) > 0 {
+		// image index - the bundle is platform independent, take the first manifest
+		manifest, _, err = client.manifest(ctx, ref, manifest.Manifests[0].Digest)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var layer *ociDescriptor
+	for i := range manifest.Layers {
+		if strings.Contains(manifest.Layers[i].MediaType, "tar") {
+			layer = &manifest.Layers[i]
+			break
+		}
+	}
+	if layer == nil {
+		return "", fmt.Errorf("OCI artifact %v contains no tar layer", uri)
+	}
+
+	ext := ".tar"
+	if strings.Contains(layer.MediaType, "gzip") {
+		ext = ".tar.gz"
+	}
+	algorithm, hash, ok := strings.Cut(layer.Digest, ":")
+	if !ok || algorithm != "sha256" {
+		return "", fmt.Errorf("unsupported digest %v of OCI artifact %v", layer.Digest, uri)
+	}
+	target := filepath.Join(cfg.OciCacheDir, hash+ext)
+	if _, err := os.Stat(target); err == nil {
+		// blobs are content addressed, cached blob is valid
+		return target, nil
+	}
+
+	if err := client.downloadBlob(ctx, ref, *layer, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// manifest gets the manifest of the reference together with the digest of the manifest
+func (this *ociClient) manifest(ctx context.Context, ref ociReference, reference string) (*ociManifest, string, error) {
+	res, err := this.get(ctx, ref, "manifests/"+reference,
+		strings.Join([]string{ociManifestMediaType, ociIndexMediaType, dockerManifestType, dockerListMediaType}, ", "))
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	hash := sha256.Sum256(content)
+	digest := "sha256:" + hex.EncodeToString(hash[:])
+	if strings.Contains(reference, ":") && digest != reference {
+		// the manifests requested by the digest, e.g. of the signed index, are content addressed
+		return nil, "", fmt.Errorf("digest mismatch of OCI manifest, expected %v, got %v", reference, digest)
+	}
+	manifest := &ociManifest{}
+	if err := json.Unmarshal(content, manifest); err != nil {
+		return nil, "", fmt.Errorf("cannot decode OCI manifest %v: %w", reference, err)
+	}
+	return manifest, digest, nil
+}
+
+// blob gets the content of the small blob, e.g. the signature payload
+func (this *ociClient) blob(ctx context.Context, ref ociReference, digest string) ([]byte, error) {
+	res, err := this.get(ctx, ref, "blobs/"+digest, "")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	actual := sha256.Sum256(content)
+	if "sha256:"+hex.EncodeToString(actual[:]) != digest {
+		return nil, fmt.Errorf("digest mismatch of OCI blob %v", digest)
+	}
+	return content, nil
+}
+
+func (this *ociClient) downloadBlob(ctx context.Context, ref ociReference, layer ociDescriptor, target string) error {
+	res, err := this.get(ctx, ref, "blobs/"+layer.Digest, "")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	digest := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, digest), res.Body); err != nil {
+		return err
+	}
+	if actual := "sha256:" + hex.EncodeToString(digest.Sum(nil)); actual != layer.Digest {
+		return fmt.Errorf("digest mismatch of OCI blob, expected %v, got %v", layer.Digest, actual)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// get requests the registry API, authorizing with bearer token when challenged
+func (this *ociClient) get(ctx context.Context, ref ociReference, resource string, accept string) (*http.Response, error) {
+	scheme := "https"
+	if this.cfg.OciInsecure {
+		scheme = "http"
+	}
+	target := scheme + "://" + ref.registry + "/v2/" + ref.repository + "/" + resource
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if this.token != "" {
+			req.Header.Set("Authorization", "Bearer "+this.token)
+		} else if this.cfg.OciUsername != "" {
+			req.SetBasicAuth(this.cfg.OciUsername, this.cfg.OciPassword)
+		}
+
+		res, err := this.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			challenge := res.Header.Get("WWW-Authenticate")
+			res.Body.Close()
+			if err := this.authorize(ctx, challenge); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if res.StatusCode != http.StatusOK {
+			res.Body.Close()
+			return nil, fmt.Errorf("registry request %v failed with status %v", target, res.Status)
+		}
+		return res, nil
+	}
+}
+
+// authorize obtains the bearer token according to the registry challenge
+func (this *ociClient) authorize(ctx context.Context, challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "bearer") {
+		// basic authentication is sent with each request
+		return nil
+	}
+
+	values := url.Values{}
+	realm := ""
+	for _, param := range strings.Split(params, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		value = strings.Trim(value, `"`)
+		if key == "realm" {
+			realm = value
+		} else {
+			values.Set(key, value)
+		}
+	}
+	if realm == "" {
+		return fmt.Errorf("registry authentication challenge without realm")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+values.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if this.cfg.OciUsername != "" {
+		req.SetBasicAuth(this.cfg.OciUsername, this.cfg.OciPassword)
+	}
+	res, err := this.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry token request failed with status %v", res.Status)
+	}
+
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return err
+	}
+	this.token = token.Token
+	if this.token == "" {
+		this.token = token.AccessToken
+	}
+	return nil
+}
+
+// ociFetch pulls the OCI bundle, the bundle is fetched again only
+// when the reference resolves to a new digest
+func ociFetch(uri string, cfg Config) fetchFunc {
+	archive := ""
+	lock := sync.Mutex{}
+	return func(ctx context.Context) (fs.FS, string, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		pulled, err := pullOCIBundle(ctx, uri, cfg)
+		if err != nil || pulled == archive {
+			return nil, "", err
+		}
+		fsys, err := openArchiveOrDir(pulled, cfg.SymlinkPolicy)
+		if err != nil {
+			return nil, "", err
+		}
+		archive = pulled
+		// the layers are named by their digest
+		return fsys, archiveRevision(pulled), nil
+	}
+}
+
+// lazyFS opens the filesystem on the first access, the failed
+// attempts are retried on the next access
+type lazyFS struct {
+	open func() (fs.FS, error)
+
+	lock sync.Mutex
+	fsys fs.FS
+}
+
+func (this *lazyFS) Open(name string) (fs.File, error) {
+	this.lock.Lock()
+	if this.fsys == nil {
+		fsys, err := this.open()
+		if err != nil {
+			this.lock.Unlock()
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		this.fsys = fsys
+	}
+	fsys := this.fsys
+	this.lock.Unlock()
+	return fsys.Open(name)
+}
@@ -0,0 +1,194 @@
+package spaserver
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type OCITestSuite struct {
+	suite.Suite
+	registry *httptest.Server
+	blob     []byte
+	pulls    int
+	// manifests are the additional manifests served by their references
+	manifests map[string][]byte
+}
+
+func TestOCITestSuite(t *testing.T) {
+	suite.Run(t, new(OCITestSuite))
+}
+
+func (suite *OCITestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.pulls = 0
+	suite.manifests = map[string][]byte{}
+
+	archive := &bytes.Buffer{}
+	tw := tar.NewWriter(archive)
+	suite.Require().Nil(tw.WriteHeader(&tar.Header{Name: "index.html", Mode: 0644, Size: int64(len(index_html))}))
+	_, err := tw.Write([]byte(index_html))
+	suite.Require().Nil(err)
+	suite.Require().Nil(tw.Close())
+	suite.blob = archive.Bytes()
+
+	hash := sha256.Sum256(suite.blob)
+	digest := "sha256:" + hex.EncodeToString(hash[:])
+	manifest, err := json.Marshal(ociManifest{
+		MediaType: ociManifestMediaType,
+		Layers: []ociDescriptor{
+			{MediaType: "application/vnd.oci.image.layer.v1.tar", Digest: digest, Size: int64(len(suite.blob))},
+		},
+	})
+	suite.Require().Nil(err)
+
+	var registry *httptest.Server
+	registry = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/token":
+			w.Write([]byte(`{"token":"secret"}`))
+		case req.Header.Get("Authorization") != "Bearer secret":
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+registry.URL+`/token",service="test",scope="repository:org/app:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case req.URL.Path == "/v2/org/app/manifests/1.0.0":
+			w.Header().Set("Content-Type", ociManifestMediaType)
+			w.Write(manifest)
+		case req.URL.Path == "/v2/org/app/blobs/"+digest:
+			suite.pulls++
+			w.Write(suite.blob)
+		case suite.manifests[strings.TrimPrefix(req.URL.Path, "/v2/org/app/manifests/")] != nil:
+			w.Write(suite.manifests[strings.TrimPrefix(req.URL.Path, "/v2/org/app/manifests/")])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	suite.registry = registry
+}
+
+func (suite *OCITestSuite) TearDownTest() {
+	suite.registry.Close()
+}
+
+func (suite *OCITestSuite) config() Config {
+	return Config{
+		RootDirs:    []string{"oci://" + strings.TrimPrefix(suite.registry.URL, "http://") + "/org/app:1.0.0"},
+		BaseURL:     "/",
+		OciCacheDir: suite.T().TempDir(),
+		OciInsecure: true,
+	}
+}
+
+func (suite *OCITestSuite) Test_Parse_reference_Then_registry_repository_and_tag() {
+
+	// when
+	ref, err := parseOCIReference("oci://ghcr.io/org/app:1.2.3")
+
+	// then
+	suite.Nil(err)
+	suite.Equal(ociReference{registry: "ghcr.io", repository: "org/app", reference: "1.2.3"}, ref)
+}
+
+func (suite *OCITestSuite) Test_Parse_reference_without_tag_Then_latest() {
+
+	// when
+	ref, err := parseOCIReference("oci://localhost:5000/app")
+
+	// then
+	suite.Nil(err)
+	suite.Equal(ociReference{registry: "localhost:5000", repository: "app", reference: "latest"}, ref)
+}
+
+func (suite *OCITestSuite) Test_OCI_root_Then_bundle_pulled_and_served() {
+
+	// given
+	sut, err := newServer(suite.config(), zerolog.New(os.Stdout))
+	suite.Require().Nil(err)
+
+	req, err := http.NewRequest("GET", "/", nil)
+	suite.Nil(err)
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(index_html, rr.Body.String())
+	suite.Equal(1, suite.pulls)
+}
+
+func (suite *OCITestSuite) Test_OCI_root_cached_Then_blob_not_pulled_again() {
+
+	// given
+	cfg := suite.config()
+	_, err := pullOCIBundle(context.Background(), cfg.RootDirs[0], cfg)
+	suite.Require().Nil(err)
+
+	// when
+	_, err = pullOCIBundle(context.Background(), cfg.RootDirs[0], cfg)
+
+	// then
+	suite.Nil(err)
+	suite.Equal(1, suite.pulls)
+}
+
+func (suite *OCITestSuite) Test_OCI_root_on_demand_Then_pulled_on_first_request() {
+
+	// given
+	cfg := suite.config()
+	cfg.OciPullOnDemand = true
+	sut, err := newServer(cfg, zerolog.New(os.Stdout))
+	suite.Require().Nil(err)
+	pullsAfterStart := suite.pulls
+
+	req, err := http.NewRequest("GET", "/", nil)
+	suite.Nil(err)
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(0, pullsAfterStart)
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(1, suite.pulls)
+}
+
+func (suite *OCITestSuite) Test_Nested_manifest_tampered_Then_rejected() {
+
+	// given
+	genuine := []byte(`{"mediaType":"` + ociManifestMediaType + `","layers":[]}`)
+	hash := sha256.Sum256(genuine)
+	nested := "sha256:" + hex.EncodeToString(hash[:])
+	index, err := json.Marshal(ociManifest{
+		MediaType: ociIndexMediaType,
+		Manifests: []ociDescriptor{{MediaType: ociManifestMediaType, Digest: nested, Size: int64(len(genuine))}},
+	})
+	suite.Require().Nil(err)
+	suite.manifests["2.0.0"] = index
+	// the registry swaps the platform manifest and its layers
+	suite.manifests[nested], err = json.Marshal(ociManifest{
+		MediaType: ociManifestMediaType,
+		Layers:    []ociDescriptor{{MediaType: "application/vnd.oci.image.layer.v1.tar", Digest: "sha256:" + strings.Repeat("0", 64)}},
+	})
+	suite.Require().Nil(err)
+	cfg := suite.config()
+
+	// when
+	_, err = pullOCIBundle(context.Background(), strings.Replace(cfg.RootDirs[0], ":1.0.0", ":2.0.0", 1), cfg)
+
+	// then
+	suite.ErrorContains(err, "digest mismatch of OCI manifest")
+	suite.Equal(0, suite.pulls)
+}
@@ -0,0 +1,99 @@
+package spaserver
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// offlinePage is the built-in page served while the roots are unavailable,
+// reloaded by the browser after the retry interval
+const offlinePage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="%d">
+<title>Starting up</title>
+</head>
+<body>
+<p>The application is starting up, please wait a moment.</p>
+</body>
+</html>
+`
+
+// rootsAvailability is the availability of the roots, checked at most once per check interval
+type rootsAvailability struct {
+	checked atomic.Int64
+	offline atomic.Bool
+}
+
+// rootsOffline reports whether none of the served roots is readable, e.g. while the
+// volume is not mounted yet, the roots are checked again after the check interval
+func (this *server) rootsOffline() bool {
+	now := time.Now().UnixNano()
+	checked := this.availability.checked.Load()
+	if checked != 0 && now-checked < int64(this.cfg.OfflineCheckInterval) {
+		return this.availability.offline.Load()
+	}
+	if !this.availability.checked.CompareAndSwap(checked, now) {
+		// checked by the concurrent request
+		return this.availability.offline.Load()
+	}
+
+	offline := !this.anyRootReadable()
+	if this.availability.offline.Swap(offline) != offline {
+		if offline {
+			this.logger.Warn().Msg("Roots unavailable, serving offline page")
+		} else {
+			this.logger.Info().Msg("Roots available")
+		}
+	}
+	return offline
+}
+
+// anyRootReadable reports whether any of the served roots can be read, or the
+// directory of the tenant roots in the multi-tenant mode
+func (this *server) anyRootReadable() bool {
+	if this.tenantsEnabled() {
+		// the default roots are not served
+		_, err := os.Stat(this.tenantsDir())
+		return err == nil
+	}
+	roots, err := this.assetRoots()
+	if err != nil {
+		return false
+	}
+	for _, root := range roots {
+		if _, err := fs.Stat(root.fsys, "."); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// serveOfflinePage responds with the configured or the built-in offline page
+func (this *server) serveOfflinePage(ctx context.Context, w http.ResponseWriter) error {
+	retryAfter := int(this.cfg.OfflineRetryAfter.Seconds())
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if this.cfg.OfflinePage != "" {
+		// the page cannot be served from the unavailable roots
+		content, err := os.ReadFile(this.cfg.OfflinePage)
+		if err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, err = w.Write(content)
+		return err
+	}
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_, err := fmt.Fprintf(w, offlinePage, max(retryAfter, 1))
+	return err
+}
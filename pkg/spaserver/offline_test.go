@@ -0,0 +1,83 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type OfflineTestSuite struct {
+	suite.Suite
+	rootDir string
+	sut     *server
+}
+
+func TestOfflineTestSuite(t *testing.T) {
+	suite.Run(t, new(OfflineTestSuite))
+}
+
+func (suite *OfflineTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	// the volume is not mounted yet
+	suite.rootDir = path.Join(suite.T().TempDir(), "public")
+	sut, err := newServer(Config{
+		RootDirs:          []string{suite.rootDir},
+		BaseURL:           "/",
+		OfflineRetryAfter: 10 * time.Second,
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *OfflineTestSuite) get(target string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, httptest.NewRequest("GET", target, nil))
+	return rr
+}
+
+func (suite *OfflineTestSuite) Test_Roots_unavailable_Then_offline_page() {
+
+	// when
+	rr := suite.get("/about")
+
+	// then
+	suite.Equal(http.StatusServiceUnavailable, rr.Code)
+	suite.Equal("10", rr.Header().Get("Retry-After"))
+	suite.Equal("no-store", rr.Header().Get("Cache-Control"))
+	suite.Contains(rr.Body.String(), `<meta http-equiv="refresh" content="10">`)
+}
+
+func (suite *OfflineTestSuite) Test_Roots_recovered_Then_served() {
+
+	// given
+	suite.Equal(http.StatusServiceUnavailable, suite.get("/").Code)
+	suite.Require().Nil(os.MkdirAll(suite.rootDir, 0755))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "index.html"), []byte("app"), 0644))
+
+	// when
+	rr := suite.get("/")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("app", rr.Body.String())
+}
+
+func (suite *OfflineTestSuite) Test_Roots_unavailable_Then_not_ready() {
+
+	// given
+	rr := httptest.NewRecorder()
+
+	// when
+	suite.sut.adminHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+
+	// then
+	suite.Equal(http.StatusServiceUnavailable, rr.Code)
+}
@@ -0,0 +1,346 @@
+package spaserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// oidcTimeout bounds the requests to the identity provider
+	oidcTimeout = 10 * time.Second
+	// oidcLoginTtl is the time to complete the login at the identity provider
+	oidcLoginTtl = 10 * time.Minute
+)
+
+var errLoginState = errors.New("login state invalid or expired")
+
+// generatedOidcKey signs the cookies if no cookie key is configured, generated once
+// per process so that the sessions survive the configuration reloads
+var generatedOidcKey = sync.OnceValues(func() ([]byte, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	return key, err
+})
+
+// oidcProvider is the discovered configuration of the identity provider
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+
+	// keys verify the id tokens, fetched from the jwks uri
+	keys *jwtKeys
+}
+
+// oidcLoginState is kept in the login cookie until the callback of the identity provider
+type oidcLoginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Return   string `json:"return"`
+	Expires  int64  `json:"exp"`
+}
+
+// oidcSession is kept in the session cookie after the login
+type oidcSession struct {
+	User    string `json:"user"`
+	Expires int64  `json:"exp"`
+}
+
+// oidcLogin protects the requests with the OIDC authorization code flow, the
+// browsers are redirected to the identity provider and get the session cookie
+// after the login
+type oidcLogin struct {
+	cfg          Config
+	logger       zerolog.Logger
+	client       *http.Client
+	callbackPath string
+	secure       bool
+	// key signs the login and the session cookies
+	key []byte
+
+	mutex    sync.Mutex
+	provider *oidcProvider
+}
+
+// newOidcLogin creates the login, the identity provider is discovered on the first login
+func newOidcLogin(cfg Config, logger zerolog.Logger) (*oidcLogin, error) {
+	redirect, err := url.Parse(cfg.OidcRedirectUrl)
+	if err != nil {
+		return nil, err
+	}
+	key := []byte(cfg.OidcCookieKey)
+	if len(key) == 0 {
+		if key, err = generatedOidcKey(); err != nil {
+			return nil, err
+		}
+	}
+	return &oidcLogin{
+		cfg:          cfg,
+		logger:       logger,
+		client:       &http.Client{Timeout: oidcTimeout},
+		callbackPath: redirect.Path,
+		secure:       redirect.Scheme == "https",
+		key:          key,
+	}, nil
+}
+
+// authenticate returns the user of the session, or serves the callback of the
+// identity provider, redirects the browsers to the login and refuses the others
+func (this *oidcLogin) authenticate(w http.ResponseWriter, req *http.Request) (string, bool) {
+	if req.URL.Path == this.callbackPath {
+		this.callback(w, req)
+		return "", false
+	}
+	if user, ok := this.session(req); ok {
+		return user, true
+	}
+	navigation := req.Method == http.MethodGet || req.Method == http.MethodHead
+	if !navigation || (len(req.Header.Get("Accept")) != 0 && !acceptsAny(req, []string{"text/html"})) {
+		// the scripts cannot follow the login, the application reloads on 401
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", false
+	}
+	if err := this.login(w, req); err != nil {
+		this.logger.Err(err).Str("issuer", this.cfg.OidcIssuer).Msg("Login redirect failed")
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+	}
+	return "", false
+}
+
+// session returns the user of the valid session cookie
+func (this *oidcLogin) session(req *http.Request) (string, bool) {
+	cookie, err := req.Cookie(this.cfg.OidcCookie)
+	if err != nil {
+		return "", false
+	}
+	session := oidcSession{}
+	if !this.open(cookie.Value, &session) || time.Now().Unix() >= session.Expires {
+		return "", false
+	}
+	return session.User, true
+}
+
+// login redirects to the authorization endpoint, the state, the nonce and the
+// PKCE verifier are kept in the login cookie
+func (this *oidcLogin) login(w http.ResponseWriter, req *http.Request) error {
+	provider, err := this.discover(req.Context())
+	if err != nil {
+		return err
+	}
+	state := oidcLoginState{Return: req.URL.RequestURI(), Expires: time.Now().Add(oidcLoginTtl).Unix()}
+	for _, token := range []*string{&state.State, &state.Nonce, &state.Verifier} {
+		if *token, err = randomToken(); err != nil {
+			return err
+		}
+	}
+	http.SetCookie(w, this.cookie(this.loginCookie(state.State), this.seal(state), oidcLoginTtl, this.callbackPath))
+
+	challenge := sha256.Sum256([]byte(state.Verifier))
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", this.cfg.OidcClientId)
+	query.Set("redirect_uri", this.cfg.OidcRedirectUrl)
+	query.Set("scope", strings.Join(this.cfg.OidcScopes, " "))
+	query.Set("state", state.State)
+	query.Set("nonce", state.Nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	separator := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, req, provider.AuthorizationEndpoint+separator+query.Encode(), http.StatusFound)
+	return nil
+}
+
+// callback exchanges the code of the identity provider for the id token and
+// starts the session of its subject
+func (this *oidcLogin) callback(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	if idpError := query.Get("error"); idpError != "" {
+		this.logger.Info().Str("error", idpError).Str("description", query.Get("error_description")).Msg("Login refused")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	state := oidcLoginState{}
+	cookie, err := req.Cookie(this.loginCookie(query.Get("state")))
+	if err != nil || !this.open(cookie.Value, &state) || time.Now().Unix() >= state.Expires ||
+		tokenMismatch(state.State, query.Get("state")) {
+		this.logger.Info().Err(errLoginState).Msg("Login refused")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := this.exchange(req.Context(), query.Get("code"), state)
+	if err != nil {
+		this.logger.Warn().Err(err).Str("issuer", this.cfg.OidcIssuer).Msg("Login failed")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user := claims.Email
+	if user == "" {
+		user = claims.Subject
+	}
+	session := oidcSession{User: user, Expires: time.Now().Add(this.cfg.OidcSessionTtl).Unix()}
+	http.SetCookie(w, this.cookie(this.loginCookie(state.State), "", -1, this.callbackPath))
+	http.SetCookie(w, this.cookie(this.cfg.OidcCookie, this.seal(session), this.cfg.OidcSessionTtl, "/"))
+	this.logger.Info().Str("user", user).Msg("Logged in")
+
+	// the return url is the request uri of this server, not an open redirect
+	target := state.Return
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") {
+		target = "/"
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, req, target, http.StatusFound)
+}
+
+// tokenMismatch reports whether the token is missing or differs, in the constant time
+func tokenMismatch(expected, actual string) bool {
+	return expected == "" || !hmac.Equal([]byte(expected), []byte(actual))
+}
+
+// exchange redeems the code at the token endpoint and verifies the id token
+func (this *oidcLogin) exchange(ctx context.Context, code string, state oidcLoginState) (jwtClaims, error) {
+	provider, err := this.discover(ctx)
+	if err != nil {
+		return jwtClaims{}, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", this.cfg.OidcRedirectUrl)
+	form.Set("code_verifier", state.Verifier)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return jwtClaims{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(this.cfg.OidcClientId), url.QueryEscape(this.cfg.OidcClientSecret))
+
+	tokens := struct {
+		IdToken string `json:"id_token"`
+	}{}
+	if err := fetchJson(this.client, req, &tokens); err != nil {
+		return jwtClaims{}, fmt.Errorf("token endpoint: %w", err)
+	}
+	return this.verifyIdToken(ctx, provider, tokens.IdToken, state.Nonce)
+}
+
+// verifyIdToken verifies the signature, the issuer, the audience, the expiration
+// and the nonce of the id token
+func (this *oidcLogin) verifyIdToken(ctx context.Context, provider *oidcProvider, token string, nonce string) (jwtClaims, error) {
+	claims := jwtClaims{}
+	if err := provider.keys.verify(ctx, token, &claims); err != nil {
+		return jwtClaims{}, err
+	}
+	if err := claims.check(provider.Issuer, this.cfg.OidcClientId); err != nil {
+		return jwtClaims{}, err
+	}
+	if tokenMismatch(nonce, claims.Nonce) {
+		return jwtClaims{}, fmt.Errorf("%w: nonce", errTokenInvalid)
+	}
+	return claims, nil
+}
+
+// discover fetches the configuration of the identity provider and creates its keys,
+// kept after the first success
+func (this *oidcLogin) discover(ctx context.Context) (*oidcProvider, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.provider != nil {
+		return this.provider, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimSuffix(this.cfg.OidcIssuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	provider := &oidcProvider{}
+	if err := fetchJson(this.client, req, provider); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != strings.TrimSuffix(this.cfg.OidcIssuer, "/") {
+		return nil, fmt.Errorf("discovery: issuer %v does not match", provider.Issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JwksUri == "" {
+		return nil, fmt.Errorf("discovery: endpoints missing")
+	}
+	provider.keys = newJwtKeys(this.client, provider.JwksUri)
+	this.provider = provider
+	return provider, nil
+}
+
+// loginCookie is the name of the login cookie of the state, so that the logins
+// started in several tabs do not overwrite each other
+func (this *oidcLogin) loginCookie(state string) string {
+	return this.cfg.OidcCookie + "_login_" + state
+}
+
+func (this *oidcLogin) cookie(name, value string, ttl time.Duration, cookiePath string) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     cookiePath,
+		Secure:   this.secure,
+		HttpOnly: true,
+		// the cookies are sent with the navigation from the identity provider
+		SameSite: http.SameSiteLaxMode,
+	}
+	if ttl < 0 {
+		cookie.MaxAge = -1
+	} else {
+		cookie.MaxAge = int(ttl.Seconds())
+	}
+	return cookie
+}
+
+// seal encodes the value with its HMAC, so that the client cannot change it
+func (this *oidcLogin) seal(value any) string {
+	content, _ := json.Marshal(value)
+	payload := base64.RawURLEncoding.EncodeToString(content)
+	mac := hmac.New(sha256.New, this.key)
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// open decodes the sealed value, false if the HMAC does not match
+func (this *oidcLogin) open(sealed string, value any) bool {
+	payload, signature, ok := strings.Cut(sealed, ".")
+	if !ok {
+		return false
+	}
+	mac := hmac.New(sha256.New, this.key)
+	mac.Write([]byte(payload))
+	if !hmac.Equal([]byte(signature), []byte(base64.RawURLEncoding.EncodeToString(mac.Sum(nil)))) {
+		return false
+	}
+	content, err := base64.RawURLEncoding.DecodeString(payload)
+	return err == nil && json.Unmarshal(content, value) == nil
+}
+
+// randomToken returns the random url-safe token
+func randomToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
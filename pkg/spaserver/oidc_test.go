@@ -0,0 +1,284 @@
+package spaserver
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type OidcTestSuite struct {
+	suite.Suite
+	idp   *httptest.Server
+	key   *rsa.PrivateKey
+	nonce string
+	sut   *server
+}
+
+func TestOidcTestSuite(t *testing.T) {
+	suite.Run(t, new(OidcTestSuite))
+}
+
+func (suite *OidcTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().Nil(err)
+	suite.key = key
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 suite.idp.URL,
+			"authorization_endpoint": suite.idp.URL + "/authorize",
+			"token_endpoint":         suite.idp.URL + "/token",
+			"jwks_uri":               suite.idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		id, secret, _ := req.BasicAuth()
+		if req.FormValue("code") != "code-1" || req.FormValue("code_verifier") == "" || id != "spa" || secret != "s3cret" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": suite.idToken(map[string]any{
+			"iss":   suite.idp.URL,
+			"aud":   "spa",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": suite.nonce,
+			"sub":   "u-1",
+			"email": "alice@example.com",
+		})})
+	})
+	suite.idp = httptest.NewServer(mux)
+	suite.T().Cleanup(suite.idp.Close)
+
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "index.html"), []byte("app"), 0644))
+	sut, err := newServer(Config{
+		RootDirs:         []string{rootDir},
+		AuthMode:         authOidc,
+		OidcIssuer:       suite.idp.URL,
+		OidcClientId:     "spa",
+		OidcClientSecret: "s3cret",
+		OidcRedirectUrl:  "https://preview.example.com/oauth2/callback",
+		OidcScopes:       []string{"openid", "email"},
+		OidcCookie:       "spa_session",
+		OidcSessionTtl:   time.Hour,
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *OidcTestSuite) idToken(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, suite.key, crypto.SHA256, digest[:])
+	suite.Require().Nil(err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (suite *OidcTestSuite) get(target string, accept string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	req.Header.Set("Accept", accept)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *OidcTestSuite) cookie(rr *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range rr.Result().Cookies() {
+		if cookie.Name == name && cookie.MaxAge > 0 {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// loginCookie returns the login cookie of the state of the login redirect
+func (suite *OidcTestSuite) loginCookie(rr *httptest.ResponseRecorder) *http.Cookie {
+	authorize, err := url.Parse(rr.Header().Get("Location"))
+	suite.Require().Nil(err)
+	return suite.cookie(rr, "spa_session_login_"+authorize.Query().Get("state"))
+}
+
+// login completes the login flow started by the redirect and returns the session cookie
+func (suite *OidcTestSuite) login(rr *httptest.ResponseRecorder, cookies ...*http.Cookie) *http.Cookie {
+	authorize, err := url.Parse(rr.Header().Get("Location"))
+	suite.Require().Nil(err)
+	suite.nonce = authorize.Query().Get("nonce")
+	callback := suite.get("/oauth2/callback?code=code-1&state="+authorize.Query().Get("state"), "text/html", cookies...)
+	return suite.cookie(callback, "spa_session")
+}
+
+func (suite *OidcTestSuite) Test_Login_flow_Then_session_served() {
+
+	// given
+	login := suite.get("/reports?tab=1", "text/html")
+	suite.Require().Equal(http.StatusFound, login.Code)
+	authorize, err := url.Parse(login.Header().Get("Location"))
+	suite.Require().Nil(err)
+	suite.nonce = authorize.Query().Get("nonce")
+	loginCookie := suite.loginCookie(login)
+	suite.Require().NotNil(loginCookie)
+
+	// when
+	callback := suite.get("/oauth2/callback?code=code-1&state="+authorize.Query().Get("state"), "text/html", loginCookie)
+	session := suite.cookie(callback, "spa_session")
+	suite.Require().NotNil(session)
+	rr := suite.get("/reports", "text/html", session)
+
+	// then
+	suite.Equal(suite.idp.URL+"/authorize", authorize.Scheme+"://"+authorize.Host+authorize.Path)
+	suite.Equal("https://preview.example.com/oauth2/callback", authorize.Query().Get("redirect_uri"))
+	suite.Equal("openid email", authorize.Query().Get("scope"))
+	suite.Equal("S256", authorize.Query().Get("code_challenge_method"))
+	suite.Equal("/oauth2/callback", loginCookie.Path)
+	suite.True(loginCookie.Secure)
+	suite.True(loginCookie.HttpOnly)
+	suite.Equal(http.StatusFound, callback.Code)
+	suite.Equal("/reports?tab=1", callback.Header().Get("Location"))
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("app", rr.Body.String())
+}
+
+func (suite *OidcTestSuite) Test_Script_without_session_Then_unauthorized() {
+
+	// when
+	rr := suite.get("/api/data.json", "application/json")
+
+	// then
+	suite.Equal(http.StatusUnauthorized, rr.Code)
+	suite.Empty(rr.Header().Get("Location"))
+}
+
+func (suite *OidcTestSuite) Test_Callback_state_mismatch_Then_unauthorized() {
+
+	// given
+	login := suite.get("/", "text/html")
+
+	// when
+	rr := suite.get("/oauth2/callback?code=code-1&state=forged", "text/html", suite.loginCookie(login))
+
+	// then
+	suite.Equal(http.StatusUnauthorized, rr.Code)
+	suite.Nil(suite.cookie(rr, "spa_session"))
+}
+
+func (suite *OidcTestSuite) Test_Tampered_session_Then_login_redirect() {
+
+	// given
+	forged := &http.Cookie{Name: "spa_session", Value: suite.sut.oidc.seal(oidcSession{User: "mallory", Expires: time.Now().Add(time.Hour).Unix()}) + "x"}
+
+	// when
+	rr := suite.get("/", "text/html", forged)
+
+	// then
+	suite.Equal(http.StatusFound, rr.Code)
+}
+
+func (suite *OidcTestSuite) Test_Id_token_wrong_nonce_Then_invalid() {
+
+	// given
+	provider, err := suite.sut.oidc.discover(context.Background())
+	suite.Require().Nil(err)
+	token := suite.idToken(map[string]any{
+		"iss": suite.idp.URL, "aud": []string{"spa"}, "exp": time.Now().Add(time.Hour).Unix(), "nonce": "other",
+	})
+
+	// when
+	_, err = suite.sut.oidc.verifyIdToken(context.Background(), provider, token, "expected")
+
+	// then
+	suite.ErrorIs(err, errTokenInvalid)
+	suite.ErrorContains(err, "nonce")
+}
+
+func (suite *OidcTestSuite) Test_Concurrent_logins_Then_provider_keys_discovered_once() {
+
+	// given
+	token := suite.idToken(map[string]any{
+		"iss": suite.idp.URL, "aud": []string{"spa"}, "exp": time.Now().Add(time.Hour).Unix(), "nonce": "expected",
+	})
+	errs := make(chan error, 8)
+
+	// when
+	for i := 0; i < 8; i++ {
+		go func() {
+			provider, err := suite.sut.oidc.discover(context.Background())
+			if err == nil {
+				_, err = suite.sut.oidc.verifyIdToken(context.Background(), provider, token, "expected")
+			}
+			errs <- err
+		}()
+	}
+
+	// then
+	for i := 0; i < 8; i++ {
+		suite.Nil(<-errs)
+	}
+}
+
+func (suite *OidcTestSuite) Test_Logins_in_two_tabs_Then_both_completed() {
+
+	// given
+	first := suite.get("/reports", "text/html")
+	second := suite.get("/settings", "text/html")
+	cookies := []*http.Cookie{suite.loginCookie(first), suite.loginCookie(second)}
+	suite.Require().NotNil(cookies[0])
+	suite.Require().NotNil(cookies[1])
+
+	// when
+	firstSession := suite.login(first, cookies...)
+	secondSession := suite.login(second, cookies...)
+
+	// then
+	suite.NotEqual(cookies[0].Name, cookies[1].Name)
+	suite.NotNil(firstSession)
+	suite.NotNil(secondSession)
+}
+
+func (suite *OidcTestSuite) Test_Generated_key_Then_session_kept_after_reload() {
+
+	// given
+	login := suite.get("/", "text/html")
+	session := suite.login(login, suite.loginCookie(login))
+	suite.Require().NotNil(session)
+	reloaded, err := newServer(suite.sut.cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = reloaded
+
+	// when
+	rr := suite.get("/", "text/html", session)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("app", rr.Body.String())
+}
@@ -0,0 +1,81 @@
+package spaserver
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/sync/semaphore"
+)
+
+// requestSlots returns the semaphore of the in-flight limit, created on the
+// first use if the server was not created by newServer
+func (this *server) requestSlots() *semaphore.Weighted {
+	this.slotsOnce.Do(func() {
+		if this.slots == nil && this.cfg.MaxInflightRequests > 0 {
+			this.slots = semaphore.NewWeighted(int64(this.cfg.MaxInflightRequests))
+		}
+	})
+	return this.slots
+}
+
+// admitRequest counts the request in flight, false if the in-flight limit is
+// exceeded and no slot is released within the queue timeout, the admitted
+// requests must be released
+func (this *server) admitRequest(ctx context.Context) bool {
+	if slots := this.requestSlots(); slots != nil && !slots.TryAcquire(1) {
+		if this.cfg.OverloadQueueTimeout <= 0 {
+			return false
+		}
+		// the requests wait in the order of their arrival
+		this.queued.Add(1)
+		ctx, cancel := context.WithTimeout(ctx, this.cfg.OverloadQueueTimeout)
+		err := slots.Acquire(ctx, 1)
+		cancel()
+		this.queued.Add(-1)
+		if err != nil {
+			return false
+		}
+	}
+	this.inflight.Add(1)
+	return true
+}
+
+// releaseRequest completes the admitted request
+func (this *server) releaseRequest() {
+	this.inflight.Add(-1)
+	if slots := this.requestSlots(); slots != nil {
+		slots.Release(1)
+	}
+}
+
+// serveOverloaded refuses the request exceeding the in-flight limit instead of queueing it
+func (this *server) serveOverloaded(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Retry-After", strconv.Itoa(max(int(this.cfg.OverloadRetryAfter.Seconds()), 1)))
+	http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+}
+
+// observeRequests reports the requests in flight and queued by the server and
+// its sites until the context is done
+func (this *server) observeRequests(ctx context.Context) {
+	registration, err := telemetry().meters.RegisterCallback(
+		func(_ context.Context, observer metric.Observer) error {
+			inflight, queued := int64(0), int64(0)
+			for _, site := range this.siteServers() {
+				inflight += site.inflight.Load()
+				queued += site.queued.Load()
+			}
+			observer.ObserveInt64(telemetry().requests_inflight, inflight)
+			observer.ObserveInt64(telemetry().requests_queued, queued)
+			return nil
+		},
+		telemetry().requests_inflight, telemetry().requests_queued,
+	)
+	if err != nil {
+		return
+	}
+	defer registration.Unregister()
+	<-ctx.Done()
+}
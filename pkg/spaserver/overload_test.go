@@ -0,0 +1,129 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type OverloadTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestOverloadTestSuite(t *testing.T) {
+	suite.Run(t, new(OverloadTestSuite))
+}
+
+func (suite *OverloadTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "index.html"), []byte("app"), 0644))
+	sut, err := newServer(Config{
+		RootDirs:            []string{rootDir},
+		BaseURL:             "/",
+		MaxInflightRequests: 2,
+		OverloadRetryAfter:  3 * time.Second,
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+// saturate admits the requests in flight
+func (suite *OverloadTestSuite) saturate(requests int) {
+	for i := 0; i < requests; i++ {
+		suite.Require().True(suite.sut.admitRequest(context.Background()))
+	}
+}
+
+func (suite *OverloadTestSuite) get() *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, httptest.NewRequest("GET", "/", nil))
+	return rr
+}
+
+func (suite *OverloadTestSuite) Test_Below_limit_Then_served_and_released() {
+
+	// given
+	suite.saturate(1)
+
+	// when
+	rr := suite.get()
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(int64(1), suite.sut.inflight.Load())
+}
+
+func (suite *OverloadTestSuite) Test_Limit_reached_Then_service_unavailable() {
+
+	// given
+	suite.saturate(2)
+
+	// when
+	rr := suite.get()
+
+	// then
+	suite.Equal(http.StatusServiceUnavailable, rr.Code)
+	suite.Equal("3", rr.Header().Get("Retry-After"))
+	suite.Equal(int64(2), suite.sut.inflight.Load())
+}
+
+func (suite *OverloadTestSuite) Test_Slot_released_within_queue_timeout_Then_served() {
+
+	// given
+	suite.sut.cfg.OverloadQueueTimeout = time.Second
+	suite.saturate(2)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		suite.sut.releaseRequest()
+	}()
+
+	// when
+	rr := suite.get()
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(int64(1), suite.sut.inflight.Load())
+	suite.Equal(int64(0), suite.sut.queued.Load())
+}
+
+func (suite *OverloadTestSuite) Test_Queue_timeout_exceeded_Then_service_unavailable() {
+
+	// given
+	suite.sut.cfg.OverloadQueueTimeout = 50 * time.Millisecond
+	suite.saturate(2)
+	started := time.Now()
+
+	// when
+	rr := suite.get()
+
+	// then
+	suite.Equal(http.StatusServiceUnavailable, rr.Code)
+	suite.GreaterOrEqual(time.Since(started), 50*time.Millisecond)
+	suite.Equal(int64(2), suite.sut.inflight.Load())
+	suite.Equal(int64(0), suite.sut.queued.Load())
+}
+
+func (suite *OverloadTestSuite) Test_Unlimited_Then_requests_counted() {
+
+	// given
+	sut := &server{cfg: Config{}}
+
+	// when
+	admitted := sut.admitRequest(context.Background())
+
+	// then
+	suite.True(admitted)
+	suite.Equal(int64(1), sut.inflight.Load())
+	sut.releaseRequest()
+	suite.Equal(int64(0), sut.inflight.Load())
+}
@@ -0,0 +1,74 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+const (
+	blockedTraversal = "traversal"
+	blockedDotfile   = "dotfile"
+	blockedExtension = "extension"
+
+	// wellKnownDir is the directory of the site metadata, served despite the dotfile blocking
+	wellKnownDir = ".well-known"
+
+	// maxPathDecodings limits the decodings of the multiply encoded paths
+	maxPathDecodings = 3
+)
+
+// traversalAttempt reports whether the request path escapes the root with `..`
+// segments, including the encoded and the multiply encoded ones, the backslash
+// separators and the NUL bytes. The path of the request is decoded once, so the
+// remaining escapes are decoded again until the path no longer changes.
+func traversalAttempt(req *http.Request) bool {
+	decoded := req.URL.Path
+	for i := 0; i < maxPathDecodings; i++ {
+		if strings.ContainsRune(decoded, 0) {
+			return true
+		}
+		segments := strings.FieldsFunc(decoded, func(r rune) bool { return r == '/' || r == '\\' })
+		if slices.Contains(segments, "..") {
+			return true
+		}
+		next, err := url.PathUnescape(decoded)
+		if err != nil || next == decoded {
+			return false
+		}
+		decoded = next
+	}
+	return false
+}
+
+// blockedResource returns the reason the resource is not served, empty if it is
+// served: the hidden files and directories, except the well-known directory, and
+// the files outside of the allowed or within the denied extensions
+func (this *server) blockedResource(resourcePath string) string {
+	name := rootName(resourcePath)
+	if this.cfg.DotfilesBlocked {
+		for _, segment := range strings.Split(name, "/") {
+			if strings.HasPrefix(segment, ".") && segment != "." && segment != wellKnownDir {
+				return blockedDotfile
+			}
+		}
+	}
+	ext := strings.ToLower(path.Ext(name))
+	if ext == "" {
+		// the routes of the application
+		return ""
+	}
+	if len(this.cfg.ExtensionsAllowed) > 0 && !slices.ContainsFunc(this.cfg.ExtensionsAllowed, func(allowed string) bool {
+		return strings.EqualFold(allowed, ext)
+	}) {
+		return blockedExtension
+	}
+	if slices.ContainsFunc(this.cfg.ExtensionsDenied, func(denied string) bool {
+		return strings.EqualFold(denied, ext)
+	}) {
+		return blockedExtension
+	}
+	return ""
+}
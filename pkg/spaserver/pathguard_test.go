@@ -0,0 +1,151 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type PathGuardTestSuite struct {
+	suite.Suite
+	rootDir string
+}
+
+func TestPathGuardTestSuite(t *testing.T) {
+	suite.Run(t, new(PathGuardTestSuite))
+}
+
+func (suite *PathGuardTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	for name, content := range map[string]string{
+		"index.html":                             "shell",
+		"main.js":                                "main",
+		"main.js.map":                            "map",
+		".env":                                   "SECRET=1",
+		".git/config":                            "[core]",
+		".well-known/apple-app-site-association": "{}",
+	} {
+		suite.Require().Nil(os.MkdirAll(path.Dir(path.Join(suite.rootDir, name)), 0755))
+		suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, name), []byte(content), 0644))
+	}
+}
+
+func (suite *PathGuardTestSuite) serve(cfg Config, target string) *httptest.ResponseRecorder {
+	cfg.RootDirs = []string{suite.rootDir}
+	cfg.BaseURL = "/"
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", "/", nil)
+	// the raw target is not cleaned by the request parsing
+	req.URL.Path = target
+	req.Header.Set("Accept", "text/html")
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *PathGuardTestSuite) Test_Traversal_Then_detected() {
+
+	for target, expected := range map[string]bool{
+		"/../etc/passwd":             true,
+		"/assets/..":                 true,
+		`/assets\..\secret`:          true,
+		"/assets/%2e%2e/secret":      true,
+		"/assets/%252e%252e%252fkey": true,
+		"/assets/main.js\x00.html":   true,
+		"/assets/..main.js":          false,
+		"/search/a%2Fb":              false,
+		"/assets/main.js":            false,
+		"/assets/100%25/index.html":  false,
+	} {
+		// given
+		req := httptest.NewRequest("GET", "/", nil)
+		req.URL.Path = target
+
+		// when
+		detected := traversalAttempt(req)
+
+		// then
+		suite.Equal(expected, detected, target)
+	}
+}
+
+func (suite *PathGuardTestSuite) Test_Traversal_Then_not_found() {
+
+	// when
+	rr := suite.serve(Config{}, "/../index.html")
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+}
+
+func (suite *PathGuardTestSuite) Test_Dotfiles_blocked_Then_not_found() {
+
+	for target, expected := range map[string]int{
+		"/.env":        http.StatusNotFound,
+		"/.git/config": http.StatusNotFound,
+		"/.git/":       http.StatusNotFound,
+		"/.well-known/apple-app-site-association": http.StatusOK,
+		"/main.js": http.StatusOK,
+	} {
+		// when
+		rr := suite.serve(Config{DotfilesBlocked: true}, target)
+
+		// then
+		suite.Equal(expected, rr.Code, target)
+		suite.NotContains(rr.Body.String(), "SECRET", target)
+	}
+}
+
+func (suite *PathGuardTestSuite) Test_Dotfiles_allowed_Then_served() {
+
+	// when
+	rr := suite.serve(Config{}, "/.env")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("SECRET=1", rr.Body.String())
+}
+
+func (suite *PathGuardTestSuite) Test_Extension_not_allowed_Then_not_found() {
+
+	// given
+	cfg := Config{ExtensionsAllowed: []string{".html", ".JS"}}
+
+	// when
+	allowed := suite.serve(cfg, "/main.js")
+	disallowed := suite.serve(cfg, "/main.js.map")
+	route := suite.serve(cfg, "/users/1")
+
+	// then
+	suite.Equal(http.StatusOK, allowed.Code)
+	suite.Equal(http.StatusNotFound, disallowed.Code)
+	suite.Equal("shell", route.Body.String())
+}
+
+func (suite *PathGuardTestSuite) Test_Extension_denied_Then_not_found() {
+
+	// when
+	rr := suite.serve(Config{ExtensionsDenied: []string{".map"}}, "/main.js.map")
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+}
+
+func (suite *PathGuardTestSuite) Test_Invalid_extension_Then_validation_error() {
+
+	// when
+	err := validateConfig(Config{ExtensionsDenied: []string{"map", ".js.map"}})
+
+	// then
+	suite.ErrorContains(err, `extensions-denied[0]: invalid extension "map"`)
+	suite.ErrorContains(err, `extensions-denied[1]: invalid extension ".js.map"`)
+}
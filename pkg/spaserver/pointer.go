@@ -0,0 +1,73 @@
+package spaserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// resolveReleasePointer resolves the local root being a symlink to the release
+// directory, e.g. the Capistrano-style `current` pointer, so that the root is
+// served from the release it pointed to when opened, not from a mix of the
+// releases while the pointer flips.
+func resolveReleasePointer(rootDir string) string {
+	// the trailing slash would follow the symlink
+	info, err := os.Lstat(filepath.Clean(rootDir))
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return rootDir
+	}
+	release, err := filepath.EvalSymlinks(rootDir)
+	if err != nil {
+		return rootDir
+	}
+	return release
+}
+
+// watchReleasePointers checks the release pointers of the local roots periodically
+func (this *server) watchReleasePointers(ctx context.Context, interval time.Duration) {
+	if _, err := this.assetRoots(); err != nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		this.checkReleasePointers(ctx)
+	}
+}
+
+// checkReleasePointers opens again the roots whose release pointer flipped and
+// invalidates the caches, reports whether any pointer flipped
+func (this *server) checkReleasePointers(ctx context.Context) bool {
+	all := append(append(slices.Clip(this.roots), this.scheduled.roots...), this.rolloutRoots...)
+	flipped := false
+	for _, root := range all {
+		previous, ok := this.releases.Load(root.name)
+		if !ok || root.reopen == nil {
+			continue
+		}
+		release, err := filepath.EvalSymlinks(root.name)
+		if err != nil || release == previous {
+			// the missing target is kept serving until the pointer is fixed
+			continue
+		}
+		logger := this.logger.With().Str("root", root.name).Str("release", release).Logger()
+		if _, err := root.reopen(ctx); err != nil {
+			this.recent.add("root_reload_failure")
+			logger.Warn().Err(err).Msg("Cannot open flipped release")
+			continue
+		}
+		flipped = true
+		logger.Info().Msg("Release pointer flipped")
+	}
+	if flipped {
+		this.invalidateCaches()
+	}
+	return flipped
+}
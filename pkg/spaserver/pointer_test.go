@@ -0,0 +1,90 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type PointerTestSuite struct {
+	suite.Suite
+	dir     string
+	current string
+	sut     *server
+}
+
+func TestPointerTestSuite(t *testing.T) {
+	suite.Run(t, new(PointerTestSuite))
+}
+
+func (suite *PointerTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.dir = suite.T().TempDir()
+	for _, release := range []string{"r1", "r2"} {
+		suite.Require().Nil(os.MkdirAll(path.Join(suite.dir, release), 0755))
+		suite.Require().Nil(os.WriteFile(path.Join(suite.dir, release, "index.html"), []byte(release), 0644))
+	}
+	suite.current = path.Join(suite.dir, "current")
+	suite.Require().Nil(os.Symlink(path.Join(suite.dir, "r1"), suite.current))
+	sut, err := newServer(Config{RootDirs: []string{suite.current + "/"}, BaseURL: "/"}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *PointerTestSuite) flip(release string) {
+	next := path.Join(suite.dir, "next")
+	suite.Require().Nil(os.Symlink(path.Join(suite.dir, release), next))
+	suite.Require().Nil(os.Rename(next, suite.current))
+}
+
+func (suite *PointerTestSuite) get() string {
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, httptest.NewRequest("GET", "/index.html", nil))
+	return rr.Body.String()
+}
+
+func (suite *PointerTestSuite) Test_Pointer_flipped_Then_release_served_after_check() {
+
+	// given
+	suite.Equal("r1", suite.get())
+	suite.flip("r2")
+
+	// when
+	before := suite.get()
+	flipped := suite.sut.checkReleasePointers(context.Background())
+
+	// then
+	suite.Equal("r1", before)
+	suite.True(flipped)
+	suite.Equal("r2", suite.get())
+}
+
+func (suite *PointerTestSuite) Test_Pointer_unchanged_Then_not_reopened() {
+
+	// when
+	flipped := suite.sut.checkReleasePointers(context.Background())
+
+	// then
+	suite.False(flipped)
+	suite.Equal("r1", suite.get())
+}
+
+func (suite *PointerTestSuite) Test_Pointer_dangling_Then_previous_release_served() {
+
+	// given
+	suite.Require().Nil(os.RemoveAll(path.Join(suite.dir, "r2")))
+	suite.flip("r2")
+
+	// when
+	flipped := suite.sut.checkReleasePointers(context.Background())
+
+	// then
+	suite.False(flipped)
+	suite.Equal("r1", suite.get())
+}
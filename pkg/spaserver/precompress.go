@@ -0,0 +1,158 @@
+package spaserver
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// precompressedExtensions are the extensions of the precompressed variants by encoding
+var precompressedExtensions = map[string]string{"br": ".br", "gzip": ".gz", "zstd": ".zst"}
+
+// precompressRoot generates the missing brotli, zstd and gzip variants of the compressible
+// files of the directory root at the best compression, so that the plain build
+// output is served precompressed. The variants are written next to the files, or
+// to the cache directory of the release if configured, e.g. for the read-only
+// roots, and served through the overlay. The variants not older than their file
+// are kept, so that opening the root again compresses only the changed files.
+func (this *server) precompressRoot(rootDir string, dir string, fsys fs.FS) fs.FS {
+	started := time.Now()
+	logger := this.logger.With().Str("root", rootDir).Logger()
+	target := dir
+	if this.cfg.PrecompressCacheDir != "" {
+		absolute, err := filepath.Abs(dir)
+		if err != nil {
+			absolute = dir
+		}
+		digest := sha256.Sum256([]byte(absolute))
+		target = filepath.Join(this.cfg.PrecompressCacheDir, hex.EncodeToString(digest[:8]))
+	}
+
+	encodings := []string{}
+	if !this.cfg.BrotliDisabled {
+		encodings = append(encodings, "br")
+	}
+	if !this.cfg.ZstdDisabled {
+		encodings = append(encodings, "zstd")
+	}
+	if !this.cfg.GzipDisabled {
+		encodings = append(encodings, "gzip")
+	}
+
+	generated := 0
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() || !this.compressible(name) {
+			return err
+		}
+		for _, ext := range precompressedExtensions {
+			if strings.HasSuffix(name, ext) {
+				return nil
+			}
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() < this.cfg.DynamicCompressionMinSize {
+			return nil
+		}
+		for _, encoding := range encodings {
+			variant := name + precompressedExtensions[encoding]
+			if _, err := fs.Stat(fsys, variant); err == nil {
+				// shipped with the bundle
+				continue
+			}
+			path := filepath.Join(target, filepath.FromSlash(variant))
+			if existing, err := os.Stat(path); err == nil && !existing.ModTime().Before(info.ModTime()) {
+				continue
+			}
+			if err := precompressFile(fsys, name, path, encoding); err != nil {
+				return err
+			}
+			generated++
+		}
+		return nil
+	})
+	if err != nil {
+		// the root is served with the variants generated so far
+		logger.Warn().Err(err).Msg("Cannot precompress root")
+	}
+	logger.Info().Int("generated", generated).Dur("duration", time.Since(started)).Msg("Root precompressed")
+
+	if target == dir {
+		return fsys
+	}
+	return &variantsFS{FS: fsys, variants: os.DirFS(target)}
+}
+
+// precompressFile writes the encoded variant of the file, the variant is renamed
+// to its path once complete, so that no partial variant is served
+func precompressFile(fsys fs.FS, name string, path string, encoding string) error {
+	source, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), ".precompress-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	var writer io.WriteCloser
+	switch encoding {
+	case "br":
+		writer = brotli.NewWriterLevel(temp, brotli.BestCompression)
+	case "zstd":
+		if writer, err = zstd.NewWriter(temp, zstd.WithEncoderLevel(zstd.SpeedBestCompression)); err != nil {
+			return err
+		}
+	default:
+		writer, _ = gzip.NewWriterLevel(temp, gzip.BestCompression)
+	}
+	if _, err := io.Copy(writer, source); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// variantsFS serves the precompressed variants missing in the root from the cache directory
+type variantsFS struct {
+	fs.FS
+	variants fs.FS
+}
+
+func (this *variantsFS) Open(name string) (fs.File, error) {
+	file, err := this.FS.Open(name)
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return file, err
+	}
+	for _, ext := range precompressedExtensions {
+		if strings.HasSuffix(name, ext) {
+			if variant, variantErr := this.variants.Open(name); variantErr == nil {
+				return variant, nil
+			}
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,159 @@
+package spaserver
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type PrecompressTestSuite struct {
+	suite.Suite
+	rootDir string
+	cfg     Config
+	content string
+}
+
+func TestPrecompressTestSuite(t *testing.T) {
+	suite.Run(t, new(PrecompressTestSuite))
+}
+
+func (suite *PrecompressTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.content = strings.Repeat("body { color: red; }\n", 100)
+	suite.Require().Nil(os.MkdirAll(path.Join(suite.rootDir, "assets"), 0755))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "assets/main.css"), []byte(suite.content), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "small.js"), []byte("small"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "logo.png"), []byte(suite.content), 0644))
+	suite.cfg = Config{
+		RootDirs:                  []string{suite.rootDir},
+		BaseURL:                   "/",
+		PrecompressOnStart:        true,
+		DynamicCompressionMinSize: 1024,
+	}
+}
+
+func (suite *PrecompressTestSuite) get(sut *server, target string, encoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	req.Header.Set("Accept-Encoding", encoding)
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *PrecompressTestSuite) Test_Enabled_Then_variants_generated_next_to_files() {
+
+	// when
+	sut, err := newServer(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.Require().Nil(err)
+	suite.FileExists(path.Join(suite.rootDir, "assets/main.css.br"))
+	suite.FileExists(path.Join(suite.rootDir, "assets/main.css.gz"))
+	suite.NoFileExists(path.Join(suite.rootDir, "small.js.gz"))
+	suite.NoFileExists(path.Join(suite.rootDir, "logo.png.gz"))
+
+	rr := suite.get(sut, "/assets/main.css", "br")
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("br", rr.Header().Get("Content-Encoding"))
+	content, err := io.ReadAll(brotli.NewReader(rr.Body))
+	suite.Nil(err)
+	suite.Equal(suite.content, string(content))
+}
+
+func (suite *PrecompressTestSuite) Test_Cache_dir_Then_variants_served_from_cache() {
+
+	// given
+	suite.cfg.PrecompressCacheDir = suite.T().TempDir()
+
+	// when
+	sut, err := newServer(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.Require().Nil(err)
+	suite.NoFileExists(path.Join(suite.rootDir, "assets/main.css.gz"))
+	rr := suite.get(sut, "/assets/main.css", "gzip")
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("gzip", rr.Header().Get("Content-Encoding"))
+	reader, err := gzip.NewReader(rr.Body)
+	suite.Require().Nil(err)
+	content, err := io.ReadAll(reader)
+	suite.Nil(err)
+	suite.Equal(suite.content, string(content))
+}
+
+func (suite *PrecompressTestSuite) Test_Zstd_accepted_Then_generated_variant_served() {
+
+	// given
+	sut, err := newServer(suite.cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+
+	// when
+	rr := suite.get(sut, "/assets/main.css", "gzip, zstd")
+
+	// then
+	suite.FileExists(path.Join(suite.rootDir, "assets/main.css.zst"))
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("zstd", rr.Header().Get("Content-Encoding"))
+	reader, err := zstd.NewReader(rr.Body)
+	suite.Require().Nil(err)
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	suite.Nil(err)
+	suite.Equal(suite.content, string(content))
+}
+
+func (suite *PrecompressTestSuite) Test_Zstd_disabled_Then_not_generated() {
+
+	// given
+	suite.cfg.ZstdDisabled = true
+
+	// when
+	_, err := newServer(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.Require().Nil(err)
+	suite.FileExists(path.Join(suite.rootDir, "assets/main.css.br"))
+	suite.NoFileExists(path.Join(suite.rootDir, "assets/main.css.zst"))
+}
+
+func (suite *PrecompressTestSuite) Test_Variant_shipped_Then_kept() {
+
+	// given
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "assets/main.css.gz"), []byte("shipped"), 0644))
+
+	// when
+	_, err := newServer(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.Require().Nil(err)
+	content, err := os.ReadFile(path.Join(suite.rootDir, "assets/main.css.gz"))
+	suite.Nil(err)
+	suite.Equal("shipped", string(content))
+	suite.FileExists(path.Join(suite.rootDir, "assets/main.css.br"))
+	suite.NoFileExists(path.Join(suite.rootDir, "assets/main.css.gz.br"))
+}
+
+func (suite *PrecompressTestSuite) Test_Disabled_Then_no_variants() {
+
+	// given
+	suite.cfg.PrecompressOnStart = false
+
+	// when
+	_, err := newServer(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.Require().Nil(err)
+	suite.NoFileExists(path.Join(suite.rootDir, "assets/main.css.br"))
+}
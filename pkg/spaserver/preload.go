@@ -0,0 +1,193 @@
+package spaserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// buildManifestChunk is the chunk of the Vite build manifest
+type buildManifestChunk struct {
+	File    string   `json:"file"`
+	IsEntry bool     `json:"isEntry"`
+	Imports []string `json:"imports"`
+	Css     []string `json:"css"`
+	Assets  []string `json:"assets"`
+}
+
+// applyPreloadHints adds the Link headers preloading the configured critical
+// assets and the static import chain of the entry chunks to the html responses,
+// so that the browser fetches the modules in parallel instead of discovering
+// them one import at a time. With the early hints enabled, the links are sent
+// in the 103 Early Hints response before the document is served.
+func (this *server) applyPreloadHints(ctx context.Context, w http.ResponseWriter, req *http.Request, name string) error {
+	if (this.cfg.PreloadManifest == "" && len(this.cfg.PreloadAssets) == 0) || !isHtml(name) {
+		return nil
+	}
+
+	links := []string{}
+	prefix := this.assetPrefix(ctx)
+	for _, asset := range this.cfg.PreloadAssets {
+		links = append(links, assetPreloadLink(asset, prefix))
+	}
+	chain, err := this.manifestPreloadLinks(ctx, name)
+	if err != nil {
+		return err
+	}
+	for _, link := range chain {
+		if !slices.Contains(links, link) {
+			links = append(links, link)
+		}
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	if this.cfg.EarlyHints && req.Method == http.MethodGet && req.ProtoAtLeast(1, 1) {
+		debugLookup(ctx, "early hints")
+		sendEarlyHints(w, links)
+	}
+	addLinks(w, links)
+	return nil
+}
+
+// manifestPreloadLinks returns the links of the static import chain of the
+// document in the build manifest, empty if no manifest is configured
+func (this *server) manifestPreloadLinks(ctx context.Context, name string) ([]string, error) {
+	if this.cfg.PreloadManifest == "" {
+		return nil, nil
+	}
+	file, ok, err := this.findFile(ctx, this.cfg.PreloadManifest)
+	if err != nil || !ok {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%v|%v|%v|%v", rootSetKey(ctx), rootName(name), info.ModTime().UnixNano(), info.Size())
+	if links, ok := this.preloads.Load(key); ok {
+		recordCacheLookup(ctx, "preloads", cacheHit)
+		return links.([]string), nil
+	}
+	recordCacheLookup(ctx, "preloads", cacheMiss)
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	manifest := map[string]buildManifestChunk{}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("cannot decode build manifest %v: %w", this.cfg.PreloadManifest, err)
+	}
+	links := preloadLinks(manifest, rootName(name), this.assetPrefix(ctx))
+	this.cache(&this.preloads, key, links, memoryCritical)
+	return links, nil
+}
+
+// assetPreloadLink returns the link preloading the configured asset by its
+// extension, the configured links are used as they are, e.g.
+// `</fonts/inter.woff2>; rel=preload; as=font; crossorigin`
+func assetPreloadLink(asset string, prefix string) string {
+	if strings.HasPrefix(asset, "<") {
+		return asset
+	}
+	target := prefix + rootName(asset)
+	switch strings.ToLower(path.Ext(asset)) {
+	case ".js", ".mjs":
+		return "<" + target + ">; rel=modulepreload"
+	case ".css":
+		return "<" + target + ">; rel=preload; as=style"
+	case ".woff2", ".woff", ".ttf", ".otf":
+		// the fonts are always fetched in the cors mode
+		return "<" + target + ">; rel=preload; as=font; crossorigin"
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp", ".avif", ".svg":
+		return "<" + target + ">; rel=preload; as=image"
+	default:
+		return "<" + target + ">; rel=preload; as=fetch; crossorigin"
+	}
+}
+
+// sendEarlyHints sends the 103 Early Hints response with the links only, the
+// headers of the final response set so far are kept for the final response
+func sendEarlyHints(w http.ResponseWriter, links []string) {
+	header := w.Header()
+	final := header.Clone()
+	for key := range header {
+		delete(header, key)
+	}
+	header["Link"] = slices.Clone(links)
+	w.WriteHeader(http.StatusEarlyHints)
+	delete(header, "Link")
+	for key, values := range final {
+		header[key] = values
+	}
+}
+
+// preloadLinks collects the static import chain of the entry of the document, or
+// of all the entries when the document is not an entry of the manifest
+func preloadLinks(manifest map[string]buildManifestChunk, document string, prefix string) []string {
+	entries := []string{}
+	if chunk, ok := manifest[document]; ok && chunk.IsEntry {
+		entries = append(entries, document)
+	} else {
+		for key, chunk := range manifest {
+			if chunk.IsEntry && strings.HasSuffix(chunk.File, ".js") {
+				entries = append(entries, key)
+			}
+		}
+	}
+
+	links := []string{}
+	visited := map[string]bool{}
+	var visit func(key string)
+	visit = func(key string) {
+		chunk, ok := manifest[key]
+		if !ok || visited[key] {
+			return
+		}
+		visited[key] = true
+		if strings.HasSuffix(chunk.File, ".js") || strings.HasSuffix(chunk.File, ".mjs") {
+			links = append(links, "<"+prefix+chunk.File+">; rel=modulepreload")
+		}
+		for _, css := range chunk.Css {
+			if !visited[css] {
+				visited[css] = true
+				links = append(links, "<"+prefix+css+">; rel=preload; as=style")
+			}
+		}
+		for _, imported := range chunk.Imports {
+			visit(imported)
+		}
+	}
+	// deterministic order of the headers
+	sort.Strings(entries)
+	for _, entry := range entries {
+		visit(entry)
+	}
+	return links
+}
+
+// assetPrefix is the url prefix of the assets served to the request
+func (this *server) assetPrefix(ctx context.Context) string {
+	prefix := this.publicBaseUrl(ctx)
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	if version := requestVersion(ctx); version != "" {
+		prefix += versionsPrefix + version + "/"
+	}
+	return prefix
+}
+
+func addLinks(w http.ResponseWriter, links []string) {
+	for _, link := range links {
+		w.Header().Add("Link", link)
+	}
+}
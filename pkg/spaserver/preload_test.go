@@ -0,0 +1,192 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type PreloadTestSuite struct {
+	suite.Suite
+	rootDir string
+	sut     *server
+}
+
+func TestPreloadTestSuite(t *testing.T) {
+	suite.Run(t, new(PreloadTestSuite))
+}
+
+func (suite *PreloadTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	suite.rootDir = rootDir
+	suite.Require().Nil(os.MkdirAll(path.Join(rootDir, ".vite"), 0755))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "index.html"), []byte("<html></html>"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, ".vite/manifest.json"), []byte(`{
+		"index.html": {"file": "assets/index-a.js", "isEntry": true, "imports": ["_vendor-b.js"], "css": ["assets/index-a.css"], "dynamicImports": ["src/lazy.ts"]},
+		"_vendor-b.js": {"file": "assets/vendor-b.js", "imports": ["_shared-c.js"]},
+		"_shared-c.js": {"file": "assets/shared-c.js", "imports": ["_vendor-b.js"]},
+		"src/lazy.ts": {"file": "assets/lazy-d.js", "isDynamicEntry": true}
+	}`), 0644))
+
+	suite.sut = suite.server(Config{})
+}
+
+func (suite *PreloadTestSuite) server(cfg Config) *server {
+	cfg.RootDirs = []string{suite.rootDir}
+	cfg.BaseURL = "/app"
+	cfg.PreloadManifest = ".vite/manifest.json"
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	return sut
+}
+
+// earlyHints requests the document from the server, returns the headers of the
+// interim responses and the final response
+func (suite *PreloadTestSuite) earlyHints(sut *server) ([]textproto.MIMEHeader, *http.Response) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sut.handler(req.Context(), w, req)
+	}))
+	suite.T().Cleanup(server.Close)
+
+	interim := []textproto.MIMEHeader{}
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code == http.StatusEarlyHints {
+				interim = append(interim, header)
+			}
+			return nil
+		},
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), "GET", server.URL+"/app/some/route", nil)
+	suite.Require().Nil(err)
+	res, err := server.Client().Do(req)
+	suite.Require().Nil(err)
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	return interim, res
+}
+
+func (suite *PreloadTestSuite) Test_Document_Then_import_chain_preloaded() {
+
+	// given
+	req, err := http.NewRequest("GET", "/app/some/route", nil)
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+
+	// when
+	suite.sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal([]string{
+		"</app/assets/index-a.js>; rel=modulepreload",
+		"</app/assets/index-a.css>; rel=preload; as=style",
+		"</app/assets/vendor-b.js>; rel=modulepreload",
+		"</app/assets/shared-c.js>; rel=modulepreload",
+	}, rr.Header().Values("Link"))
+}
+
+func (suite *PreloadTestSuite) Test_Document_not_in_manifest_Then_all_entries_preloaded() {
+
+	// when
+	links := preloadLinks(map[string]buildManifestChunk{
+		"src/main.ts":  {File: "assets/main.js", IsEntry: true},
+		"src/admin.ts": {File: "assets/admin.js", IsEntry: true},
+		"src/lazy.ts":  {File: "assets/lazy.js"},
+	}, "index.html", "/")
+
+	// then
+	suite.Equal([]string{"</assets/admin.js>; rel=modulepreload", "</assets/main.js>; rel=modulepreload"}, links)
+}
+
+func (suite *PreloadTestSuite) Test_Preload_assets_Then_preloaded_before_chain() {
+
+	// given
+	sut := suite.server(Config{PreloadAssets: []string{
+		"assets/index-a.js",
+		"fonts/inter.woff2",
+		"</cdn/logo.svg>; rel=preload; as=image",
+	}})
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, httptest.NewRequest("GET", "/app/some/route", nil))
+
+	// then
+	suite.Equal([]string{
+		"</app/assets/index-a.js>; rel=modulepreload",
+		"</app/fonts/inter.woff2>; rel=preload; as=font; crossorigin",
+		"</cdn/logo.svg>; rel=preload; as=image",
+		"</app/assets/index-a.css>; rel=preload; as=style",
+		"</app/assets/vendor-b.js>; rel=modulepreload",
+		"</app/assets/shared-c.js>; rel=modulepreload",
+	}, rr.Header().Values("Link"))
+}
+
+func (suite *PreloadTestSuite) Test_Asset_extension_Then_link_type() {
+
+	for asset, expected := range map[string]string{
+		"main.mjs":         "</main.mjs>; rel=modulepreload",
+		"/assets/app.CSS":  "</assets/app.CSS>; rel=preload; as=style",
+		"hero.webp":        "</hero.webp>; rel=preload; as=image",
+		"data/config.json": "</data/config.json>; rel=preload; as=fetch; crossorigin",
+	} {
+		// when
+		link := assetPreloadLink(asset, "/")
+
+		// then
+		suite.Equal(expected, link, asset)
+	}
+}
+
+func (suite *PreloadTestSuite) Test_Early_hints_Then_links_sent_before_document() {
+
+	// given
+	sut := suite.server(Config{EarlyHints: true, Headers: map[string]string{"X-Frame-Options": "DENY"}})
+
+	// when
+	interim, res := suite.earlyHints(sut)
+
+	// then
+	suite.Require().Len(interim, 1)
+	suite.Equal([]string{
+		"</app/assets/index-a.js>; rel=modulepreload",
+		"</app/assets/index-a.css>; rel=preload; as=style",
+		"</app/assets/vendor-b.js>; rel=modulepreload",
+		"</app/assets/shared-c.js>; rel=modulepreload",
+	}, interim[0].Values("Link"))
+	suite.Empty(interim[0].Get("X-Frame-Options"), "the headers of the document are not hinted")
+	suite.Equal(http.StatusOK, res.StatusCode)
+	suite.Equal("DENY", res.Header.Get("X-Frame-Options"))
+	suite.Len(res.Header.Values("Link"), 4)
+}
+
+func (suite *PreloadTestSuite) Test_Early_hints_disabled_Then_no_interim_response() {
+
+	// when
+	interim, res := suite.earlyHints(suite.sut)
+
+	// then
+	suite.Empty(interim)
+	suite.Len(res.Header.Values("Link"), 4)
+}
+
+func (suite *PreloadTestSuite) Test_Invalid_preload_asset_Then_validation_error() {
+
+	// when
+	err := validateConfig(Config{PreloadAssets: []string{"", "main.js\r\nX-Injected: 1"}})
+
+	// then
+	suite.ErrorContains(err, `preload-assets[0]: invalid asset ""`)
+	suite.ErrorContains(err, "preload-assets[1]: invalid asset")
+}
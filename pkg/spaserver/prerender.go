@@ -0,0 +1,198 @@
+package spaserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// prerenderClient passes the redirects of the prerender service to the crawlers
+var prerenderClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// prerenderEnabled reports whether the crawlers are served the prerendered pages
+func (this *server) prerenderEnabled() bool {
+	return this.cfg.PrerenderUrl != "" || this.cfg.PrerenderDir != ""
+}
+
+// navigationPath reports whether the path is a page of the application rather than an asset
+func navigationPath(resourcePath string) bool {
+	ext := strings.ToLower(path.Ext(resourcePath))
+	return ext == "" || ext == ".html"
+}
+
+// crawlerRequest reports whether the request comes from a crawler user agent
+func (this *server) crawlerRequest(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	userAgent := req.UserAgent()
+	regex := this.regexes().prerenderUserAgent
+	return userAgent != "" && regex != nil && regex.MatchString(userAgent)
+}
+
+// prerender serves the snapshot of the page or proxies the request to the prerender
+// service, false if neither provides the page
+func (this *server) prerender(ctx context.Context, w http.ResponseWriter, req *http.Request, resourcePath string) (bool, error) {
+	if this.cfg.PrerenderDir != "" {
+		found, err := this.serveSnapshotPage(w, req, resourcePath)
+		if found || err != nil {
+			return found, err
+		}
+	}
+	if this.cfg.PrerenderUrl != "" {
+		return this.proxyPrerender(ctx, w, req)
+	}
+	return false, nil
+}
+
+// snapshotCandidates are the names of the pre-generated snapshots of the page,
+// e.g. `about.html` or `about/index.html` for `/about`
+func snapshotCandidates(resourcePath string) []string {
+	name := strings.Trim(resourcePath, "/")
+	switch {
+	case name == "":
+		return []string{"index.html"}
+	case strings.HasSuffix(strings.ToLower(name), ".html"):
+		return []string{name}
+	default:
+		return []string{name + ".html", name + "/index.html"}
+	}
+}
+
+// serveSnapshotPage serves the pre-generated snapshot of the page from the prerender directory
+func (this *server) serveSnapshotPage(w http.ResponseWriter, req *http.Request, resourcePath string) (bool, error) {
+	snapshots := os.DirFS(this.cfg.PrerenderDir)
+	for _, name := range snapshotCandidates(resourcePath) {
+		if !fs.ValidPath(name) {
+			return false, nil
+		}
+		file, err := snapshots.Open(name)
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			return false, err
+		}
+		if info.IsDir() {
+			continue
+		}
+		content, ok := file.(io.ReadSeeker)
+		if !ok {
+			return false, fmt.Errorf("snapshot %v is not seekable", name)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		http.ServeContent(w, req, name, info.ModTime(), content)
+		return true, nil
+	}
+	return false, nil
+}
+
+// proxyPrerender passes the request to the prerender service, the service receives
+// the absolute url of the page appended to its url
+func (this *server) proxyPrerender(ctx context.Context, w http.ResponseWriter, req *http.Request) (bool, error) {
+	if this.cfg.PrerenderTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, this.cfg.PrerenderTimeout)
+		defer cancel()
+	}
+
+	target := strings.TrimSuffix(this.cfg.PrerenderUrl, "/") + "/" + requestUrl(req)
+	prerenderReq, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return false, err
+	}
+	prerenderReq.Header.Set("User-Agent", req.UserAgent())
+	prerenderReq.Header.Set("Accept", "text/html")
+
+	// the failing service is replaced with the stale page if cached, else with the application
+	key := "prerender|" + target
+	cacheable := this.upstreamCacheable(req)
+	failed := func(err error) (bool, error) {
+		if cacheable && this.serveStaleUpstreamResponse(ctx, w, req, key) {
+			this.logger.Warn().Err(err).Str("path", req.URL.Path).Msg("Prerender failed")
+			return true, nil
+		}
+		return false, err
+	}
+	if cacheable && this.serveFreshUpstreamResponse(ctx, w, req, key) {
+		return true, nil
+	}
+
+	started := time.Now()
+	resp, err := prerenderClient.Do(prerenderReq)
+	if err != nil {
+		return failed(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return failed(fmt.Errorf("prerender service responded with %v", resp.Status))
+	}
+
+	header := http.Header{}
+	for _, name := range []string{"Content-Type", "Location", "Cache-Control", "Last-Modified"} {
+		if value := resp.Header.Get(name); value != "" {
+			header.Set(name, value)
+		}
+	}
+	if cacheable && sharedUpstreamResponse(resp) {
+		body, buffered, err := bufferUpstreamBody(resp, this.cfg.UpstreamCacheMaxSize)
+		if err != nil {
+			return failed(err)
+		}
+		if buffered {
+			this.cacheUpstreamResponse(key, &upstreamResponse{status: resp.StatusCode, header: header.Clone(), body: body, fetched: time.Now()})
+		}
+	}
+	for name, values := range header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	if req.Method != http.MethodHead {
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			// the response is already started
+			this.logger.Warn().Err(err).Str("path", req.URL.Path).Msg("Prerender response interrupted")
+		}
+	}
+	this.logger.Debug().Str("path", req.URL.Path).Int("status", resp.StatusCode).
+		Dur("duration", time.Since(started)).Msg("prerendered")
+	return true, nil
+}
+
+// requestUrl reconstructs the absolute url of the request as seen by the client
+func requestUrl(req *http.Request) string {
+	return requestOrigin(req) + req.URL.RequestURI()
+}
+
+// requestOrigin reconstructs the scheme and the host of the request as seen by the client
+func requestOrigin(req *http.Request) string {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	if proto := req.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	host := req.Host
+	if forwarded := req.Header.Get("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+	return scheme + "://" + host
+}
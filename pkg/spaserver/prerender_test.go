@@ -0,0 +1,123 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type PrerenderTestSuite struct {
+	suite.Suite
+	rootDir     string
+	snapshotDir string
+}
+
+func TestPrerenderTestSuite(t *testing.T) {
+	suite.Run(t, new(PrerenderTestSuite))
+}
+
+func (suite *PrerenderTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.snapshotDir = suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "index.html"), []byte("<div id=app></div>"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "main.js"), []byte("init()"), 0644))
+	suite.Require().Nil(os.MkdirAll(path.Join(suite.snapshotDir, "about"), 0755))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.snapshotDir, "about", "index.html"), []byte("<h1>About</h1>"), 0644))
+}
+
+func (suite *PrerenderTestSuite) serve(cfg Config, target string, userAgent string) *httptest.ResponseRecorder {
+	cfg.RootDirs = []string{suite.rootDir}
+	cfg.BaseURL = "/"
+	cfg.PrerenderUserAgentRegex = defaultCrawlerUserAgents
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", target, nil)
+	req.Header.Set("User-Agent", userAgent)
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *PrerenderTestSuite) Test_Crawler_Then_snapshot_served() {
+
+	// when
+	rr := suite.serve(Config{PrerenderDir: suite.snapshotDir}, "/about", "Mozilla/5.0 (compatible; Googlebot/2.1)")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("<h1>About</h1>", rr.Body.String())
+	suite.Equal("User-Agent", rr.Header().Get("Vary"))
+}
+
+func (suite *PrerenderTestSuite) Test_Browser_Then_application_served() {
+
+	// when
+	rr := suite.serve(Config{PrerenderDir: suite.snapshotDir}, "/about", "Mozilla/5.0 (X11; Linux x86_64) Firefox/120.0")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("<div id=app></div>", rr.Body.String())
+	suite.Equal("User-Agent", rr.Header().Get("Vary"))
+}
+
+func (suite *PrerenderTestSuite) Test_Crawler_asset_Then_not_prerendered() {
+
+	// when
+	rr := suite.serve(Config{PrerenderDir: suite.snapshotDir}, "/main.js", "Googlebot")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("init()", rr.Body.String())
+	suite.NotContains(rr.Header().Values("Vary"), "User-Agent")
+}
+
+func (suite *PrerenderTestSuite) Test_Crawler_Then_proxied_to_service() {
+
+	// given
+	var received string
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received = req.URL.Path
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<h1>Products</h1>"))
+	}))
+	defer service.Close()
+
+	// when
+	rr := suite.serve(Config{PrerenderUrl: service.URL, PrerenderDir: suite.snapshotDir}, "/products?page=2", "bingbot/2.0")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("<h1>Products</h1>", rr.Body.String())
+	suite.Equal("/http://example.com/products", received)
+}
+
+func (suite *PrerenderTestSuite) Test_Service_failure_Then_application_served() {
+
+	// given
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer service.Close()
+
+	// when
+	rr := suite.serve(Config{PrerenderUrl: service.URL, PrerenderTimeout: 50 * time.Millisecond}, "/products", "Twitterbot/1.0")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("<div id=app></div>", rr.Body.String())
+}
+
+func (suite *PrerenderTestSuite) Test_Snapshot_candidates() {
+	suite.Equal([]string{"index.html"}, snapshotCandidates("/"))
+	suite.Equal([]string{"about.html", "about/index.html"}, snapshotCandidates("/about/"))
+	suite.Equal([]string{"docs/intro.html"}, snapshotCandidates("/docs/intro.html"))
+}
@@ -0,0 +1,132 @@
+package spaserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"runtime/pprof"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// profiler pushes the pprof profiles of the running server to the ingest
+// API of Pyroscope, or any server compatible with it
+type profiler struct {
+	cfg    Config
+	logger zerolog.Logger
+}
+
+// run collects the profiles in the periods of the profiling interval, the CPU
+// profile covers the whole period and the other profiles are snapshots taken
+// at the end of the period
+func (this *profiler) run(ctx context.Context) {
+	for {
+		from := time.Now()
+		var cpu *bytes.Buffer
+		if slices.Contains(this.cfg.ProfilingTypes, "cpu") {
+			cpu = &bytes.Buffer{}
+			if err := pprof.StartCPUProfile(cpu); err != nil {
+				this.logger.Warn().Err(err).Msg("Cannot start CPU profile")
+				cpu = nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(this.cfg.ProfilingInterval):
+		}
+		if cpu != nil {
+			pprof.StopCPUProfile()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		until := time.Now()
+
+		for _, profileType := range this.cfg.ProfilingTypes {
+			profile := &bytes.Buffer{}
+			if profileType == "cpu" {
+				if cpu == nil {
+					continue
+				}
+				profile = cpu
+			} else if lookup := pprof.Lookup(profileType); lookup != nil {
+				if err := lookup.WriteTo(profile, 0); err != nil {
+					this.logger.Warn().Err(err).Str("profile", profileType).Msg("Cannot collect profile")
+					continue
+				}
+			} else {
+				this.logger.Warn().Str("profile", profileType).Msg("Unknown profile type")
+				continue
+			}
+
+			if err := this.upload(ctx, profileType, profile.Bytes(), from, until); err != nil {
+				this.logger.Warn().Err(err).Str("profile", profileType).Msg("Cannot upload profile")
+			}
+		}
+	}
+}
+
+// upload sends the pprof profile to the ingest endpoint, the upload is bounded by
+// the profiling interval, so that a stalled endpoint does not hold the next period
+func (this *profiler) upload(ctx context.Context, profileType string, profile []byte, from time.Time, until time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, this.cfg.ProfilingInterval)
+	defer cancel()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("profile", "profile.pprof")
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(profile); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("name", this.cfg.ProfilingAppName+"."+profileType+this.labels())
+	query.Set("from", strconv.FormatInt(from.Unix(), 10))
+	query.Set("until", strconv.FormatInt(until.Unix(), 10))
+	query.Set("spyName", "gospy")
+	query.Set("sampleRate", "100")
+
+	target := strings.TrimSuffix(this.cfg.ProfilingUrl, "/") + "/ingest?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("profile upload failed with status %v", res.Status)
+	}
+	return nil
+}
+
+// labels formats the profiling labels in the `{key=value,...}` form of the application name
+func (this *profiler) labels() string {
+	if len(this.cfg.ProfilingLabels) == 0 {
+		return ""
+	}
+	labels := make([]string, 0, len(this.cfg.ProfilingLabels))
+	for key, value := range this.cfg.ProfilingLabels {
+		labels = append(labels, key+"="+value)
+	}
+	slices.Sort(labels)
+	return "{" + strings.Join(labels, ",") + "}"
+}
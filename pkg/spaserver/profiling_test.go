@@ -0,0 +1,99 @@
+package spaserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type ProfilingTestSuite struct {
+	suite.Suite
+}
+
+func TestProfilingTestSuite(t *testing.T) {
+	suite.Run(t, new(ProfilingTestSuite))
+}
+
+func (suite *ProfilingTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+}
+
+func (suite *ProfilingTestSuite) Test_Profiling_Then_profiles_pushed() {
+
+	// given
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lock := sync.Mutex{}
+	names := []string{}
+	sizes := []int64{}
+	ingest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		file, header, err := req.FormFile("profile")
+		if err != nil || req.URL.Path != "/ingest" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		file.Close()
+
+		lock.Lock()
+		defer lock.Unlock()
+		names = append(names, req.URL.Query().Get("name"))
+		sizes = append(sizes, header.Size)
+		if len(names) == 2 {
+			cancel()
+		}
+	}))
+	defer ingest.Close()
+
+	sut := &profiler{
+		cfg: Config{
+			ProfilingUrl:      ingest.URL,
+			ProfilingAppName:  "spa_d",
+			ProfilingLabels:   map[string]string{"env": "test"},
+			ProfilingInterval: 50 * time.Millisecond,
+			ProfilingTypes:    []string{"cpu", "heap"},
+		},
+		logger: zerolog.New(os.Stdout),
+	}
+
+	// when
+	sut.run(ctx)
+
+	// then
+	lock.Lock()
+	defer lock.Unlock()
+	suite.Equal([]string{"spa_d.cpu{env=test}", "spa_d.heap{env=test}"}, names)
+	for _, size := range sizes {
+		suite.Greater(size, int64(0))
+	}
+}
+
+func (suite *ProfilingTestSuite) Test_Ingest_stalled_Then_upload_bounded_by_interval() {
+
+	// given
+	stalled := make(chan struct{})
+	ingest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-stalled
+	}))
+	defer ingest.Close()
+	defer close(stalled)
+	sut := &profiler{
+		cfg:    Config{ProfilingUrl: ingest.URL, ProfilingAppName: "spa_d", ProfilingInterval: 50 * time.Millisecond},
+		logger: zerolog.New(os.Stdout),
+	}
+	started := time.Now()
+
+	// when
+	err := sut.upload(context.Background(), "heap", []byte("profile"), started, started)
+
+	// then
+	suite.ErrorIs(err, context.DeadlineExceeded)
+	suite.Less(time.Since(started), time.Second)
+}
@@ -0,0 +1,22 @@
+package spaserver
+
+import (
+	"net/http"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/exporters/prometheus"
+	metricsdk "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// prometheusExporter creates the metric reader collected by the Prometheus scrapes
+// of the returned handler, served as /metrics on the admin port. The registry is
+// not the global one, which the Prometheus exporter of autoexport registers to.
+func prometheusExporter() (metricsdk.Reader, http.Handler, error) {
+	registry := promclient.NewRegistry()
+	reader, err := prometheus.New(prometheus.WithRegisterer(registry))
+	if err != nil {
+		return nil, nil, err
+	}
+	return reader, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}), nil
+}
@@ -0,0 +1,123 @@
+package spaserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// reverseProxy passes the matching requests to its upstream
+type reverseProxy struct {
+	cfg      Proxy
+	regex    *regexp.Regexp
+	upstream *url.URL
+	handler  *httputil.ReverseProxy
+}
+
+// newReverseProxies creates the reverse proxies of the configured routes
+func (this *server) newReverseProxies() ([]*reverseProxy, error) {
+	proxies := make([]*reverseProxy, 0, len(this.cfg.Proxies))
+	for _, cfg := range this.cfg.Proxies {
+		proxy := &reverseProxy{cfg: cfg}
+		var err error
+		if cfg.Regexp != "" {
+			if proxy.regex, err = regexp.Compile(cfg.Regexp); err != nil {
+				return nil, err
+			}
+		}
+		if proxy.upstream, err = url.Parse(cfg.Upstream); err != nil {
+			return nil, err
+		}
+		proxy.handler = &httputil.ReverseProxy{
+			Rewrite: proxy.rewrite,
+			// the client span is the child of the request span, the trace
+			// context is propagated to the upstream
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+			// the streamed responses, e.g. server-sent events, are not buffered
+			FlushInterval:  -1,
+			ModifyResponse: this.modifyProxiedResponse,
+			ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+				this.logger.Warn().Err(err).Str("upstream", cfg.Upstream).Str("path", req.URL.Path).Msg("Upstream failed")
+				if key, ok := req.Context().Value(upstreamCacheKeyType{}).(string); ok && this.serveStaleUpstreamResponse(req.Context(), w, req, key) {
+					return
+				}
+				http.Error(w, "Bad Gateway", http.StatusBadGateway)
+			},
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
+
+// matches reports whether the request path is passed to the upstream
+func (this *reverseProxy) matches(requestPath string) bool {
+	if this.regex != nil {
+		return this.regex.MatchString(requestPath)
+	}
+	return strings.HasPrefix(requestPath, this.cfg.Prefix)
+}
+
+// rewrite targets the upstream, its path is prepended to the request path
+func (this *reverseProxy) rewrite(req *httputil.ProxyRequest) {
+	if this.cfg.StripPrefix && this.cfg.Prefix != "" {
+		req.Out.URL.Path = "/" + strings.TrimLeft(strings.TrimPrefix(req.Out.URL.Path, this.cfg.Prefix), "/")
+		req.Out.URL.RawPath = ""
+	}
+	req.SetURL(this.upstream)
+	req.SetXForwarded()
+}
+
+// matchingProxy returns the first reverse proxy matching the request path, nil if none
+func (this *server) matchingProxy(requestPath string) *reverseProxy {
+	for _, proxy := range this.proxies {
+		if proxy.matches(requestPath) {
+			return proxy
+		}
+	}
+	return nil
+}
+
+// serveProxied passes the request to the upstream unless its response is cached
+func (this *server) serveProxied(ctx context.Context, w http.ResponseWriter, req *http.Request, proxy *reverseProxy) {
+	if this.upstreamCacheable(req) {
+		key := proxiedCacheKey(proxy.cfg.Upstream, req)
+		if this.serveFreshUpstreamResponse(ctx, w, req, key) {
+			return
+		}
+		ctx = context.WithValue(ctx, upstreamCacheKeyType{}, key)
+	}
+	proxy.handler.ServeHTTP(w, req.WithContext(ctx))
+}
+
+// modifyProxiedResponse caches the shared responses of the upstream, the server errors
+// fail over to the error handler serving the stale response if one is cached
+func (this *server) modifyProxiedResponse(resp *http.Response) error {
+	key, ok := resp.Request.Context().Value(upstreamCacheKeyType{}).(string)
+	if !ok {
+		return nil
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		if _, _, stale := this.cachedUpstreamResponse(key, time.Now()); stale {
+			return fmt.Errorf("upstream responded with %v", resp.Status)
+		}
+		return nil
+	}
+	if !sharedUpstreamResponse(resp) {
+		return nil
+	}
+	body, buffered, err := bufferUpstreamBody(resp, this.cfg.UpstreamCacheMaxSize)
+	if err != nil || !buffered {
+		return err
+	}
+	this.cacheUpstreamResponse(key, &upstreamResponse{
+		status: resp.StatusCode, header: resp.Header.Clone(), body: body, fetched: time.Now(),
+	})
+	return nil
+}
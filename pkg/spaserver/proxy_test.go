@@ -0,0 +1,177 @@
+package spaserver
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type ProxyTestSuite struct {
+	suite.Suite
+	cfg      Config
+	upstream *httptest.Server
+	received *http.Request
+}
+
+func TestProxyTestSuite(t *testing.T) {
+	suite.Run(t, new(ProxyTestSuite))
+}
+
+func (suite *ProxyTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	suite.upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		suite.received = req
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"path":"`+req.URL.Path+`"}`)
+	}))
+	suite.T().Cleanup(suite.upstream.Close)
+	_, filename, _, _ := runtime.Caller(0)
+	suite.cfg = Config{
+		RootDirs: []string{path.Join(path.Dir(filename), "test/data")},
+		BaseURL:  "/",
+		Proxies:  []Proxy{{Prefix: "/api/", Upstream: suite.upstream.URL + "/v1"}},
+	}
+}
+
+func (suite *ProxyTestSuite) get(ctx context.Context, target string) *httptest.ResponseRecorder {
+	sut, err := newServer(suite.cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+	sut.handler(ctx, rr, httptest.NewRequest("GET", target, nil))
+	return rr
+}
+
+func (suite *ProxyTestSuite) Test_Prefix_matching_Then_proxied_instead_of_fallback() {
+
+	// when
+	rr := suite.get(context.Background(), "/api/users/42?tab=1")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("application/json", rr.Header().Get("Content-Type"))
+	suite.Equal(`{"path":"/v1/api/users/42"}`, rr.Body.String())
+	suite.Equal("tab=1", suite.received.URL.RawQuery)
+	suite.Equal("example.com", suite.received.Header.Get("X-Forwarded-Host"))
+}
+
+func (suite *ProxyTestSuite) Test_Strip_prefix_Then_upstream_path_without_prefix() {
+
+	// given
+	suite.cfg.Proxies[0].StripPrefix = true
+
+	// when
+	rr := suite.get(context.Background(), "/api/users/42")
+
+	// then
+	suite.Equal(`{"path":"/v1/users/42"}`, rr.Body.String())
+}
+
+func (suite *ProxyTestSuite) Test_Regexp_matching_Then_proxied() {
+
+	// given
+	suite.cfg.Proxies = []Proxy{{Regexp: `^/(graphql|auth)(/|$)`, Upstream: suite.upstream.URL}}
+
+	// when
+	rr := suite.get(context.Background(), "/graphql")
+
+	// then
+	suite.Equal(`{"path":"/graphql"}`, rr.Body.String())
+}
+
+func (suite *ProxyTestSuite) Test_Not_matching_Then_served_from_roots() {
+
+	// when
+	rr := suite.get(context.Background(), "/apis/users")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Nil(suite.received)
+	suite.Contains(rr.Header().Get("Content-Type"), "text/html")
+}
+
+func (suite *ProxyTestSuite) Test_Traced_request_Then_trace_context_propagated() {
+
+	// given
+	traceID, _ := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	spanID, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+
+	// when
+	suite.get(ctx, "/api/users")
+
+	// then
+	suite.Require().NotNil(suite.received)
+	suite.Contains(suite.received.Header.Get("Traceparent"), traceID.String())
+}
+
+func (suite *ProxyTestSuite) Test_Upstream_down_Then_bad_gateway() {
+
+	// given
+	suite.upstream.Close()
+
+	// when
+	rr := suite.get(context.Background(), "/api/users")
+
+	// then
+	suite.Equal(http.StatusBadGateway, rr.Code)
+}
+
+func (suite *ProxyTestSuite) Test_Streamed_response_Then_flushed_before_complete() {
+
+	// given
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: first\n")
+		w.(http.Flusher).Flush()
+		<-release
+		io.WriteString(w, "data: second\n")
+	}))
+	defer upstream.Close()
+	defer close(release)
+	suite.cfg.Proxies = []Proxy{{Prefix: "/events", Upstream: upstream.URL}}
+	sut, err := newServer(suite.cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	spa := httptest.NewServer(sut)
+	defer spa.Close()
+
+	// when
+	resp, err := http.Get(spa.URL + "/events")
+
+	// then
+	suite.Require().Nil(err)
+	defer resp.Body.Close()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	suite.Nil(err)
+	suite.Equal("data: first\n", line)
+}
+
+func (suite *ProxyTestSuite) Test_Invalid_proxy_Then_invalid() {
+
+	// given
+	suite.cfg.Proxies = []Proxy{{Upstream: "api:8080"}}
+
+	// when
+	_, err := newServer(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "proxies[0]: exactly one of prefix and regexp")
+	suite.ErrorContains(err, "proxies[0].upstream")
+}
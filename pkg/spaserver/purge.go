@@ -0,0 +1,77 @@
+package spaserver
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// purgeCaches drops the cached entries of the files with the path matching the
+// regexp, or all the caches including the opened versions and tenants if nil.
+// The entries are keyed by the root set key followed by the file path.
+func (this *server) purgeCaches(pathRegex *regexp.Regexp) int {
+	fileCaches := []*sync.Map{&this.transforms, &this.cspHashes, &this.preloads, &this.hashedFiles, &this.etags, &this.dirHeaders}
+	if pathRegex == nil {
+		purged := purgeMap(&this.fileStats, func(string) bool { return true })
+		this.fileStatsCount.Store(0)
+		purged += int(this.upstreamResponsesCount.Load())
+		this.clearUpstreamResponses()
+		for _, cache := range append(fileCaches, &this.redirects, &this.sitemaps, &this.buildTimes, &this.versions, &this.tenants) {
+			purged += purgeMap(cache, func(string) bool { return true })
+		}
+		return purged
+	}
+
+	purged := purgeMap(&this.fileStats, func(key string) bool {
+		_, name, _ := strings.Cut(key, "|")
+		return pathRegex.MatchString("/" + name)
+	})
+	this.fileStatsCount.Add(-int64(purged))
+	for _, cache := range fileCaches {
+		purged += purgeMap(cache, func(key string) bool {
+			fields := strings.SplitN(key, "|", 6)
+			return len(fields) > 4 && pathRegex.MatchString("/"+rootName(fields[4]))
+		})
+	}
+	return purged
+}
+
+func purgeMap(cache *sync.Map, matches func(string) bool) int {
+	purged := 0
+	cache.Range(func(key, _ any) bool {
+		if matches(key.(string)) {
+			cache.Delete(key)
+			purged++
+		}
+		return true
+	})
+	return purged
+}
+
+// servePurge purges the caches: DELETE drops the entries of the files matching
+// the `path` regexp, or all the caches without it
+func (this *server) servePurge(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodDelete {
+		w.Header().Set("Allow", "DELETE")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var pathRegex *regexp.Regexp
+	if value := req.URL.Query().Get("path"); value != "" {
+		var err error
+		pathRegex, err = regexp.Compile(value)
+		if err != nil {
+			http.Error(w, "Invalid path regexp: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	purged := this.purgeCaches(pathRegex)
+	logger := this.logger.Warn().Int("purged", purged)
+	if pathRegex != nil {
+		logger = logger.Str("path", pathRegex.String())
+	}
+	logger.Msg("Caches purged")
+	writeJSON(w, http.StatusOK, map[string]any{"purged": purged})
+}
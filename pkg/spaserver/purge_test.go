@@ -0,0 +1,107 @@
+package spaserver
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type PurgeTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestPurgeTestSuite(t *testing.T) {
+	suite.Run(t, new(PurgeTestSuite))
+}
+
+func (suite *PurgeTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "index.html"), []byte("app"), 0644))
+	sut, err := newServer(Config{
+		RootDirs:   []string{rootDir},
+		BaseURL:    "/",
+		AdminToken: "secret",
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+
+	suite.sut.etags.Store("||||assets/app.js|br|1|2", `"app"`)
+	suite.sut.etags.Store("||||index.html||1|2", `"index"`)
+	suite.sut.transforms.Store("|v1|||/assets/app.css|1|2", []byte("css"))
+	suite.sut.redirects.Store("||||1|2", []redirectRule{})
+}
+
+func (suite *PurgeTestSuite) purge(target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("DELETE", target, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rr := httptest.NewRecorder()
+	suite.sut.adminHandler().ServeHTTP(rr, req)
+	return rr
+}
+
+func (suite *PurgeTestSuite) cached() int {
+	count := 0
+	for _, cache := range []*sync.Map{&suite.sut.etags, &suite.sut.transforms, &suite.sut.redirects} {
+		cache.Range(func(_, _ any) bool {
+			count++
+			return true
+		})
+	}
+	return count
+}
+
+func (suite *PurgeTestSuite) Test_Path_Then_matching_files_purged() {
+
+	// when
+	rr := suite.purge("/cache?path=^/assets/")
+
+	// then
+	suite.Equal(200, rr.Code)
+	suite.JSONEq(`{"purged": 2}`, rr.Body.String())
+	_, ok := suite.sut.etags.Load("||||index.html||1|2")
+	suite.True(ok)
+	suite.Equal(2, suite.cached())
+}
+
+func (suite *PurgeTestSuite) Test_No_path_Then_all_purged() {
+
+	// when
+	rr := suite.purge("/cache")
+
+	// then
+	suite.Equal(200, rr.Code)
+	suite.JSONEq(`{"purged": 4}`, rr.Body.String())
+	suite.Equal(0, suite.cached())
+}
+
+func (suite *PurgeTestSuite) Test_Invalid_path_Then_bad_request() {
+
+	// when
+	rr := suite.purge("/cache?path=(")
+
+	// then
+	suite.Equal(400, rr.Code)
+	suite.Equal(4, suite.cached())
+}
+
+func (suite *PurgeTestSuite) Test_Get_Then_method_not_allowed() {
+
+	// given
+	req := httptest.NewRequest("GET", "/cache", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rr := httptest.NewRecorder()
+
+	// when
+	suite.sut.adminHandler().ServeHTTP(rr, req)
+
+	// then
+	suite.Equal(405, rr.Code)
+}
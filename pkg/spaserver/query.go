@@ -0,0 +1,37 @@
+package spaserver
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// normalizePath normalizes the request path reported in the metrics: the query
+// string is not part of the path, and the dot segments and duplicate slashes
+// are resolved the same way as in the file lookup
+func normalizePath(requestPath string) string {
+	if i := strings.IndexByte(requestPath, '?'); i >= 0 {
+		requestPath = requestPath[:i]
+	}
+	normalized := path.Clean("/" + requestPath)
+	if strings.HasSuffix(requestPath, "/") && normalized != "/" {
+		normalized += "/"
+	}
+	return normalized
+}
+
+// cacheBusted returns true if the request carries any of the cache-bust query
+// parameters, e.g. `/config.json?v=1.2.0`, so that the response can be cached
+// as immutable
+func (this *server) cacheBusted(req *http.Request) bool {
+	if len(this.cfg.CacheBustParams) == 0 || req.URL.RawQuery == "" {
+		return false
+	}
+	query := req.URL.Query()
+	for _, param := range this.cfg.CacheBustParams {
+		if query.Get(param) != "" {
+			return true
+		}
+	}
+	return false
+}
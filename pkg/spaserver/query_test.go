@@ -0,0 +1,74 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type QueryTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestQueryTestSuite(t *testing.T) {
+	suite.Run(t, new(QueryTestSuite))
+}
+
+func (suite *QueryTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "config.json"), []byte("{}"), 0644))
+	sut, err := newServer(Config{
+		RootDirs: []string{rootDir},
+		BaseURL:  "/",
+		HeadersPerPathRegex: map[string]map[string]string{
+			`\.json$`: {"Cache-Control": "no-cache"},
+		},
+		CacheBustParams: []string{"v", "hash"},
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *QueryTestSuite) get(target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+	return rr
+}
+
+func (suite *QueryTestSuite) Test_Cache_bust_param_Then_immutable() {
+
+	// when
+	rr := suite.get("/config.json?v=1.2.0")
+
+	// then
+	suite.Equal("{}", rr.Body.String())
+	suite.Equal("public, max-age=31536000, immutable", rr.Header().Get("Cache-Control"))
+}
+
+func (suite *QueryTestSuite) Test_Other_query_Then_configured_cache_control() {
+
+	// when
+	rr := suite.get("/config.json?utm_source=mail")
+
+	// then
+	suite.Equal("{}", rr.Body.String())
+	suite.Equal("no-cache", rr.Header().Get("Cache-Control"))
+}
+
+func (suite *QueryTestSuite) Test_Normalize_path() {
+	suite.Equal("/assets/main.js", normalizePath("/assets//./main.js?v=1"))
+	suite.Equal("/main.js", normalizePath("/assets/../main.js"))
+	suite.Equal("/docs/", normalizePath("//docs/"))
+	suite.Equal("/", normalizePath(""))
+}
@@ -0,0 +1,87 @@
+package spaserver
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimitPruneInterval is the interval of removing the buckets of the idle clients
+const rateLimitPruneInterval = time.Minute
+
+// rateLimiter limits the requests of each client with the token bucket refilled
+// with the rate up to the burst
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+	pruned  time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// newRateLimiter creates the limiter, nil if the rate is not limited
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{rate: rate, burst: math.Max(float64(burst), 1), buckets: map[string]*tokenBucket{}}
+}
+
+// allow takes the token of the client, or returns the delay until the next token
+func (this *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if now.Sub(this.pruned) >= rateLimitPruneInterval {
+		this.prune(now)
+	}
+
+	bucket, ok := this.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: this.burst, updated: now}
+		this.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(this.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*this.rate)
+	bucket.updated = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - bucket.tokens) / this.rate * float64(time.Second))
+}
+
+// prune removes the buckets refilled to the burst, which are the same as the new ones
+func (this *rateLimiter) prune(now time.Time) {
+	this.pruned = now
+	for key, bucket := range this.buckets {
+		if bucket.tokens+now.Sub(bucket.updated).Seconds()*this.rate >= this.burst {
+			delete(this.buckets, key)
+		}
+	}
+}
+
+// rateLimitKey is the value of the rate limit header set by the trusted proxy, or
+// the client address if the header is not configured or missing. The clients
+// connecting directly would evade the limit with a new header value per request.
+func (this *server) rateLimitKey(req *http.Request) string {
+	if this.cfg.RateLimitHeader != "" && this.trustedProxy(req.RemoteAddr) {
+		if value := req.Header.Get(this.cfg.RateLimitHeader); value != "" {
+			return "header:" + value
+		}
+	}
+	return this.clientIp(req).String()
+}
+
+// serveThrottled refuses the request of the client exceeding the rate limit
+func serveThrottled(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(retryAfter.Seconds())), 1)))
+	http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+}
@@ -0,0 +1,127 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimitTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestRateLimitTestSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitTestSuite))
+}
+
+func (suite *RateLimitTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "index.html"), []byte("app"), 0644))
+	sut, err := newServer(Config{
+		RootDirs:        []string{rootDir},
+		RateLimit:       0.5,
+		RateLimitBurst:  2,
+		RateLimitHeader: "X-Api-Key",
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *RateLimitTestSuite) get(remoteAddr string, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = remoteAddr
+	if apiKey != "" {
+		req.Header.Set("X-Api-Key", apiKey)
+	}
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *RateLimitTestSuite) Test_Burst_exceeded_Then_too_many_requests() {
+
+	// when
+	first := suite.get("192.0.2.1:4000", "")
+	second := suite.get("192.0.2.1:4000", "")
+	third := suite.get("192.0.2.1:4000", "")
+	other := suite.get("192.0.2.2:4000", "")
+
+	// then
+	suite.Equal(http.StatusOK, first.Code)
+	suite.Equal(http.StatusOK, second.Code)
+	suite.Equal(http.StatusTooManyRequests, third.Code)
+	suite.Equal("2", third.Header().Get("Retry-After"))
+	suite.Equal(http.StatusOK, other.Code)
+}
+
+func (suite *RateLimitTestSuite) Test_Header_key_Then_limited_by_header() {
+
+	// given
+	proxies, err := parseCidrs([]string{"192.0.2.0/24"})
+	suite.Require().Nil(err)
+	suite.sut.trustedProxies = proxies
+	suite.get("192.0.2.1:4000", "key-1")
+	suite.get("192.0.2.2:4000", "key-1")
+
+	// when
+	rr := suite.get("192.0.2.3:4000", "key-1")
+
+	// then
+	suite.Equal(http.StatusTooManyRequests, rr.Code)
+	suite.Equal(http.StatusOK, suite.get("192.0.2.3:4000", "key-2").Code)
+}
+
+func (suite *RateLimitTestSuite) Test_Header_key_from_untrusted_peer_Then_limited_by_address() {
+
+	// given
+	suite.get("192.0.2.1:4000", "key-1")
+	suite.get("192.0.2.1:4000", "key-2")
+
+	// when
+	rr := suite.get("192.0.2.1:4000", "key-3")
+
+	// then
+	suite.Equal(http.StatusTooManyRequests, rr.Code)
+}
+
+func (suite *RateLimitTestSuite) Test_Bucket_refilled_Then_allowed() {
+
+	// given
+	limiter := newRateLimiter(1, 1)
+	now := time.Now()
+	limiter.allow("client", now)
+
+	// when
+	throttled, retryAfter := limiter.allow("client", now.Add(500*time.Millisecond))
+	refilled, _ := limiter.allow("client", now.Add(1500*time.Millisecond))
+
+	// then
+	suite.False(throttled)
+	suite.Equal(500*time.Millisecond, retryAfter)
+	suite.True(refilled)
+}
+
+func (suite *RateLimitTestSuite) Test_Idle_buckets_Then_pruned() {
+
+	// given
+	limiter := newRateLimiter(1, 5)
+	now := time.Now()
+	limiter.allow("idle", now)
+
+	// when
+	limiter.allow("active", now.Add(2*rateLimitPruneInterval))
+
+	// then
+	suite.Len(limiter.buckets, 1)
+	suite.Contains(limiter.buckets, "active")
+}
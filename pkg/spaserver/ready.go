@@ -0,0 +1,112 @@
+package spaserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// readiness checks configurable in addition to the drain mode and the roots availability
+const (
+	// readyFallbackDocument requires the fallback document in the roots
+	readyFallbackDocument = "fallback-document"
+	// readyPrerender requires the prerender service to respond without a server error
+	readyPrerender = "prerender"
+	// readySyncAge requires the remote roots synced within the maximal sync age
+	readySyncAge = "sync-age"
+)
+
+var readyChecks = []string{readyFallbackDocument, readyPrerender, readySyncAge}
+
+// readinessFailures runs the configured readiness checks, the failures are
+// reported in the order of the checks
+func (this *server) readinessFailures(ctx context.Context) []string {
+	failures := []string{}
+	for _, check := range this.cfg.ReadyChecks {
+		var err error
+		switch check {
+		case readyFallbackDocument:
+			err = this.checkFallbackDocument(ctx)
+		case readyPrerender:
+			err = this.checkPrerender(ctx)
+		case readySyncAge:
+			err = this.checkSyncAge()
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%v: %v", check, err))
+		}
+	}
+	return failures
+}
+
+// checkFallbackDocument checks the fallback documents in the served roots, the
+// tenant roots are opened on demand and not checked
+func (this *server) checkFallbackDocument(ctx context.Context) error {
+	if this.cfg.FallbackDisabled || this.tenantsEnabled() {
+		return nil
+	}
+	for _, document := range this.fallbackDocuments() {
+		file, ok, err := this.findFile(ctx, document)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%v not found", document)
+		}
+		file.Close()
+	}
+	return nil
+}
+
+// checkPrerender checks that the prerender service responds without a server error
+func (this *server) checkPrerender(ctx context.Context) error {
+	if this.cfg.PrerenderUrl == "" {
+		return nil
+	}
+	timeout := this.cfg.PrerenderTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, this.cfg.PrerenderUrl, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := prerenderClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("responded with %v", resp.Status)
+	}
+	return nil
+}
+
+// checkSyncAge checks the time since the last successful sync of the remote
+// roots, the maximal age is three sync intervals unless configured
+func (this *server) checkSyncAge() error {
+	maxAge := this.cfg.ReadyMaxSyncAge
+	if maxAge <= 0 {
+		maxAge = 3 * this.cfg.SyncInterval
+	}
+	if maxAge <= 0 {
+		return nil
+	}
+	roots, err := this.assetRoots()
+	if err != nil {
+		return err
+	}
+	for _, root := range roots {
+		if root.refresh == nil {
+			continue
+		}
+		if age := time.Since(time.Unix(0, root.synced.Load())); age > maxAge {
+			return fmt.Errorf("root %v not synced for %v", rootLabel(root.name), age.Round(time.Second))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package spaserver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReadyTestSuite struct {
+	suite.Suite
+	rootDir string
+}
+
+func TestReadyTestSuite(t *testing.T) {
+	suite.Run(t, new(ReadyTestSuite))
+}
+
+func (suite *ReadyTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "index.html"), []byte("app"), 0644))
+}
+
+func (suite *ReadyTestSuite) ready(cfg Config) *httptest.ResponseRecorder {
+	cfg.RootDirs = []string{suite.rootDir}
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+	sut.adminHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+	return rr
+}
+
+func (suite *ReadyTestSuite) Test_Fallback_document_present_Then_ready() {
+
+	// when
+	rr := suite.ready(Config{ReadyChecks: []string{"fallback-document"}})
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+}
+
+func (suite *ReadyTestSuite) Test_Fallback_document_missing_Then_not_ready() {
+
+	// when
+	rr := suite.ready(Config{ReadyChecks: []string{"fallback-document"}, FallbackDocument: "200.html"})
+
+	// then
+	suite.Equal(http.StatusServiceUnavailable, rr.Code)
+	suite.Contains(rr.Body.String(), "fallback-document: /200.html not found")
+}
+
+func (suite *ReadyTestSuite) Test_Prerender_failing_Then_not_ready() {
+
+	// given
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	prerender := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer prerender.Close()
+	cfg := Config{ReadyChecks: []string{"prerender"}, PrerenderUrl: prerender.URL}
+
+	// when
+	healthy := suite.ready(cfg)
+	status.Store(http.StatusBadGateway)
+	failing := suite.ready(cfg)
+
+	// then
+	suite.Equal(http.StatusOK, healthy.Code)
+	suite.Equal(http.StatusServiceUnavailable, failing.Code)
+	suite.Contains(failing.Body.String(), "prerender: responded with 502 Bad Gateway")
+}
+
+func (suite *ReadyTestSuite) Test_Sync_stale_Then_not_ready() {
+
+	// given
+	sut, err := newServer(Config{RootDirs: []string{suite.rootDir}, ReadyChecks: []string{"sync-age"}, SyncInterval: time.Minute}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	roots, _ := sut.assetRoots()
+	roots[0].refresh = roots[0].reopen
+	roots[0].synced.Store(time.Now().Add(-time.Hour).UnixNano())
+
+	// when
+	failures := sut.readinessFailures(httptest.NewRequest("GET", "/ready", nil).Context())
+
+	// then
+	suite.Len(failures, 1)
+	suite.Contains(failures[0], "sync-age: root")
+}
+
+func (suite *ReadyTestSuite) Test_Unknown_check_Then_invalid_configuration() {
+
+	// when
+	_, err := newServer(Config{RootDirs: []string{suite.rootDir}, ReadyChecks: []string{"database"}}, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "ready-checks[0]: unknown readiness check database")
+}
+
+func (suite *ReadyTestSuite) Test_Draining_Then_readyz_fails_and_livez_ok() {
+
+	// given
+	sut, err := newServer(Config{RootDirs: []string{suite.rootDir}}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	sut.draining.Store(true)
+
+	for target, status := range map[string]int{
+		"/readyz":  http.StatusServiceUnavailable,
+		"/livez":   http.StatusOK,
+		"/healthz": http.StatusOK,
+	} {
+		rr := httptest.NewRecorder()
+
+		// when
+		sut.adminHandler().ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+
+		// then
+		suite.Equal(status, rr.Code, target)
+		suite.Equal("no-store", rr.Header().Get("Cache-Control"), target)
+	}
+}
+
+func (suite *ReadyTestSuite) Test_Admin_token_Then_probes_without_token() {
+
+	// given
+	sut, err := newServer(Config{RootDirs: []string{suite.rootDir}, AdminToken: "secret"}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.adminHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/livez", nil))
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("ok\n", rr.Body.String())
+}
@@ -0,0 +1,333 @@
+package spaserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// redirectRule is the rule of the Netlify-style `_redirects` file
+type redirectRule struct {
+	from   *regexp.Regexp
+	to     string
+	status int
+	// force applies the rule even if the file exists
+	force bool
+}
+
+// parseRedirects parses the rules of the `_redirects` file, e.g.
+//
+//	/blog/*          /news/:splat   301
+//	/users/:id       /profile.html  200
+//	/old/index.html  /              302!
+//
+// The rules with query parameters, conditions or proxied to other hosts are
+// not supported and reported as invalid.
+func parseRedirects(content []byte) ([]redirectRule, []string) {
+	rules := []redirectRule{}
+	invalid := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if comment := strings.Index(line, "#"); comment >= 0 {
+			line = strings.TrimSpace(line[:comment])
+		}
+		if line == "" {
+			continue
+		}
+		rule, err := parseRedirectRule(strings.Fields(line))
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%v: %v", line, err))
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, invalid
+}
+
+func parseRedirectRule(fields []string) (redirectRule, error) {
+	if len(fields) < 2 || len(fields) > 3 {
+		return redirectRule{}, fmt.Errorf("expected the source, the target and the optional status")
+	}
+	for _, field := range fields {
+		if strings.Contains(field, "=") {
+			return redirectRule{}, fmt.Errorf("query parameters and conditions are not supported")
+		}
+	}
+	rule := redirectRule{to: fields[1], status: http.StatusMovedPermanently}
+	if len(fields) == 3 {
+		status, force := strings.CutSuffix(fields[2], "!")
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			return redirectRule{}, fmt.Errorf("invalid status %v", fields[2])
+		}
+		rule.status, rule.force = code, force
+	}
+	if !strings.HasPrefix(fields[0], "/") {
+		return redirectRule{}, fmt.Errorf("the source must be an absolute path")
+	}
+	if !strings.HasPrefix(rule.to, "/") && rule.status == http.StatusOK {
+		return redirectRule{}, fmt.Errorf("proxying to other hosts is not supported")
+	}
+
+	// placeholders match a path segment, the splat matches the rest of the path
+	pattern := strings.Builder{}
+	pattern.WriteString("^")
+	for i, segment := range strings.Split(fields[0], "/") {
+		if i > 0 {
+			pattern.WriteString("/")
+		}
+		switch {
+		case segment == "*":
+			pattern.WriteString("(?P<splat>.*)")
+		case strings.HasPrefix(segment, ":"):
+			pattern.WriteString("(?P<" + regexp.QuoteMeta(segment[1:]) + ">[^/]+)")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(segment))
+		}
+	}
+	// the trailing slash is optional
+	pattern.WriteString("/?$")
+	from, err := regexp.Compile(strings.Replace(pattern.String(), "//?$", "/?$", 1))
+	if err != nil {
+		return redirectRule{}, fmt.Errorf("invalid source: %w", err)
+	}
+	rule.from = from
+	return rule, nil
+}
+
+// target replaces the placeholders and the splat of the target with the matched segments
+func (this redirectRule) target(requestPath string) (string, bool) {
+	match := this.from.FindStringSubmatch(requestPath)
+	if match == nil {
+		return "", false
+	}
+	target := this.to
+	for i, name := range this.from.SubexpNames() {
+		if name != "" {
+			target = strings.ReplaceAll(target, ":"+name, match[i])
+		}
+	}
+	return target, true
+}
+
+// redirectRules loads the rules of the redirects file of the request roots
+func (this *server) redirectRules(ctx context.Context) ([]redirectRule, error) {
+	file, ok, err := this.findFile(ctx, this.cfg.RedirectsFile)
+	if err != nil || !ok {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%v|%v|%v", rootSetKey(ctx), info.ModTime().UnixNano(), info.Size())
+	if rules, ok := this.redirects.Load(key); ok {
+		recordCacheLookup(ctx, "redirects", cacheHit)
+		return rules.([]redirectRule), nil
+	}
+	recordCacheLookup(ctx, "redirects", cacheMiss)
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	rules, invalid := parseRedirects(content)
+	for _, line := range invalid {
+		this.logger.Warn().Str("rule", line).Msg("Invalid redirect rule ignored")
+	}
+	this.cache(&this.redirects, key, rules, memoryCritical)
+	return rules, nil
+}
+
+// configFileRequested reports whether the request asks for the redirects file or
+// the header overrides of a directory, which are not served
+func (this *server) configFileRequested(resourcePath string) bool {
+	name := rootName(resourcePath)
+	if this.cfg.RedirectsFile != "" && name == rootName(this.cfg.RedirectsFile) {
+		return true
+	}
+	return this.cfg.DirectoryHeadersFile != "" && path.Base(name) == this.cfg.DirectoryHeadersFile
+}
+
+// applyRedirects applies the first rule matching the path, the rules without force
+// apply only to the paths not found in the roots. The redirects respond with the
+// location of the target, the rewrites serve the target in place, and the other
+// statuses serve the target with the status, e.g. the custom 404 pages.
+func (this *server) applyRedirects(ctx context.Context, w http.ResponseWriter, req *http.Request, resourcePath string, forced bool) (bool, error) {
+	if this.cfg.RedirectsFile == "" {
+		return false, nil
+	}
+	rules, err := this.redirectRules(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// the repeated slashes would turn the targets to protocol relative urls
+	requestPath := normalizePath("/" + resourcePath)
+	for _, rule := range rules {
+		if rule.force != forced {
+			continue
+		}
+		target, ok := rule.target(requestPath)
+		if !ok {
+			continue
+		}
+		debugLookup(ctx, "redirect %v %v %v", rule.from, target, rule.status)
+
+		switch {
+		case rule.status >= 300 && rule.status < 400:
+			location := target
+			if strings.HasPrefix(target, "/") {
+				location = strings.TrimSuffix(this.publicBaseUrl(ctx), "/") + target
+			}
+			http.Redirect(w, req, location, rule.status)
+			return true, nil
+		case rule.status == http.StatusOK:
+			found, err := this.findAndServeHinted(ctx, target, w, req)
+			if found || err != nil {
+				return found, err
+			}
+		default:
+			found, err := this.findAndServeHinted(ctx, target, &statusOverride{responseWriterPassthrough: responseWriterPassthrough{w}, status: rule.status}, req)
+			if found || err != nil {
+				return found, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// statusOverride replaces the success status of the response, e.g. to serve
+// the custom 404 page
+type statusOverride struct {
+	responseWriterPassthrough
+	status  int
+	written bool
+}
+
+func (this *statusOverride) WriteHeader(status int) {
+	if informational(status) {
+		this.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if status == http.StatusOK {
+		status = this.status
+	}
+	this.written = true
+	this.ResponseWriter.WriteHeader(status)
+}
+
+func (this *statusOverride) Write(b []byte) (int, error) {
+	if !this.written {
+		this.WriteHeader(http.StatusOK)
+	}
+	return this.ResponseWriter.Write(b)
+}
+
+// ReadFrom keeps the zero-copy path of the underlying writer
+func (this *statusOverride) ReadFrom(src io.Reader) (int64, error) {
+	if !this.written {
+		this.WriteHeader(http.StatusOK)
+	}
+	return this.readFrom(src)
+}
+
+// redirectStatuses are the statuses of the configured redirects
+var redirectStatuses = []int{
+	http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+	http.StatusTemporaryRedirect, http.StatusPermanentRedirect,
+}
+
+// configuredRedirect is the compiled redirect of the configuration
+type configuredRedirect struct {
+	cfg   Redirect
+	regex *regexp.Regexp
+}
+
+// newRedirects compiles the configured redirects
+func (this *server) newRedirects() ([]configuredRedirect, error) {
+	redirects := make([]configuredRedirect, 0, len(this.cfg.Redirects))
+	for _, cfg := range this.cfg.Redirects {
+		regex, err := regexp.Compile(cfg.Regexp)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Status == 0 {
+			cfg.Status = http.StatusMovedPermanently
+		}
+		redirects = append(redirects, configuredRedirect{cfg: cfg, regex: regex})
+	}
+	return redirects, nil
+}
+
+// configuredRedirectLocation returns the location and the status of the first
+// configured redirect matching the request, the query is kept unless the target
+// has its own
+func (this *server) configuredRedirectLocation(req *http.Request) (string, int, bool) {
+	for _, redirect := range this.configuredRedirects {
+		subject := req.URL.Path
+		if redirect.cfg.Host {
+			subject = strings.ToLower(req.Host) + subject
+		}
+		match := redirect.regex.FindStringSubmatchIndex(subject)
+		if match == nil {
+			continue
+		}
+		location := string(redirect.regex.ExpandString(nil, redirect.cfg.Target, subject, match))
+		if req.URL.RawQuery != "" && !strings.Contains(location, "?") {
+			location += "?" + req.URL.RawQuery
+		}
+		return location, redirect.cfg.Status, true
+	}
+	return "", 0, false
+}
+
+// configuredRewrite is the compiled rewrite of the configuration
+type configuredRewrite struct {
+	cfg   Rewrite
+	regex *regexp.Regexp
+}
+
+// newRewrites compiles the configured rewrites
+func (this *server) newRewrites() ([]configuredRewrite, error) {
+	rewrites := make([]configuredRewrite, 0, len(this.cfg.Rewrites))
+	for _, cfg := range this.cfg.Rewrites {
+		regex, err := regexp.Compile(cfg.Regexp)
+		if err != nil {
+			return nil, err
+		}
+		rewrites = append(rewrites, configuredRewrite{cfg: cfg, regex: regex})
+	}
+	return rewrites, nil
+}
+
+// rewritePath returns the path of the first configured rewrite matching the request path
+func (this *server) rewritePath(requestPath string) (string, bool) {
+	for _, rewrite := range this.rewrites {
+		match := rewrite.regex.FindStringSubmatchIndex(requestPath)
+		if match == nil {
+			continue
+		}
+		// the expanded groups may contain the repeated slashes or the dot segments
+		return normalizePath(string(rewrite.regex.ExpandString(nil, rewrite.cfg.Target, requestPath, match))), true
+	}
+	return "", false
+}
+
+// rewriteRequest returns the shallow copy of the request with the rewritten path
+func rewriteRequest(req *http.Request, rewritten string) *http.Request {
+	clone := *req
+	url := *req.URL
+	url.Path, url.RawPath = rewritten, ""
+	clone.URL = &url
+	return &clone
+}
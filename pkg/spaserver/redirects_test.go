@@ -0,0 +1,235 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type RedirectsTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestRedirectsTestSuite(t *testing.T) {
+	suite.Run(t, new(RedirectsTestSuite))
+}
+
+func (suite *RedirectsTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	for name, content := range map[string]string{
+		"index.html":   "app",
+		"profile.html": "profile",
+		"404.html":     "missing",
+		"old.html":     "old",
+		"_redirects": `
+# migrated from netlify
+/blog/*          /news/:splat   301
+/users/:id       /profile.html  200
+/old.html        /              302!
+/store id=:id    /products/:id  301
+/docs/*          /404.html      404
+`,
+	} {
+		suite.Require().Nil(os.WriteFile(path.Join(rootDir, name), []byte(content), 0644))
+	}
+	sut, err := newServer(Config{
+		RootDirs:      []string{rootDir},
+		BaseURL:       "/app/",
+		RedirectsFile: "_redirects",
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *RedirectsTestSuite) get(target string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, httptest.NewRequest("GET", target, nil))
+	return rr
+}
+
+func (suite *RedirectsTestSuite) Test_Splat_redirect_Then_location_under_base_url() {
+
+	// when
+	rr := suite.get("/app/blog/2024/hello")
+
+	// then
+	suite.Equal(http.StatusMovedPermanently, rr.Code)
+	suite.Equal("/app/news/2024/hello", rr.Header().Get("Location"))
+}
+
+func (suite *RedirectsTestSuite) Test_Rewrite_Then_target_served_in_place() {
+
+	// when
+	rr := suite.get("/app/users/42")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("profile", rr.Body.String())
+}
+
+func (suite *RedirectsTestSuite) Test_Forced_rule_Then_existing_file_shadowed() {
+
+	// when
+	rr := suite.get("/app/old.html")
+
+	// then
+	suite.Equal(http.StatusFound, rr.Code)
+	suite.Equal("/app/", rr.Header().Get("Location"))
+}
+
+func (suite *RedirectsTestSuite) Test_Status_rule_Then_target_served_with_status() {
+
+	// when
+	rr := suite.get("/app/docs/intro")
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+	suite.Equal("missing", rr.Body.String())
+}
+
+func (suite *RedirectsTestSuite) Test_Unmatched_path_Then_fallback() {
+
+	// when
+	rr := suite.get("/app/some/route")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("app", rr.Body.String())
+}
+
+func (suite *RedirectsTestSuite) Test_Redirects_file_Then_not_served() {
+
+	// when
+	rr := suite.get("/app/_redirects")
+
+	// then
+	suite.NotContains(rr.Body.String(), "netlify")
+}
+
+func (suite *RedirectsTestSuite) Test_Unsupported_rules_Then_reported_invalid() {
+
+	// when
+	rules, invalid := parseRedirects([]byte("/a /b\n/store id=:id /p/:id\n/c https://example.com/c 200\n/d /e abc"))
+
+	// then
+	suite.Len(rules, 1)
+	suite.Len(invalid, 3)
+}
+
+func (suite *RedirectsTestSuite) configured(redirects ...Redirect) {
+	cfg := suite.sut.cfg
+	cfg.Redirects = redirects
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *RedirectsTestSuite) Test_Configured_redirect_Then_capture_groups_expanded() {
+
+	// given
+	suite.configured(Redirect{Regexp: `^/app/legacy/(?P<page>[^/]+)$`, Target: "/app/pages/${page}", Status: http.StatusPermanentRedirect})
+
+	// when
+	rr := suite.get("/app/legacy/about?tab=1")
+
+	// then
+	suite.Equal(http.StatusPermanentRedirect, rr.Code)
+	suite.Equal("/app/pages/about?tab=1", rr.Header().Get("Location"))
+}
+
+func (suite *RedirectsTestSuite) Test_Host_redirect_Then_canonical_host() {
+
+	// given
+	suite.configured(Redirect{Regexp: `^www\.example\.com(/.*)$`, Host: true, Target: "https://example.com$1"})
+	req := httptest.NewRequest("GET", "/app/profile.html", nil)
+	req.Host = "WWW.example.com"
+	rr := httptest.NewRecorder()
+
+	// when
+	suite.sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusMovedPermanently, rr.Code)
+	suite.Equal("https://example.com/app/profile.html", rr.Header().Get("Location"))
+}
+
+func (suite *RedirectsTestSuite) Test_Configured_redirect_Then_applied_before_existing_file() {
+
+	// given
+	suite.configured(Redirect{Regexp: `^(/app/[^.]*[^/.])$`, Target: "$1/", Status: http.StatusFound})
+
+	// when
+	rr := suite.get("/app/users")
+
+	// then
+	suite.Equal(http.StatusFound, rr.Code)
+	suite.Equal("/app/users/", rr.Header().Get("Location"))
+	suite.Equal(http.StatusOK, suite.get("/app/profile.html").Code)
+}
+
+func (suite *RedirectsTestSuite) Test_Invalid_configured_redirect_Then_invalid() {
+
+	// when
+	_, err := newServer(Config{Redirects: []Redirect{{Regexp: "(", Status: 200}}}, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "redirects[0].regexp")
+	suite.ErrorContains(err, "redirects[0].target: the target is required")
+	suite.ErrorContains(err, "redirects[0].status: 200 is not a redirect status")
+}
+
+func (suite *RedirectsTestSuite) Test_Rewrite_configured_Then_target_served_without_redirect() {
+
+	// given
+	cfg := suite.sut.cfg
+	cfg.Rewrites = []Rewrite{{Regexp: `^/v2/app/(.*)$`, Target: "/app/$1"}}
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+
+	// when
+	rr := suite.get("/v2/app/profile.html")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("profile", rr.Body.String())
+	suite.Empty(rr.Header().Get("Location"))
+}
+
+func (suite *RedirectsTestSuite) Test_Invalid_rewrite_Then_invalid() {
+
+	// when
+	_, err := newServer(Config{Rewrites: []Rewrite{{Regexp: "(", Target: "app/$1"}}}, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "rewrites[0].regexp")
+	suite.ErrorContains(err, `rewrites[0].target: the target "app/$1" must be an absolute path`)
+}
+
+func (suite *RedirectsTestSuite) Test_File_copied_through_status_override_Then_underlying_ReadFrom_used() {
+
+	// given
+	file, err := os.Open("test/data/testfile.json")
+	suite.Require().Nil(err)
+	defer file.Close()
+	underlying := &readerFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+	writer := &statusOverride{responseWriterPassthrough: responseWriterPassthrough{underlying}, status: http.StatusNotFound}
+
+	// when
+	_, err = writer.ReadFrom(file)
+
+	// then
+	suite.Nil(err)
+	suite.Equal(http.StatusNotFound, underlying.Code)
+	suite.Equal(testfile_json, underlying.Body.String())
+	suite.Equal([]io.Reader{file}, underlying.sources)
+}
@@ -0,0 +1,159 @@
+package spaserver
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// regexList matches a value against any of the regexps
+type regexList []*regexp.Regexp
+
+func (this regexList) matches(value string) bool {
+	for _, regex := range this {
+		if regex.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// headersMatcher sets the headers of the paths matching the regexp
+type headersMatcher struct {
+	regex   *regexp.Regexp
+	headers map[string]string
+}
+
+// etagMatcher selects the ETag strategy of the paths matching the regexp
+type etagMatcher struct {
+	regex *regexp.Regexp
+	mode  string
+}
+
+// bandwidthMatcher limits the bandwidth of the paths matching the regexp
+type bandwidthMatcher struct {
+	regex *regexp.Regexp
+	limit int64
+}
+
+// templateMatcher replaces the matches of the regexp in the metric paths
+type templateMatcher struct {
+	regex       *regexp.Regexp
+	replacement string
+}
+
+// compiledRegexes are the configured regexps compiled once, instead of on each
+// request. The lists keep the configured order, the regexps configured as the
+// keys of the maps are ordered by their patterns, so that the overlapping
+// regexps apply in the same order on each request.
+type compiledRegexes struct {
+	notFound           regexList
+	traceExclude       regexList
+	logExclude         regexList
+	signedUrl          regexList
+	authExclude        regexList
+	directoryListing   regexList
+	tenant             *regexp.Regexp
+	hashedFile         *regexp.Regexp
+	prerenderUserAgent *regexp.Regexp
+	headersPerPath     []headersMatcher
+	etagPerPath        []etagMatcher
+	bandwidthPerPath   []bandwidthMatcher
+	// clientHints are the regexps of the client hints variants by index, nil if not set
+	clientHints   []*regexp.Regexp
+	pathTemplates []templateMatcher
+}
+
+// neverMatches replaces the invalid regexps
+var neverMatches = regexp.MustCompile(`[^\s\S]`)
+
+// compileRegexes compiles the configured regexps, the invalid regexps are
+// reported and never match
+func compileRegexes(cfg Config) (*compiledRegexes, error) {
+	errs := []error{}
+	compile := func(key string, pattern string) *regexp.Regexp {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", key, err))
+			return neverMatches
+		}
+		return regex
+	}
+	list := func(key string, patterns []string) regexList {
+		regexes := regexList{}
+		for _, pattern := range patterns {
+			regexes = append(regexes, compile(key, pattern))
+		}
+		return regexes
+	}
+
+	compiled := &compiledRegexes{
+		notFound:         list("no-fallback-regexp", cfg.NotFoundRegexs),
+		traceExclude:     list("trace-exclude-regexp", cfg.TraceExcludeRegexs),
+		logExclude:       list("log-exclude-regexp", cfg.LogExcludeRegexs),
+		signedUrl:        list("signed-url-regexp", cfg.SignedUrlRegexs),
+		authExclude:      list("auth-exclude-regexp", cfg.AuthExcludeRegexs),
+		directoryListing: list("directory-listing-regexp", cfg.DirectoryListingRegexs),
+		tenant:           compile("tenant-regexp", cfg.TenantRegex),
+	}
+	if cfg.HashedFileRegex != "" {
+		compiled.hashedFile = compile("hashed-file-regexp", cfg.HashedFileRegex)
+	}
+	if cfg.PrerenderUserAgentRegex != "" {
+		compiled.prerenderUserAgent = compile("prerender-user-agent-regexp", cfg.PrerenderUserAgentRegex)
+	}
+	for _, pattern := range sortedKeys(cfg.HeadersPerPathRegex) {
+		compiled.headersPerPath = append(compiled.headersPerPath, headersMatcher{
+			regex:   compile(fmt.Sprintf("headers-per-regexp[%v]", pattern), pattern),
+			headers: cfg.HeadersPerPathRegex[pattern],
+		})
+	}
+	for _, pattern := range sortedKeys(cfg.EtagPerPathRegex) {
+		compiled.etagPerPath = append(compiled.etagPerPath, etagMatcher{
+			regex: compile(fmt.Sprintf("etag-per-regexp[%v]", pattern), pattern),
+			mode:  cfg.EtagPerPathRegex[pattern],
+		})
+	}
+	for _, pattern := range sortedKeys(cfg.BandwidthLimitPerRegex) {
+		compiled.bandwidthPerPath = append(compiled.bandwidthPerPath, bandwidthMatcher{
+			regex: compile(fmt.Sprintf("bandwidth-limit-per-regexp[%v]", pattern), pattern),
+			limit: cfg.BandwidthLimitPerRegex[pattern],
+		})
+	}
+	for i, variant := range cfg.ClientHintsVariants {
+		var regex *regexp.Regexp
+		if variant.Regexp != "" {
+			regex = compile(fmt.Sprintf("client-hints-variants[%v].regexp", i), variant.Regexp)
+		}
+		compiled.clientHints = append(compiled.clientHints, regex)
+	}
+	for i, template := range cfg.MetricsPathTemplates {
+		compiled.pathTemplates = append(compiled.pathTemplates, templateMatcher{
+			regex:       compile(fmt.Sprintf("metrics-path-templates[%v].regexp", i), template.Regexp),
+			replacement: template.Replacement,
+		})
+	}
+	return compiled, errors.Join(errs...)
+}
+
+// regexes returns the compiled regexps of the configuration, compiled on the
+// first use if the server was not created by newServer
+func (this *server) regexes() *compiledRegexes {
+	this.regexesOnce.Do(func() {
+		if this.compiled == nil {
+			this.compiled, _ = compileRegexes(this.cfg)
+		}
+	})
+	return this.compiled
+}
+
+// sortedKeys returns the keys of the map in the ascending order
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
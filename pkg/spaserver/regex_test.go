@@ -0,0 +1,116 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type RegexTestSuite struct {
+	suite.Suite
+}
+
+func TestRegexTestSuite(t *testing.T) {
+	suite.Run(t, new(RegexTestSuite))
+}
+
+func (suite *RegexTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+}
+
+func (suite *RegexTestSuite) Test_Invalid_regexps_Then_reported_and_never_match() {
+
+	// when
+	compiled, err := compileRegexes(Config{
+		NotFoundRegexs:      []string{`(`, `\.js$`},
+		HeadersPerPathRegex: map[string]map[string]string{`[`: {"X-Test": "1"}},
+		ClientHintsVariants: []ClientHintsVariant{{Regexp: `(`}, {}},
+	})
+
+	// then
+	suite.ErrorContains(err, "no-fallback-regexp: error parsing regexp")
+	suite.ErrorContains(err, "headers-per-regexp[[]: error parsing regexp")
+	suite.ErrorContains(err, "client-hints-variants[0].regexp: error parsing regexp")
+	suite.True(compiled.notFound.matches("/main.js"))
+	suite.False(compiled.notFound.matches("/("))
+	suite.False(compiled.headersPerPath[0].regex.MatchString(""))
+	suite.False(compiled.clientHints[0].MatchString("index.html"))
+	suite.Nil(compiled.clientHints[1])
+}
+
+func (suite *RegexTestSuite) Test_Map_regexps_Then_ordered_by_pattern() {
+
+	// when
+	compiled, err := compileRegexes(Config{EtagPerPathRegex: map[string]string{
+		`^/b`: etagWeak, `^/a`: etagStrong, `^/`: etagNone,
+	}})
+
+	// then
+	suite.Nil(err)
+	patterns := []string{}
+	for _, matcher := range compiled.etagPerPath {
+		patterns = append(patterns, matcher.regex.String())
+	}
+	suite.Equal([]string{`^/`, `^/a`, `^/b`}, patterns)
+}
+
+func (suite *RegexTestSuite) Test_Invalid_regexp_Then_server_not_created() {
+
+	// when
+	_, err := newServer(Config{RootDirs: []string{suite.T().TempDir()}, LogExcludeRegexs: []string{`(`}}, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "invalid configuration")
+}
+
+func (suite *RegexTestSuite) Test_Server_literal_Then_compiled_on_first_use() {
+
+	// given
+	sut := &server{cfg: Config{AuthMode: authBasic, AuthExcludeRegexs: []string{`^/public/`}}}
+
+	// when
+	required := sut.authRequired("/private/index.html")
+	excluded := sut.authRequired("/public/index.html")
+
+	// then
+	suite.True(required)
+	suite.False(excluded)
+}
+
+// BenchmarkRegexHotPath serves the requests matched by the typical regexps of
+// the fallback, the headers, the logs and the traces
+func BenchmarkRegexHotPath(b *testing.B) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	_, filename, _, _ := runtime.Caller(0)
+	sut, err := newServer(Config{
+		RootDirs:       []string{path.Join(path.Dir(filename), "test/data")},
+		NotFoundRegexs: []string{`\.js$`, `\.css$`, `\.json$`, `\.(png|jpe?g|gif|svg|webp)$`, `\.(woff2?|ttf)$`},
+		HeadersPerPathRegex: map[string]map[string]string{
+			`\.html$`:    {"Cache-Control": "no-cache"},
+			`^assets/`:   {"Cache-Control": "public, max-age=31536000, immutable"},
+			`\.json$`:    {"Cache-Control": "no-store"},
+			`^sw\.js$`:   {"Service-Worker-Allowed": "/"},
+			`\.(js|css)`: {"X-Content-Type-Options": "nosniff"},
+		},
+		LogExcludeRegexs:   []string{`^/healthz$`, `^/assets/`},
+		TraceExcludeRegexs: []string{`^/healthz$`, `^/assets/`},
+	}, zerolog.New(io.Discard))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, target := range []string{"/testfile.json", "/missing.js", "/users/1"} {
+			req := httptest.NewRequest("GET", target, nil)
+			req.Header.Set("Accept", "text/html")
+			sut.handler(context.Background(), httptest.NewRecorder(), req)
+		}
+	}
+}
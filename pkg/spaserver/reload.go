@@ -0,0 +1,72 @@
+package spaserver
+
+import (
+	"context"
+	"io/fs"
+	"slices"
+	"time"
+)
+
+// reopenableRoot serves the local root from the filesystem swapped atomically
+// when the root is opened again
+func (this *server) reopenableRoot(rootDir string, fsys fs.FS) (fs.FS, refreshFunc) {
+	current := &swapFS{}
+	current.swap(fsys)
+	return current, func(ctx context.Context) (bool, error) {
+		fsys, _, err := this.openRoot(rootDir)
+		if err != nil {
+			return false, err
+		}
+		current.swap(fsys)
+		return true, nil
+	}
+}
+
+// invalidateCaches drops the cached content of the roots and the opened versions and tenants
+func (this *server) invalidateCaches() {
+	clearMap(&this.transforms)
+	clearMap(&this.cspHashes)
+	clearMap(&this.preloads)
+	clearMap(&this.hashedFiles)
+	clearMap(&this.redirects)
+	clearMap(&this.sitemaps)
+	clearMap(&this.dirHeaders)
+	clearMap(&this.etags)
+	clearMap(&this.buildTimes)
+	this.clearFileStats()
+	clearMap(&this.versions)
+	clearMap(&this.tenants)
+}
+
+// reload invalidates the caches and opens the roots again, e.g. after the files
+// were swapped on the shared volume. The local roots are indexed, verified and
+// snapshotted again, the remote roots are synced. A root failing to reload keeps
+// serving its previous content.
+func (this *server) reload(ctx context.Context) {
+	started := time.Now()
+	if _, err := this.assetRoots(); err != nil {
+		return
+	}
+
+	this.invalidateCaches()
+
+	all := append(append(slices.Clip(this.roots), this.scheduled.roots...), this.rolloutRoots...)
+	failed := 0
+	for _, root := range all {
+		if root.refresh != nil {
+			this.syncRoot(ctx, root)
+			continue
+		}
+		if _, err := root.reopen(ctx); err != nil {
+			failed++
+			this.recent.add("root_reload_failure")
+			this.logger.Warn().Err(err).Str("root", rootLabel(root.name)).Msg("Cannot reload root")
+		}
+	}
+	this.logger.Info().Int("roots", len(all)).Int("failed", failed).Dur("duration", time.Since(started)).
+		Msg("Caches invalidated and roots reloaded")
+
+	for _, site := range this.sites {
+		site.server.reload(ctx)
+	}
+}
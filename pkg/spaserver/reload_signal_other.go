@@ -0,0 +1,11 @@
+//go:build windows || plan9
+
+package spaserver
+
+import "os"
+
+// reloadSignals are not supported on this platform
+var reloadSignals = []os.Signal{}
+
+// configReloadSignals are not supported on this platform
+var configReloadSignals = []os.Signal{}
@@ -0,0 +1,14 @@
+//go:build !windows && !plan9
+
+package spaserver
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignals invalidate the caches and reload the roots
+var reloadSignals = []os.Signal{syscall.SIGUSR1}
+
+// configReloadSignals reload the configuration
+var configReloadSignals = []os.Signal{syscall.SIGHUP}
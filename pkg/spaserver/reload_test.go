@@ -0,0 +1,74 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReloadTestSuite struct {
+	suite.Suite
+	rootDir string
+	sut     *server
+}
+
+func TestReloadTestSuite(t *testing.T) {
+	suite.Run(t, new(ReloadTestSuite))
+}
+
+func (suite *ReloadTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "index.html"), []byte("<p>v1</p>"), 0644))
+	sut, err := newServer(Config{
+		RootDirs:              []string{suite.rootDir},
+		BaseURL:               "/",
+		SnapshotEnabled:       true,
+		ContentSecurityPolicy: "default-src 'self'",
+		CspInlineHashes:       true,
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *ReloadTestSuite) get() string {
+	req := httptest.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+	return rr.Body.String()
+}
+
+func (suite *ReloadTestSuite) Test_Reload_Then_swapped_files_served() {
+
+	// given
+	suite.Equal("<p>v1</p>", suite.get())
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "index.html"), []byte("<p>v2</p>"), 0644))
+	suite.Equal("<p>v1</p>", suite.get())
+
+	// when
+	suite.sut.reload(context.Background())
+
+	// then
+	suite.Equal("<p>v2</p>", suite.get())
+}
+
+func (suite *ReloadTestSuite) Test_Reload_failure_Then_previous_content_served() {
+
+	// given
+	suite.Require().Nil(os.RemoveAll(suite.rootDir))
+
+	// when
+	suite.sut.reload(context.Background())
+
+	// then
+	suite.Equal("<p>v1</p>", suite.get())
+	suite.Equal(int64(1), suite.sut.recent.snapshot()["root_reload_failure"])
+}
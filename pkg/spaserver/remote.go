@@ -0,0 +1,306 @@
+package spaserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// refreshFunc updates the remote root, returns true if the served content changed
+type refreshFunc func(ctx context.Context) (bool, error)
+
+// fetchFunc fetches the remote content and its revision, e.g. the digest of the
+// archive, returns nil filesystem if the content did not change since the last fetch
+type fetchFunc func(ctx context.Context) (fs.FS, string, error)
+
+// openRemoteRoot fetches the remote content and serves it from the filesystem
+// swapped atomically on each refresh. The lazy root is fetched on first access.
+func openRemoteRoot(fetch func(ctx context.Context) (fs.FS, error), lazy bool) (fs.FS, refreshFunc, error) {
+	current := &swapFS{}
+	if lazy {
+		current.swap(&lazyFS{open: func() (fs.FS, error) {
+			fsys, err := fetch(context.Background())
+			if err == nil && fsys == nil {
+				err = fmt.Errorf("remote root fetched without content")
+			}
+			return fsys, err
+		}})
+	} else {
+		fsys, err := fetch(context.Background())
+		if err != nil {
+			return nil, nil, err
+		}
+		current.swap(fsys)
+	}
+
+	refresh := func(ctx context.Context) (bool, error) {
+		fsys, err := fetch(ctx)
+		if err != nil || fsys == nil {
+			return false, err
+		}
+		current.swap(fsys)
+		return true, nil
+	}
+	return current, refresh, nil
+}
+
+// swapFS serves the current filesystem, which is swapped atomically
+type swapFS struct {
+	current atomic.Pointer[fs.FS]
+}
+
+func (this *swapFS) swap(fsys fs.FS) {
+	this.current.Store(&fsys)
+}
+
+func (this *swapFS) Open(name string) (fs.File, error) {
+	return (*this.current.Load()).Open(name)
+}
+
+// syncRoots periodically refreshes the remote roots until the context is done
+func (this *server) syncRoots(ctx context.Context, interval time.Duration) {
+	if _, err := this.assetRoots(); err != nil {
+		return
+	}
+	// the scheduled roots are kept in sync before their activation
+	roots := append(slices.Clip(this.roots), this.scheduled.roots...)
+	roots = append(roots, this.rolloutRoots...)
+
+	registration, err := telemetry().meters.RegisterCallback(
+		func(_ context.Context, observer metric.Observer) error {
+			for _, root := range roots {
+				if root.refresh == nil {
+					continue
+				}
+				age := time.Since(time.Unix(0, root.synced.Load()))
+				observer.ObserveFloat64(telemetry().root_sync_age, age.Seconds(),
+					metric.WithAttributes(attribute.String("root", rootLabel(root.name))))
+				if revision := this.rootRevision(root.name); revision != "" {
+					observer.ObserveInt64(telemetry().root_revision, 1, metric.WithAttributes(
+						attribute.String("root", rootLabel(root.name)), attribute.String("revision", revision)))
+				}
+			}
+			return nil
+		},
+		telemetry().root_sync_age, telemetry().root_revision,
+	)
+	if err == nil {
+		defer registration.Unregister()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, root := range roots {
+			if root.refresh == nil {
+				continue
+			}
+			this.syncRoot(ctx, root)
+		}
+	}
+}
+
+func (this *server) syncRoot(ctx context.Context, root assetRoot) {
+	logger := this.logger.With().Str("root", rootLabel(root.name)).Logger()
+	changed, err := root.refresh(ctx)
+	if err != nil {
+		telemetry().root_sync_failures.Add(ctx, 1,
+			metric.WithAttributes(attribute.String("root", rootLabel(root.name))))
+		this.recent.add("root_sync_failure")
+		logger.Warn().Err(err).Msg("Cannot sync root")
+		return
+	}
+	root.synced.Store(time.Now().UnixNano())
+	if changed {
+		this.purgeFileStats(root.name)
+		logger.Info().Msg("Root content updated")
+	}
+}
+
+// rootRevision returns the revision of the served content of the remote root, empty if unknown
+func (this *server) rootRevision(name string) string {
+	if revision, ok := this.revisions.Load(name); ok {
+		return revision.(string)
+	}
+	return ""
+}
+
+// rootLabel removes credentials from the root urls, so that the root can be logged
+func rootLabel(name string) string {
+	for _, scheme := range []string{"", gitScheme} {
+		if !strings.HasPrefix(name, scheme) {
+			continue
+		}
+		if parsed, err := url.Parse(strings.TrimPrefix(name, scheme)); err == nil && parsed.User != nil {
+			parsed.User = nil
+			return scheme + parsed.String()
+		}
+	}
+	return name
+}
+
+// remoteClient bounds the requests of the remote roots, so that a stalled server
+// does not block the startup or the sync of the roots
+func remoteClient(cfg Config) *http.Client {
+	return &http.Client{Timeout: cfg.SyncTimeout}
+}
+
+// httpSource downloads the tar or zip archive from the url, conditional requests
+// are used to detect changes of the archive
+type httpSource struct {
+	url string
+	cfg Config
+
+	etag         string
+	lastModified string
+	archive      string
+}
+
+func (this *httpSource) fetch(ctx context.Context) (fs.FS, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, this.url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if this.etag != "" {
+		req.Header.Set("If-None-Match", this.etag)
+	}
+	if this.lastModified != "" {
+		req.Header.Set("If-Modified-Since", this.lastModified)
+	}
+
+	res, err := remoteClient(this.cfg).Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotModified {
+		return nil, "", nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("download of %v failed with status %v", rootLabel(this.url), res.Status)
+	}
+
+	ext := ".tar"
+	switch name := path.Base(req.URL.Path); {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		ext = ".tar.gz"
+	case strings.HasSuffix(name, ".zip"):
+		ext = ".zip"
+	}
+	archive, err := this.download(res.Body, ext)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if archive == this.archive {
+		this.etag = res.Header.Get("ETag")
+		this.lastModified = res.Header.Get("Last-Modified")
+		return nil, "", nil
+	}
+
+	if this.cfg.SyncChecksums {
+		if err := verifyChecksum(ctx, this.cfg, this.url, archive); err != nil {
+			os.Remove(archive)
+			return nil, "", fmt.Errorf("checksum verification of %v failed: %w", rootLabel(this.url), err)
+		}
+	}
+	if signatureVerified(this.cfg) {
+		if err := verifyBlobSignature(ctx, this.cfg, this.url, archive); err != nil {
+			os.Remove(archive)
+			return nil, "", fmt.Errorf("signature verification of %v failed: %w", rootLabel(this.url), err)
+		}
+	}
+	this.etag = res.Header.Get("ETag")
+	this.lastModified = res.Header.Get("Last-Modified")
+
+	fsys, err := openArchiveOrDir(archive, this.cfg.SymlinkPolicy)
+	if err != nil {
+		return nil, "", err
+	}
+	if this.archive != "" {
+		// in-flight requests keep the opened archive
+		os.Remove(this.archive)
+	}
+	this.archive = archive
+	return fsys, archiveRevision(archive), nil
+}
+
+// verifyChecksum compares the digest of the downloaded archive with the
+// checksum file of the url in the sha256sum format
+func verifyChecksum(ctx context.Context, cfg Config, archiveUrl string, archive string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, archiveUrl+".sha256", nil)
+	if err != nil {
+		return err
+	}
+	res, err := remoteClient(cfg).Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("download of checksum failed with status %v", res.Status)
+	}
+	content, err := io.ReadAll(io.LimitReader(res.Body, 4096))
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return fmt.Errorf("empty checksum file")
+	}
+	// the archives are named by their content hash
+	if expected, actual := "sha256:"+strings.ToLower(fields[0]), archiveRevision(archive); expected != actual {
+		return fmt.Errorf("checksum mismatch, expected %v, got %v", expected, actual)
+	}
+	return nil
+}
+
+// archiveRevision is the revision of the archive named by its content hash
+func archiveRevision(archive string) string {
+	name := filepath.Base(archive)
+	return "sha256:" + name[:strings.Index(name, ".")]
+}
+
+// download stores the archive in the cache directory, named by its content hash
+func (this *httpSource) download(body io.Reader, ext string) (string, error) {
+	if err := os.MkdirAll(this.cfg.SyncCacheDir, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(this.cfg.SyncCacheDir, ".download-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	digest := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, digest), body); err != nil {
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	archive := filepath.Join(this.cfg.SyncCacheDir, hex.EncodeToString(digest.Sum(nil))+ext)
+	return archive, os.Rename(tmp.Name(), archive)
+}
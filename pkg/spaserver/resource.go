@@ -0,0 +1,91 @@
+package spaserver
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// path of the namespace file mounted to pods with service account token
+var serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// kubernetesDetector detects the kubernetes resource attributes of the pod. The
+// attributes are read from the environment variables populated by the downward API:
+//
+//	env:
+//	- name: POD_NAME
+//	  valueFrom: { fieldRef: { fieldPath: metadata.name } }
+//	- name: POD_NAMESPACE
+//	  valueFrom: { fieldRef: { fieldPath: metadata.namespace } }
+//	- name: POD_UID
+//	  valueFrom: { fieldRef: { fieldPath: metadata.uid } }
+//	- name: NODE_NAME
+//	  valueFrom: { fieldRef: { fieldPath: spec.nodeName } }
+//	- name: CONTAINER_NAME
+//	  value: spa
+//
+// The pod name falls back to the hostname and the namespace to the service account namespace.
+type kubernetesDetector struct{}
+
+func (kubernetesDetector) Detect(ctx context.Context) (*resource.Resource, error) {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		// not running in kubernetes
+		return resource.Empty(), nil
+	}
+
+	attrs := []attribute.KeyValue{}
+
+	podName := os.Getenv("POD_NAME")
+	if podName == "" {
+		podName, _ = os.Hostname()
+	}
+	if podName != "" {
+		attrs = append(attrs, semconv.K8SPodName(podName))
+	}
+
+	namespace := os.Getenv("POD_NAMESPACE")
+	if namespace == "" {
+		if content, err := os.ReadFile(serviceAccountNamespaceFile); err == nil {
+			namespace = strings.TrimSpace(string(content))
+		}
+	}
+	if namespace != "" {
+		attrs = append(attrs, semconv.K8SNamespaceName(namespace))
+	}
+
+	if uid := os.Getenv("POD_UID"); uid != "" {
+		attrs = append(attrs, semconv.K8SPodUID(uid))
+	}
+
+	if node := os.Getenv("NODE_NAME"); node != "" {
+		attrs = append(attrs, semconv.K8SNodeName(node))
+	}
+
+	if container := os.Getenv("CONTAINER_NAME"); container != "" {
+		attrs = append(attrs, semconv.K8SContainerName(container))
+	}
+
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...), nil
+}
+
+// telemetryResource creates the resource describing this service instance
+func telemetryResource(ctx context.Context, cfg Config) (*resource.Resource, error) {
+	detectors := []resource.Detector{}
+	if !cfg.KubernetesDetectionDisabled {
+		detectors = append(detectors, kubernetesDetector{})
+	}
+
+	detected, err := resource.New(ctx,
+		resource.WithDetectors(detectors...),
+		// explicitly configured attributes take precedence
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return resource.Merge(resource.Default(), detected)
+}
@@ -0,0 +1,65 @@
+package spaserver
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+type ResourceTestSuite struct {
+	suite.Suite
+}
+
+func TestResourceTestSuite(t *testing.T) {
+	suite.Run(t, new(ResourceTestSuite))
+}
+
+func (suite *ResourceTestSuite) Test_Running_in_kubernetes_Then_pod_attributes_detected() {
+
+	// given
+	suite.T().Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	suite.T().Setenv("POD_NAME", "spa-7d9f-x2k4")
+	suite.T().Setenv("POD_NAMESPACE", "")
+	suite.T().Setenv("NODE_NAME", "node-1")
+	suite.T().Setenv("CONTAINER_NAME", "spa")
+
+	namespaceFile := path.Join(suite.T().TempDir(), "namespace")
+	suite.Nil(os.WriteFile(namespaceFile, []byte("frontend\n"), 0644))
+	original := serviceAccountNamespaceFile
+	serviceAccountNamespaceFile = namespaceFile
+	defer func() { serviceAccountNamespaceFile = original }()
+
+	// when
+	res, err := kubernetesDetector{}.Detect(context.Background())
+
+	// then
+	suite.Nil(err)
+	suite.Equal(semconv.SchemaURL, res.SchemaURL())
+	attrs := res.Set()
+	value, _ := attrs.Value(semconv.K8SPodNameKey)
+	suite.Equal("spa-7d9f-x2k4", value.AsString())
+	value, _ = attrs.Value(semconv.K8SNamespaceNameKey)
+	suite.Equal("frontend", value.AsString())
+	value, _ = attrs.Value(semconv.K8SNodeNameKey)
+	suite.Equal("node-1", value.AsString())
+	value, _ = attrs.Value(semconv.K8SContainerNameKey)
+	suite.Equal("spa", value.AsString())
+}
+
+func (suite *ResourceTestSuite) Test_Not_running_in_kubernetes_Then_empty_resource() {
+
+	// given
+	suite.T().Setenv("KUBERNETES_SERVICE_HOST", "")
+	suite.T().Setenv("POD_NAME", "spa-7d9f-x2k4")
+
+	// when
+	res, err := kubernetesDetector{}.Detect(context.Background())
+
+	// then
+	suite.Nil(err)
+	suite.Equal(0, res.Len())
+}
@@ -0,0 +1,95 @@
+package spaserver
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// responseWriterPassthrough is embedded by the wrappers of the response writer,
+// so that the http.ResponseController reaches the underlying writer and the
+// bodies keep its zero-copy path, e.g. sendfile of the opened files
+type responseWriterPassthrough struct {
+	http.ResponseWriter
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer
+func (this responseWriterPassthrough) Unwrap() http.ResponseWriter {
+	return this.ResponseWriter
+}
+
+// readFrom copies the body to the underlying writer, with its ReadFrom if any
+func (this responseWriterPassthrough) readFrom(src io.Reader) (int64, error) {
+	if readerFrom, ok := this.ResponseWriter.(io.ReaderFrom); ok {
+		return readerFrom.ReadFrom(src)
+	}
+	return copyWrites(this.ResponseWriter, src)
+}
+
+// copyWrites copies the body with the Write of the writer, its ReadFrom is hidden
+// from io.Copy, which would call it again
+func copyWrites(w io.Writer, src io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{w}, src)
+}
+
+// statusRecorder captures the status code and the count of bytes written to the wrapped response writer
+type statusRecorder struct {
+	responseWriterPassthrough
+	status  int
+	written int64
+	// beforeHeaders is called with the status before the headers are written, nil if none
+	beforeHeaders func(status int)
+}
+
+// record records the first status, before the headers are written
+func (this *statusRecorder) record(status int) {
+	if this.status != 0 {
+		return
+	}
+	this.status = status
+	if this.beforeHeaders != nil {
+		this.beforeHeaders(status)
+	}
+}
+
+func (this *statusRecorder) WriteHeader(status int) {
+	if !informational(status) {
+		this.record(status)
+	}
+	this.ResponseWriter.WriteHeader(status)
+}
+
+func (this *statusRecorder) Write(b []byte) (int, error) {
+	this.record(http.StatusOK)
+	n, err := this.ResponseWriter.Write(b)
+	this.written += int64(n)
+	return n, err
+}
+
+// ReadFrom keeps the zero-copy path of the underlying writer, which copies the
+// opened files to the connection with sendfile
+func (this *statusRecorder) ReadFrom(src io.Reader) (int64, error) {
+	this.record(http.StatusOK)
+	n, err := this.readFrom(src)
+	this.written += n
+	return n, err
+}
+
+// Status returns the recorded status code, http.StatusOK if nothing was written yet
+func (this *statusRecorder) Status() int {
+	if this.status == 0 {
+		return http.StatusOK
+	}
+	return this.status
+}
+
+// informational reports whether the status is an interim response preceding
+// the final one, e.g. 103 Early Hints
+func informational(status int) bool {
+	return status >= 100 && status < 200 && status != http.StatusSwitchingProtocols
+}
+
+// statusClass returns the class of the status code, e.g. `2xx` for 200
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
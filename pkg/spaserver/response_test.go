@@ -0,0 +1,67 @@
+package spaserver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ResponseTestSuite struct {
+	suite.Suite
+}
+
+func TestResponseTestSuite(t *testing.T) {
+	suite.Run(t, new(ResponseTestSuite))
+}
+
+// readerFromRecorder records the sources copied with ReadFrom
+type readerFromRecorder struct {
+	*httptest.ResponseRecorder
+	sources []io.Reader
+}
+
+func (this *readerFromRecorder) ReadFrom(src io.Reader) (int64, error) {
+	this.sources = append(this.sources, src)
+	return io.Copy(this.ResponseRecorder, src)
+}
+
+func (suite *ResponseTestSuite) Test_File_served_through_recorders_Then_underlying_ReadFrom_used() {
+
+	// given
+	file, err := os.Open("test/data/testfile.json")
+	suite.Require().Nil(err)
+	defer file.Close()
+	underlying := &readerFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+	sut := &statusRecorder{responseWriterPassthrough: responseWriterPassthrough{&statusRecorder{responseWriterPassthrough: responseWriterPassthrough{underlying}}}}
+	req := httptest.NewRequest("GET", "/testfile.json", nil)
+
+	// when
+	http.ServeContent(sut, req, "testfile.json", time.Time{}, file)
+
+	// then
+	suite.Len(underlying.sources, 1)
+	suite.Equal(testfile_json, underlying.Body.String())
+	suite.Equal(int64(len(testfile_json)), sut.written)
+	suite.Equal(http.StatusOK, sut.Status())
+}
+
+func (suite *ResponseTestSuite) Test_Writer_without_ReadFrom_Then_copied() {
+
+	// given
+	underlying := httptest.NewRecorder()
+	sut := &statusRecorder{responseWriterPassthrough: responseWriterPassthrough{underlying}}
+
+	// when
+	n, err := sut.ReadFrom(strings.NewReader("content"))
+
+	// then
+	suite.Nil(err)
+	suite.Equal(int64(7), n)
+	suite.Equal("content", underlying.Body.String())
+}
@@ -0,0 +1,88 @@
+package spaserver
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// root-absolute urls of the html attributes, e.g. `src="/assets/main.js"`
+	htmlUrlRegex = regexp.MustCompile(`((?:src|href|action|poster)\s*=\s*["'])(/[^"']*)`)
+	// root-absolute urls of the css, e.g. `url(/assets/font.woff2)`
+	cssUrlRegex = regexp.MustCompile(`(url\(\s*["']?)(/[^)"']*)`)
+	// base and head tags of the html documents with their attributes
+	baseTagRegex  = regexp.MustCompile(`(?is)<base(\s[^>]*)?>`)
+	headTagRegex  = regexp.MustCompile(`(?is)<head(\s[^>]*)?>`)
+	hrefAttrRegex = regexp.MustCompile(`(?is)\shref\s*=\s*("[^"]*"|'[^']*'|[^\s>]*)`)
+)
+
+// rewritesUrls returns true if the root-absolute urls of the resource
+// are rewritten to the public base url
+func (this *server) rewritesUrls(ctx context.Context, resourcePath string) bool {
+	if !this.cfg.RewriteAbsoluteUrls || strings.TrimSuffix(this.publicBaseUrl(ctx), "/") == "" {
+		return false
+	}
+	return isHtml(resourcePath) || strings.EqualFold(path.Ext(resourcePath), ".css")
+}
+
+// rewriteUrls prefixes the root-absolute urls of the html or css content with
+// the public base url, so that the bundles built for `/` work under the base url
+func (this *server) rewriteUrls(ctx context.Context, name string, content []byte) []byte {
+	prefix := escapeUrlPath(strings.TrimSuffix(this.publicBaseUrl(ctx), "/"))
+	rewrite := func(regex *regexp.Regexp) {
+		content = regex.ReplaceAllFunc(content, func(match []byte) []byte {
+			groups := regex.FindSubmatch(match)
+			url := string(groups[2])
+			if strings.HasPrefix(url, "//") || url == prefix || strings.HasPrefix(url, prefix+"/") {
+				// protocol relative urls and urls already under the base url are kept
+				return match
+			}
+			return append(append([]byte{}, groups[1]...), prefix+url...)
+		})
+	}
+	if strings.EqualFold(path.Ext(name), ".css") {
+		rewrite(cssUrlRegex)
+	} else {
+		rewrite(htmlUrlRegex)
+		// inline styles
+		rewrite(cssUrlRegex)
+	}
+	return content
+}
+
+// rewritesBaseHref returns true if the base href of the html document is set to
+// the public base url
+func (this *server) rewritesBaseHref(ctx context.Context, resourcePath string) bool {
+	return this.cfg.BaseHref && isHtml(resourcePath) && this.publicBaseUrl(ctx) != "/"
+}
+
+// rewriteBaseHref sets the href of the base tag of the html document to the public
+// base url, the base tag is injected into the head if missing, so that the bundle
+// built for `/` resolves its relative urls under the base url
+func (this *server) rewriteBaseHref(ctx context.Context, content []byte) []byte {
+	href := ` href="` + escapeUrlPath(this.publicBaseUrl(ctx)) + `"`
+	if location := baseTagRegex.FindSubmatchIndex(content); location != nil {
+		attributes := []byte{}
+		if location[2] >= 0 {
+			attributes = hrefAttrRegex.ReplaceAll(content[location[2]:location[3]], nil)
+		}
+		tag := append([]byte("<base"+href), attributes...)
+		tag = append(tag, '>')
+		return append(append(append([]byte{}, content[:location[0]]...), tag...), content[location[1]:]...)
+	}
+	if location := headTagRegex.FindIndex(content); location != nil {
+		injected := append([]byte{}, content[:location[1]]...)
+		injected = append(injected, "<base"+href+">"...)
+		return append(injected, content[location[1]:]...)
+	}
+	return content
+}
+
+// escapeUrlPath percent-encodes the path written into the html attributes and the
+// css urls, so that the quotes and brackets cannot end the attribute or the url
+func escapeUrlPath(urlPath string) string {
+	return (&url.URL{Path: urlPath}).EscapedPath()
+}
@@ -0,0 +1,136 @@
+package spaserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type RewriteTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestRewriteTestSuite(t *testing.T) {
+	suite.Run(t, new(RewriteTestSuite))
+}
+
+func (suite *RewriteTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "index.html"), []byte(
+		`<link href="/assets/main.css"><script src='/assets/main.js'></script>`+
+			`<a href="//cdn.example.com/x">cdn</a><img src="/app/logo.png">`), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "main.css"), []byte(
+		`@font-face { src: url("/fonts/roboto.woff2") } body { background: url(/img/bg.png) }`), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "main.css.br"), []byte("compressed"), 0644))
+
+	suite.sut = &server{
+		cfg: Config{
+			RootDirs:            []string{rootDir},
+			BaseURL:             "/app/",
+			RewriteAbsoluteUrls: true,
+		},
+		logger: zerolog.New(os.Stdout),
+	}
+}
+
+func (suite *RewriteTestSuite) get(requestPath string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", requestPath, nil)
+	suite.Require().Nil(err)
+	req.Header.Set("Accept-Encoding", "br")
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *RewriteTestSuite) Test_Html_Then_absolute_urls_prefixed() {
+
+	// when
+	rr := suite.get("/app/some/route")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(
+		`<link href="/app/assets/main.css"><script src='/app/assets/main.js'></script>`+
+			`<a href="//cdn.example.com/x">cdn</a><img src="/app/logo.png">`,
+		rr.Body.String())
+}
+
+func (suite *RewriteTestSuite) Test_Css_Then_urls_prefixed_and_not_encoded() {
+
+	// when
+	rr := suite.get("/app/main.css")
+	cached := suite.get("/app/main.css")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Empty(rr.Header().Get("Content-Encoding"))
+	suite.Equal(`@font-face { src: url("/app/fonts/roboto.woff2") } body { background: url(/app/img/bg.png) }`, rr.Body.String())
+	suite.Equal(rr.Body.String(), cached.Body.String())
+}
+
+func (suite *RewriteTestSuite) Test_Base_href_Then_replaced_with_base_url() {
+
+	// given
+	suite.sut.cfg.BaseHref = true
+
+	// when
+	content := suite.sut.rewriteBaseHref(context.Background(), []byte(`<head><base target="_blank" href="/"></head>`))
+
+	// then
+	suite.Equal(`<head><base href="/app/" target="_blank"></head>`, string(content))
+}
+
+func (suite *RewriteTestSuite) Test_Missing_base_Then_injected_into_head() {
+
+	// given
+	ctx := withForwardedPrefix(context.Background(), "/shop")
+
+	// when
+	content := suite.sut.rewriteBaseHref(ctx, []byte(`<html><head lang="en"><title>app</title></head></html>`))
+
+	// then
+	suite.Equal(`<html><head lang="en"><base href="/shop/app/"><title>app</title></head></html>`, string(content))
+}
+
+func (suite *RewriteTestSuite) Test_Base_href_served_Then_document_rewritten() {
+
+	// given
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "index.html"), []byte(`<head><base href="/"></head>`), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "index.html.br"), []byte("compressed"), 0644))
+	sut, err := newServer(Config{RootDirs: []string{rootDir}, BaseURL: "/app/", BaseHref: true}, zerolog.New(os.Stdout))
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", "/app/users/42", nil)
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("Accept-Encoding", "br")
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(`<head><base href="/app/"></head>`, rr.Body.String())
+}
+
+func (suite *RewriteTestSuite) Test_Prefix_with_quote_Then_escaped() {
+
+	// given
+	ctx := withForwardedPrefix(context.Background(), `/shop"'()`)
+
+	// when
+	content := suite.sut.rewriteUrls(ctx, "index.html", []byte(`<img src="/logo.png" style="background: url('/bg.png')">`))
+	base := suite.sut.rewriteBaseHref(ctx, []byte(`<head></head>`))
+
+	// then
+	suite.Equal(`<img src="/shop%22%27%28%29/app/logo.png" style="background: url('/shop%22%27%28%29/app/bg.png')">`, string(content))
+	suite.Equal(`<head><base href="/shop%22%27%28%29/app/"></head>`, string(base))
+}
@@ -0,0 +1,86 @@
+package spaserver
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+)
+
+// variants of the weighted rollout
+const (
+	variantCurrent = "current"
+	variantRollout = "rollout"
+)
+
+type rolloutVariantKey struct{}
+
+// rolloutEnabled returns true if the traffic is split between the roots and the rollout roots
+func (this *server) rolloutEnabled() bool {
+	return len(this.cfg.RolloutRoots) > 0
+}
+
+// selectVariant assigns the request to the rollout variant. The variant of the
+// header is served as requested, e.g. to the testers. The variant is kept in
+// the cookie, so that the session stays on one variant, new sessions are
+// assigned to the rollout roots by the configured percentage.
+func (this *server) selectVariant(ctx context.Context, w http.ResponseWriter, req *http.Request) string {
+	// the responses differ by the cookie and must not be shared by the caches
+	w.Header().Add("Vary", "Cookie")
+	if this.cfg.RolloutHeader != "" {
+		w.Header().Add("Vary", this.cfg.RolloutHeader)
+		if variant := req.Header.Get(this.cfg.RolloutHeader); variant == variantCurrent || variant == variantRollout {
+			return variant
+		}
+	}
+	if cookie, err := req.Cookie(this.cfg.RolloutCookie); err == nil {
+		if cookie.Value == variantCurrent || cookie.Value == variantRollout {
+			return cookie.Value
+		}
+	}
+
+	variant := variantCurrent
+	if rand.Intn(100) < this.cfg.RolloutPercentage {
+		variant = variantRollout
+	}
+	cookiePath := this.publicBaseUrl(ctx)
+	http.SetCookie(w, &http.Cookie{
+		Name:     this.cfg.RolloutCookie,
+		Value:    variant,
+		Path:     cookiePath,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return variant
+}
+
+// withVariant stores the variant of the request in the context
+func withVariant(ctx context.Context, variant string) context.Context {
+	return context.WithValue(ctx, rolloutVariantKey{}, variant)
+}
+
+// requestVariant returns the variant of the request, empty if the rollout is disabled
+func requestVariant(ctx context.Context) string {
+	variant, _ := ctx.Value(rolloutVariantKey{}).(string)
+	return variant
+}
+
+// requestRoots returns the roots serving the request
+func (this *server) requestRoots(ctx context.Context) ([]assetRoot, error) {
+	if tenant := requestTenant(ctx); tenant != "" {
+		return this.tenantRoots(tenant)
+	}
+	if version := requestVersion(ctx); version != "" {
+		return this.versionRoots(version)
+	}
+	roots, err := this.assetRoots()
+	if err == nil && requestVariant(ctx) == variantRollout {
+		return this.rolloutRoots, nil
+	}
+	return roots, err
+}
+
+// rootSetKey identifies the roots serving the request in the cache keys, including
+// the forwarded prefix rewriting the content
+func rootSetKey(ctx context.Context) string {
+	return requestVariant(ctx) + "|" + requestVersion(ctx) + "|" + requestTenant(ctx) + "|" + requestForwardedPrefix(ctx)
+}
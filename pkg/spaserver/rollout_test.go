@@ -0,0 +1,138 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type RolloutTestSuite struct {
+	suite.Suite
+	current string
+	rollout string
+}
+
+func TestRolloutTestSuite(t *testing.T) {
+	suite.Run(t, new(RolloutTestSuite))
+}
+
+func (suite *RolloutTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.current = suite.T().TempDir()
+	suite.rollout = suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(suite.current, "index.html"), []byte("current"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rollout, "index.html"), []byte("rollout"), 0644))
+}
+
+func (suite *RolloutTestSuite) server(percentage int) *server {
+	sut, err := newServer(Config{
+		RootDirs:          []string{suite.current},
+		BaseURL:           "/",
+		RolloutRoots:      []string{suite.rollout},
+		RolloutPercentage: percentage,
+		RolloutCookie:     "spa_d_variant",
+		CoalesceMaxSize:   1024,
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	return sut
+}
+
+func (suite *RolloutTestSuite) get(sut *server, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", "/", nil)
+	suite.Require().Nil(err)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+	return rr
+}
+
+func (suite *RolloutTestSuite) Test_Full_percentage_Then_new_session_assigned_to_rollout() {
+
+	// given
+	sut := suite.server(100)
+
+	// when
+	rr := suite.get(sut, nil)
+
+	// then
+	suite.Equal("rollout", rr.Body.String())
+	suite.Equal("Cookie", rr.Header().Get("Vary"))
+	cookies := rr.Result().Cookies()
+	suite.Require().Len(cookies, 1)
+	suite.Equal("spa_d_variant", cookies[0].Name)
+	suite.Equal(variantRollout, cookies[0].Value)
+}
+
+func (suite *RolloutTestSuite) Test_Session_cookie_Then_session_stays_on_variant() {
+
+	// given
+	sut := suite.server(0)
+
+	// when
+	rr := suite.get(sut, &http.Cookie{Name: "spa_d_variant", Value: variantRollout})
+
+	// then
+	suite.Equal("rollout", rr.Body.String())
+	suite.Empty(rr.Result().Cookies())
+}
+
+func (suite *RolloutTestSuite) Test_Zero_percentage_Then_new_session_assigned_to_current() {
+
+	// given
+	sut := suite.server(0)
+
+	// when
+	rr := suite.get(sut, &http.Cookie{Name: "spa_d_variant", Value: "unknown"})
+
+	// then
+	suite.Equal("current", rr.Body.String())
+	cookies := rr.Result().Cookies()
+	suite.Require().Len(cookies, 1)
+	suite.Equal(variantCurrent, cookies[0].Value)
+}
+
+func (suite *RolloutTestSuite) Test_Variant_header_Then_variant_served_over_cookie() {
+
+	// given
+	sut := suite.server(0)
+	sut.cfg.RolloutHeader = "X-Variant"
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Variant", variantRollout)
+	req.AddCookie(&http.Cookie{Name: "spa_d_variant", Value: variantCurrent})
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal("rollout", rr.Body.String())
+	suite.Contains(rr.Header().Values("Vary"), "X-Variant")
+	suite.Empty(rr.Result().Cookies())
+}
+
+func (suite *RolloutTestSuite) Test_Unknown_variant_header_Then_session_variant() {
+
+	// given
+	sut := suite.server(100)
+	sut.cfg.RolloutHeader = "X-Variant"
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Variant", "canary")
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal("rollout", rr.Body.String())
+	suite.Len(rr.Result().Cookies(), 1)
+}
@@ -0,0 +1,357 @@
+package spaserver
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// asset is an opened resource served to clients
+type asset interface {
+	fs.File
+	io.Seeker
+}
+
+// assetRoot is a source of the served assets
+type assetRoot struct {
+	// name is the configured root
+	name string
+	fsys fs.FS
+
+	// refresh updates the remote root, nil for local roots
+	refresh refreshFunc
+	// reopen opens the local root again, nil for remote roots
+	reopen refreshFunc
+
+	// synced is the time of the last successful sync of the remote root in unix nanoseconds
+	synced *atomic.Int64
+}
+
+// openRoots opens the configured roots, each root is either a directory,
+// a tar or zip archive or a squashfs image served without extraction, an OCI artifact reference,
+// a git repository reference, a prefix of an S3 bucket, or a http(s) url of
+// an archive
+func (this *server) openRoots(rootDirs []string) ([]assetRoot, error) {
+	roots := make([]assetRoot, 0, len(rootDirs))
+	for _, rootDir := range rootDirs {
+		fsys, refresh, err := this.openRoot(rootDir)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open root %v: %w", rootLabel(rootDir), err)
+		}
+		root := assetRoot{name: rootDir, fsys: fsys, refresh: refresh, synced: &atomic.Int64{}}
+		if refresh == nil {
+			root.fsys, root.reopen = this.reopenableRoot(rootDir, fsys)
+		}
+		root.synced.Store(time.Now().UnixNano())
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
+
+func (this *server) openRoot(rootDir string) (fs.FS, refreshFunc, error) {
+	var fetch fetchFunc
+	lazy := false
+	switch {
+	case strings.HasPrefix(rootDir, ociScheme):
+		fetch = ociFetch(rootDir, this.cfg)
+		lazy = this.cfg.OciPullOnDemand
+	case strings.HasPrefix(rootDir, gitScheme):
+		if signatureVerified(this.cfg) {
+			return nil, nil, fmt.Errorf("signature verification is not supported for git roots")
+		}
+		source, err := newGitSource(rootDir, this.cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		fetch = source.checkout
+	case strings.HasPrefix(rootDir, s3Scheme):
+		if signatureVerified(this.cfg) {
+			return nil, nil, fmt.Errorf("signature verification is not supported for S3 roots")
+		}
+		source, err := newS3Source(rootDir, this.cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		fetch = source.fetch
+	case rootDir == embedScheme:
+		fsys, ok := embeddedRoot()
+		if !ok {
+			return nil, nil, fmt.Errorf("no SPA is embedded in the binary")
+		}
+		fsys, err := this.prepareRoot(rootDir, fsys)
+		return fsys, nil, err
+	case strings.HasPrefix(rootDir, bundleScheme):
+		archive, err := bundleArchive(rootDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		fsys, err := openArchiveOrDir(archive, this.cfg.SymlinkPolicy)
+		if err != nil {
+			return nil, nil, err
+		}
+		fsys, err = this.prepareRoot(rootDir, fsys)
+		return fsys, nil, err
+	case strings.HasPrefix(rootDir, "http://"), strings.HasPrefix(rootDir, "https://"):
+		fetch = (&httpSource{url: rootDir, cfg: this.cfg}).fetch
+	default:
+		release := resolveReleasePointer(rootDir)
+		fsys, err := openArchiveOrDir(release, this.cfg.SymlinkPolicy)
+		if err != nil {
+			return nil, nil, err
+		}
+		if info, err := os.Stat(release); err == nil && info.IsDir() && this.cfg.PrecompressOnStart {
+			fsys = this.precompressRoot(rootDir, release, fsys)
+		}
+		fsys, err = this.prepareRoot(rootDir, fsys)
+		if err == nil && release != rootDir {
+			// the flip of the pointer is detected against the served release
+			this.releases.Store(rootDir, release)
+		}
+		return fsys, nil, err
+	}
+
+	return openRemoteRoot(func(ctx context.Context) (fs.FS, error) {
+		fsys, revision, err := fetch(ctx)
+		if err != nil || fsys == nil {
+			return nil, err
+		}
+		// fetched content is served only if verified
+		if fsys, err = this.prepareRoot(rootDir, fsys); err != nil {
+			return nil, err
+		}
+		this.revisions.Store(rootDir, revision)
+		return fsys, nil
+	}, lazy)
+}
+
+// prepareRoot verifies the opened root and loads it to memory if configured
+func (this *server) prepareRoot(rootDir string, fsys fs.FS) (fs.FS, error) {
+	if this.cfg.SnapshotEnabled {
+		// verified is the content actually served
+		snapshot, err := this.snapshot(rootDir, fsys)
+		if err != nil {
+			return nil, err
+		}
+		fsys = snapshot
+	}
+	if err := this.verifyIntegrity(rootDir, fsys); err != nil {
+		return nil, err
+	}
+	return fsys, nil
+}
+
+func openArchiveOrDir(rootDir string, symlinkPolicy string) (fs.FS, error) {
+	// the extensions are case insensitive, e.g. on Windows
+	ext := strings.ToLower(rootDir)
+	switch {
+	case strings.HasSuffix(ext, ".tar"):
+		return openTar(rootDir)
+	case strings.HasSuffix(ext, ".tar.gz"), strings.HasSuffix(ext, ".tgz"):
+		return openTarGz(rootDir)
+	case strings.HasSuffix(ext, ".zip"):
+		return openZip(rootDir)
+	case strings.HasSuffix(ext, ".squashfs"), strings.HasSuffix(ext, ".sqsh"):
+		return openSquashfs(rootDir)
+	default:
+		return dirFS(rootDir, symlinkPolicy)
+	}
+}
+
+// rootName converts the resource path to the name of the file within the root,
+// the path is cleaned so that it cannot escape the root
+func rootName(resourcePath string) string {
+	name := strings.TrimPrefix(path.Clean("/"+resourcePath), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// tarFS serves the regular files of a tar archive directly from the archive
+type tarFS struct {
+	data    io.ReaderAt
+	entries map[string]*tarEntry
+}
+
+type tarEntry struct {
+	info   fs.FileInfo
+	offset int64
+}
+
+// openTar indexes the uncompressed tar archive, the file contents are
+// read from the archive on demand
+func openTar(archive string) (fs.FS, error) {
+	file, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	return indexTar(file)
+}
+
+// openTarGz loads the compressed tar archive to memory, since compressed
+// streams cannot be read at random offsets
+func openTarGz(archive string) (fs.FS, error) {
+	file, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, err
+	}
+	return indexTar(bytes.NewReader(content))
+}
+
+type tarSource interface {
+	io.ReadSeeker
+	io.ReaderAt
+}
+
+func indexTar(source tarSource) (*tarFS, error) {
+	position := &positionReader{ReadSeeker: source}
+	reader := tar.NewReader(position)
+	fsys := &tarFS{data: source, entries: map[string]*tarEntry{}}
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := rootName(header.Name)
+		switch header.Typeflag {
+		case tar.TypeReg:
+			fsys.entries[name] = &tarEntry{info: header.FileInfo(), offset: position.pos}
+		case tar.TypeDir:
+			fsys.entries[name] = &tarEntry{info: header.FileInfo()}
+		default:
+			// links and special files are not served
+			continue
+		}
+
+		// implicit parent directories
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if _, ok := fsys.entries[dir]; !ok {
+				fsys.entries[dir] = &tarEntry{info: dirInfo(path.Base(dir))}
+			}
+		}
+	}
+	fsys.entries["."] = &tarEntry{info: dirInfo(".")}
+	return fsys, nil
+}
+
+func (this *tarFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	entry, ok := this.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.info.IsDir() {
+		return newDirFile(entry.info, dirEntries(this.entries, name, func(entry *tarEntry) fs.FileInfo { return entry.info })), nil
+	}
+	return &tarFile{
+		SectionReader: io.NewSectionReader(this.data, entry.offset, entry.info.Size()),
+		info:          entry.info,
+	}, nil
+}
+
+// tarFile is a file of the tar archive
+type tarFile struct {
+	*io.SectionReader
+	info fs.FileInfo
+}
+
+func (this *tarFile) Stat() (fs.FileInfo, error) { return this.info, nil }
+func (this *tarFile) Close() error               { return nil }
+
+// dirFile is a directory of the archive listing the entries of the directory
+type dirFile struct {
+	tarFile
+	entries []fs.DirEntry
+}
+
+func newDirFile(info fs.FileInfo, entries []fs.DirEntry) *dirFile {
+	return &dirFile{
+		tarFile: tarFile{SectionReader: io.NewSectionReader(bytes.NewReader(nil), 0, 0), info: info},
+		entries: entries,
+	}
+}
+
+// ReadDir reads the next entries of the directory, all the remaining if n <= 0
+func (this *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := this.entries
+		this.entries = nil
+		return entries, nil
+	}
+	if len(this.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(this.entries))
+	entries := this.entries[:n]
+	this.entries = this.entries[n:]
+	return entries, nil
+}
+
+// dirEntries returns the entries of the archive directly within the directory sorted by name
+func dirEntries[E any](entries map[string]E, dir string, info func(E) fs.FileInfo) []fs.DirEntry {
+	children := []fs.DirEntry{}
+	for name, entry := range entries {
+		if name != "." && path.Dir(name) == dir {
+			children = append(children, fs.FileInfoToDirEntry(info(entry)))
+		}
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Name() < children[j].Name() })
+	return children
+}
+
+// positionReader tracks the position within the read stream
+type positionReader struct {
+	io.ReadSeeker
+	pos int64
+}
+
+func (this *positionReader) Read(p []byte) (int, error) {
+	n, err := this.ReadSeeker.Read(p)
+	this.pos += int64(n)
+	return n, err
+}
+
+func (this *positionReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := this.ReadSeeker.Seek(offset, whence)
+	if err == nil {
+		this.pos = pos
+	}
+	return pos, err
+}
+
+// dirInfo describes directories implied by the archive entries
+type dirInfo string
+
+func (this dirInfo) Name() string       { return string(this) }
+func (this dirInfo) Size() int64        { return 0 }
+func (this dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (this dirInfo) ModTime() time.Time { return time.Time{} }
+func (this dirInfo) IsDir() bool        { return true }
+func (this dirInfo) Sys() any           { return nil }
@@ -0,0 +1,154 @@
+package spaserver
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type RootTestSuite struct {
+	suite.Suite
+	dataDir string
+}
+
+func TestRootTestSuite(t *testing.T) {
+	suite.Run(t, new(RootTestSuite))
+}
+
+func (suite *RootTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	_, filename, _, _ := runtime.Caller(0)
+	suite.dataDir = path.Join(path.Dir(filename), "test/data")
+}
+
+// writeArchive packs the test data files into the tar archive, optionally gzipped
+func (suite *RootTestSuite) writeArchive(name string, compressed bool) string {
+	archive := path.Join(suite.T().TempDir(), name)
+	file, err := os.Create(archive)
+	suite.Require().Nil(err)
+	defer file.Close()
+
+	var out io.Writer = file
+	if compressed {
+		gz := gzip.NewWriter(file)
+		defer gz.Close()
+		out = gz
+	}
+
+	tw := tar.NewWriter(out)
+	defer tw.Close()
+	for _, name := range []string{"index.html", "testfile.json", "prebr.js", "prebr.js.br"} {
+		content, err := os.ReadFile(path.Join(suite.dataDir, name))
+		suite.Require().Nil(err)
+		suite.Require().Nil(tw.WriteHeader(&tar.Header{
+			Name:     "./" + name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err = tw.Write(content)
+		suite.Require().Nil(err)
+	}
+	return archive
+}
+
+func (suite *RootTestSuite) serve(roots []string, requestPath string, acceptEncoding string) *httptest.ResponseRecorder {
+	sut := &server{
+		cfg:    Config{RootDirs: roots, BaseURL: "/"},
+		logger: zerolog.New(os.Stdout),
+	}
+	req, err := http.NewRequest("GET", requestPath, nil)
+	suite.Require().Nil(err)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *RootTestSuite) Test_Tar_root_Then_OK_With_Content() {
+
+	// given
+	archive := suite.writeArchive("bundle.tar", false)
+
+	// when
+	rr := suite.serve([]string{archive}, "/testfile.json", "")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(testfile_json, rr.Body.String())
+}
+
+func (suite *RootTestSuite) Test_Tar_root_precompressed_Then_OK_and_encoded() {
+
+	// given
+	archive := suite.writeArchive("bundle.tar", false)
+
+	// when
+	rr := suite.serve([]string{archive}, "/prebr.js", "br")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("br", rr.Header().Get("Content-Encoding"))
+	suite.Equal(prebr_js_br, rr.Body.String())
+}
+
+func (suite *RootTestSuite) Test_Tar_gz_root_fallback_Then_OK_With_Index() {
+
+	// given
+	archive := suite.writeArchive("bundle.tgz", true)
+
+	// when
+	rr := suite.serve([]string{archive}, "/some/route", "")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(index_html, rr.Body.String())
+}
+
+func (suite *RootTestSuite) Test_File_missing_in_first_root_Then_found_in_next_root() {
+
+	// given
+	empty := suite.T().TempDir()
+
+	// when
+	rr := suite.serve([]string{empty, suite.dataDir}, "/testfile.json", "")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(testfile_json, rr.Body.String())
+}
+
+func (suite *RootTestSuite) Test_Directory_in_first_root_Then_file_found_in_next_root() {
+
+	// given
+	overlay := suite.T().TempDir()
+	suite.Require().Nil(os.Mkdir(path.Join(overlay, "testfile.json"), 0755))
+
+	// when
+	rr := suite.serve([]string{overlay, suite.dataDir}, "/testfile.json", "")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(testfile_json, rr.Body.String())
+}
+
+func (suite *RootTestSuite) Test_Path_traversal_Then_stays_in_root() {
+
+	// when
+	name := rootName("/../../etc/passwd")
+
+	// then
+	suite.Equal("etc/passwd", name)
+}
@@ -0,0 +1,210 @@
+package spaserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Run runs the spa_d command with the arguments, e.g. `spa_d --port 8080`, or
+// its embed, validate or explain subcommands, until the process is stopped
+func Run(args []string) {
+	if len(args) > 0 && args[0] == "embed" {
+		if err := embedCommand(args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "validate" {
+		if err := validateCommand(args[1:]); err != nil {
+			if !errors.Is(err, pflag.ErrHelp) {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "explain" {
+		if err := explainCommand(args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	setDefaults(viper.GetViper())
+	if err := parseFlags(viper.GetViper(), args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return
+		}
+		os.Exit(2)
+	}
+
+	cfg := loadConfiguration()
+	logger := configureLogger(cfg)
+	ctx := context.Background()
+	tuneRuntime(cfg, logger)
+
+	var metricsHandler http.Handler
+	if !cfg.TelemetryDisabled {
+		shutdownTelemetry, handler, err := initTelemetry(ctx, cfg, &logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Cannot initialize telemetry")
+		}
+		defer shutdownTelemetry(ctx)
+		metricsHandler = handler
+	}
+
+	// the profiling stops on shutdown
+	profilingCtx, stopProfiling := context.WithCancel(ctx)
+	defer stopProfiling()
+	if cfg.ProfilingUrl != "" {
+		go (&profiler{cfg: cfg, logger: logger}).run(profilingCtx)
+	}
+
+	spa, err := newServer(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Cannot initialize server")
+	}
+	spa.metricsHandler = metricsHandler
+	switcher := newServerSwitch(ctx, spa)
+
+	// the current server keeps serving if the configuration cannot be reloaded
+	reloadConfig := func() {
+		next, err := reloadConfiguration()
+		if err == nil {
+			err = switcher.reloadConfig(next, configureLogger(next))
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Cannot reload configuration")
+		}
+	}
+	if cfg.ConfigWatch {
+		viper.OnConfigChange(func(event fsnotify.Event) {
+			logger.Info().Str("file", event.Name).Msg("Configuration file changed")
+			reloadConfig()
+		})
+		viper.WatchConfig()
+	}
+
+	if cfg.AdminPort > 0 {
+		go func() {
+			logger.Info().Int("port", cfg.AdminPort).Msg("Starting admin server")
+			adminServer := newHttpServer(cfg, switcher.adminHandler())
+			adminServer.Addr = ":" + strconv.Itoa(cfg.AdminPort)
+			err := adminServer.ListenAndServe()
+			logger.Error().Err(err).Msg("Admin server failed")
+		}()
+	}
+
+	listeners, err := listen(ctx, ":"+strconv.Itoa(cfg.Port), cfg.ReusePortListeners)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Cannot listen")
+	}
+
+	tlsCfg, certificates, err := tlsConfig(cfg, spa.acme)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Cannot configure TLS")
+	}
+	if certificates != nil && cfg.TlsReloadInterval > 0 {
+		go certificates.watch(ctx, cfg.TlsReloadInterval, logger)
+	}
+	if spa.acme != nil && cfg.AcmeHttpPort > 0 {
+		go func() {
+			logger.Info().Int("port", cfg.AcmeHttpPort).Strs("hosts", cfg.AcmeHosts).Msg("Starting ACME challenge server")
+			acmeServer := newHttpServer(cfg, spa.acmeHandler())
+			acmeServer.Addr = ":" + strconv.Itoa(cfg.AcmeHttpPort)
+			err := acmeServer.ListenAndServe()
+			logger.Error().Err(err).Msg("ACME challenge server failed")
+		}()
+	}
+
+	httpServer := newHttpServer(cfg, otelhttp.NewHandler(switcher, "serve-spa",
+		otelhttp.WithFilter(func(req *http.Request) bool {
+			return !switcher.traceExcluded(req.URL.Path)
+		}),
+	))
+	httpServer.TLSConfig = tlsCfg
+	connections := countConnections(httpServer)
+	if cfg.H2C {
+		if err := enableH2C(httpServer); err != nil {
+			logger.Fatal().Err(err).Msg("Cannot enable h2c")
+		}
+	}
+	if cfg.Http3 {
+		h3s, err := enableHTTP3(httpServer, ":"+strconv.Itoa(cfg.Port), cfg.Http3AdvertisedPort)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Cannot enable HTTP/3")
+		}
+		// the QUIC connections are closed on shutdown
+		httpServer.RegisterOnShutdown(func() { h3s.Close() })
+		go func() {
+			logger.Info().Int("port", cfg.Port).Msg("Starting HTTP/3 server")
+			err := h3s.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error().Err(err).Msg("HTTP/3 server failed")
+			}
+		}()
+	}
+
+	// the signals are handled while serving
+	go func() {
+		logger.Info().Int("port", cfg.Port).Int("listeners", len(listeners)).Bool("tls", tlsCfg != nil).Bool("h2c", cfg.H2C).Msg("Starting server")
+		err := serve(httpServer, listeners)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal().Err(err).Msg("Server failed")
+		}
+	}()
+
+	started, err := runService(func() {
+		logger.Info().Msg("Service stopped")
+		switcher.current.Load().draining.Store(true)
+		shutdownServer(httpServer, connections, cfg.ShutdownDrainDelay, cfg.ShutdownTimeout, logger)
+		switcher.shutdown()
+	})
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Service failed")
+	}
+	if started {
+		return
+	}
+
+	signalChannel := make(chan os.Signal, 2)
+	signal.Notify(signalChannel, append([]os.Signal{os.Interrupt, syscall.SIGTERM}, append(reloadSignals, configReloadSignals...)...)...)
+	for {
+		sig := <-signalChannel
+		switch sig {
+		case os.Interrupt, syscall.SIGTERM:
+			logger.Info().Str("signal", sig.String()).Msg("shutdown")
+			// the readiness fails while draining
+			switcher.current.Load().draining.Store(true)
+			shutdownServer(httpServer, connections, cfg.ShutdownDrainDelay, cfg.ShutdownTimeout, logger)
+			switcher.shutdown()
+			return
+		default:
+			if slices.Contains(configReloadSignals, sig) {
+				logger.Info().Str("signal", sig.String()).Msg("reload configuration")
+				reloadConfig()
+				continue
+			}
+			logger.Info().Str("signal", sig.String()).Msg("reload")
+			switcher.current.Load().reload(ctx)
+			if certificates != nil {
+				certificates.check(logger)
+			}
+		}
+	}
+
+}
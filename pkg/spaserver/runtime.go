@@ -0,0 +1,44 @@
+package spaserver
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/runtime"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// startRuntimeMetrics exports the Go runtime metrics (GC pauses, heap, goroutines)
+// and the count of open file descriptors through the meter provider
+func startRuntimeMetrics(provider metric.MeterProvider, interval time.Duration) error {
+	err := runtime.Start(
+		runtime.WithMeterProvider(provider),
+		runtime.WithMinimumReadMemStatsInterval(interval),
+	)
+	if err != nil {
+		return err
+	}
+
+	meter := provider.Meter("spa_d")
+	_, err = meter.Int64ObservableGauge("process.open_fds",
+		metric.WithDescription("Number of open file descriptors of the process"),
+		metric.WithInt64Callback(func(_ context.Context, observer metric.Int64Observer) error {
+			if count, ok := openFileDescriptors(); ok {
+				observer.Observe(count)
+			}
+			return nil
+		}),
+	)
+	return err
+}
+
+// openFileDescriptors counts the open file descriptors, available only on linux
+func openFileDescriptors() (int64, bool) {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0, false
+	}
+	// the directory read itself holds one descriptor
+	return int64(len(entries) - 1), true
+}
@@ -0,0 +1,45 @@
+package spaserver
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	metricsdk "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+type RuntimeTestSuite struct {
+	suite.Suite
+}
+
+func TestRuntimeTestSuite(t *testing.T) {
+	suite.Run(t, new(RuntimeTestSuite))
+}
+
+func (suite *RuntimeTestSuite) Test_Runtime_metrics_Then_collected() {
+
+	// given
+	reader := metricsdk.NewManualReader()
+	provider := metricsdk.NewMeterProvider(metricsdk.WithReader(reader))
+	suite.Require().Nil(startRuntimeMetrics(provider, time.Second))
+
+	// when
+	data := metricdata.ResourceMetrics{}
+	suite.Require().Nil(reader.Collect(context.Background(), &data))
+
+	// then
+	names := []string{}
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			names = append(names, m.Name)
+		}
+	}
+	suite.Contains(names, "process.runtime.go.goroutines")
+	suite.Contains(names, "process.runtime.go.mem.heap_alloc")
+	if runtime.GOOS == "linux" {
+		suite.Contains(names, "process.open_fds")
+	}
+}
@@ -0,0 +1,430 @@
+package spaserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	s3Scheme = "s3://"
+	// s3EmptyPayload is the SHA-256 of the empty body of the signed requests
+	s3EmptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// s3Downloads is the number of the objects downloaded concurrently
+	s3Downloads = 8
+)
+
+// s3Object is the object of the bucket listing
+type s3Object struct {
+	Key          string    `xml:"Key"`
+	ETag         string    `xml:"ETag"`
+	Size         int64     `xml:"Size"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+type s3ListResult struct {
+	IsTruncated           bool       `xml:"IsTruncated"`
+	NextContinuationToken string     `xml:"NextContinuationToken"`
+	Contents              []s3Object `xml:"Contents"`
+}
+
+// s3Source mirrors the objects under the prefix of the bucket to the cache
+// directory, e.g. `s3://my-bucket/app/`. Each object is downloaded once per
+// its ETag, so that the served tree can be swapped atomically.
+type s3Source struct {
+	bucket string
+	prefix string
+	base   *url.URL
+	dir    string
+	cfg    Config
+	client *http.Client
+
+	accessKeyId     string
+	secretAccessKey string
+	sessionToken    string
+
+	lock    sync.Mutex
+	listing string
+	// served are the cache files of the served objects
+	served map[string]bool
+}
+
+func newS3Source(uri string, cfg Config) (*s3Source, error) {
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(uri, s3Scheme), "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid S3 root %v, bucket missing", uri)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		// the prefix is a directory, not a key prefix
+		prefix += "/"
+	}
+
+	endpoint := cfg.S3Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%v.amazonaws.com", cfg.S3Region)
+	}
+	base, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %v: %w", endpoint, err)
+	}
+	if cfg.S3PathStyle {
+		base.Path += "/" + bucket
+	} else {
+		base.Host = bucket + "." + base.Host
+	}
+
+	source := &s3Source{
+		bucket:          bucket,
+		prefix:          prefix,
+		base:            base,
+		cfg:             cfg,
+		client:          remoteClient(cfg),
+		accessKeyId:     cfg.S3AccessKeyId,
+		secretAccessKey: cfg.S3SecretAccessKey,
+		sessionToken:    cfg.S3SessionToken,
+	}
+	if source.accessKeyId == "" {
+		source.accessKeyId = os.Getenv("AWS_ACCESS_KEY_ID")
+		source.secretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		source.sessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	hash := sha256.Sum256([]byte(base.String() + "/" + prefix))
+	source.dir = filepath.Join(cfg.S3CacheDir, hex.EncodeToString(hash[:8]))
+	return source, nil
+}
+
+// fetch lists the objects and downloads the changed ones, the revision is the
+// digest of the listing, the returned filesystem is nil if the listing did
+// not change since the last fetch
+func (this *s3Source) fetch(ctx context.Context) (fs.FS, string, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	objects, err := this.list(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	digest := sha256.New()
+	for _, object := range objects {
+		fmt.Fprintf(digest, "%v\x00%v\x00", object.Key, object.ETag)
+	}
+	listing := hex.EncodeToString(digest.Sum(nil))
+	if listing == this.listing {
+		return nil, "", nil
+	}
+
+	if err := os.MkdirAll(this.dir, 0755); err != nil {
+		return nil, "", err
+	}
+	fsys := &objectFS{entries: map[string]*objectEntry{".": {info: dirInfo(".")}}}
+	files := map[string]bool{}
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(s3Downloads)
+	for _, object := range objects {
+		name := rootName(strings.TrimPrefix(object.Key, this.prefix))
+		if name == "." {
+			continue
+		}
+		file := filepath.Join(this.dir, this.cacheName(object))
+		files[file] = true
+		fsys.add(name, file, object)
+		if info, err := os.Stat(file); err == nil && info.Size() == object.Size {
+			continue
+		}
+		object := object
+		group.Go(func() error {
+			return this.download(groupCtx, object, file)
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, "", err
+	}
+
+	this.prune(files)
+	this.listing = listing
+	this.served = files
+	return fsys, "sha256:" + listing, nil
+}
+
+// cacheName is the name of the cache file of the object version
+func (this *s3Source) cacheName(object s3Object) string {
+	hash := sha256.Sum256([]byte(object.Key + "\x00" + object.ETag))
+	return hex.EncodeToString(hash[:])
+}
+
+// prune removes the cache files of neither the fetched nor the served
+// objects, the in-flight requests keep serving the replaced tree
+func (this *s3Source) prune(fetched map[string]bool) {
+	entries, err := os.ReadDir(this.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		file := filepath.Join(this.dir, entry.Name())
+		if !fetched[file] && !this.served[file] {
+			os.Remove(file)
+		}
+	}
+}
+
+// list lists the objects under the prefix, following the continuation tokens
+func (this *s3Source) list(ctx context.Context) ([]s3Object, error) {
+	objects := []s3Object{}
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {this.prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := this.newRequest(ctx, "", query)
+		if err != nil {
+			return nil, err
+		}
+		this.sign(req, time.Now())
+		res, err := this.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		result := s3ListResult{}
+		err = s3Response(res, http.StatusOK)
+		if err == nil {
+			err = xml.NewDecoder(res.Body).Decode(&result)
+		}
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("listing of s3://%v/%v failed: %w", this.bucket, this.prefix, err)
+		}
+		for _, object := range result.Contents {
+			// the folder markers are not served
+			if !strings.HasSuffix(object.Key, "/") {
+				objects = append(objects, object)
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// download stores the object version of the listing in the cache file, the
+// conditional request fails if the object changed since the listing
+func (this *s3Source) download(ctx context.Context, object s3Object, file string) error {
+	req, err := this.newRequest(ctx, object.Key, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("If-Match", object.ETag)
+	this.sign(req, time.Now())
+	res, err := this.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := s3Response(res, http.StatusOK); err != nil {
+		return fmt.Errorf("download of s3://%v/%v failed: %w", this.bucket, object.Key, err)
+	}
+
+	tmp, err := os.CreateTemp(this.dir, ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err := io.Copy(tmp, res.Body); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if !object.LastModified.IsZero() {
+		os.Chtimes(tmp.Name(), object.LastModified, object.LastModified)
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// s3Response returns the error of the unexpected response status with the error code of the storage
+func s3Response(res *http.Response, status int) error {
+	if res.StatusCode == status {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	failure := struct {
+		Code string `xml:"Code"`
+	}{}
+	if xml.Unmarshal(body, &failure) == nil && failure.Code != "" {
+		return fmt.Errorf("status %v: %v", res.StatusCode, failure.Code)
+	}
+	return fmt.Errorf("status %v", res.StatusCode)
+}
+
+// newRequest creates the GET request of the object, or of the bucket if the key is empty
+func (this *s3Source) newRequest(ctx context.Context, key string, query url.Values) (*http.Request, error) {
+	target := *this.base
+	target.Path += "/" + key
+	target.RawPath = s3Escape(target.Path, false)
+	target.RawQuery = s3Query(query)
+	return http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+}
+
+// sign signs the request with AWS Signature Version 4, the requests are
+// anonymous without the credentials
+func (this *s3Source) sign(req *http.Request, now time.Time) {
+	if this.accessKeyId == "" {
+		return
+	}
+	signS3Request(req, this.cfg.S3Region, this.accessKeyId, this.secretAccessKey, this.sessionToken, now)
+}
+
+// signS3Request signs the headers of the request without a body
+func signS3Request(req *http.Request, region, accessKeyId, secretAccessKey, sessionToken string, now time.Time) {
+	stamp := now.UTC().Format("20060102T150405Z")
+	scope := stamp[:8] + "/" + region + "/s3/aws4_request"
+	req.Header.Del("Authorization")
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", s3EmptyPayload)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	values := map[string]string{"host": req.URL.Host}
+	for name, value := range req.Header {
+		values[strings.ToLower(name)] = strings.TrimSpace(strings.Join(value, ","))
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	headers := bytes.Buffer{}
+	for _, name := range names {
+		fmt.Fprintf(&headers, "%v:%v\n", name, values[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method, s3Escape(req.URL.Path, false), s3Query(req.URL.Query()),
+		headers.String(), signedHeaders, s3EmptyPayload,
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := []byte("AWS4" + secretAccessKey)
+	for _, part := range []string{stamp[:8], region, "s3", "aws4_request", toSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%v/%v, SignedHeaders=%v, Signature=%v",
+		accessKeyId, scope, signedHeaders, hex.EncodeToString(key)))
+}
+
+// s3Query encodes the query sorted by the keys, as signed
+func s3Query(query url.Values) string {
+	pairs := []string{}
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(key, true)+"="+s3Escape(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// s3Escape percent-encodes all but the unreserved characters, and the slashes of the paths
+func s3Escape(value string, slash bool) string {
+	escaped := strings.Builder{}
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '.', b == '_', b == '~', b == '/' && !slash:
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+// objectFS serves the objects downloaded to the cache files
+type objectFS struct {
+	entries map[string]*objectEntry
+}
+
+type objectEntry struct {
+	info fs.FileInfo
+	// file is the cache file of the object, empty for the directories
+	file string
+}
+
+// add adds the object and its implicit parent directories
+func (this *objectFS) add(name string, file string, object s3Object) {
+	this.entries[name] = &objectEntry{
+		info: &objectInfo{name: path.Base(name), size: object.Size, modTime: object.LastModified},
+		file: file,
+	}
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if _, ok := this.entries[dir]; !ok {
+			this.entries[dir] = &objectEntry{info: dirInfo(path.Base(dir))}
+		}
+	}
+}
+
+func (this *objectFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	entry, ok := this.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.file == "" {
+		return newDirFile(entry.info, dirEntries(this.entries, name, func(entry *objectEntry) fs.FileInfo { return entry.info })), nil
+	}
+	file, err := os.Open(entry.file)
+	if err != nil {
+		return nil, err
+	}
+	return &objectFile{File: file, info: entry.info}, nil
+}
+
+// objectFile is the cache file described as the object
+type objectFile struct {
+	*os.File
+	info fs.FileInfo
+}
+
+func (this *objectFile) Stat() (fs.FileInfo, error) { return this.info, nil }
+
+// objectInfo describes the object of the bucket
+type objectInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (this *objectInfo) Name() string       { return this.name }
+func (this *objectInfo) Size() int64        { return this.size }
+func (this *objectInfo) Mode() fs.FileMode  { return 0444 }
+func (this *objectInfo) ModTime() time.Time { return this.modTime }
+func (this *objectInfo) IsDir() bool        { return false }
+func (this *objectInfo) Sys() any           { return nil }
@@ -0,0 +1,68 @@
+package spaserver
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// scheduledRoots replace the configured roots at the activation time, e.g. for
+// embargoed releases. The roots are opened and verified at startup, so that
+// the switch is a swap of the served roots.
+type scheduledRoots struct {
+	activation time.Time
+	roots      []assetRoot
+	active     atomic.Bool
+	timer      *time.Timer
+}
+
+// openScheduledRoots opens the scheduled roots and plans their activation
+func (this *server) openScheduledRoots() error {
+	if len(this.cfg.ScheduledRoots) == 0 && this.cfg.ScheduledActivation == "" {
+		return nil
+	}
+	if len(this.cfg.ScheduledRoots) == 0 || this.cfg.ScheduledActivation == "" {
+		return fmt.Errorf("both scheduled roots and scheduled activation must be configured")
+	}
+	activation, err := time.Parse(time.RFC3339, this.cfg.ScheduledActivation)
+	if err != nil {
+		return fmt.Errorf("invalid scheduled activation %v: %w", this.cfg.ScheduledActivation, err)
+	}
+	roots, err := this.openRoots(this.cfg.ScheduledRoots)
+	if err != nil {
+		return err
+	}
+
+	this.scheduled.activation = activation
+	this.scheduled.roots = roots
+	if !activation.After(time.Now()) {
+		this.activateScheduledRoots()
+		return nil
+	}
+	this.logger.Info().Time("activation", activation).Strs("roots", rootLabels(this.cfg.ScheduledRoots)).
+		Msg("Scheduled roots switchover")
+	this.scheduled.timer = time.AfterFunc(time.Until(activation), this.activateScheduledRoots)
+	return nil
+}
+
+// activateScheduledRoots switches the served roots to the scheduled roots
+func (this *server) activateScheduledRoots() {
+	if !this.scheduled.active.CompareAndSwap(false, true) {
+		return
+	}
+	telemetry().root_switches.Add(context.Background(), 1)
+	this.logger.Info().
+		Time("activation", this.scheduled.activation).
+		Dur("delay", time.Since(this.scheduled.activation)).
+		Strs("roots", rootLabels(this.cfg.ScheduledRoots)).
+		Msg("Switched to scheduled roots")
+}
+
+func rootLabels(rootDirs []string) []string {
+	labels := make([]string, 0, len(rootDirs))
+	for _, rootDir := range rootDirs {
+		labels = append(labels, rootLabel(rootDir))
+	}
+	return labels
+}
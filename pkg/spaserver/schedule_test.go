@@ -0,0 +1,124 @@
+package spaserver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type ScheduleTestSuite struct {
+	suite.Suite
+	current   string
+	scheduled string
+}
+
+func TestScheduleTestSuite(t *testing.T) {
+	suite.Run(t, new(ScheduleTestSuite))
+}
+
+func (suite *ScheduleTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.current = suite.T().TempDir()
+	suite.scheduled = suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(suite.current, "index.html"), []byte("current"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.scheduled, "index.html"), []byte("scheduled"), 0644))
+}
+
+func (suite *ScheduleTestSuite) server(activation time.Time) *server {
+	sut, err := newServer(Config{
+		RootDirs:            []string{suite.current},
+		BaseURL:             "/",
+		ScheduledRoots:      []string{suite.scheduled},
+		ScheduledActivation: activation.Format(time.RFC3339Nano),
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	return sut
+}
+
+func (suite *ScheduleTestSuite) get(sut *server) string {
+	req, err := http.NewRequest("GET", "/", nil)
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	suite.Equal(http.StatusOK, rr.Code)
+	return rr.Body.String()
+}
+
+func (suite *ScheduleTestSuite) Test_Before_activation_Then_current_roots_served() {
+
+	// given
+	sut := suite.server(time.Now().Add(time.Hour))
+	defer sut.scheduled.timer.Stop()
+
+	// when
+	body := suite.get(sut)
+
+	// then
+	suite.Equal("current", body)
+}
+
+func (suite *ScheduleTestSuite) Test_At_activation_Then_scheduled_roots_served() {
+
+	// given
+	sut := suite.server(time.Now().Add(50 * time.Millisecond))
+	suite.Equal("current", suite.get(sut))
+
+	// when
+	suite.Eventually(sut.scheduled.active.Load, time.Second, 10*time.Millisecond)
+
+	// then
+	suite.Equal("scheduled", suite.get(sut))
+}
+
+func (suite *ScheduleTestSuite) Test_Past_activation_Then_scheduled_roots_served_at_startup() {
+
+	// given
+	sut := suite.server(time.Now().Add(-time.Hour))
+
+	// when
+	body := suite.get(sut)
+
+	// then
+	suite.Equal("scheduled", body)
+}
+
+func (suite *ScheduleTestSuite) Test_Activation_without_roots_Then_error() {
+
+	// when
+	_, err := newServer(Config{
+		RootDirs:            []string{suite.current},
+		ScheduledActivation: time.Now().Format(time.RFC3339),
+	}, zerolog.New(io.Discard))
+
+	// then
+	suite.NotNil(err)
+}
+
+func (suite *ScheduleTestSuite) Test_Reload_before_activation_Then_switched_once() {
+
+	// given
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	defer zerolog.SetGlobalLevel(zerolog.Disabled)
+	logs := &bytes.Buffer{}
+	sut := suite.server(time.Now().Add(100 * time.Millisecond))
+	sut.logger = zerolog.New(logs)
+	switcher := newServerSwitch(context.Background(), sut)
+
+	// when
+	suite.Require().Nil(switcher.reloadConfig(sut.cfg, zerolog.New(logs)))
+	time.Sleep(300 * time.Millisecond)
+
+	// then
+	suite.Equal(1, strings.Count(logs.String(), "Switched to scheduled roots"))
+	suite.Equal("scheduled", suite.get(switcher.current.Load()))
+}
@@ -0,0 +1,47 @@
+package spaserver
+
+import (
+	"context"
+	"net/http"
+)
+
+// security header presets
+const (
+	securityHeadersOff    = "off"
+	securityHeadersBasic  = "basic"
+	securityHeadersStrict = "strict"
+)
+
+// securityHeaderPresets are the security headers of the presets, suitable for
+// the single page applications loading their assets from the same origin
+var securityHeaderPresets = map[string]map[string]string{
+	securityHeadersOff: {},
+	securityHeadersBasic: {
+		"X-Content-Type-Options": "nosniff",
+		"X-Frame-Options":        "SAMEORIGIN",
+		"Referrer-Policy":        "strict-origin-when-cross-origin",
+	},
+	securityHeadersStrict: {
+		"Strict-Transport-Security":  "max-age=63072000; includeSubDomains",
+		"X-Content-Type-Options":     "nosniff",
+		"X-Frame-Options":            "DENY",
+		"Referrer-Policy":            "same-origin",
+		"Permissions-Policy":         "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+		"Cross-Origin-Opener-Policy": "same-origin",
+	},
+}
+
+// applySecurityHeaders sets the headers of the configured preset not set by the
+// configured headers
+func (this *server) applySecurityHeaders(ctx context.Context, w http.ResponseWriter) {
+	preset := securityHeaderPresets[this.cfg.SecurityHeaders]
+	if len(preset) == 0 {
+		return
+	}
+	debugLookup(ctx, "security-headers %v", this.cfg.SecurityHeaders)
+	for name, value := range preset {
+		if len(w.Header().Values(name)) == 0 {
+			w.Header().Set(name, value)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type SecurityHeadersTestSuite struct {
+	suite.Suite
+	cfg Config
+}
+
+func TestSecurityHeadersTestSuite(t *testing.T) {
+	suite.Run(t, new(SecurityHeadersTestSuite))
+}
+
+func (suite *SecurityHeadersTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	dir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(dir, "index.html"), []byte("index"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(dir, "embed.html"), []byte("embed"), 0644))
+	suite.cfg = Config{RootDirs: []string{dir}, BaseURL: "/"}
+}
+
+func (suite *SecurityHeadersTestSuite) get(target string) *httptest.ResponseRecorder {
+	sut, err := newServer(suite.cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, httptest.NewRequest("GET", target, nil))
+	return rr
+}
+
+func (suite *SecurityHeadersTestSuite) Test_Strict_preset_Then_headers_set() {
+
+	// given
+	suite.cfg.SecurityHeaders = "strict"
+
+	// when
+	rr := suite.get("/index.html")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("max-age=63072000; includeSubDomains", rr.Header().Get("Strict-Transport-Security"))
+	suite.Equal("nosniff", rr.Header().Get("X-Content-Type-Options"))
+	suite.Equal("DENY", rr.Header().Get("X-Frame-Options"))
+	suite.Equal("same-origin", rr.Header().Get("Cross-Origin-Opener-Policy"))
+}
+
+func (suite *SecurityHeadersTestSuite) Test_Configured_headers_Then_override_preset() {
+
+	// given
+	suite.cfg.SecurityHeaders = "basic"
+	suite.cfg.Headers = map[string]string{"referrer-policy": "no-referrer"}
+	suite.cfg.HeadersPerPathRegex = map[string]map[string]string{`^embed\.html$`: {"X-Frame-Options": "ALLOWALL"}}
+
+	// when
+	rr := suite.get("/embed.html")
+
+	// then
+	suite.Equal("no-referrer", rr.Header().Get("Referrer-Policy"))
+	suite.Equal("ALLOWALL", rr.Header().Get("X-Frame-Options"))
+	suite.Equal("nosniff", rr.Header().Get("X-Content-Type-Options"))
+	suite.Empty(rr.Header().Get("Strict-Transport-Security"))
+}
+
+func (suite *SecurityHeadersTestSuite) Test_Off_Then_no_headers() {
+
+	// given
+	suite.cfg.SecurityHeaders = "off"
+
+	// when
+	rr := suite.get("/index.html")
+
+	// then
+	suite.Empty(rr.Header().Get("X-Content-Type-Options"))
+}
+
+func (suite *SecurityHeadersTestSuite) Test_Unknown_preset_Then_invalid() {
+
+	// given
+	suite.cfg.SecurityHeaders = "paranoid"
+
+	// when
+	_, err := newServer(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "security-headers: unknown preset paranoid")
+}
@@ -0,0 +1,1090 @@
+package spaserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/sync/singleflight"
+)
+
+// outcomes of the request routing reported in the responses metric
+const (
+	outcomeServed           = "served"
+	outcomeFallback         = "fallback"
+	outcomeNotFound         = "not_found"
+	outcomeBaseUrlMismatch  = "base_url_mismatch"
+	outcomeBaseUrlRedirect  = "base_url_redirect"
+	outcomeMaintenance      = "maintenance"
+	outcomePrerendered      = "prerendered"
+	outcomeForbidden        = "forbidden"
+	outcomeUnauthorized     = "unauthorized"
+	outcomeOffline          = "offline"
+	outcomeRedirected       = "redirected"
+	outcomeProxied          = "proxied"
+	outcomeHooked           = "hooked"
+	outcomeOverloaded       = "overloaded"
+	outcomeThrottled        = "throttled"
+	outcomeBlocked          = "blocked"
+	outcomeMethodNotAllowed = "method_not_allowed"
+	outcomeError            = "error"
+)
+
+// built-in Cache-Control of the responses, unless configured
+const (
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	unhashedCacheControl  = "public, max-age=300"
+	indexCacheControl     = "no-cache"
+)
+
+type server struct {
+	cfg    Config
+	logger zerolog.Logger
+
+	rootsOnce sync.Once
+	roots     []assetRoot
+	rootsErr  error
+	// fileStats caches the infos of the files of the roots and the missing files by the root and the file
+	fileStats      sync.Map
+	fileStatsCount atomic.Int64
+	// compiled are the configured regexps compiled once
+	regexesOnce sync.Once
+	compiled    *compiledRegexes
+	// scheduled replace the roots at the activation time
+	scheduled scheduledRoots
+	// rolloutRoots serve the sessions assigned to the rollout variant
+	rolloutRoots []assetRoot
+	// versions caches the opened roots of the versions
+	versions sync.Map
+	// tenants caches the opened roots of the tenants
+	tenants sync.Map
+	// preloads caches the preload links of the documents
+	preloads sync.Map
+	// hashedFiles caches the files of the build manifests
+	hashedFiles sync.Map
+	// redirects caches the rules of the redirects files
+	redirects sync.Map
+	// sitemaps caches the routes of the sitemap routes files
+	sitemaps sync.Map
+	// releases are the release directories of the local roots being symlinks
+	releases sync.Map
+	// revisions are the revisions of the served content of the remote roots
+	revisions sync.Map
+	// sites serve the requests of the matching hosts instead of this default site
+	sites []site
+	// proxies pass the matching requests to the upstreams
+	proxies []*reverseProxy
+	// redirects are the configured redirects applied before the lookup
+	configuredRedirects []configuredRedirect
+	// rewrites are the internal rewrites of the request paths
+	rewrites []configuredRewrite
+	// basicAuth protects the requests with the basic auth, nil if not configured
+	basicAuth *basicAuth
+	// oidc protects the requests with the OIDC login, nil if not configured
+	oidc *oidcLogin
+	// bearerAuth protects the requests with the bearer tokens, nil if not configured
+	bearerAuth *bearerAuth
+	// ipFilter allows the clients by their addresses, nil if not configured
+	ipFilter *ipFilter
+	// trustedProxies are the ranges of the proxies whose forwarded headers are honored
+	trustedProxies []*net.IPNet
+	// rateLimiter limits the requests of each client, nil if not configured
+	rateLimiter *rateLimiter
+	// accessLog logs the responses, nil if disabled
+	accessLog *accessLog
+	// acme obtains the certificates of the server, nil if not configured
+	acme *autocert.Manager
+	// metricsHandler serves the Prometheus scrapes on the admin port, nil if disabled
+	metricsHandler http.Handler
+	// admin serves the admin endpoints, built once when the server is activated
+	admin http.Handler
+	// dirHeaders caches the header overrides of the directories
+	dirHeaders sync.Map
+	// etags caches the strong etags of the files
+	etags sync.Map
+	// buildTime overrides the modification times of the files, zero if not configured
+	buildTime time.Time
+	// buildTimes caches the build timestamps read from the roots
+	buildTimes sync.Map
+
+	// lookups coalesces the concurrent lookups of the same file
+	lookups       singleflight.Group
+	coalesceStats struct{ lookups, coalesced atomic.Int64 }
+
+	started time.Time
+	// recent counts the recent errors reported by the status
+	recent recentCounts
+	// har is the debugging capture of the requests
+	har harCaptures
+	// transforms caches the transformed content of the files
+	transforms sync.Map
+	// cspHashes caches the hashes of the inline scripts and styles of the documents
+	cspHashes sync.Map
+	// upstreamResponses caches the responses of the prerender service and the proxied upstreams
+	upstreamResponses      sync.Map
+	upstreamResponsesCount atomic.Int64
+	// memoryLevel is the memory pressure level degrading the caches
+	memoryLevel atomic.Int32
+	// maintenance is the maintenance mode, nil if disabled
+	maintenance atomic.Pointer[maintenanceState]
+	// draining fails the readiness while serving
+	draining atomic.Bool
+	// availability of the roots switching to the offline page
+	availability rootsAvailability
+	// inflight counts the admitted requests in flight
+	inflight atomic.Int64
+	// queued counts the requests waiting for a slot of the in-flight limit
+	queued atomic.Int64
+	// slots limit the requests in flight, nil if unlimited
+	slotsOnce sync.Once
+	slots     *semaphore.Weighted
+	// hooks extend the request handling, set by the library options
+	hooks []Hooks
+}
+
+// newServer creates the server and opens its roots
+func newServer(cfg Config, logger zerolog.Logger) (*server, error) {
+	if err := validateConfig(cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	this := &server{cfg: cfg, logger: logger, started: time.Now(), acme: acmeManager(cfg)}
+	var err error
+	if this.compiled, err = compileRegexes(cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if cfg.LastModified != "" {
+		buildTime, err := parseBuildTime(cfg.LastModified)
+		if err != nil {
+			return nil, err
+		}
+		this.buildTime = buildTime
+	}
+	proxies, err := this.newReverseProxies()
+	if err != nil {
+		return nil, err
+	}
+	this.proxies = proxies
+	if this.configuredRedirects, err = this.newRedirects(); err != nil {
+		return nil, err
+	}
+	if this.rewrites, err = this.newRewrites(); err != nil {
+		return nil, err
+	}
+	if err := this.newAuth(); err != nil {
+		return nil, err
+	}
+	if this.ipFilter, err = this.newIpFilter(); err != nil {
+		return nil, err
+	}
+	if this.trustedProxies, err = parseCidrs(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+	this.rateLimiter = newRateLimiter(cfg.RateLimit, cfg.RateLimitBurst)
+	if this.accessLog, err = newAccessLog(cfg); err != nil {
+		return nil, err
+	}
+	if this.sites, err = this.newSites(); err != nil {
+		return nil, err
+	}
+	if _, err := this.assetRoots(); err != nil {
+		return nil, err
+	}
+	return this, nil
+}
+
+func (this *server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	this.handler(ctx, w, req)
+}
+
+func (this *server) handler(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	if site := this.matchingSite(req.Host); site != nil {
+		site.handler(ctx, w, req)
+		return
+	}
+
+	if this.traceExcluded(req.URL.Path) {
+		ctx = context.WithValue(ctx, traceExcludedKey{}, true)
+	}
+
+	ctx, span := startSpan(
+		ctx, "spa_d.serve_asset",
+		trace.WithAttributes(attribute.String("path", req.URL.Path)),
+	)
+	defer span.End()
+
+	if capture := this.har.active(req.URL.Path); capture != nil {
+		writer := &harWriter{responseWriterPassthrough: responseWriterPassthrough{w}, maxBodySize: capture.maxBodySize}
+		w = writer
+		started := time.Now()
+		defer capture.record(req, writer, started)
+	}
+
+	if this.debugRequested(req) {
+		var debug *lookupDebug
+		ctx, debug = withLookupDebug(ctx)
+		w = &debugWriter{responseWriterPassthrough: responseWriterPassthrough{w}, debug: debug}
+	}
+
+	// the request is logged as received, before the rewrites
+	accessReq := req
+	started := time.Now()
+	if this.cfg.ServerTiming {
+		ctx = withServerTiming(ctx, started)
+	}
+	recorder := &statusRecorder{responseWriterPassthrough: responseWriterPassthrough{w}, beforeHeaders: this.beforeHeaders(ctx, w, req)}
+	w = recorder
+	outcome := outcomeServed
+	defer func() {
+		attrs := []attribute.KeyValue{
+			attribute.Int("status_code", recorder.Status()),
+			attribute.String("status_class", statusClass(recorder.Status())),
+			attribute.String("outcome", outcome),
+		}
+		if this.tenantsEnabled() {
+			attrs = append(attrs, attribute.String("tenant", requestTenant(ctx)))
+		}
+		telemetry().responses.Add(ctx, 1, metric.WithAttributes(attrs...))
+
+		encoding := recorder.Header().Get("Content-Encoding")
+		if encoding == "" {
+			encoding = "identity"
+		}
+		served := metric.WithAttributes(
+			attribute.String("status_class", statusClass(recorder.Status())),
+			attribute.String("encoding", encoding),
+		)
+		telemetry().request_duration.Record(ctx, time.Since(started).Milliseconds(), served)
+		telemetry().response_bytes.Add(ctx, recorder.written, served)
+		if outcome == outcomeError || outcome == outcomeNotFound {
+			this.recent.add(outcome)
+		}
+		if this.accessLog != nil && this.accessLog.sampled(accessReq.URL.Path, recorder.Status()) {
+			entry := accessLogEntry{
+				ClientIp:   this.clientIp(accessReq).String(),
+				Host:       accessReq.Host,
+				Method:     accessReq.Method,
+				Uri:        accessReq.RequestURI,
+				Protocol:   accessReq.Proto,
+				Status:     recorder.Status(),
+				Bytes:      recorder.written,
+				DurationMs: float64(time.Since(started).Microseconds()) / 1000,
+				Encoding:   recorder.Header().Get("Content-Encoding"),
+				Referer:    accessReq.Referer(),
+				UserAgent:  accessReq.UserAgent(),
+			}
+			if spanContext := span.SpanContext(); spanContext.HasTraceID() {
+				entry.TraceId = spanContext.TraceID().String()
+			}
+			this.accessLog.log(entry, started)
+		}
+	}()
+
+	logger := this.requestLogger(req)
+
+	if prefix, ok := this.forwardedPrefix(req); ok {
+		ctx = withForwardedPrefix(ctx, prefix)
+		logger = logger.With().Str("forwarded_prefix", prefix).Logger()
+	}
+
+	if traversalAttempt(req) {
+		outcome = outcomeBlocked
+		debugLookup(ctx, "path traversal blocked")
+		span.SetStatus(codes.Error, "path traversal")
+		telemetry().blocked_requests.Add(ctx, 1, metric.WithAttributes(attribute.String("reason", blockedTraversal)))
+		logger.Warn().Int("status", http.StatusNotFound).Msg("not found - path traversal blocked")
+		this.notFound(w, req.WithContext(ctx))
+		return
+	}
+
+	if this.ipFilter != nil {
+		if client := this.clientIp(req); !this.ipFilter.allows(client) {
+			outcome = outcomeForbidden
+			debugLookup(ctx, "client %v blocked", client)
+			span.SetStatus(codes.Error, "client blocked")
+			logger.Info().Stringer("client", client).Int("status", http.StatusForbidden).Msg("client blocked")
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	if this.rateLimiter != nil {
+		if ok, retryAfter := this.rateLimiter.allow(this.rateLimitKey(req), time.Now()); !ok {
+			outcome = outcomeThrottled
+			debugLookup(ctx, "throttled")
+			span.SetStatus(codes.Error, "throttled")
+			telemetry().throttled_requests.Add(ctx, 1)
+			logger.Info().Int("status", http.StatusTooManyRequests).Msg("throttled")
+			serveThrottled(w, retryAfter)
+			return
+		}
+	}
+
+	if !this.admitRequest(ctx) {
+		outcome = outcomeOverloaded
+		debugLookup(ctx, "overloaded")
+		span.SetStatus(codes.Error, "overloaded")
+		logger.Warn().Int("status", http.StatusServiceUnavailable).Msg("overloaded")
+		this.serveOverloaded(w)
+		return
+	}
+	defer this.releaseRequest()
+
+	if state := this.maintenance.Load(); state != nil {
+		outcome = outcomeMaintenance
+		debugLookup(ctx, "maintenance")
+		if err := this.serveMaintenancePage(ctx, w, state); err != nil {
+			outcome = outcomeError
+			span.SetStatus(codes.Error, err.Error())
+			logger.Err(err).Msg("Error serving maintenance page")
+		}
+		return
+	}
+
+	if this.authRequired(req.URL.Path) {
+		var user string
+		var ok bool
+		if ctx, user, ok = this.authenticate(ctx, w, req.WithContext(ctx)); !ok {
+			// the login redirects and the login callback are redirected
+			outcome = outcomeRedirected
+			if recorder.Status() >= http.StatusBadRequest {
+				outcome = outcomeUnauthorized
+			}
+			debugLookup(ctx, "unauthenticated")
+			logger.Info().Int("status", recorder.Status()).Msg("unauthenticated")
+			return
+		}
+		span.SetAttributes(attribute.String("enduser.id", user))
+		logger = logger.With().Str("user", user).Logger()
+	}
+
+	if location, status, ok := this.configuredRedirectLocation(req); ok {
+		outcome = outcomeRedirected
+		debugLookup(ctx, "redirect %v %v", location, status)
+		logger.Info().Int("status", status).Str("location", location).Msg("redirected")
+		http.Redirect(w, req, location, status)
+		return
+	}
+
+	if proxy := this.matchingProxy(req.URL.Path); proxy != nil {
+		outcome = outcomeProxied
+		debugLookup(ctx, "proxied to %v", proxy.cfg.Upstream)
+		span.SetAttributes(attribute.String("upstream", proxy.cfg.Upstream))
+		this.serveProxied(ctx, w, req, proxy)
+		logger.Info().Int("status", recorder.Status()).Str("upstream", proxy.cfg.Upstream).Msg("proxied")
+		return
+	}
+
+	if this.rootsOffline() {
+		outcome = outcomeOffline
+		debugLookup(ctx, "roots offline")
+		if err := this.serveOfflinePage(ctx, w); err != nil {
+			outcome = outcomeError
+			span.SetStatus(codes.Error, err.Error())
+			logger.Err(err).Msg("Error serving offline page")
+		}
+		return
+	}
+
+	if this.signedUrlRequired(req.URL.Path) {
+		if err := this.verifySignedUrl(req, time.Now()); err != nil {
+			outcome = outcomeForbidden
+			debugLookup(ctx, "signed url %v", err)
+			span.SetStatus(codes.Error, err.Error())
+			logger.Info().Err(err).Int("status", http.StatusForbidden).Msg("forbidden")
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	if rewritten, ok := this.rewritePath(req.URL.Path); ok {
+		debugLookup(ctx, "rewrite %v", rewritten)
+		span.SetAttributes(attribute.String("rewrite.from", req.URL.Path), attribute.String("rewrite.to", rewritten))
+		req = rewriteRequest(req, rewritten)
+	}
+
+	resourcePath := req.URL.Path
+	// strip base url
+	if this.cfg.BaseURL != "" || len(this.cfg.StripPrefixes) > 0 {
+		if stripped, ok := this.stripBaseUrl(req.URL.Path); ok {
+			resourcePath = stripped
+		} else if this.cfg.RedirectToBaseUrl && !this.cfg.AllowSkipBaseUrl {
+			outcome = outcomeBaseUrlRedirect
+			debugLookup(ctx, "base url redirect")
+			logger.Info().Int("status", http.StatusFound).Msg("redirect to base url")
+			http.Redirect(w, req, this.publicBaseUrl(ctx), http.StatusFound)
+			return
+		} else if !this.cfg.AllowSkipBaseUrl {
+			outcome = outcomeBaseUrlMismatch
+			debugLookup(ctx, "base url mismatch")
+			span.SetStatus(codes.Error, "base url missing")
+			logger.Info().Int("status", http.StatusNotFound).Msg("not found - base url mismatch")
+			this.notFound(w, req.WithContext(ctx))
+			return
+		}
+	}
+
+	if this.tenantsEnabled() {
+		tenant, tenantPath, ok := this.splitTenant(req.Host, resourcePath)
+		if ok {
+			roots, err := this.tenantRoots(tenant)
+			ok = err == nil && roots != nil
+			if err != nil {
+				logger.Err(err).Str("tenant", tenant).Msg("Error opening tenant root")
+			}
+		}
+		if !ok {
+			outcome = outcomeNotFound
+			debugLookup(ctx, "unknown tenant")
+			span.SetStatus(codes.Error, "unknown tenant")
+			logger.Info().Int("status", http.StatusNotFound).Msg("not found - unknown tenant")
+			this.notFound(w, req.WithContext(ctx))
+			return
+		}
+		resourcePath = tenantPath
+		ctx = withTenant(ctx, tenant)
+		debugLookup(ctx, "tenant %v", tenant)
+		span.SetAttributes(attribute.String("tenant", tenant))
+		logger = logger.With().Str("tenant", tenant).Logger()
+	}
+
+	version, versionPath, versioned := this.versionedPath(resourcePath)
+	if versioned {
+		// the versioned requests are served from the version directory, including the fallback
+		resourcePath = versionPath
+		ctx = withVersion(ctx, version)
+		debugLookup(ctx, "version %v", version)
+		span.SetAttributes(attribute.String("version", version))
+		logger = logger.With().Str("version", version).Logger()
+	}
+
+	if resourcePath == "" {
+		resourcePath = "index.html"
+	}
+
+	if reason := this.blockedResource(resourcePath); reason != "" {
+		outcome = outcomeBlocked
+		debugLookup(ctx, "%v blocked", reason)
+		span.SetStatus(codes.Error, reason+" blocked")
+		telemetry().blocked_requests.Add(ctx, 1, metric.WithAttributes(attribute.String("reason", reason)))
+		logger.Info().Str("reason", reason).Int("status", http.StatusNotFound).Msg("not found - blocked")
+		this.notFound(w, req.WithContext(ctx))
+		return
+	}
+
+	if this.cfg.CspNonce {
+		var err error
+		if ctx, err = withCspNonce(ctx); err != nil {
+			outcome = outcomeError
+			span.SetStatus(codes.Error, err.Error())
+			logger.Err(err).Int("status", http.StatusInternalServerError).Msg("Error generating nonce")
+			this.internalError(w, req.WithContext(ctx), err)
+			return
+		}
+	}
+
+	if !this.beforeLookup(w, req.WithContext(ctx), resourcePath) {
+		outcome = outcomeHooked
+		debugLookup(ctx, "served by hook")
+		logger.Info().Int("status", recorder.Status()).Msg("served by hook")
+		return
+	}
+
+	if served, status := serveMethod(w, req); served {
+		if status == http.StatusMethodNotAllowed {
+			outcome = outcomeMethodNotAllowed
+			span.SetStatus(codes.Error, "method not allowed")
+		}
+		debugLookup(ctx, "method %v", req.Method)
+		logger.Info().Str("method", req.Method).Int("status", status).Msg("method answered")
+		return
+	}
+
+	if this.rolloutEnabled() && !versioned {
+		variant := this.selectVariant(ctx, w, req)
+		ctx = withVariant(ctx, variant)
+		telemetry().variant_requests.Add(ctx, 1, metric.WithAttributes(attribute.String("variant", variant)))
+		debugLookup(ctx, "variant %v", variant)
+		span.SetAttributes(attribute.String("rollout.variant", variant))
+		logger = logger.With().Str("variant", variant).Logger()
+	}
+
+	if this.prerenderEnabled() && navigationPath(resourcePath) {
+		// the crawlers get a different response
+		w.Header().Add("Vary", "User-Agent")
+		if this.crawlerRequest(req) {
+			span.SetAttributes(attribute.Bool("prerender", true))
+			debugLookup(ctx, "prerender crawler")
+			prerendered, err := this.prerender(ctx, w, req, resourcePath)
+			if err != nil {
+				// the crawlers get the application as the users
+				logger.Warn().Err(err).Msg("Prerendering failed")
+			}
+			if prerendered {
+				outcome = outcomePrerendered
+				logger.Info().Msg("prerendered")
+				span.SetStatus(codes.Ok, "ok")
+				return
+			}
+		}
+	}
+
+	redirected, err := this.applyRedirects(ctx, w, req, resourcePath, true)
+	found := redirected
+
+	if !found && err == nil && !this.configFileRequested(resourcePath) {
+		found, err = this.findAndServeHinted(ctx, resourcePath, w, req)
+	}
+
+	if !found && err == nil && resourcePath == sitemapName && this.sitemapEnabled() {
+		// the sitemap file of the root takes precedence
+		found, err = this.serveSitemap(ctx, w, req)
+	}
+
+	if !found && err == nil && this.directoryEnabled() {
+		found, err = this.serveDirectory(ctx, w, req, resourcePath)
+	}
+
+	if !found && err == nil {
+		redirected, err = this.applyRedirects(ctx, w, req, resourcePath, false)
+		found = redirected
+	}
+
+	if redirected {
+		outcome = outcomeRedirected
+	}
+
+	if !found && err == nil {
+		outcome = outcomeFallback
+		found, err = this.fallback(ctx, w, req, resourcePath)
+	}
+
+	if err != nil {
+		outcome = outcomeError
+		span.SetStatus(codes.Error, err.Error())
+		logger.Err(err).Int("status", http.StatusInternalServerError).Msg("Error serving asset")
+		this.internalError(w, req.WithContext(ctx), err)
+		return
+	}
+
+	if !found {
+		outcome = outcomeNotFound
+		debugLookup(ctx, "not found")
+		telemetry().not_found.Add(ctx, 1,
+			metric.WithAttributes(this.metricPathAttributes(req.URL.Path)...))
+
+		logger.Info().Int("status", http.StatusNotFound).Msg("not found")
+		span.SetStatus(codes.Error, "not found")
+		this.notFound(w, req.WithContext(ctx))
+	}
+	span.SetStatus(codes.Ok, "ok")
+}
+
+// stripBaseUrl strips the longest of the base url and the strip prefixes
+// matching the request path
+func (this *server) stripBaseUrl(requestPath string) (string, bool) {
+	longest := -1
+	for _, prefix := range append([]string{this.cfg.BaseURL}, this.cfg.StripPrefixes...) {
+		if prefix != "" && strings.HasPrefix(requestPath, prefix) && len(prefix) > longest {
+			longest = len(prefix)
+		}
+	}
+	if longest < 0 {
+		return requestPath, false
+	}
+	return requestPath[longest:], true
+}
+
+// requestLogger creates the logger of the request, info logs are suppressed
+// for the paths matching any of the log exclusion regexps
+func (this *server) requestLogger(req *http.Request) zerolog.Logger {
+	logger := this.logger.With().Str("path", req.URL.Path).Logger()
+	if this.regexes().logExclude.matches(req.URL.Path) {
+		return logger.Level(zerolog.WarnLevel)
+	}
+	return logger
+}
+
+// fallbackDocument is the path of the application shell served as the fallback
+func (this *server) fallbackDocument() string {
+	if this.cfg.FallbackDocument == "" {
+		return "/index.html"
+	}
+	return "/" + rootName(this.cfg.FallbackDocument)
+}
+
+// routeFallbackDocument is the fallback document of the application mounted under
+// the longest prefix matching the path, the fallback document if none matches
+func (this *server) routeFallbackDocument(resourcePath string) string {
+	resourcePath = path.Clean("/" + resourcePath)
+	document, longest := this.fallbackDocument(), -1
+	for _, route := range this.cfg.FallbackRoutes {
+		prefix := strings.TrimSuffix(route.Prefix, "/")
+		matches := resourcePath == prefix || strings.HasPrefix(resourcePath, prefix+"/")
+		if matches && len(prefix) > longest {
+			document, longest = "/"+rootName(route.Document), len(prefix)
+		}
+	}
+	return document
+}
+
+// fallbackDocuments are the paths of all the fallback documents
+func (this *server) fallbackDocuments() []string {
+	documents := []string{this.fallbackDocument()}
+	for _, route := range this.cfg.FallbackRoutes {
+		if document := "/" + rootName(route.Document); !slices.Contains(documents, document) {
+			documents = append(documents, document)
+		}
+	}
+	languages := []string{}
+	for _, document := range this.cfg.FallbackLanguages {
+		if document := "/" + rootName(document); !slices.Contains(documents, document) && !slices.Contains(languages, document) {
+			languages = append(languages, document)
+		}
+	}
+	// the map is iterated in random order
+	slices.Sort(languages)
+	return append(documents, languages...)
+}
+
+// acceptsFallback checks the Accept header for any of the media ranges
+// qualifying for the fallback, the ranges with zero quality are not acceptable
+func (this *server) acceptsFallback(req *http.Request) bool {
+	acceptTypes := this.cfg.FallbackAcceptTypes
+	if len(acceptTypes) == 0 {
+		acceptTypes = []string{"text/html"}
+	}
+	return acceptsAny(req, acceptTypes)
+}
+
+// acceptsAny checks the Accept header for any of the media ranges, the ranges
+// with zero quality are not acceptable
+func acceptsAny(req *http.Request, acceptTypes []string) bool {
+	for _, value := range req.Header.Values("Accept") {
+		for _, item := range strings.Split(value, ",") {
+			mediaRange, params, _ := strings.Cut(item, ";")
+			mediaRange = strings.ToLower(strings.TrimSpace(mediaRange))
+			if !slices.ContainsFunc(acceptTypes, func(acceptType string) bool {
+				return strings.EqualFold(acceptType, mediaRange)
+			}) {
+				continue
+			}
+			if quality(params) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (this *server) fallback(ctx context.Context, w http.ResponseWriter, req *http.Request, resourcePath string) (bool, error) {
+	if this.cfg.FallbackDisabled {
+		debugLookup(ctx, "fallback disabled")
+		return false, nil
+	}
+
+	if len(req.Header.Get("Accept")) != 0 && !this.acceptsFallback(req) {
+		debugLookup(ctx, "fallback skipped: accept %v", req.Header.Get("Accept"))
+		return false, nil
+	}
+
+	for _, regex := range this.regexes().notFound {
+		if regex.MatchString(req.URL.Path) {
+			debugLookup(ctx, "fallback skipped: not-found-regexp %v", regex)
+			return false, nil
+		}
+	}
+
+	document := this.routeFallbackDocument(resourcePath)
+	if document == this.fallbackDocument() && len(this.cfg.FallbackLanguages) > 0 {
+		document = this.languageFallbackDocument(w, req)
+	}
+	debugLookup(ctx, "fallback %v", document)
+	found, err := this.findAndServeHinted(ctx, document, w, req)
+	if found {
+		telemetry().fallbacks.Add(ctx, 1,
+			metric.WithAttributes(this.metricPathAttributes(req.URL.Path)...))
+	}
+
+	return found, err
+}
+
+func (this *server) findAndServeEncoded(ctx context.Context, resourcePath string, w http.ResponseWriter, req *http.Request) (bool, error) {
+	if this.transformsContent(ctx, resourcePath) {
+		// precompressed variants cannot be transformed
+		debugLookup(ctx, "transformed, precompressed skipped")
+		if this.cfg.DynamicCompression && this.compressible(resourcePath) {
+			// also the identity response varies, it must not be served to the clients accepting the encodings
+			w.Header().Add("Vary", "Accept-Encoding")
+		}
+		if encoding := this.dynamicEncoding(req, resourcePath); encoding != "" {
+			return this.findAndServeCompressed(ctx, resourcePath, encoding, w, req)
+		}
+		return this.findAndServe(ctx, resourcePath, w, req)
+	}
+
+	encodings := []string{}
+
+	if !this.cfg.BrotliDisabled {
+		encodings = append(encodings, "br")
+	}
+
+	if !this.cfg.ZstdDisabled {
+		encodings = append(encodings, "zstd")
+	}
+
+	if !this.cfg.GzipDisabled {
+		encodings = append(encodings, "gzip")
+	}
+
+	if len(encodings) > 0 {
+		// the representation is selected by the accepted encodings
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+
+	// the client preference selects among the enabled encodings
+	accepted := acceptedEncodings(req, encodings)
+	for _, encoding := range accepted {
+		found, err := func() (bool, error) {
+			ctx, span := startSpan(
+				ctx, "spa_d.lookup_"+encoding+"_asset",
+				trace.WithAttributes(attribute.String("path", req.URL.Path)),
+				trace.WithAttributes(attribute.String("encoding", encoding)),
+			)
+			defer span.End()
+
+			ext := encoding
+			switch encoding {
+			case "gzip":
+				ext = "gz"
+			case "zstd":
+				ext = "zst"
+			}
+
+			file, root, ok, _ := this.findRootFile(ctx, resourcePath+"."+ext)
+			result := cacheMiss
+			if ok {
+				result = cacheHit
+			}
+			telemetry().precompressed_lookups.Add(ctx, 1,
+				metric.WithAttributes(attribute.String("encoding", encoding), attribute.String("result", result)))
+			if ok {
+				defer file.Close()
+
+				// set content type of unencrypted file
+				w.Header().Set("Content-Encoding", encoding)
+				ctype := mime.TypeByExtension(path.Ext(resourcePath))
+				if ctype == "" {
+					// find original resource and sniff content type
+					org, ok, err := this.findFile(ctx, resourcePath)
+					if err != nil {
+						return false, err
+					}
+					if ok {
+						defer org.Close()
+						// read a chunk to decide between utf-8 text and binary
+						var buf [512]byte
+						n, _ := io.ReadFull(org, buf[:])
+						ctype = http.DetectContentType(buf[:n])
+					}
+				}
+
+				if ctype == "" {
+					// fallback to binary if content type could not be detected
+					ctype = "application/octet-stream"
+				}
+
+				w.Header().Set("Content-Type", ctype)
+				if encoding == "br" {
+					telemetry().brotli_encrypted.Add(ctx, 1,
+						metric.WithAttributes(this.metricPathAttributes(req.URL.Path)...))
+				}
+				if encoding == "zstd" {
+					telemetry().zstd_encrypted.Add(ctx, 1,
+						metric.WithAttributes(this.metricPathAttributes(req.URL.Path)...))
+				}
+				if encoding == "gzip" {
+					telemetry().gzip_encrypted.Add(ctx, 1,
+						metric.WithAttributes(this.metricPathAttributes(req.URL.Path)...))
+				}
+				// the Content-Length and Content-Range are of the encoded file
+				err := this.serveContent(ctx, w, encodedRangeRequest(req), resourcePath, root, file)
+				return err == nil, err
+			}
+			return false, nil
+		}()
+		if found || err != nil {
+			return found, err
+		}
+	}
+
+	if encoding := this.dynamicEncoding(req, resourcePath); encoding != "" {
+		return this.findAndServeCompressed(ctx, resourcePath, encoding, w, req)
+	}
+
+	found, err := this.findAndServe(ctx, resourcePath, w, req)
+	if found && len(accepted) > 0 {
+		// client accepts encoding but bundle ships without precompressed variant
+		telemetry().precompressed_missing.Add(ctx, 1,
+			metric.WithAttributes(this.metricPathAttributes(req.URL.Path)...))
+	}
+	return found, err
+}
+
+func (this *server) findAndServe(ctx context.Context, resourcePath string, w http.ResponseWriter, req *http.Request) (bool, error) {
+	file, root, ok, err := this.findRootFile(ctx, resourcePath)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, nil
+	}
+	markResourceFound(ctx)
+	if this.transformsContent(ctx, resourcePath) {
+		file, err = this.transformContent(ctx, resourcePath, file)
+		if err != nil {
+			return false, err
+		}
+	}
+	defer file.Close()
+	err = this.serveContent(ctx, w, req, resourcePath, root, file)
+	return err == nil, err
+}
+
+// serveContent serves the file found in the root
+func (this *server) serveContent(ctx context.Context, w http.ResponseWriter, req *http.Request, name string, root string, file asset) error {
+	logger := this.requestLogger(req)
+	this.applyHeaders(ctx, w, req, name)
+	this.applyAcceptClientHints(w, name)
+	if err := this.applyContentSecurityPolicy(ctx, w, name); err != nil {
+		logger.Err(err).Int("status", http.StatusInternalServerError).Msg("Error computing content security policy")
+		return err
+	}
+	if err := this.applyPreloadHints(ctx, w, req, name); err != nil {
+		logger.Err(err).Int("status", http.StatusInternalServerError).Msg("Error computing preload hints")
+		return err
+	}
+	if err := this.applyDirectoryHeaders(ctx, w, name); err != nil {
+		logger.Err(err).Int("status", http.StatusInternalServerError).Msg("Error reading header overrides")
+		return err
+	}
+	if revision := this.rootRevision(root); revision != "" && this.cfg.RevisionHeader != "" {
+		w.Header().Set(this.cfg.RevisionHeader, revision)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		logger.Err(err).Int("status", http.StatusInternalServerError).Msg("Error getting file info")
+		return err
+	}
+	modTime, err := this.modTime(ctx, info)
+	if err != nil {
+		logger.Err(err).Int("status", http.StatusInternalServerError).Msg("Error getting build timestamp")
+		return err
+	}
+	if this.personalizesContent(ctx, name) {
+		// the cached document would be revalidated with the content of another response
+		modTime = time.Time{}
+	} else if err := this.applyEtag(ctx, w, name, file, info, modTime); err != nil {
+		logger.Err(err).Int("status", http.StatusInternalServerError).Msg("Error computing etag")
+		return err
+	}
+
+	if w.Header().Get("Content-Encoding") != "" {
+		// the precompressed file is served as is, unlike the encoding on the fly
+		// assumed by ServeContent, which omits the length of the encoded responses
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	}
+
+	recorder := &statusRecorder{responseWriterPassthrough: responseWriterPassthrough{this.throttle(ctx, w, req, name)}}
+	http.ServeContent(recorder, req, name, modTime, file)
+	logger.Info().Int("status", recorder.Status()).Msg("asset served")
+
+	if recorder.Status() < http.StatusBadRequest {
+		// the overlay roots are hit only if they override the files
+		telemetry().resources_served.Add(ctx, 1,
+			metric.WithAttributes(attribute.String("root", rootLabel(root))))
+	}
+
+	if recorder.Status() == http.StatusOK {
+		this.recordTransfer(ctx, w.Header().Get("Content-Encoding"), name, info.Size(), recorder.written)
+	}
+	return nil
+}
+
+// recordTransfer records the original and transferred bytes of the served asset
+func (this *server) recordTransfer(ctx context.Context, encoding string, name string, size int64, transferred int64) {
+	original := size
+	if encoding == "" {
+		encoding = "identity"
+	} else {
+		info, ok, err := this.statFile(ctx, name)
+		if err != nil || !ok {
+			return
+		}
+		original = info.Size()
+	}
+
+	attrs := metric.WithAttributes(attribute.String("encoding", encoding))
+	telemetry().original_bytes.Add(ctx, original, attrs)
+	telemetry().transferred_bytes.Add(ctx, transferred, attrs)
+}
+
+func (this *server) findFile(ctx context.Context, resourcePath string) (asset, bool, error) {
+	file, _, ok, err := this.findRootFile(ctx, resourcePath)
+	return file, ok, err
+}
+
+// findRootFile finds the file and the name of the root containing it
+func (this *server) findRootFile(ctx context.Context, resourcePath string) (asset, string, bool, error) {
+	ctx, span := startSpan(
+		ctx, "spa_d.lookup_asset",
+		trace.WithAttributes(attribute.String("file", resourcePath)),
+	)
+	defer span.End()
+
+	roots, err := this.requestRoots(ctx)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	name := rootName(resourcePath)
+	var file asset
+	var root int
+	// the coalesced lookups are reported to the debugged request executing them
+	if this.cfg.CoalesceMaxSize > 0 && !debugging(ctx) {
+		file, root, err = this.coalescedLookup(ctx, roots, name)
+	} else {
+		file, _, root, err = this.lookupFile(ctx, roots, name)
+	}
+	if file == nil {
+		return nil, "", false, err
+	}
+	return file, roots[root].name, true, err
+}
+
+// lookupFile opens the file in the first root containing it, the returned
+// file is nil if none of the roots contains the file
+func (this *server) lookupFile(ctx context.Context, roots []assetRoot, name string) (asset, fs.FileInfo, int, error) {
+	for i, root := range roots {
+		if info, cached := this.cachedFileStat(root.name, name); cached && (info == nil || info.IsDir()) {
+			debugLookup(ctx, "%v:%v missing (cached)", root.name, name)
+			continue
+		}
+		logger := this.logger.With().Str("path", name).Str("root", root.name).Logger()
+		file, info, err := this.openFile(ctx, root.fsys, name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+				// search in the next root, names invalid on the platform
+				// are not found, e.g. with backslashes on Windows
+				debugLookup(ctx, "%v:%v missing", root.name, name)
+				this.cacheFileStat(root.name, name, nil)
+				continue
+			}
+			logger.Err(err).Msg("Error opening file")
+			debugLookup(ctx, "%v:%v error", root.name, name)
+			return nil, nil, 0, err
+		}
+
+		this.cacheFileStat(root.name, name, info)
+		if info.IsDir() {
+			file.Close()
+			debugLookup(ctx, "%v:%v directory", root.name, name)
+			continue
+		}
+		debugLookup(ctx, "%v:%v found", root.name, name)
+		return file, info, i, nil
+	}
+
+	return nil, nil, 0, nil
+}
+
+// assetRoots returns the currently served roots of the server
+func (this *server) assetRoots() ([]assetRoot, error) {
+	this.rootsOnce.Do(func() {
+		this.roots, this.rootsErr = this.openRoots(this.cfg.RootDirs)
+		if this.rootsErr == nil {
+			this.rootsErr = this.openScheduledRoots()
+		}
+		if this.rootsErr == nil && this.rolloutEnabled() {
+			this.rolloutRoots, this.rootsErr = this.openRoots(this.cfg.RolloutRoots)
+		}
+	})
+	if this.rootsErr == nil && this.scheduled.active.Load() {
+		return this.scheduled.roots, nil
+	}
+	return this.roots, this.rootsErr
+}
+
+func (this *server) applyHeaders(
+	ctx context.Context,
+	w http.ResponseWriter,
+	req *http.Request,
+	resourcePath string,
+) {
+
+	// path specific headers
+	for _, matcher := range this.regexes().headersPerPath {
+		if matcher.regex.MatchString(resourcePath) {
+			debugLookup(ctx, "headers-per-regexp %v", matcher.regex)
+			for hdr, value := range matcher.headers {
+				w.Header().Set(hdr, value)
+			}
+		}
+	}
+
+	// merge missing global headers
+	for key, value := range this.cfg.Headers {
+		if _, ok := w.Header()[key]; !ok {
+			w.Header().Set(key, value)
+		}
+	}
+
+	this.applySecurityHeaders(ctx, w)
+
+	if this.cacheBusted(req) {
+		// the url changes with the content, the configured cache control is upgraded
+		debugLookup(ctx, "cache busted")
+		w.Header().Set("Cache-Control", immutableCacheControl)
+	}
+
+	// default cache control
+	if _, ok := w.Header()["Cache-Control"]; !ok && !this.cfg.DefaultCacheControlDisabled {
+		debugLookup(ctx, "default cache-control")
+		cacheControl := this.cfg.DefaultCacheControl
+		if cacheControl == "" {
+			cacheControl = this.assetCacheControl(ctx, req, resourcePath)
+		}
+		if this.indexDocument(resourcePath) {
+			// index.html may be ssr rendered
+			cacheControl = this.cfg.IndexCacheControl
+			if cacheControl == "" {
+				cacheControl = indexCacheControl
+			}
+		}
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+
+	if authenticated(ctx) {
+		debugLookup(ctx, "private cache-control")
+		w.Header().Set("Cache-Control", privateCacheControl(w.Header().Get("Cache-Control")))
+	}
+}
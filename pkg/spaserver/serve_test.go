@@ -0,0 +1,871 @@
+package spaserver
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+//go:embed test/data/testfile.json
+var testfile_json string
+
+//go:embed test/data/index.html
+var index_html string
+
+//go:embed test/data/prebr.js
+var prebr_js string
+
+//go:embed test/data/prebr.js.br
+var prebr_js_br string
+
+//go:embed test/data/prebr.js.gz
+var prebr_js_gz string
+
+//go:embed test/data/prebr.js.zst
+var prebr_js_zst string
+
+type ServeTestSuite struct {
+	suite.Suite
+	testfile_json string
+	cfg           Config
+}
+
+func TestServeTestSuite(t *testing.T) {
+	suite.Run(t, new(ServeTestSuite))
+}
+
+func (suite *ServeTestSuite) SetupTest() {
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.testfile_json = testfile_json
+
+	_, filename, _, _ := runtime.Caller(0)
+	projectRoot := path.Join(path.Dir(filename), "test/data")
+
+	suite.cfg = Config{
+		Port:                7105,
+		RootDirs:            []string{projectRoot},
+		Headers:             map[string]string{},
+		HeadersPerPathRegex: map[string]map[string]string{},
+		NotFoundRegexs:      []string{},
+		LoggingLevel:        "info",
+		JsonLogging:         false,
+	}
+
+}
+
+func (suite *ServeTestSuite) Test_File_exists_Then_OK_With_Content() {
+
+	// given
+	sut := &server{
+		cfg:    suite.cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/testfile.json", nil)
+
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(testfile_json, rr.Body.String())
+}
+
+func (suite *ServeTestSuite) Test_File_not_exists_Then_Fallback_To_Index() {
+
+	// given
+	sut := &server{
+		cfg:    suite.cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/nonexistent", nil)
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(index_html, rr.Body.String())
+
+}
+
+func (suite *ServeTestSuite) Test_File_not_exists_and_fallback_document_Then_Fallback_To_Document() {
+
+	// given
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "200.html"), []byte("shell"), 0644))
+	cfg := suite.cfg
+	cfg.RootDirs = []string{rootDir}
+	cfg.FallbackDocument = "200.html"
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req := httptest.NewRequest("GET", "/users/1", nil)
+	req.Header.Set("Accept", "text/html")
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("shell", rr.Body.String())
+	suite.Equal("no-cache", rr.Header().Get("Cache-Control"))
+}
+
+func (suite *ServeTestSuite) Test_File_not_exists_under_fallback_route_Then_Fallback_To_Route_Document() {
+
+	// given
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.MkdirAll(path.Join(rootDir, "admin/reports"), 0755))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "index.html"), []byte("shell"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "admin/index.html"), []byte("admin"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "admin/reports/index.html"), []byte("reports"), 0644))
+	cfg := suite.cfg
+	cfg.RootDirs = []string{rootDir}
+	cfg.BaseURL = "/"
+	cfg.FallbackRoutes = []FallbackRoute{
+		{Prefix: "/admin/", Document: "admin/index.html"},
+		{Prefix: "/admin/reports", Document: "admin/reports/index.html"},
+	}
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	for target, expected := range map[string]string{
+		"/admin":                 "admin",
+		"/admin/users/1":         "admin",
+		"/admin/reports/2024/q1": "reports",
+		"/administration":        "shell",
+		"/users/1":               "shell",
+	} {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Accept", "text/html")
+		rr := httptest.NewRecorder()
+
+		// when
+		sut.handler(context.Background(), rr, req)
+
+		// then
+		suite.Equal(http.StatusOK, rr.Code, target)
+		suite.Equal(expected, rr.Body.String(), target)
+	}
+}
+
+func (suite *ServeTestSuite) Test_Fallback_route_document_missing_Then_not_ready() {
+
+	// given
+	cfg := suite.cfg
+	cfg.FallbackRoutes = []FallbackRoute{{Prefix: "/admin", Document: "admin/index.html"}}
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	// when
+	err := sut.checkFallbackDocument(context.Background())
+
+	// then
+	suite.ErrorContains(err, "/admin/index.html not found")
+}
+
+func (suite *ServeTestSuite) Test_File_not_exists_and_fallback_accept_type_Then_Fallback_To_Index() {
+
+	// given
+	cfg := suite.cfg
+	cfg.FallbackAcceptTypes = []string{"text/html", "*/*"}
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	for accept, status := range map[string]int{
+		"*/*":                             http.StatusOK,
+		"application/xhtml+xml, */*":      http.StatusOK,
+		"application/json":                http.StatusNotFound,
+		"application/json, text/html;q=0": http.StatusNotFound,
+	} {
+		req := httptest.NewRequest("GET", "/nonexistent", nil)
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+
+		// when
+		sut.handler(context.Background(), rr, req)
+
+		// then
+		suite.Equal(status, rr.Code, accept)
+	}
+}
+
+func (suite *ServeTestSuite) Test_File_not_exists_and_fallback_disabled_Then_NotFound() {
+
+	// given
+	cfg := suite.cfg
+	cfg.FallbackDisabled = true
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/nonexistent", nil)
+	suite.Nil(err)
+	req.Header.Set("Accept", "text/html")
+
+	rr := httptest.NewRecorder()
+
+	// when
+
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+}
+
+func (suite *ServeTestSuite) Test_File_not_exists_and_excluded_Then_NotFound() {
+
+	// given
+	cfg := suite.cfg
+	cfg.NotFoundRegexs = []string{"\\.json"}
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/nonexistent.json", nil)
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+}
+
+func (suite *ServeTestSuite) Test_File_not_exists_and_not_accepts_html_Then_NotFound() {
+
+	// given
+	cfg := suite.cfg
+	cfg.NotFoundRegexs = []string{"\\.json"}
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/nonexistent.json", nil)
+	req.Header.Set("Accept", "application/json")
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+}
+
+func (suite *ServeTestSuite) Test_File_precompressed_br_Then_OK_and_encoded() {
+
+	// given
+	sut := &server{
+		cfg:    suite.cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/prebr.js", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("br", rr.Header().Get("Content-Encoding"))
+	// application/javascript or text/javascript;charset=utf-8 - platform dependent
+	suite.True(
+		strings.Contains(rr.Header().Get("Content-Type"), "/javascript"),
+		"was %v but want `*/javascript`",
+		rr.Header().Get("Content-Type"))
+	suite.Equal(prebr_js_br, rr.Body.String())
+}
+
+func (suite *ServeTestSuite) Test_File_precompressed_zstd_Then_OK_and_encoded() {
+
+	// given
+	sut := &server{
+		cfg:    suite.cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req := httptest.NewRequest("GET", "/prebr.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip, zstd")
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("zstd", rr.Header().Get("Content-Encoding"))
+	suite.Equal(prebr_js_zst, rr.Body.String())
+}
+
+func (suite *ServeTestSuite) Test_File_precompressed_zstd_disabled_Then_gzip() {
+
+	// given
+	cfg := suite.cfg
+	cfg.ZstdDisabled = true
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req := httptest.NewRequest("GET", "/prebr.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip, zstd")
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("gzip", rr.Header().Get("Content-Encoding"))
+	suite.Equal(prebr_js_gz, rr.Body.String())
+}
+
+func (suite *ServeTestSuite) Test_File_precompressed_range_Then_range_of_encoded_file() {
+
+	// given
+	sut := &server{
+		cfg:    suite.cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req := httptest.NewRequest("GET", "/prebr.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0.5, br;q=0.9")
+	req.Header.Set("Range", "bytes=0-3")
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusPartialContent, rr.Code)
+	suite.Equal("br", rr.Header().Get("Content-Encoding"))
+	suite.Equal("4", rr.Header().Get("Content-Length"))
+	suite.Equal(fmt.Sprintf("bytes 0-3/%v", len(prebr_js_br)), rr.Header().Get("Content-Range"))
+	suite.Equal(prebr_js_br[:4], rr.Body.String())
+	suite.Contains(rr.Header().Values("Vary"), "Accept-Encoding")
+}
+
+func (suite *ServeTestSuite) Test_File_precompressed_if_range_date_Then_full_encoded_file() {
+
+	// given
+	sut := &server{
+		cfg:    suite.cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req := httptest.NewRequest("GET", "/prebr.js", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	req.Header.Set("Range", "bytes=0-3")
+	req.Header.Set("If-Range", time.Now().UTC().Format(http.TimeFormat))
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(strconv.Itoa(len(prebr_js_br)), rr.Header().Get("Content-Length"))
+	suite.Equal(prebr_js_br, rr.Body.String())
+}
+
+func (suite *ServeTestSuite) Test_File_precompressed_Then_etag_per_encoding() {
+
+	// given
+	cfg := suite.cfg
+	cfg.Etag = "strong"
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	etags := map[string]bool{}
+	for _, encoding := range []string{"br", "zstd", "gzip", "identity"} {
+		req := httptest.NewRequest("GET", "/prebr.js", nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		rr := httptest.NewRecorder()
+
+		// when
+		sut.handler(context.Background(), rr, req)
+
+		// then
+		suite.Equal(http.StatusOK, rr.Code)
+		etags[rr.Header().Get("ETag")] = true
+	}
+	suite.Len(etags, 4)
+}
+
+func (suite *ServeTestSuite) Test_File_precompressed_br_refused_Then_gzip() {
+
+	// given
+	sut := &server{
+		cfg:    suite.cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req := httptest.NewRequest("GET", "/prebr.js", nil)
+	req.Header.Set("Accept-Encoding", "*, br;q=0, zstd;q=0")
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("gzip", rr.Header().Get("Content-Encoding"))
+}
+
+func (suite *ServeTestSuite) Test_File_precompressed_br_disabled_Then_OK_and_not_encoded() {
+
+	// given
+	cfg := suite.cfg
+	cfg.BrotliDisabled = true
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/prebr.js", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("", rr.Header().Get("Content-Encoding"))
+	// application/javascript or text/javascript;charset=utf-8 - platform dependent
+	suite.True(
+		strings.Contains(rr.Header().Get("Content-Type"), "/javascript"),
+		"was %v but want `*/javascript`",
+		rr.Header().Get("Content-Type"))
+	suite.Equal(prebr_js, rr.Body.String())
+}
+
+func (suite *ServeTestSuite) Test_File_precompressed_gz_Then_OK_and_encoded() {
+
+	// given
+	sut := &server{
+		cfg:    suite.cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/prebr.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("gzip", rr.Header().Get("Content-Encoding"))
+	// application/javascript or text/javascript;charset=utf-8 - platform dependent
+	suite.True(
+		strings.Contains(rr.Header().Get("Content-Type"), "/javascript"),
+		"was %v but want `*/javascript`",
+		rr.Header().Get("Content-Type"))
+	suite.Equal(prebr_js_gz, rr.Body.String())
+}
+
+func (suite *ServeTestSuite) Test_File_precompressed_gz_and_base_url_set_Then_OK_and_encoded() {
+
+	cfg := suite.cfg
+	cfg.BaseURL = "/prefix/to/"
+	// given
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/prefix/to/prebr.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("gzip", rr.Header().Get("Content-Encoding"))
+	// application/javascript or text/javascript;charset=utf-8 - platform dependent
+	suite.True(
+		strings.Contains(rr.Header().Get("Content-Type"), "/javascript"),
+		"was %v but want `*/javascript`",
+		rr.Header().Get("Content-Type"))
+	suite.Equal(prebr_js_gz, rr.Body.String())
+}
+
+func (suite *ServeTestSuite) Test_File_precompressed_gzip_disabled_Then_OK_and_not_encoded() {
+
+	// given
+	cfg := suite.cfg
+	cfg.GzipDisabled = true
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/prebr.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("", rr.Header().Get("Content-Encoding"))
+	// application/javascript or text/javascript;charset=utf-8 - platform dependent
+	suite.True(
+		strings.Contains(rr.Header().Get("Content-Type"), "/javascript"),
+		"was %v but want `*/javascript`",
+		rr.Header().Get("Content-Type"))
+	suite.Equal(prebr_js, rr.Body.String())
+}
+
+func (suite *ServeTestSuite) Test_Unhashed_file_exist_Then_cache_revalidated() {
+
+	// given
+	sut := &server{
+		cfg:    suite.cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/testfile.json", nil)
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("public, max-age=300", rr.Header().Get("Cache-Control"))
+}
+
+func (suite *ServeTestSuite) Test_Index_Then_no_cache() {
+
+	// given
+	sut := &server{
+		cfg:    suite.cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/", nil)
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("no-cache", rr.Header().Get("Cache-Control"))
+}
+
+func (suite *ServeTestSuite) Test_Default_cache_control_configured_Then_applied() {
+
+	// given
+	cfg := suite.cfg
+	cfg.DefaultCacheControl = "public, max-age=300"
+	cfg.IndexCacheControl = "no-store"
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	file := httptest.NewRecorder()
+	index := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), file, httptest.NewRequest("GET", "/testfile.json", nil))
+	sut.handler(context.Background(), index, httptest.NewRequest("GET", "/", nil))
+
+	// then
+	suite.Equal("public, max-age=300", file.Header().Get("Cache-Control"))
+	suite.Equal("no-store", index.Header().Get("Cache-Control"))
+}
+
+func (suite *ServeTestSuite) Test_Default_cache_control_disabled_Then_not_sent() {
+
+	// given
+	cfg := suite.cfg
+	cfg.DefaultCacheControlDisabled = true
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, httptest.NewRequest("GET", "/testfile.json", nil))
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.NotContains(rr.Header(), "Cache-Control")
+}
+
+func (suite *ServeTestSuite) Test_File_exist_Then_global_headers_are_applied() {
+
+	// given
+	cfg := suite.cfg
+	cfg.Headers = map[string]string{"X-Test": "test", "Cache-Control": "no-cache"}
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/testfile.json", nil)
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("test", rr.Header().Get("X-Test"))
+	suite.Equal("no-cache", rr.Header().Get("Cache-Control"))
+}
+
+func (suite *ServeTestSuite) Test_File_exist_Then_resource_headers_are_applied() {
+
+	// given
+	cfg := suite.cfg
+	cfg.Headers = map[string]string{"X-Test": "test", "Cache-Control": "no-cache"}
+	cfg.HeadersPerPathRegex = map[string]map[string]string{
+		"\\.json": {"X-Test2": "test2", "Cache-Control": "immutable"},
+		"\\.txt":  {"X-Test2": "test3"},
+	}
+
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/testfile.json", nil)
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("test", rr.Header().Get("X-Test"))
+	suite.Equal("test2", rr.Header().Get("X-Test2"))
+	suite.Equal("immutable", rr.Header().Get("Cache-Control"))
+}
+
+func (suite *ServeTestSuite) Test_BaseUrl_prefix_and_relative_file_exists_Then_OK_With_Content() {
+
+	// given
+	cfg := suite.cfg
+	cfg.BaseURL = "/prefix/to/"
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/prefix/to/testfile.json", nil)
+
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(testfile_json, rr.Body.String())
+
+}
+
+func (suite *ServeTestSuite) Test_Path_excluded_from_logging_Then_no_info_logs() {
+
+	// given
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	cfg := suite.cfg
+	cfg.LogExcludeRegexs = []string{"^/testfile\\.json$"}
+	logs := &bytes.Buffer{}
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(logs),
+	}
+
+	excluded, err := http.NewRequest("GET", "/testfile.json", nil)
+	suite.Nil(err)
+	included, err := http.NewRequest("GET", "/prebr.js", nil)
+	suite.Nil(err)
+
+	// when
+	sut.handler(context.Background(), httptest.NewRecorder(), excluded)
+	excludedLogs := logs.String()
+	sut.handler(context.Background(), httptest.NewRecorder(), included)
+
+	// then
+	suite.Empty(excludedLogs)
+	suite.Contains(logs.String(), "/prebr.js")
+}
+
+func (suite *ServeTestSuite) Test_Path_outside_BaseUrl_with_redirect_Then_Found() {
+
+	// given
+	cfg := suite.cfg
+	cfg.BaseURL = "/app/"
+	cfg.RedirectToBaseUrl = true
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/", nil)
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusFound, rr.Code)
+	suite.Equal("/app/", rr.Header().Get("Location"))
+}
+
+func (suite *ServeTestSuite) Test_Path_outside_BaseUrl_without_redirect_Then_Not_Found() {
+
+	// given
+	cfg := suite.cfg
+	cfg.BaseURL = "/app/"
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	req, err := http.NewRequest("GET", "/", nil)
+	suite.Nil(err)
+
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+}
+
+func (suite *ServeTestSuite) Test_Strip_prefixes_Then_longest_prefix_stripped() {
+
+	// given
+	cfg := suite.cfg
+	cfg.BaseURL = "/app/"
+	cfg.StripPrefixes = []string{"/app/v2/", "/other/"}
+	sut := &server{
+		cfg:    cfg,
+		logger: zerolog.New(os.Stdout),
+	}
+
+	// when
+	base, baseOk := sut.stripBaseUrl("/app/testfile.json")
+	nested, nestedOk := sut.stripBaseUrl("/app/v2/testfile.json")
+	other, otherOk := sut.stripBaseUrl("/other/testfile.json")
+	_, mismatchOk := sut.stripBaseUrl("/testfile.json")
+
+	// then
+	suite.True(baseOk && nestedOk && otherOk)
+	suite.False(mismatchOk)
+	suite.Equal("testfile.json", base)
+	suite.Equal("testfile.json", nested)
+	suite.Equal("testfile.json", other)
+}
+
+func (suite *ServeTestSuite) Test_Overlay_root_Then_root_of_file_reported() {
+
+	// given
+	overlay := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(overlay, "index.html"), []byte("override"), 0644))
+	suite.cfg.RootDirs = append([]string{overlay}, suite.cfg.RootDirs...)
+	suite.cfg.CoalesceMaxSize = 1024
+	sut := &server{cfg: suite.cfg}
+
+	// when
+	overridden, overriddenRoot, _, err := sut.findRootFile(context.Background(), "/index.html")
+	suite.Require().Nil(err)
+	defer overridden.Close()
+	base, baseRoot, _, err := sut.findRootFile(context.Background(), "/testfile.json")
+	suite.Require().Nil(err)
+	defer base.Close()
+
+	// then
+	suite.Equal(overlay, overriddenRoot)
+	suite.Equal(suite.cfg.RootDirs[1], baseRoot)
+}
@@ -0,0 +1,88 @@
+package spaserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// trace response headers
+const (
+	traceResponseXTraceId = "x-trace-id"
+	traceResponseW3c      = "traceresponse"
+)
+
+var traceResponseHeaders = []string{traceResponseXTraceId, traceResponseW3c}
+
+type serverTimingKey struct{}
+
+// serverTiming measures the phases of the request, the lookup of the resource
+// and the serving of the found resource until the headers are written
+type serverTiming struct {
+	started time.Time
+	found   time.Time
+}
+
+func withServerTiming(ctx context.Context, started time.Time) context.Context {
+	return context.WithValue(ctx, serverTimingKey{}, &serverTiming{started: started})
+}
+
+// markResourceFound ends the lookup phase of the request at the first found resource
+func markResourceFound(ctx context.Context) {
+	if timing, ok := ctx.Value(serverTimingKey{}).(*serverTiming); ok && timing.found.IsZero() {
+		timing.found = time.Now()
+	}
+}
+
+// header formats the Server-Timing header value of the phases until now
+func (this *serverTiming) header(now time.Time) string {
+	metrics := []string{}
+	if !this.found.IsZero() {
+		metrics = append(metrics,
+			serverTimingMetric("lookup", this.found.Sub(this.started)),
+			serverTimingMetric("serve", now.Sub(this.found)))
+	}
+	return strings.Join(append(metrics, serverTimingMetric("total", now.Sub(this.started))), ", ")
+}
+
+func serverTimingMetric(name string, duration time.Duration) string {
+	return fmt.Sprintf("%v;dur=%.3f", name, float64(duration.Microseconds())/1000)
+}
+
+// beforeHeaders returns the callback of the response before the headers are
+// written, nil if none
+func (this *server) beforeHeaders(ctx context.Context, w http.ResponseWriter, req *http.Request) func(status int) {
+	hooks := this.afterHeaders(w, req)
+	if !this.cfg.ServerTiming && this.cfg.TraceResponseHeader == "" {
+		return hooks
+	}
+	return func(status int) {
+		this.applyResponseTiming(ctx, w)
+		if hooks != nil {
+			hooks(status)
+		}
+	}
+}
+
+// applyResponseTiming sets the Server-Timing and the trace id headers of the
+// response, called before the headers are written
+func (this *server) applyResponseTiming(ctx context.Context, w http.ResponseWriter) {
+	if timing, ok := ctx.Value(serverTimingKey{}).(*serverTiming); ok {
+		w.Header().Set("Server-Timing", timing.header(time.Now()))
+	}
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return
+	}
+	switch this.cfg.TraceResponseHeader {
+	case traceResponseXTraceId:
+		w.Header().Set("X-Trace-Id", spanContext.TraceID().String())
+	case traceResponseW3c:
+		w.Header().Set("Traceresponse", fmt.Sprintf("00-%v-%v-%v",
+			spanContext.TraceID(), spanContext.SpanID(), spanContext.TraceFlags()))
+	}
+}
@@ -0,0 +1,105 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type ServerTimingTestSuite struct {
+	suite.Suite
+	rootDir string
+}
+
+func TestServerTimingTestSuite(t *testing.T) {
+	suite.Run(t, new(ServerTimingTestSuite))
+}
+
+func (suite *ServerTimingTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	spanRecorder()
+	suite.rootDir = suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "index.html"), []byte("app"), 0644))
+}
+
+func (suite *ServerTimingTestSuite) serve(cfg Config, target string) *httptest.ResponseRecorder {
+	cfg.RootDirs = []string{suite.rootDir}
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, httptest.NewRequest("GET", target, nil))
+	return rr
+}
+
+func (suite *ServerTimingTestSuite) Test_Server_timing_Then_phases() {
+
+	// when
+	rr := suite.serve(Config{ServerTiming: true}, "/index.html")
+
+	// then
+	suite.Equal(200, rr.Code)
+	suite.Regexp(`^lookup;dur=\d+\.\d{3}, serve;dur=\d+\.\d{3}, total;dur=\d+\.\d{3}$`, rr.Header().Get("Server-Timing"))
+}
+
+func (suite *ServerTimingTestSuite) Test_Server_timing_not_found_Then_total_only() {
+
+	// when
+	rr := suite.serve(Config{ServerTiming: true, FallbackDisabled: true}, "/missing.js")
+
+	// then
+	suite.Equal(404, rr.Code)
+	suite.Regexp(`^total;dur=\d+\.\d{3}$`, rr.Header().Get("Server-Timing"))
+}
+
+func (suite *ServerTimingTestSuite) Test_Disabled_Then_no_headers() {
+
+	// when
+	rr := suite.serve(Config{}, "/index.html")
+
+	// then
+	suite.Empty(rr.Header().Get("Server-Timing"))
+	suite.Empty(rr.Header().Get("X-Trace-Id"))
+	suite.Empty(rr.Header().Get("Traceresponse"))
+}
+
+func (suite *ServerTimingTestSuite) Test_X_trace_id_Then_trace_of_span() {
+
+	// when
+	rr := suite.serve(Config{TraceResponseHeader: traceResponseXTraceId}, "/index.html")
+
+	// then
+	suite.Regexp(`^[0-9a-f]{32}$`, rr.Header().Get("X-Trace-Id"))
+}
+
+func (suite *ServerTimingTestSuite) Test_Traceresponse_Then_w3c_format() {
+
+	// when
+	rr := suite.serve(Config{TraceResponseHeader: traceResponseW3c}, "/index.html")
+
+	// then
+	suite.Regexp(`^00-[0-9a-f]{32}-[0-9a-f]{16}-0[01]$`, rr.Header().Get("Traceresponse"))
+}
+
+func (suite *ServerTimingTestSuite) Test_Trace_excluded_Then_no_trace_id() {
+
+	// when
+	rr := suite.serve(Config{TraceResponseHeader: traceResponseXTraceId, TraceExcludeRegexs: []string{"^/index"}}, "/index.html")
+
+	// then
+	suite.Empty(rr.Header().Get("X-Trace-Id"))
+}
+
+func (suite *ServerTimingTestSuite) Test_Unknown_trace_header_Then_invalid() {
+
+	// when
+	_, err := newServer(Config{TraceResponseHeader: "b3"}, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "trace-response-header: unknown header b3")
+}
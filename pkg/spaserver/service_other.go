@@ -0,0 +1,8 @@
+//go:build !windows
+
+package spaserver
+
+// runService does nothing, services are supported only on Windows
+func runService(shutdown func()) (started bool, err error) {
+	return false, nil
+}
@@ -0,0 +1,40 @@
+//go:build windows
+
+package spaserver
+
+import (
+	"golang.org/x/sys/windows/svc"
+)
+
+// serviceName is the name of the service registered in the service control manager
+const serviceName = "spa_d"
+
+// runService runs the server under the Windows service control manager until the
+// service is stopped, started is false when the process is not a service
+func runService(shutdown func()) (started bool, err error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false, err
+	}
+	return true, svc.Run(serviceName, &serviceHandler{shutdown: shutdown})
+}
+
+// serviceHandler handles the requests of the service control manager
+type serviceHandler struct {
+	shutdown func()
+}
+
+func (this *serviceHandler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for request := range requests {
+		switch request.Cmd {
+		case svc.Interrogate:
+			status <- request.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			status <- svc.Status{State: svc.StopPending}
+			this.shutdown()
+			return false, 0
+		}
+	}
+	return false, 0
+}
@@ -0,0 +1,74 @@
+package spaserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// connectionCounter counts the open connections of the server, so that the
+// shutdown reports the drained and the closed ones
+type connectionCounter struct {
+	open atomic.Int64
+}
+
+// countConnections tracks the connections of the server, it must be called
+// before the server starts serving
+func countConnections(httpServer *http.Server) *connectionCounter {
+	counter := &connectionCounter{}
+	previous := httpServer.ConnState
+	httpServer.ConnState = func(conn net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			counter.open.Add(1)
+		case http.StateHijacked, http.StateClosed:
+			counter.open.Add(-1)
+		}
+		if previous != nil {
+			previous(conn, state)
+		}
+	}
+	return counter
+}
+
+func (this *connectionCounter) count() int64 {
+	if this == nil {
+		return 0
+	}
+	return this.open.Load()
+}
+
+// shutdownServer drains the server: the requests keep being served for the drain
+// delay, while the readiness fails, so that the load balancers stop sending new
+// traffic, then the server stops accepting the connections and waits for the
+// requests in flight, e.g. long downloads, until the timeout, the remaining
+// connections are closed
+func shutdownServer(httpServer *http.Server, connections *connectionCounter, delay time.Duration, timeout time.Duration, logger zerolog.Logger) error {
+	if delay > 0 {
+		logger.Info().Dur("delay", delay).Int64("connections", connections.count()).Msg("Waiting for the load balancers to stop the traffic")
+		time.Sleep(delay)
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	open := connections.count()
+	logger.Info().Dur("timeout", timeout).Int64("connections", open).Msg("Draining requests")
+	err := httpServer.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		stragglers := connections.count()
+		logger.Warn().Dur("timeout", timeout).Int64("drained", max(open-stragglers, 0)).Int64("closed", stragglers).
+			Msg("Shutdown timeout exceeded, closing connections")
+		return httpServer.Close()
+	}
+	logger.Info().Int64("drained", open).Msg("Requests drained")
+	return err
+}
@@ -0,0 +1,115 @@
+package spaserver
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type ShutdownTestSuite struct {
+	suite.Suite
+}
+
+func TestShutdownTestSuite(t *testing.T) {
+	suite.Run(t, new(ShutdownTestSuite))
+}
+
+func (suite *ShutdownTestSuite) serve(delay time.Duration) (*http.Server, *connectionCounter, chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().Nil(err)
+	started := make(chan struct{})
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		close(started)
+		time.Sleep(delay)
+		io.WriteString(w, "done")
+	})}
+	connections := countConnections(httpServer)
+	go httpServer.Serve(listener)
+
+	response := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err == nil {
+			_, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		response <- err
+	}()
+	<-started
+	return httpServer, connections, response
+}
+
+func (suite *ShutdownTestSuite) Test_Request_within_timeout_Then_completed() {
+
+	// given
+	httpServer, connections, response := suite.serve(100 * time.Millisecond)
+
+	// when
+	err := shutdownServer(httpServer, connections, 0, time.Second, zerolog.Nop())
+
+	// then
+	suite.Nil(err)
+	suite.Nil(<-response)
+}
+
+func (suite *ShutdownTestSuite) Test_Timeout_exceeded_Then_connections_closed() {
+
+	// given
+	httpServer, connections, response := suite.serve(2 * time.Second)
+	started := time.Now()
+
+	// when
+	err := shutdownServer(httpServer, connections, 0, 100*time.Millisecond, zerolog.Nop())
+
+	// then
+	suite.Nil(err)
+	suite.Less(time.Since(started), time.Second)
+	suite.NotNil(<-response)
+}
+
+func (suite *ShutdownTestSuite) Test_Drain_delay_Then_requests_served_before_shutdown() {
+
+	// given
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().Nil(err)
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "done")
+	})}
+	connections := countConnections(httpServer)
+	go httpServer.Serve(listener)
+	done := make(chan error, 1)
+
+	// when
+	go func() {
+		done <- shutdownServer(httpServer, connections, 200*time.Millisecond, time.Second, zerolog.Nop())
+	}()
+	time.Sleep(50 * time.Millisecond)
+	resp, err := http.Get("http://" + listener.Addr().String())
+
+	// then
+	suite.Require().Nil(err, "the new requests are served during the drain delay")
+	resp.Body.Close()
+	suite.Nil(<-done)
+	suite.Eventually(func() bool { return connections.count() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func (suite *ShutdownTestSuite) Test_Connections_Then_counted_until_closed() {
+
+	// given
+	httpServer, connections, response := suite.serve(100 * time.Millisecond)
+
+	// when
+	open := connections.count()
+	suite.Nil(<-response)
+	err := shutdownServer(httpServer, connections, 0, time.Second, zerolog.Nop())
+
+	// then
+	suite.Nil(err)
+	suite.Equal(int64(1), open)
+	suite.Eventually(func() bool { return connections.count() == 0 }, time.Second, 10*time.Millisecond)
+}
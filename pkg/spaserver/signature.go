@@ -0,0 +1,457 @@
+package spaserver
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	cosignSignatureAnnotation   = "dev.cosignproject.cosign/signature"
+	cosignCertificateAnnotation = "dev.sigstore.cosign/certificate"
+	cosignChainAnnotation       = "dev.sigstore.cosign/chain"
+	cosignBundleAnnotation      = "dev.sigstore.cosign/bundle"
+	cosignSimpleSigningType     = "application/vnd.dev.cosign.simplesigning.v1+json"
+)
+
+var (
+	// fulcioIssuerV1Oid is the certificate extension of the OIDC issuer as a raw string
+	fulcioIssuerV1Oid = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	// fulcioIssuerOid is the certificate extension of the OIDC issuer as a DER encoded string
+	fulcioIssuerOid = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// signatureVerified reports whether the fetched bundles must be signed
+func signatureVerified(cfg Config) bool {
+	return len(cfg.SignaturePublicKeys) > 0 || len(cfg.SignatureIdentities) > 0
+}
+
+// loadPublicKeys loads the PEM encoded public keys trusted to sign the bundles
+func loadPublicKeys(files []string) ([]crypto.PublicKey, error) {
+	keys := make([]crypto.PublicKey, 0, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode(content)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM encoded key in %v", file)
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse public key %v: %w", file, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// verifySignature verifies the signature of the payload as created by
+// `cosign sign-blob`, the signature is valid if any of the keys verifies it
+func verifySignature(keys []crypto.PublicKey, payload []byte, signature []byte) error {
+	digest := sha256.Sum256(payload)
+	for _, key := range keys {
+		switch key := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(key, digest[:], signature) {
+				return nil
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+				return nil
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(key, payload, signature) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("signature not verified by any of the trusted keys")
+}
+
+// decodeSignature decodes the base64 encoded signature
+func decodeSignature(encoded []byte) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+}
+
+// signatureIdentities are the certificate identities trusted to sign the bundles
+// keyless, i.e. by the short-lived certificates of the Fulcio CA, whose signing
+// time is proven by the Rekor transparency log
+type signatureIdentities struct {
+	identities    []string
+	issuer        string
+	roots         *x509.CertPool
+	intermediates []*x509.Certificate
+	// tlogKeys are the public keys of the transparency logs by their log id
+	tlogKeys map[string]crypto.PublicKey
+}
+
+// rekorBundle is the offline proof of the transparency log entry attached by cosign
+type rekorBundle struct {
+	SignedEntryTimestamp []byte       `json:"SignedEntryTimestamp"`
+	Payload              rekorPayload `json:"Payload"`
+}
+
+// rekorPayload is the transparency log entry, the fields are in the canonical
+// order signed by the signed entry timestamp
+type rekorPayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// hashedRekord is the body of the transparency log entry of a signed digest
+type hashedRekord struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   []byte `json:"content"`
+			PublicKey struct {
+				Content []byte `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// cosignBlobBundle is the bundle created by `cosign sign-blob --bundle`
+type cosignBlobBundle struct {
+	Base64Signature string          `json:"base64Signature"`
+	Cert            string          `json:"cert"`
+	RekorBundle     json.RawMessage `json:"rekorBundle"`
+}
+
+// loadSignatureIdentities loads the certificate authorities and the transparency
+// logs trusted to sign for the identities, nil if no identities are configured
+func loadSignatureIdentities(cfg Config) (*signatureIdentities, error) {
+	if len(cfg.SignatureIdentities) == 0 {
+		return nil, nil
+	}
+	this := &signatureIdentities{
+		identities: cfg.SignatureIdentities,
+		issuer:     cfg.SignatureOidcIssuer,
+		roots:      x509.NewCertPool(),
+		tlogKeys:   map[string]crypto.PublicKey{},
+	}
+	for _, file := range cfg.SignatureCertificateRoots {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		certificates, err := parseCertificates(content)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse certificates %v: %w", file, err)
+		}
+		if len(certificates) == 0 {
+			return nil, fmt.Errorf("no PEM encoded certificate in %v", file)
+		}
+		for _, certificate := range certificates {
+			// the self-signed certificates are the roots, the others the intermediates
+			if bytes.Equal(certificate.RawIssuer, certificate.RawSubject) && certificate.CheckSignatureFrom(certificate) == nil {
+				this.roots.AddCert(certificate)
+			} else {
+				this.intermediates = append(this.intermediates, certificate)
+			}
+		}
+	}
+
+	keys, err := loadPublicKeys(cfg.SignatureTlogPublicKeys)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			return nil, err
+		}
+		logId := sha256.Sum256(der)
+		this.tlogKeys[hex.EncodeToString(logId[:])] = key
+	}
+	return this, nil
+}
+
+// parseCertificates parses the PEM encoded certificates
+func parseCertificates(content []byte) ([]*x509.Certificate, error) {
+	certificates := []*x509.Certificate{}
+	for block, rest := pem.Decode(content); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, certificate)
+	}
+	return certificates, nil
+}
+
+// verify verifies the signature of the payload by the certificate of any of the
+// identities. The certificate is short-lived, so it is verified at the time the
+// signature was integrated into the transparency log.
+func (this *signatureIdentities) verify(payload []byte, signature []byte, certificatePem []byte, chainPem []byte, bundle []byte) error {
+	certificates, err := parseCertificates(certificatePem)
+	if err != nil || len(certificates) == 0 {
+		return fmt.Errorf("invalid signing certificate")
+	}
+	certificate := certificates[0]
+	integrated, err := this.verifyTransparencyLog(bundle, payload, signature, certificate)
+	if err != nil {
+		return err
+	}
+
+	intermediates := x509.NewCertPool()
+	chain, err := parseCertificates(chainPem)
+	if err != nil {
+		return fmt.Errorf("invalid certificate chain: %w", err)
+	}
+	for _, intermediate := range append(chain, this.intermediates...) {
+		intermediates.AddCert(intermediate)
+	}
+	_, err = certificate.Verify(x509.VerifyOptions{
+		Roots:         this.roots,
+		Intermediates: intermediates,
+		CurrentTime:   integrated,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return fmt.Errorf("untrusted signing certificate: %w", err)
+	}
+
+	if issuer := certificateIssuer(certificate); issuer != this.issuer {
+		return fmt.Errorf("signing certificate issued by %q, expected %v", issuer, this.issuer)
+	}
+	identities := append(slices.Clone(certificate.EmailAddresses), certificateUris(certificate)...)
+	if !slices.ContainsFunc(identities, func(identity string) bool { return slices.Contains(this.identities, identity) }) {
+		return fmt.Errorf("signing certificate identities %v not trusted", identities)
+	}
+	return verifySignature([]crypto.PublicKey{certificate.PublicKey}, payload, signature)
+}
+
+// verifyTransparencyLog verifies the signed entry timestamp of the transparency log
+// entry recording the signature, and returns the time the entry was integrated
+func (this *signatureIdentities) verifyTransparencyLog(encoded []byte, payload []byte, signature []byte, certificate *x509.Certificate) (time.Time, error) {
+	bundle := rekorBundle{}
+	if err := json.Unmarshal(encoded, &bundle); err != nil {
+		return time.Time{}, fmt.Errorf("invalid transparency log bundle: %w", err)
+	}
+	key, ok := this.tlogKeys[bundle.Payload.LogID]
+	if !ok {
+		return time.Time{}, fmt.Errorf("transparency log %q not trusted", bundle.Payload.LogID)
+	}
+	canonical, err := json.Marshal(bundle.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := verifySignature([]crypto.PublicKey{key}, canonical, bundle.SignedEntryTimestamp); err != nil {
+		return time.Time{}, fmt.Errorf("invalid signed entry timestamp: %w", err)
+	}
+
+	// the entry must record the signature of the payload by the certificate
+	entry := hashedRekord{}
+	body, err := base64.StdEncoding.DecodeString(bundle.Payload.Body)
+	if err != nil || json.Unmarshal(body, &entry) != nil || entry.Kind != "hashedrekord" {
+		return time.Time{}, fmt.Errorf("transparency log entry is not a hashedrekord")
+	}
+	digest := sha256.Sum256(payload)
+	block, _ := pem.Decode(entry.Spec.Signature.PublicKey.Content)
+	if entry.Spec.Data.Hash.Algorithm != "sha256" || entry.Spec.Data.Hash.Value != hex.EncodeToString(digest[:]) ||
+		!bytes.Equal(entry.Spec.Signature.Content, signature) || block == nil || !bytes.Equal(block.Bytes, certificate.Raw) {
+		return time.Time{}, fmt.Errorf("transparency log entry does not match the signature")
+	}
+	return time.Unix(bundle.Payload.IntegratedTime, 0), nil
+}
+
+// certificateIssuer returns the OIDC issuer of the identity the Fulcio certificate was issued for
+func certificateIssuer(certificate *x509.Certificate) string {
+	issuer := ""
+	for _, extension := range certificate.Extensions {
+		switch {
+		case extension.Id.Equal(fulcioIssuerOid):
+			if _, err := asn1.Unmarshal(extension.Value, &issuer); err == nil {
+				return issuer
+			}
+		case extension.Id.Equal(fulcioIssuerV1Oid):
+			issuer = string(extension.Value)
+		}
+	}
+	return issuer
+}
+
+// certificateUris returns the URI subject alternative names, e.g. the workflows of the CI
+func certificateUris(certificate *x509.Certificate) []string {
+	uris := make([]string, 0, len(certificate.URIs))
+	for _, uri := range certificate.URIs {
+		uris = append(uris, uri.String())
+	}
+	return uris
+}
+
+// verifyBlobSignature verifies the downloaded archive against the detached
+// signature published next to it with the `.sig` suffix, or against the bundle
+// published with the `.bundle` suffix when signed keyless by the identities
+func verifyBlobSignature(ctx context.Context, cfg Config, blobUrl string, archive string) error {
+	payload, err := os.ReadFile(archive)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	if len(cfg.SignaturePublicKeys) > 0 {
+		err := verifyBlobKeySignature(ctx, cfg, blobUrl, payload)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	if len(cfg.SignatureIdentities) > 0 {
+		err := verifyBlobIdentitySignature(ctx, cfg, blobUrl, payload)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// verifyBlobKeySignature verifies the payload against the `.sig` signature by the trusted keys
+func verifyBlobKeySignature(ctx context.Context, cfg Config, blobUrl string, payload []byte) error {
+	keys, err := loadPublicKeys(cfg.SignaturePublicKeys)
+	if err != nil {
+		return err
+	}
+	encoded, err := downloadSignature(ctx, cfg, blobUrl, ".sig")
+	if err != nil {
+		return err
+	}
+	signature, err := decodeSignature(encoded)
+	if err != nil {
+		return fmt.Errorf("invalid signature of %v: %w", rootLabel(blobUrl), err)
+	}
+	return verifySignature(keys, payload, signature)
+}
+
+// verifyBlobIdentitySignature verifies the payload against the `.bundle` created by
+// `cosign sign-blob --bundle`, holding the signature, the certificate and the proof
+// of the transparency log entry
+func verifyBlobIdentitySignature(ctx context.Context, cfg Config, blobUrl string, payload []byte) error {
+	identities, err := loadSignatureIdentities(cfg)
+	if err != nil {
+		return err
+	}
+	encoded, err := downloadSignature(ctx, cfg, blobUrl, ".bundle")
+	if err != nil {
+		return err
+	}
+	bundle := cosignBlobBundle{}
+	if err := json.Unmarshal(encoded, &bundle); err != nil {
+		return fmt.Errorf("invalid signature bundle of %v: %w", rootLabel(blobUrl), err)
+	}
+	signature, err := decodeSignature([]byte(bundle.Base64Signature))
+	if err != nil {
+		return fmt.Errorf("invalid signature of %v: %w", rootLabel(blobUrl), err)
+	}
+	// the certificate is the base64 encoded PEM
+	certificate, err := base64.StdEncoding.DecodeString(bundle.Cert)
+	if err != nil {
+		return fmt.Errorf("invalid signing certificate of %v: %w", rootLabel(blobUrl), err)
+	}
+	return identities.verify(payload, signature, certificate, nil, bundle.RekorBundle)
+}
+
+// downloadSignature downloads the signature published next to the blob with the suffix
+func downloadSignature(ctx context.Context, cfg Config, blobUrl string, suffix string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, blobUrl+suffix, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := remoteClient(cfg).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download of signature %v of %v failed with status %v", suffix, rootLabel(blobUrl), res.Status)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// verifyManifestSignature verifies the cosign signature of the artifact manifest,
+// stored by `cosign sign` in the registry under the `sha256-<digest>.sig` tag,
+// signed by any of the trusted keys or keyless by any of the identities
+func (this *ociClient) verifyManifestSignature(ctx context.Context, ref ociReference, digest string) error {
+	keys, err := loadPublicKeys(this.cfg.SignaturePublicKeys)
+	if err != nil {
+		return err
+	}
+	identities, err := loadSignatureIdentities(this.cfg)
+	if err != nil {
+		return err
+	}
+
+	signatures, _, err := this.manifest(ctx, ref, strings.Replace(digest, ":", "-", 1)+".sig")
+	if err != nil {
+		return fmt.Errorf("cannot get signatures of %v: %w", digest, err)
+	}
+
+	for _, layer := range signatures.Layers {
+		encoded, ok := layer.Annotations[cosignSignatureAnnotation]
+		if !ok || layer.MediaType != cosignSimpleSigningType {
+			continue
+		}
+		signature, err := decodeSignature([]byte(encoded))
+		if err != nil {
+			continue
+		}
+		payload, err := this.blob(ctx, ref, layer.Digest)
+		if err != nil {
+			return err
+		}
+		verified := verifySignature(keys, payload, signature) == nil
+		if certificate, ok := layer.Annotations[cosignCertificateAnnotation]; !verified && ok && identities != nil {
+			verified = identities.verify(payload, signature, []byte(certificate),
+				[]byte(layer.Annotations[cosignChainAnnotation]), []byte(layer.Annotations[cosignBundleAnnotation])) == nil
+		}
+		if !verified {
+			continue
+		}
+
+		// signed payload must refer to the verified manifest
+		simpleSigning := struct {
+			Critical struct {
+				Image struct {
+					Digest string `json:"docker-manifest-digest"`
+				} `json:"image"`
+			} `json:"critical"`
+		}{}
+		if err := json.Unmarshal(payload, &simpleSigning); err == nil && simpleSigning.Critical.Image.Digest == digest {
+			return nil
+		}
+	}
+	return fmt.Errorf("no valid signature of %v signed by the trusted keys or identities", digest)
+}
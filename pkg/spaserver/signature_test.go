@@ -0,0 +1,416 @@
+package spaserver
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type SignatureTestSuite struct {
+	suite.Suite
+	key       *ecdsa.PrivateKey
+	publicKey string
+	bundle    []byte
+	signature []byte
+	remote    *httptest.Server
+	// keyless is the bundle of the keyless signature, not published if nil
+	keyless []byte
+	// ca issues the identity certificates, the transparency log records the keyless signatures
+	caKey          *ecdsa.PrivateKey
+	ca             *x509.Certificate
+	caFile         string
+	tlogKey        *ecdsa.PrivateKey
+	tlogFile       string
+	integratedTime time.Time
+}
+
+const releaseWorkflow = "https://github.com/polyfea/app/.github/workflows/release.yml@refs/heads/main"
+
+func TestSignatureTestSuite(t *testing.T) {
+	suite.Run(t, new(SignatureTestSuite))
+}
+
+func (suite *SignatureTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	var err error
+	suite.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().Nil(err)
+	suite.publicKey = suite.writeKey(&suite.key.PublicKey)
+
+	archive := &bytes.Buffer{}
+	tw := tar.NewWriter(archive)
+	suite.Require().Nil(tw.WriteHeader(&tar.Header{Name: "index.html", Mode: 0644, Size: int64(len(index_html))}))
+	_, err = tw.Write([]byte(index_html))
+	suite.Require().Nil(err)
+	suite.Require().Nil(tw.Close())
+	suite.bundle = archive.Bytes()
+	suite.signature = suite.sign(suite.key, suite.bundle)
+	suite.keyless = nil
+	suite.remote = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasSuffix(req.URL.Path, ".sig") {
+			w.Write([]byte(base64.StdEncoding.EncodeToString(suite.signature)))
+			return
+		}
+		if strings.HasSuffix(req.URL.Path, ".bundle") {
+			if suite.keyless == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(suite.keyless)
+			return
+		}
+		w.Write(suite.bundle)
+	}))
+
+	suite.caKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().Nil(err)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "sigstore"},
+		NotBefore:             time.Now().Add(-24 * time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, &x509.Certificate{Subject: pkix.Name{CommonName: "sigstore"}}, &suite.caKey.PublicKey, suite.caKey)
+	suite.Require().Nil(err)
+	suite.ca, err = x509.ParseCertificate(der)
+	suite.Require().Nil(err)
+	suite.caFile = filepath.Join(suite.T().TempDir(), "fulcio.pem")
+	suite.Require().Nil(os.WriteFile(suite.caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	suite.tlogKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().Nil(err)
+	suite.tlogFile = suite.writeKey(&suite.tlogKey.PublicKey)
+	// the certificate expired since, the signature is verified at the integration time
+	suite.integratedTime = time.Now().Add(-55 * time.Minute)
+}
+
+func (suite *SignatureTestSuite) TearDownTest() {
+	suite.remote.Close()
+}
+
+func (suite *SignatureTestSuite) writeKey(key any) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	suite.Require().Nil(err)
+	file := filepath.Join(suite.T().TempDir(), "cosign.pub")
+	suite.Require().Nil(os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644))
+	return file
+}
+
+func (suite *SignatureTestSuite) sign(key *ecdsa.PrivateKey, payload []byte) []byte {
+	digest := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	suite.Require().Nil(err)
+	return signature
+}
+
+// signKeyless signs the payload by the certificate issued for the identity and records the
+// signature in the transparency log, returns the certificate and the transparency log bundle
+func (suite *SignatureTestSuite) signKeyless(payload []byte, identity string) ([]byte, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().Nil(err)
+	uri, err := url.Parse(identity)
+	suite.Require().Nil(err)
+	issuer, err := asn1.MarshalWithParams("https://token.actions.githubusercontent.com", "utf8")
+	suite.Require().Nil(err)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().Add(-50 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		URIs:            []*url.URL{uri},
+		ExtraExtensions: []pkix.Extension{{Id: fulcioIssuerOid, Value: issuer}},
+	}, suite.ca, &key.PublicKey, suite.caKey)
+	suite.Require().Nil(err)
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	signature := suite.sign(key, payload)
+
+	digest := sha256.Sum256(payload)
+	body, err := json.Marshal(map[string]any{
+		"apiVersion": "0.0.1",
+		"kind":       "hashedrekord",
+		"spec": map[string]any{
+			"data":      map[string]any{"hash": map[string]any{"algorithm": "sha256", "value": hex.EncodeToString(digest[:])}},
+			"signature": map[string]any{"content": signature, "publicKey": map[string]any{"content": certificate}},
+		},
+	})
+	suite.Require().Nil(err)
+	tlogDer, err := x509.MarshalPKIXPublicKey(&suite.tlogKey.PublicKey)
+	suite.Require().Nil(err)
+	logId := sha256.Sum256(tlogDer)
+	entry := rekorPayload{
+		Body:           base64.StdEncoding.EncodeToString(body),
+		IntegratedTime: suite.integratedTime.Unix(),
+		LogID:          hex.EncodeToString(logId[:]),
+		LogIndex:       42,
+	}
+	canonical, err := json.Marshal(entry)
+	suite.Require().Nil(err)
+	bundle, err := json.Marshal(rekorBundle{SignedEntryTimestamp: suite.sign(suite.tlogKey, canonical), Payload: entry})
+	suite.Require().Nil(err)
+	return signature, certificate, bundle
+}
+
+// signBlobKeyless creates the bundle of `cosign sign-blob --bundle`
+func (suite *SignatureTestSuite) signBlobKeyless(payload []byte, identity string) []byte {
+	signature, certificate, bundle := suite.signKeyless(payload, identity)
+	encoded, err := json.Marshal(cosignBlobBundle{
+		Base64Signature: base64.StdEncoding.EncodeToString(signature),
+		Cert:            base64.StdEncoding.EncodeToString(certificate),
+		RekorBundle:     bundle,
+	})
+	suite.Require().Nil(err)
+	return encoded
+}
+
+func (suite *SignatureTestSuite) keylessConfig() Config {
+	return Config{
+		RootDirs:                  []string{suite.remote.URL + "/bundle.tar"},
+		BaseURL:                   "/",
+		SyncCacheDir:              suite.T().TempDir(),
+		SignatureIdentities:       []string{releaseWorkflow},
+		SignatureOidcIssuer:       "https://token.actions.githubusercontent.com",
+		SignatureCertificateRoots: []string{suite.caFile},
+		SignatureTlogPublicKeys:   []string{suite.tlogFile},
+	}
+}
+
+func (suite *SignatureTestSuite) config() Config {
+	return Config{
+		RootDirs:            []string{suite.remote.URL + "/bundle.tar"},
+		BaseURL:             "/",
+		SyncCacheDir:        suite.T().TempDir(),
+		SignaturePublicKeys: []string{suite.publicKey},
+	}
+}
+
+func (suite *SignatureTestSuite) Test_Http_root_signed_Then_served() {
+
+	// given
+	sut, err := newServer(suite.config(), zerolog.New(os.Stdout))
+	suite.Require().Nil(err)
+	req, err := http.NewRequest("GET", "/", nil)
+	suite.Nil(err)
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(req.Context(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(index_html, rr.Body.String())
+}
+
+func (suite *SignatureTestSuite) Test_Http_root_signed_by_untrusted_key_Then_refused() {
+
+	// given
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().Nil(err)
+	suite.signature = suite.sign(other, suite.bundle)
+
+	// when
+	_, err = newServer(suite.config(), zerolog.New(os.Stdout))
+
+	// then
+	suite.NotNil(err)
+}
+
+func (suite *SignatureTestSuite) Test_Ed25519_signature_Then_verified() {
+
+	// given
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	suite.Require().Nil(err)
+	keys, err := loadPublicKeys([]string{suite.writeKey(public)})
+	suite.Require().Nil(err)
+
+	// when
+	valid := verifySignature(keys, []byte("payload"), ed25519.Sign(private, []byte("payload")))
+	tampered := verifySignature(keys, []byte("tampered"), ed25519.Sign(private, []byte("payload")))
+
+	// then
+	suite.Nil(valid)
+	suite.NotNil(tampered)
+}
+
+// ociRegistry serves the bundle as an OCI artifact signed by `cosign sign`, the signature
+// layer is annotated by the annotations of the signed payload
+func (suite *SignatureTestSuite) ociRegistry(annotations func(payload []byte) map[string]string) *httptest.Server {
+	hash := sha256.Sum256(suite.bundle)
+	layerDigest := "sha256:" + hex.EncodeToString(hash[:])
+	manifest, err := json.Marshal(ociManifest{
+		MediaType: ociManifestMediaType,
+		Layers:    []ociDescriptor{{MediaType: "application/vnd.oci.image.layer.v1.tar", Digest: layerDigest}},
+	})
+	suite.Require().Nil(err)
+	hash = sha256.Sum256(manifest)
+	manifestDigest := "sha256:" + hex.EncodeToString(hash[:])
+
+	payload := []byte(`{"critical":{"image":{"docker-manifest-digest":"` + manifestDigest + `"},"type":"cosign container image signature"}}`)
+	hash = sha256.Sum256(payload)
+	payloadDigest := "sha256:" + hex.EncodeToString(hash[:])
+	signatures, err := json.Marshal(ociManifest{
+		MediaType: ociManifestMediaType,
+		Layers: []ociDescriptor{{
+			MediaType:   cosignSimpleSigningType,
+			Digest:      payloadDigest,
+			Annotations: annotations(payload),
+		}},
+	})
+	suite.Require().Nil(err)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		content := map[string][]byte{
+			"/v2/app/manifests/1.0.0": manifest,
+			"/v2/app/manifests/" + strings.Replace(manifestDigest, ":", "-", 1) + ".sig": signatures,
+			"/v2/app/blobs/" + payloadDigest:                                             payload,
+			"/v2/app/blobs/" + layerDigest:                                               suite.bundle,
+		}[req.URL.Path]
+		if content == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(content)
+	}))
+}
+
+func (suite *SignatureTestSuite) Test_OCI_root_signed_Then_pulled() {
+
+	// given
+	registry := suite.ociRegistry(func(payload []byte) map[string]string {
+		return map[string]string{cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(suite.sign(suite.key, payload))}
+	})
+	defer registry.Close()
+
+	cfg := Config{
+		OciCacheDir:         suite.T().TempDir(),
+		OciInsecure:         true,
+		SignaturePublicKeys: []string{suite.publicKey},
+	}
+	uri := "oci://" + strings.TrimPrefix(registry.URL, "http://") + "/app:1.0.0"
+
+	// when
+	archive, err := pullOCIBundle(context.Background(), uri, cfg)
+
+	// then
+	suite.Nil(err)
+	suite.FileExists(archive)
+}
+
+func (suite *SignatureTestSuite) Test_Http_root_signed_keyless_Then_served() {
+
+	// given
+	suite.keyless = suite.signBlobKeyless(suite.bundle, releaseWorkflow)
+	sut, err := newServer(suite.keylessConfig(), zerolog.New(os.Stdout))
+	suite.Require().Nil(err)
+	req, err := http.NewRequest("GET", "/", nil)
+	suite.Nil(err)
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.handler(req.Context(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(index_html, rr.Body.String())
+}
+
+func (suite *SignatureTestSuite) Test_Http_root_signed_keyless_by_other_identity_Then_refused() {
+
+	// given
+	suite.keyless = suite.signBlobKeyless(suite.bundle, "https://github.com/attacker/app/.github/workflows/release.yml@refs/heads/main")
+
+	// when
+	_, err := newServer(suite.keylessConfig(), zerolog.New(os.Stdout))
+
+	// then
+	suite.ErrorContains(err, "not trusted")
+}
+
+func (suite *SignatureTestSuite) Test_Keyless_signature_integrated_after_certificate_expired_Then_refused() {
+
+	// given
+	suite.integratedTime = time.Now()
+	suite.keyless = suite.signBlobKeyless(suite.bundle, releaseWorkflow)
+
+	// when
+	_, err := newServer(suite.keylessConfig(), zerolog.New(os.Stdout))
+
+	// then
+	suite.ErrorContains(err, "untrusted signing certificate")
+}
+
+func (suite *SignatureTestSuite) Test_Keyless_signature_of_other_payload_Then_refused() {
+
+	// given
+	suite.keyless = suite.signBlobKeyless([]byte("other"), releaseWorkflow)
+
+	// when
+	_, err := newServer(suite.keylessConfig(), zerolog.New(os.Stdout))
+
+	// then
+	suite.ErrorContains(err, "transparency log entry does not match the signature")
+}
+
+func (suite *SignatureTestSuite) Test_OCI_root_signed_keyless_Then_pulled() {
+
+	// given
+	registry := suite.ociRegistry(func(payload []byte) map[string]string {
+		signature, certificate, bundle := suite.signKeyless(payload, releaseWorkflow)
+		return map[string]string{
+			cosignSignatureAnnotation:   base64.StdEncoding.EncodeToString(signature),
+			cosignCertificateAnnotation: string(certificate),
+			cosignChainAnnotation:       string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: suite.ca.Raw})),
+			cosignBundleAnnotation:      string(bundle),
+		}
+	})
+	defer registry.Close()
+
+	cfg := suite.keylessConfig()
+	cfg.OciCacheDir = suite.T().TempDir()
+	cfg.OciInsecure = true
+	uri := "oci://" + strings.TrimPrefix(registry.URL, "http://") + "/app:1.0.0"
+
+	// when
+	archive, err := pullOCIBundle(context.Background(), uri, cfg)
+
+	// then
+	suite.Nil(err)
+	suite.FileExists(archive)
+}
+
+func (suite *SignatureTestSuite) Test_Identities_without_trust_Then_invalid_config() {
+
+	// when
+	err := validateConfig(Config{SignatureIdentities: []string{releaseWorkflow}})
+
+	// then
+	suite.ErrorContains(err, "signature-oidc-issuer: the issuer of the signature identities is required")
+	suite.ErrorContains(err, "signature-certificate-roots: ")
+	suite.ErrorContains(err, "signature-tlog-public-keys: ")
+}
@@ -0,0 +1,108 @@
+package spaserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+)
+
+// query parameters of the signed urls
+const (
+	signedUrlExpires   = "expires"
+	signedUrlSignature = "signature"
+)
+
+var (
+	errSignatureMissing = errors.New("signature missing")
+	errSignatureInvalid = errors.New("signature invalid")
+	errSignatureExpired = errors.New("signature expired")
+)
+
+// signedUrlRequired reports whether the path is protected by the signed urls
+func (this *server) signedUrlRequired(requestPath string) bool {
+	if this.cfg.SignedUrlKey == "" {
+		return false
+	}
+	return this.regexes().signedUrl.matches(signedUrlPath(requestPath))
+}
+
+// signedUrlPath is the cleaned path resolved by the lookup, so that the
+// non-canonical forms of the path, e.g. `//media/` or `/./media/`, are protected
+// and signed as the canonical one
+func signedUrlPath(requestPath string) string {
+	return path.Clean("/" + requestPath)
+}
+
+// urlSignature is the HMAC-SHA256 of the path and the expiration, so that
+// neither of them can be changed without invalidating the url
+func (this *server) urlSignature(requestPath string, expires string) string {
+	mac := hmac.New(sha256.New, []byte(this.cfg.SignedUrlKey))
+	mac.Write([]byte(requestPath + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifySignedUrl validates the signature and the expiration of the request url
+func (this *server) verifySignedUrl(req *http.Request, now time.Time) error {
+	query := req.URL.Query()
+	expires, signature := query.Get(signedUrlExpires), query.Get(signedUrlSignature)
+	if expires == "" || signature == "" {
+		return errSignatureMissing
+	}
+	expected := this.urlSignature(signedUrlPath(req.URL.Path), expires)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return errSignatureInvalid
+	}
+	seconds, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errSignatureInvalid
+	}
+	if now.After(time.Unix(seconds, 0)) {
+		return errSignatureExpired
+	}
+	return nil
+}
+
+// signUrl creates the signed url of the path valid until the expiration
+func (this *server) signUrl(requestPath string, expires time.Time) string {
+	requestPath = signedUrlPath(requestPath)
+	seconds := strconv.FormatInt(expires.Unix(), 10)
+	query := url.Values{}
+	query.Set(signedUrlExpires, seconds)
+	query.Set(signedUrlSignature, this.urlSignature(requestPath, seconds))
+	return requestPath + "?" + query.Encode()
+}
+
+// serveSign creates the signed url: POST /sign?path=/media/video.mp4&ttl=1h
+func (this *server) serveSign(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if this.cfg.SignedUrlKey == "" {
+		http.Error(w, "Signed url key not configured", http.StatusForbidden)
+		return
+	}
+	requestPath := req.URL.Query().Get("path")
+	if requestPath == "" || requestPath[0] != '/' {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	ttl := this.cfg.SignedUrlTtl
+	if value := req.URL.Query().Get("ttl"); value != "" {
+		var err error
+		ttl, err = time.ParseDuration(value)
+		if err != nil || ttl <= 0 {
+			http.Error(w, "Invalid ttl", http.StatusBadRequest)
+			return
+		}
+	}
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	writeJSON(w, http.StatusCreated, map[string]any{"url": this.signUrl(requestPath, expires), "expires": expires})
+}
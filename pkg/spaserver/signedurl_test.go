@@ -0,0 +1,137 @@
+package spaserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type SignedUrlTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestSignedUrlTestSuite(t *testing.T) {
+	suite.Run(t, new(SignedUrlTestSuite))
+}
+
+func (suite *SignedUrlTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.MkdirAll(path.Join(rootDir, "media"), 0755))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "media", "video.mp4"), []byte("video"), 0644))
+	sut, err := newServer(Config{
+		RootDirs:        []string{rootDir},
+		BaseURL:         "/",
+		AdminToken:      "secret",
+		SignedUrlKey:    "key",
+		SignedUrlRegexs: []string{"^/media/"},
+		SignedUrlTtl:    time.Hour,
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *SignedUrlTestSuite) get(target string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, httptest.NewRequest("GET", target, nil))
+	return rr
+}
+
+func (suite *SignedUrlTestSuite) Test_Signed_url_Then_served() {
+
+	// given
+	target := suite.sut.signUrl("/media/video.mp4", time.Now().Add(time.Minute))
+
+	// when
+	rr := suite.get(target)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("video", rr.Body.String())
+}
+
+func (suite *SignedUrlTestSuite) Test_Unsigned_url_Then_forbidden() {
+
+	// when
+	rr := suite.get("/media/video.mp4")
+
+	// then
+	suite.Equal(http.StatusForbidden, rr.Code)
+}
+
+func (suite *SignedUrlTestSuite) Test_Non_canonical_unsigned_url_Then_not_served() {
+
+	for _, target := range []string{"//media/video.mp4", "/./media/video.mp4", "/a/../media/video.mp4", "/media//video.mp4"} {
+		// when
+		rr := suite.get(target)
+
+		// then
+		suite.NotEqual(http.StatusOK, rr.Code, target)
+		suite.NotContains(rr.Body.String(), "video", target)
+	}
+}
+
+func (suite *SignedUrlTestSuite) Test_Non_canonical_signed_url_Then_served() {
+
+	// given
+	signed := suite.sut.signUrl("/media/video.mp4", time.Now().Add(time.Minute))
+
+	// when
+	rr := suite.get("/./" + strings.TrimPrefix(signed, "/"))
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("video", rr.Body.String())
+}
+
+func (suite *SignedUrlTestSuite) Test_Expired_url_Then_forbidden() {
+
+	// given
+	target := suite.sut.signUrl("/media/video.mp4", time.Now().Add(-time.Minute))
+
+	// when
+	rr := suite.get(target)
+
+	// then
+	suite.Equal(http.StatusForbidden, rr.Code)
+}
+
+func (suite *SignedUrlTestSuite) Test_Other_path_with_signature_Then_forbidden() {
+
+	// given
+	target := suite.sut.signUrl("/media/other.mp4", time.Now().Add(time.Minute))
+
+	// when
+	rr := suite.get(strings.Replace(target, "other", "video", 1))
+
+	// then
+	suite.Equal(http.StatusForbidden, rr.Code)
+}
+
+func (suite *SignedUrlTestSuite) Test_Sign_endpoint_Then_url_served() {
+
+	// given
+	req := httptest.NewRequest("POST", "/sign?path=/media/video.mp4&ttl=5m", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rr := httptest.NewRecorder()
+
+	// when
+	suite.sut.adminHandler().ServeHTTP(rr, req)
+
+	// then
+	suite.Equal(http.StatusCreated, rr.Code)
+	var signed struct{ Url string }
+	suite.Require().Nil(json.Unmarshal(rr.Body.Bytes(), &signed))
+	suite.Equal(http.StatusOK, suite.get(signed.Url).Code)
+}
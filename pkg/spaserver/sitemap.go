@@ -0,0 +1,150 @@
+package spaserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// sitemapName is the path of the generated sitemap below the base url
+const sitemapName = "sitemap.xml"
+
+// sitemapRoute is the route of the routes file, either the path string or the
+// object with the optional SEO metadata, e.g.
+//
+//	["/", {"path": "/about", "lastmod": "2024-01-31", "changefreq": "monthly", "priority": 0.8}]
+type sitemapRoute struct {
+	Path       string   `json:"path"`
+	LastMod    string   `json:"lastmod"`
+	ChangeFreq string   `json:"changefreq"`
+	Priority   *float64 `json:"priority"`
+}
+
+func (this *sitemapRoute) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		return json.Unmarshal(data, &this.Path)
+	}
+	type route sitemapRoute
+	return json.Unmarshal(data, (*route)(this))
+}
+
+type sitemapUrl struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+	Priority   string `xml:"priority,omitempty"`
+}
+
+type sitemapUrlSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Urls    []sitemapUrl `xml:"url"`
+}
+
+// sitemapEnabled reports whether the sitemap is generated from the routes file or the configured routes
+func (this *server) sitemapEnabled() bool {
+	return this.cfg.SitemapRoutesFile != "" || len(this.cfg.SitemapRoutes) > 0
+}
+
+// parseSitemapRoutes parses the JSON array of the routes file
+func parseSitemapRoutes(content []byte) ([]sitemapRoute, error) {
+	routes := []sitemapRoute{}
+	if err := json.Unmarshal(content, &routes); err != nil {
+		return nil, err
+	}
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, "/") {
+			return nil, fmt.Errorf("the route %q must be an absolute path", route.Path)
+		}
+	}
+	return routes, nil
+}
+
+// sitemapRoutes loads the routes of the routes file of the request roots, or the
+// configured routes if the file is not configured or not found
+func (this *server) sitemapRoutes(ctx context.Context) ([]sitemapRoute, error) {
+	configured := make([]sitemapRoute, 0, len(this.cfg.SitemapRoutes))
+	for _, route := range this.cfg.SitemapRoutes {
+		configured = append(configured, sitemapRoute{Path: route})
+	}
+	if this.cfg.SitemapRoutesFile == "" {
+		return configured, nil
+	}
+	file, ok, err := this.findFile(ctx, this.cfg.SitemapRoutesFile)
+	if err != nil || !ok {
+		return configured, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%v|%v|%v", rootSetKey(ctx), info.ModTime().UnixNano(), info.Size())
+	if routes, ok := this.sitemaps.Load(key); ok {
+		recordCacheLookup(ctx, "sitemaps", cacheHit)
+		return routes.([]sitemapRoute), nil
+	}
+	recordCacheLookup(ctx, "sitemaps", cacheMiss)
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := parseSitemapRoutes(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid routes file %v: %w", this.cfg.SitemapRoutesFile, err)
+	}
+	this.cache(&this.sitemaps, key, routes, memoryCritical)
+	return routes, nil
+}
+
+// serveSitemap generates the sitemap of the routes with the absolute urls under
+// the public base url, the origin is configured or taken from the request
+func (this *server) serveSitemap(ctx context.Context, w http.ResponseWriter, req *http.Request) (bool, error) {
+	routes, err := this.sitemapRoutes(ctx)
+	if err != nil {
+		return false, err
+	}
+	origin := strings.TrimSuffix(this.cfg.SitemapOrigin, "/")
+	if origin == "" {
+		origin = requestOrigin(req)
+	}
+	base := strings.TrimSuffix(this.publicBaseUrl(ctx), "/")
+
+	urlSet := sitemapUrlSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9", Urls: []sitemapUrl{}}
+	for _, route := range routes {
+		url := sitemapUrl{Loc: origin + base + route.Path, LastMod: route.LastMod, ChangeFreq: route.ChangeFreq}
+		if route.Priority != nil {
+			url.Priority = strconv.FormatFloat(*route.Priority, 'f', -1, 64)
+		}
+		urlSet.Urls = append(urlSet.Urls, url)
+	}
+	content, err := xml.MarshalIndent(urlSet, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	debugLookup(ctx, "sitemap %v routes", len(routes))
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(xml.Header)+len(content)))
+	if _, ok := w.Header()["Cache-Control"]; !ok && !this.cfg.DefaultCacheControlDisabled {
+		// the sitemap changes with the routes file under the same url
+		cacheControl := this.cfg.IndexCacheControl
+		if cacheControl == "" {
+			cacheControl = indexCacheControl
+		}
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+	w.WriteHeader(http.StatusOK)
+	if req.Method != http.MethodHead {
+		io.WriteString(w, xml.Header)
+		w.Write(content)
+	}
+	return true, nil
+}
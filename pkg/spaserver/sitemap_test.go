@@ -0,0 +1,138 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type SitemapTestSuite struct {
+	suite.Suite
+	rootDir string
+	cfg     Config
+}
+
+func TestSitemapTestSuite(t *testing.T) {
+	suite.Run(t, new(SitemapTestSuite))
+}
+
+func (suite *SitemapTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "index.html"), []byte("app"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "routes.json"),
+		[]byte(`["/", {"path": "/about", "lastmod": "2024-01-31", "changefreq": "monthly", "priority": 0.8}]`), 0644))
+	suite.cfg = Config{
+		RootDirs:            []string{suite.rootDir},
+		BaseURL:             "/app/",
+		FallbackDocument:    "index.html",
+		FallbackAcceptTypes: []string{"text/html"},
+		SitemapRoutesFile:   "routes.json",
+	}
+}
+
+func (suite *SitemapTestSuite) get(cfg Config, target string) *httptest.ResponseRecorder {
+	sut, err := newServer(cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, httptest.NewRequest("GET", target, nil))
+	return rr
+}
+
+func (suite *SitemapTestSuite) Test_Routes_file_Then_sitemap_generated() {
+
+	// when
+	rr := suite.get(suite.cfg, "http://www.example.com/app/sitemap.xml")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("application/xml; charset=utf-8", rr.Header().Get("Content-Type"))
+	suite.Equal("no-cache", rr.Header().Get("Cache-Control"))
+	suite.Equal(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>http://www.example.com/app/</loc>
+  </url>
+  <url>
+    <loc>http://www.example.com/app/about</loc>
+    <lastmod>2024-01-31</lastmod>
+    <changefreq>monthly</changefreq>
+    <priority>0.8</priority>
+  </url>
+</urlset>`, rr.Body.String())
+}
+
+func (suite *SitemapTestSuite) Test_Routes_file_missing_Then_configured_routes_with_origin() {
+
+	// given
+	suite.cfg.SitemapRoutesFile = "missing.json"
+	suite.cfg.SitemapRoutes = []string{"/contact"}
+	suite.cfg.SitemapOrigin = "https://example.com/"
+
+	// when
+	rr := suite.get(suite.cfg, "/app/sitemap.xml")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Contains(rr.Body.String(), "<loc>https://example.com/app/contact</loc>")
+	suite.NotContains(rr.Body.String(), "/about")
+}
+
+func (suite *SitemapTestSuite) Test_Sitemap_file_in_root_Then_served() {
+
+	// given
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "sitemap.xml"), []byte("static"), 0644))
+
+	// when
+	rr := suite.get(suite.cfg, "/app/sitemap.xml")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("static", rr.Body.String())
+}
+
+func (suite *SitemapTestSuite) Test_Disabled_Then_fallback() {
+
+	// given
+	suite.cfg.SitemapRoutesFile = ""
+
+	// when
+	rr := suite.get(suite.cfg, "/app/sitemap.xml")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("app", rr.Body.String())
+}
+
+func (suite *SitemapTestSuite) Test_Invalid_routes_file_Then_error() {
+
+	// given
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "routes.json"), []byte(`["about"]`), 0644))
+
+	// when
+	rr := suite.get(suite.cfg, "/app/sitemap.xml")
+
+	// then
+	suite.Equal(http.StatusInternalServerError, rr.Code)
+}
+
+func (suite *SitemapTestSuite) Test_Relative_route_configured_Then_invalid() {
+
+	// given
+	suite.cfg.SitemapRoutes = []string{"about"}
+	suite.cfg.SitemapOrigin = "example.com"
+
+	// when
+	_, err := newServer(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "sitemap-routes[0]")
+	suite.ErrorContains(err, "sitemap-origin")
+}
@@ -0,0 +1,103 @@
+package spaserver
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// site serves the requests of the hosts matching its pattern
+type site struct {
+	// pattern is the hostname, possibly with the `*` wildcards
+	pattern string
+	server  *server
+}
+
+// newSites creates the servers of the configured sites, most specific patterns first
+func (this *server) newSites() ([]site, error) {
+	sites := make([]site, 0, len(this.cfg.Sites))
+	for pattern, overrides := range this.cfg.Sites {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("sites[%v]: %w", pattern, err)
+		}
+		cfg, err := siteConfig(this.cfg, overrides)
+		if err != nil {
+			return nil, fmt.Errorf("sites[%v]: %w", pattern, err)
+		}
+		server, err := newServer(cfg, this.logger.With().Str("site", pattern).Logger())
+		if err != nil {
+			return nil, fmt.Errorf("sites[%v]: %w", pattern, err)
+		}
+		sites = append(sites, site{pattern: strings.ToLower(pattern), server: server})
+	}
+	sort.Slice(sites, func(i, j int) bool {
+		wildcardI, wildcardJ := strings.Contains(sites[i].pattern, "*"), strings.Contains(sites[j].pattern, "*")
+		if wildcardI != wildcardJ {
+			return !wildcardI
+		}
+		if len(sites[i].pattern) != len(sites[j].pattern) {
+			return len(sites[i].pattern) > len(sites[j].pattern)
+		}
+		return sites[i].pattern < sites[j].pattern
+	})
+	return sites, nil
+}
+
+// siteConfig overrides the keys of the configuration by the keys of the site, the
+// overridden lists and maps are replaced as a whole
+func siteConfig(cfg Config, overrides map[string]any) (Config, error) {
+	cfg.Sites = nil
+	if _, nested := overrides["sites"]; nested {
+		return cfg, fmt.Errorf("sites cannot be nested")
+	}
+	fields := reflect.ValueOf(&cfg).Elem()
+	for i := 0; i < fields.NumField(); i++ {
+		if _, ok := overrides[fields.Type().Field(i).Tag.Get("mapstructure")]; ok {
+			fields.Field(i).Set(reflect.Zero(fields.Field(i).Type()))
+		}
+	}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:      &cfg,
+		ErrorUnused: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+	})
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, decoder.Decode(overrides)
+}
+
+// matchingSite returns the server of the site matching the host of the request,
+// nil if the host is served by the default site
+func (this *server) matchingSite(host string) *server {
+	if len(this.sites) == 0 {
+		return nil
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, site := range this.sites {
+		if match, _ := path.Match(site.pattern, host); match {
+			return site.server
+		}
+	}
+	return nil
+}
+
+// siteServers returns the servers of the default site and of the configured sites
+func (this *server) siteServers() []*server {
+	servers := []*server{this}
+	for _, site := range this.sites {
+		servers = append(servers, site.server)
+	}
+	return servers
+}
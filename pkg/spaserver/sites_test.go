@@ -0,0 +1,162 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type SitesTestSuite struct {
+	suite.Suite
+	cfg Config
+}
+
+func TestSitesTestSuite(t *testing.T) {
+	suite.Run(t, new(SitesTestSuite))
+}
+
+// root creates the root with the index of the content
+func (suite *SitesTestSuite) root(content string) string {
+	dir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(dir, "index.html"), []byte(content), 0644))
+	return dir
+}
+
+func (suite *SitesTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.cfg = Config{
+		RootDirs: []string{suite.root("default")},
+		BaseURL:  "/",
+		Sites: map[string]map[string]any{
+			"app.example.com": {
+				"roots":    []any{suite.root("app")},
+				"base-url": "/app/",
+			},
+			"*.example.com": {
+				"roots":   []any{suite.root("wildcard")},
+				"headers": map[string]any{"X-Site": "wildcard"},
+			},
+		},
+	}
+}
+
+func (suite *SitesTestSuite) get(host string, target string) *httptest.ResponseRecorder {
+	sut, err := newServer(suite.cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", target, nil)
+	req.Host = host
+	req.Header.Set("Accept", "text/html")
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *SitesTestSuite) Test_Exact_host_Then_site_with_its_base_url() {
+
+	// when
+	rr := suite.get("app.example.com", "/app/users/42")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("app", rr.Body.String())
+}
+
+func (suite *SitesTestSuite) Test_Wildcard_host_with_port_Then_site_with_its_headers() {
+
+	// when
+	rr := suite.get("Shop.Example.com:8443", "/cart")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("wildcard", rr.Body.String())
+	suite.Equal("wildcard", rr.Header().Get("X-Site"))
+}
+
+func (suite *SitesTestSuite) Test_Other_host_Then_default_site() {
+
+	// when
+	rr := suite.get("example.org", "/cart")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("default", rr.Body.String())
+	suite.Empty(rr.Header().Get("X-Site"))
+}
+
+func (suite *SitesTestSuite) Test_Fallback_disabled_by_site_Then_not_found() {
+
+	// given
+	suite.cfg.Sites["*.example.com"]["fallback-disabled"] = true
+
+	// when
+	rr := suite.get("shop.example.com", "/cart")
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+	suite.Equal("default", suite.get("example.org", "/cart").Body.String())
+}
+
+func (suite *SitesTestSuite) Test_Duration_override_Then_decoded() {
+
+	// given
+	suite.cfg.Sites["*.example.com"]["offline-retry-after"] = "45s"
+
+	// when
+	sut, err := newServer(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.Require().Nil(err)
+	suite.Equal(45*time.Second, sut.matchingSite("shop.example.com").cfg.OfflineRetryAfter)
+	suite.Equal("/", sut.matchingSite("shop.example.com").cfg.BaseURL)
+}
+
+func (suite *SitesTestSuite) Test_Unknown_key_Then_invalid() {
+
+	// given
+	suite.cfg.Sites["app.example.com"]["base-uri"] = "/app/"
+
+	// when
+	_, err := newServer(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "sites[app.example.com]")
+	suite.ErrorContains(err, "base-uri")
+}
+
+func (suite *SitesTestSuite) Test_Invalid_site_configuration_Then_invalid() {
+
+	// given
+	suite.cfg.Sites["app.example.com"]["no-fallback-regexp"] = []any{"("}
+
+	// when
+	_, err := newServer(suite.cfg, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "sites[app.example.com]")
+	suite.ErrorContains(err, "no-fallback-regexp[0]")
+}
+
+func (suite *SitesTestSuite) Test_Effective_configuration_Then_sites_redacted() {
+
+	// given
+	suite.cfg.Sites["app.example.com"]["signed-url-key"] = "site-secret"
+	sut, err := newServer(suite.cfg, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.adminHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/config", nil))
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.NotContains(rr.Body.String(), "site-secret")
+	suite.Contains(rr.Body.String(), `"base-url": "/app/"`)
+}
@@ -0,0 +1,75 @@
+package spaserver
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+)
+
+// snapshot loads all files of the root to memory, the snapshot is immutable
+// so that changes of the underlying volume do not affect the served content.
+// The snapshot reuses the layout of the indexed tar archives, with the file
+// contents concatenated in a single buffer.
+func (this *server) snapshot(rootDir string, fsys fs.FS) (fs.FS, error) {
+	content := &bytes.Buffer{}
+	snapshot := &tarFS{entries: map[string]*tarEntry{}}
+
+	add := func(name string, info fs.FileInfo) error {
+		if info.IsDir() {
+			snapshot.entries[name] = &tarEntry{info: info}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			// links and special files are not served
+			return nil
+		}
+
+		file, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		offset := int64(content.Len())
+		if _, err := io.Copy(content, file); err != nil {
+			return err
+		}
+		snapshot.entries[name] = &tarEntry{info: info, offset: offset}
+		return nil
+	}
+
+	if archive, ok := fsys.(*tarFS); ok {
+		// archives are indexed already and do not support listing of directories
+		for name, entry := range archive.entries {
+			if err := add(name, entry.info); err != nil {
+				return nil, err
+			}
+		}
+		if closer, ok := archive.data.(io.Closer); ok {
+			// the opened archive is not served
+			closer.Close()
+		}
+	} else {
+		err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			return add(name, info)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot.data = bytes.NewReader(content.Bytes())
+	this.logger.Info().
+		Str("root", rootLabel(rootDir)).
+		Int("files", len(snapshot.entries)).
+		Int("bytes", content.Len()).
+		Msg("Root loaded to memory snapshot")
+	return snapshot, nil
+}
@@ -0,0 +1,73 @@
+package spaserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type SnapshotTestSuite struct {
+	suite.Suite
+	rootDir string
+	sut     *server
+}
+
+func TestSnapshotTestSuite(t *testing.T) {
+	suite.Run(t, new(SnapshotTestSuite))
+}
+
+func (suite *SnapshotTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.rootDir = suite.T().TempDir()
+	suite.Require().Nil(os.MkdirAll(path.Join(suite.rootDir, "assets"), 0755))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "index.html"), []byte(index_html), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(suite.rootDir, "assets/testfile.json"), []byte(testfile_json), 0644))
+
+	var err error
+	suite.sut, err = newServer(Config{
+		RootDirs:        []string{suite.rootDir},
+		BaseURL:         "/",
+		SnapshotEnabled: true,
+	}, zerolog.New(os.Stdout))
+	suite.Require().Nil(err)
+}
+
+func (suite *SnapshotTestSuite) get(requestPath string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", requestPath, nil)
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *SnapshotTestSuite) Test_Snapshot_Then_nested_file_served() {
+
+	// when
+	rr := suite.get("/assets/testfile.json")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(testfile_json, rr.Body.String())
+}
+
+func (suite *SnapshotTestSuite) Test_Volume_changed_Then_snapshot_served() {
+
+	// given
+	suite.get("/")
+	suite.Require().Nil(os.RemoveAll(suite.rootDir))
+
+	// when
+	rr := suite.get("/assets/testfile.json")
+	fallback := suite.get("/some/route")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(testfile_json, rr.Body.String())
+	suite.Equal(index_html, fallback.Body.String())
+}
@@ -0,0 +1,73 @@
+// Package spaserver serves the single page applications: the fallback to the
+// index document, the precompressed variants, the header rules and the other
+// features configured by the Config, so that the Go services embed the
+// serving behind their own routers. The spa_d command is a thin wrapper of Run.
+package spaserver
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+// Option customizes the handler created by New
+type Option func(*options)
+
+type options struct {
+	logger zerolog.Logger
+	ctx    context.Context
+	hooks  []Hooks
+}
+
+// WithLogger logs the requests and the problems of the roots with the logger,
+// nothing is logged by default
+func WithLogger(logger zerolog.Logger) Option {
+	return func(this *options) {
+		this.logger = logger
+	}
+}
+
+// WithBackgroundTasks runs the periodic tasks, e.g. the sync of the remote roots
+// and the check of the release pointers, until the context is done
+func WithBackgroundTasks(ctx context.Context) Option {
+	return func(this *options) {
+		this.ctx = ctx
+	}
+}
+
+// New creates the handler serving the configuration, the configuration is
+// validated and the roots are opened, e.g.
+//
+//	cfg := spaserver.DefaultConfig()
+//	cfg.RootDirs = []string{"./dist"}
+//	handler, err := spaserver.New(cfg, spaserver.WithLogger(logger))
+func New(cfg Config, opts ...Option) (http.Handler, error) {
+	o := options{logger: zerolog.Nop()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	spa, err := newServer(cfg, o.logger)
+	if err != nil {
+		return nil, err
+	}
+	for _, site := range spa.siteServers() {
+		site.hooks = o.hooks
+	}
+	if o.ctx != nil {
+		spa.runBackground(o.ctx)
+	}
+	return spa, nil
+}
+
+// DefaultConfig returns the configuration with the defaults of the spa_d command
+func DefaultConfig() (cfg Config) {
+	v := viper.New()
+	setDefaults(v)
+	// the defaults are decoded like the configuration file
+	if err := v.Unmarshal(&cfg); err != nil {
+		panic(err)
+	}
+	return cfg
+}
@@ -0,0 +1,54 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SpaServerTestSuite struct {
+	suite.Suite
+}
+
+func TestSpaServerTestSuite(t *testing.T) {
+	suite.Run(t, new(SpaServerTestSuite))
+}
+
+func (suite *SpaServerTestSuite) Test_Default_config_Then_fallback_served() {
+
+	// given
+	dir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(dir, "index.html"), []byte("index"), 0644))
+	cfg := DefaultConfig()
+	cfg.RootDirs = []string{dir}
+	handler, err := New(cfg)
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	req.Header.Set("Accept", "text/html")
+	rr := httptest.NewRecorder()
+
+	// when
+	handler.ServeHTTP(rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("index", rr.Body.String())
+	suite.Equal(7105, cfg.Port)
+}
+
+func (suite *SpaServerTestSuite) Test_Invalid_config_Then_error() {
+
+	// given
+	cfg := DefaultConfig()
+	cfg.NotFoundRegexs = []string{"("}
+
+	// when
+	_, err := New(cfg)
+
+	// then
+	suite.ErrorContains(err, "no-fallback-regexp[0]")
+}
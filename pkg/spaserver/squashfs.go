@@ -0,0 +1,493 @@
+package spaserver
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	squashfsMagic = 0x73717368
+	// squashfsMetadataSize is the uncompressed size of the metadata blocks
+	squashfsMetadataSize = 8192
+	// squashfsUncompressedMetadata flags the size of the stored metadata block
+	squashfsUncompressedMetadata = 0x8000
+	// squashfsUncompressedData flags the size of the stored data block
+	squashfsUncompressedData = 1 << 24
+	squashfsNoFragment       = 0xFFFFFFFF
+	// squashfsFragmentEntries is the count of the fragment entries of a metadata block
+	squashfsFragmentEntries = squashfsMetadataSize / 16
+)
+
+// compressions of the squashfs images
+const (
+	squashfsGzip = 1
+	squashfsZstd = 6
+)
+
+// inode types of the squashfs images
+const (
+	squashfsDir     = 1
+	squashfsFile    = 2
+	squashfsExtDir  = 8
+	squashfsExtFile = 9
+)
+
+var errSquashfsCorrupted = errors.New("squashfs image corrupted")
+
+// squashfsSuperblock is the header of the squashfs 4.0 image
+type squashfsSuperblock struct {
+	Magic               uint32
+	InodeCount          uint32
+	ModTime             uint32
+	BlockSize           uint32
+	FragmentCount       uint32
+	Compression         uint16
+	BlockLog            uint16
+	Flags               uint16
+	IdCount             uint16
+	VersionMajor        uint16
+	VersionMinor        uint16
+	RootInode           uint64
+	BytesUsed           uint64
+	IdTableStart        uint64
+	XattrTableStart     uint64
+	InodeTableStart     uint64
+	DirectoryTableStart uint64
+	FragmentTableStart  uint64
+	ExportTableStart    uint64
+}
+
+// squashFS serves the regular files of a squashfs image directly from the image,
+// the gzip and zstd compressed images are supported
+type squashFS struct {
+	data    io.ReaderAt
+	super   squashfsSuperblock
+	decoder *zstd.Decoder
+	entries map[string]*squashfsInfo
+}
+
+// squashfsInode is the directory or the regular file of the image
+type squashfsInode struct {
+	mode    fs.FileMode
+	modTime time.Time
+
+	// listing of the directory in the directory table
+	dirBlock  uint32
+	dirOffset uint16
+	dirSize   uint32
+
+	// blocks of the file, its tail may be stored in the fragment
+	blocksStart    uint64
+	size           uint64
+	fragment       uint32
+	fragmentOffset uint32
+	blockSizes     []uint32
+}
+
+// squashfsDirEntry is the entry of the directory listing
+type squashfsDirEntry struct {
+	name  string
+	inode uint64
+}
+
+// openSquashfs indexes the squashfs image, the file contents are read from the
+// image when opened
+func openSquashfs(image string) (fs.FS, error) {
+	file, err := os.Open(image)
+	if err != nil {
+		return nil, err
+	}
+	fsys, err := indexSquashfs(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return fsys, nil
+}
+
+func indexSquashfs(data io.ReaderAt) (*squashFS, error) {
+	fsys := &squashFS{data: data, entries: map[string]*squashfsInfo{}}
+	if err := binary.Read(io.NewSectionReader(data, 0, 96), binary.LittleEndian, &fsys.super); err != nil {
+		return nil, err
+	}
+	super := fsys.super
+	switch {
+	case super.Magic != squashfsMagic:
+		return nil, fmt.Errorf("not a squashfs image")
+	case super.VersionMajor != 4:
+		return nil, fmt.Errorf("squashfs version %v.%v is not supported", super.VersionMajor, super.VersionMinor)
+	case super.BlockSize < 4096 || super.BlockSize > 1<<20 || super.BlockSize&(super.BlockSize-1) != 0:
+		return nil, errSquashfsCorrupted
+	}
+	switch super.Compression {
+	case squashfsGzip:
+	case squashfsZstd:
+		decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		fsys.decoder = decoder
+	default:
+		return nil, fmt.Errorf("squashfs compression %v is not supported, use gzip or zstd", super.Compression)
+	}
+
+	root, err := fsys.readInode(super.RootInode)
+	if err != nil {
+		return nil, err
+	}
+	if !root.mode.IsDir() {
+		return nil, errSquashfsCorrupted
+	}
+	if err := fsys.index(".", root, map[uint64]bool{super.RootInode: true}); err != nil {
+		return nil, err
+	}
+	return fsys, nil
+}
+
+// index adds the directory and its regular files and directories to the entries
+func (this *squashFS) index(name string, dir *squashfsInode, visited map[uint64]bool) error {
+	this.entries[name] = &squashfsInfo{name: path.Base(name), inode: dir}
+	children, err := this.readDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if !fs.ValidPath(child.name) || path.Base(child.name) != child.name || child.name == "." {
+			return errSquashfsCorrupted
+		}
+		inode, err := this.readInode(child.inode)
+		if err != nil {
+			return err
+		}
+		childName := path.Join(name, child.name)
+		switch {
+		case inode.mode.IsDir():
+			// the directories cannot be linked, a cycle is a corrupted image
+			if visited[child.inode] {
+				return errSquashfsCorrupted
+			}
+			visited[child.inode] = true
+			if err := this.index(childName, inode, visited); err != nil {
+				return err
+			}
+		case inode.mode.IsRegular():
+			this.entries[childName] = &squashfsInfo{name: child.name, inode: inode}
+		default:
+			// links and special files are not served
+		}
+	}
+	return nil
+}
+
+// readInode reads the inode of the reference, i.e. the position of its metadata
+// block within the inode table and its offset within the block
+func (this *squashFS) readInode(ref uint64) (*squashfsInode, error) {
+	reader, err := this.metadata(int64(this.super.InodeTableStart+ref>>16), int(ref&0xFFFF))
+	if err != nil {
+		return nil, err
+	}
+	header := struct {
+		Type, Permissions, Uid, Gid uint16
+		ModTime, Number             uint32
+	}{}
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	inode := &squashfsInode{
+		mode:    fs.FileMode(header.Permissions & 0777),
+		modTime: time.Unix(int64(header.ModTime), 0),
+	}
+	switch header.Type {
+	case squashfsDir:
+		dir := struct {
+			Block, Links   uint32
+			Size, Offset   uint16
+			ParentInodeRef uint32
+		}{}
+		if err := binary.Read(reader, binary.LittleEndian, &dir); err != nil {
+			return nil, err
+		}
+		inode.mode |= fs.ModeDir
+		inode.dirBlock, inode.dirOffset, inode.dirSize = dir.Block, dir.Offset, uint32(dir.Size)
+	case squashfsExtDir:
+		dir := struct {
+			Links, Size, Block, ParentInodeRef uint32
+			IndexCount, Offset                 uint16
+			Xattr                              uint32
+		}{}
+		if err := binary.Read(reader, binary.LittleEndian, &dir); err != nil {
+			return nil, err
+		}
+		inode.mode |= fs.ModeDir
+		inode.dirBlock, inode.dirOffset, inode.dirSize = dir.Block, dir.Offset, dir.Size
+	case squashfsFile:
+		file := struct{ BlocksStart, Fragment, FragmentOffset, Size uint32 }{}
+		if err := binary.Read(reader, binary.LittleEndian, &file); err != nil {
+			return nil, err
+		}
+		inode.blocksStart, inode.size = uint64(file.BlocksStart), uint64(file.Size)
+		inode.fragment, inode.fragmentOffset = file.Fragment, file.FragmentOffset
+	case squashfsExtFile:
+		file := struct {
+			BlocksStart, Size, Sparse              uint64
+			Links, Fragment, FragmentOffset, Xattr uint32
+		}{}
+		if err := binary.Read(reader, binary.LittleEndian, &file); err != nil {
+			return nil, err
+		}
+		inode.blocksStart, inode.size = file.BlocksStart, file.Size
+		inode.fragment, inode.fragmentOffset = file.Fragment, file.FragmentOffset
+	default:
+		inode.mode |= fs.ModeIrregular
+		return inode, nil
+	}
+	if inode.mode.IsDir() {
+		return inode, nil
+	}
+
+	blocks := inode.size / uint64(this.super.BlockSize)
+	if inode.fragment == squashfsNoFragment && inode.size%uint64(this.super.BlockSize) != 0 {
+		blocks++
+	}
+	if blocks > this.super.BytesUsed {
+		return nil, errSquashfsCorrupted
+	}
+	inode.blockSizes = make([]uint32, blocks)
+	if err := binary.Read(reader, binary.LittleEndian, inode.blockSizes); err != nil {
+		return nil, err
+	}
+	return inode, nil
+}
+
+// readDir reads the entries of the directory listing
+func (this *squashFS) readDir(dir *squashfsInode) ([]squashfsDirEntry, error) {
+	// the size counts the . and .. entries, which are not stored
+	if dir.dirSize <= 3 {
+		return nil, nil
+	}
+	reader, err := this.metadata(int64(this.super.DirectoryTableStart)+int64(dir.dirBlock), int(dir.dirOffset))
+	if err != nil {
+		return nil, err
+	}
+	listing, err := io.ReadAll(io.LimitReader(reader, int64(dir.dirSize)-3))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []squashfsDirEntry{}
+	content := bytes.NewReader(listing)
+	for content.Len() > 0 {
+		header := struct{ Count, Start, Number uint32 }{}
+		if err := binary.Read(content, binary.LittleEndian, &header); err != nil {
+			return nil, err
+		}
+		if header.Count >= 256 {
+			return nil, errSquashfsCorrupted
+		}
+		for i := uint32(0); i <= header.Count; i++ {
+			entry := struct {
+				Offset       uint16
+				NumberOffset int16
+				Type         uint16
+				NameSize     uint16
+			}{}
+			if err := binary.Read(content, binary.LittleEndian, &entry); err != nil {
+				return nil, err
+			}
+			name := make([]byte, int(entry.NameSize)+1)
+			if _, err := io.ReadFull(content, name); err != nil {
+				return nil, err
+			}
+			entries = append(entries, squashfsDirEntry{name: string(name), inode: uint64(header.Start)<<16 | uint64(entry.Offset)})
+		}
+	}
+	return entries, nil
+}
+
+// readFile reads the blocks and the fragment of the file
+func (this *squashFS) readFile(file *squashfsInode) ([]byte, error) {
+	content := []byte{}
+	position := int64(file.blocksStart)
+	for _, size := range file.blockSizes {
+		if size&^squashfsUncompressedData == 0 {
+			// the sparse block of zeros
+			content = append(content, make([]byte, min(uint64(this.super.BlockSize), file.size-uint64(len(content))))...)
+			continue
+		}
+		block, err := this.dataBlock(position, size)
+		if err != nil {
+			return nil, err
+		}
+		position += int64(size &^ squashfsUncompressedData)
+		content = append(content, block...)
+	}
+	if file.fragment != squashfsNoFragment {
+		fragment, err := this.fragmentBlock(file.fragment)
+		if err != nil {
+			return nil, err
+		}
+		tail := file.size - uint64(len(content))
+		if uint64(file.fragmentOffset)+tail > uint64(len(fragment)) {
+			return nil, errSquashfsCorrupted
+		}
+		content = append(content, fragment[file.fragmentOffset:uint64(file.fragmentOffset)+tail]...)
+	}
+	if uint64(len(content)) != file.size {
+		return nil, errSquashfsCorrupted
+	}
+	return content, nil
+}
+
+// fragmentBlock reads the block of the fragment, the tails of several files
+func (this *squashFS) fragmentBlock(index uint32) ([]byte, error) {
+	if index >= this.super.FragmentCount {
+		return nil, errSquashfsCorrupted
+	}
+	pointer := make([]byte, 8)
+	if _, err := this.data.ReadAt(pointer, int64(this.super.FragmentTableStart)+8*int64(index/squashfsFragmentEntries)); err != nil {
+		return nil, err
+	}
+	reader, err := this.metadata(int64(binary.LittleEndian.Uint64(pointer)), int(index%squashfsFragmentEntries)*16)
+	if err != nil {
+		return nil, err
+	}
+	entry := struct {
+		Start        uint64
+		Size, Unused uint32
+	}{}
+	if err := binary.Read(reader, binary.LittleEndian, &entry); err != nil {
+		return nil, err
+	}
+	return this.dataBlock(int64(entry.Start), entry.Size)
+}
+
+// dataBlock reads the data block of the size, flagged if stored uncompressed
+func (this *squashFS) dataBlock(position int64, size uint32) ([]byte, error) {
+	length := size &^ squashfsUncompressedData
+	if length > this.super.BlockSize {
+		return nil, errSquashfsCorrupted
+	}
+	raw := make([]byte, length)
+	if _, err := this.data.ReadAt(raw, position); err != nil {
+		return nil, err
+	}
+	if size&squashfsUncompressedData != 0 {
+		return raw, nil
+	}
+	return this.decompress(raw, int(this.super.BlockSize))
+}
+
+// metadata reads the metadata blocks from the position of the block, starting
+// at the offset within the uncompressed block
+func (this *squashFS) metadata(position int64, offset int) (io.Reader, error) {
+	reader := &squashfsMetadata{fsys: this, position: position}
+	if _, err := io.CopyN(io.Discard, reader, int64(offset)); err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+// squashfsMetadata reads the consecutive metadata blocks
+type squashfsMetadata struct {
+	fsys     *squashFS
+	position int64
+	block    []byte
+}
+
+func (this *squashfsMetadata) Read(p []byte) (int, error) {
+	if len(this.block) == 0 {
+		header := make([]byte, 2)
+		if _, err := this.fsys.data.ReadAt(header, this.position); err != nil {
+			return 0, err
+		}
+		size := binary.LittleEndian.Uint16(header)
+		length := int(size &^ squashfsUncompressedMetadata)
+		if length == 0 || length > squashfsMetadataSize {
+			return 0, errSquashfsCorrupted
+		}
+		raw := make([]byte, length)
+		if _, err := this.fsys.data.ReadAt(raw, this.position+2); err != nil {
+			return 0, err
+		}
+		this.position += 2 + int64(length)
+		if size&squashfsUncompressedMetadata != 0 {
+			this.block = raw
+		} else {
+			block, err := this.fsys.decompress(raw, squashfsMetadataSize)
+			if err != nil {
+				return 0, err
+			}
+			this.block = block
+		}
+	}
+	n := copy(p, this.block)
+	this.block = this.block[n:]
+	return n, nil
+}
+
+// decompress decompresses the block up to the max size
+func (this *squashFS) decompress(raw []byte, maxSize int) ([]byte, error) {
+	var content []byte
+	if this.decoder != nil {
+		decoded, err := this.decoder.DecodeAll(raw, nil)
+		if err != nil {
+			return nil, err
+		}
+		content = decoded
+	} else {
+		reader, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		if content, err = io.ReadAll(io.LimitReader(reader, int64(maxSize)+1)); err != nil {
+			return nil, err
+		}
+	}
+	if len(content) > maxSize {
+		return nil, errSquashfsCorrupted
+	}
+	return content, nil
+}
+
+func (this *squashFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	info, ok := this.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if info.IsDir() {
+		return newDirFile(info, dirEntries(this.entries, name, func(info *squashfsInfo) fs.FileInfo { return info })), nil
+	}
+	// the compressed blocks cannot be read at random offsets
+	content, err := this.readFile(info.inode)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return &tarFile{SectionReader: io.NewSectionReader(bytes.NewReader(content), 0, int64(len(content))), info: info}, nil
+}
+
+// squashfsInfo describes the directory or the regular file of the image
+type squashfsInfo struct {
+	name  string
+	inode *squashfsInode
+}
+
+func (this *squashfsInfo) Name() string       { return this.name }
+func (this *squashfsInfo) Size() int64        { return int64(this.inode.size) }
+func (this *squashfsInfo) Mode() fs.FileMode  { return this.inode.mode }
+func (this *squashfsInfo) ModTime() time.Time { return this.inode.modTime }
+func (this *squashfsInfo) IsDir() bool        { return this.inode.mode.IsDir() }
+func (this *squashfsInfo) Sys() any           { return nil }
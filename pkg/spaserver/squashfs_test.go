@@ -0,0 +1,114 @@
+package spaserver
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type SquashfsTestSuite struct {
+	suite.Suite
+	dataDir string
+	// imageDir holds the images of the test data built by an independent squashfs writer
+	imageDir string
+}
+
+func TestSquashfsTestSuite(t *testing.T) {
+	suite.Run(t, new(SquashfsTestSuite))
+}
+
+func (suite *SquashfsTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	_, filename, _, _ := runtime.Caller(0)
+	suite.dataDir = path.Join(path.Dir(filename), "test/data")
+	suite.imageDir = path.Join(path.Dir(filename), "test/squashfs")
+}
+
+func (suite *SquashfsTestSuite) serve(roots []string, requestPath string, acceptEncoding string) *httptest.ResponseRecorder {
+	sut := &server{
+		cfg:    Config{RootDirs: roots, BaseURL: "/"},
+		logger: zerolog.New(os.Stdout),
+	}
+	req, err := http.NewRequest("GET", requestPath, nil)
+	suite.Require().Nil(err)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *SquashfsTestSuite) Test_Squashfs_root_Then_OK_With_Content() {
+
+	// when
+	rr := suite.serve([]string{path.Join(suite.imageDir, "app.sqsh")}, "/testfile.json", "")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(testfile_json, rr.Body.String())
+}
+
+func (suite *SquashfsTestSuite) Test_Squashfs_root_precompressed_Then_OK_and_encoded() {
+
+	// when
+	rr := suite.serve([]string{path.Join(suite.imageDir, "app.sqsh")}, "/prebr.js", "br")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("br", rr.Header().Get("Content-Encoding"))
+	suite.Equal(prebr_js_br, rr.Body.String())
+}
+
+func (suite *SquashfsTestSuite) Test_Zstd_squashfs_bundle_Then_nested_file_served() {
+
+	// given
+	logo, err := os.ReadFile(path.Join(suite.dataDir, "logo.png"))
+	suite.Require().Nil(err)
+
+	// when
+	rr := suite.serve([]string{bundleScheme + path.Join(suite.imageDir, "app-zstd.sqsh")}, "/assets/logo.png", "")
+	fallback := suite.serve([]string{bundleScheme + path.Join(suite.imageDir, "app-zstd.sqsh")}, "/some/route", "")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(logo, rr.Body.Bytes())
+	suite.Equal(http.StatusOK, fallback.Code)
+	suite.Equal(index_html, fallback.Body.String())
+}
+
+func (suite *SquashfsTestSuite) Test_Squashfs_directories_Then_listed() {
+
+	// given
+	fsys, err := openSquashfs(path.Join(suite.imageDir, "app.sqsh"))
+	suite.Require().Nil(err)
+
+	// when
+	entries, err := fs.ReadDir(fsys, ".")
+	suite.Require().Nil(err)
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	// then
+	suite.Equal([]string{"assets", "index.html", "prebr.js", "prebr.js.br", "testfile.json"}, names)
+	suite.True(entries[0].IsDir())
+}
+
+func (suite *SquashfsTestSuite) Test_Not_squashfs_Then_error() {
+
+	// when
+	_, err := openSquashfs(path.Join(suite.dataDir, "logo.png"))
+
+	// then
+	suite.ErrorContains(err, "not a squashfs image")
+}
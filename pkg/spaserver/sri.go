@@ -0,0 +1,104 @@
+package spaserver
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// script and link tags, the groups are the tag name and its attributes
+	subresourceTagRegex = regexp.MustCompile(`(?i)<(script|link)(\s[^>]*?)\s*(/?)>`)
+	attributeRegex      = regexp.MustCompile(`(?i)\s([a-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// injectsIntegrity returns true if the subresource integrity is injected to the resource
+func (this *server) injectsIntegrity(resourcePath string) bool {
+	return this.cfg.SriEnabled && isHtml(resourcePath)
+}
+
+// injectIntegrity adds the integrity and crossorigin attributes to the scripts and
+// stylesheets of the document, which reference the files served from the roots
+func (this *server) injectIntegrity(ctx context.Context, name string, content []byte) []byte {
+	return subresourceTagRegex.ReplaceAllFunc(content, func(tag []byte) []byte {
+		groups := subresourceTagRegex.FindSubmatch(tag)
+		attributes := map[string]string{}
+		for _, attribute := range attributeRegex.FindAllSubmatch(groups[2], -1) {
+			attributes[strings.ToLower(string(attribute[1]))] = string(attribute[2]) + string(attribute[3]) + string(attribute[4])
+		}
+		if _, ok := attributes["integrity"]; ok {
+			return tag
+		}
+
+		reference := ""
+		switch strings.ToLower(string(groups[1])) {
+		case "script":
+			reference = attributes["src"]
+		case "link":
+			switch strings.ToLower(attributes["rel"]) {
+			case "stylesheet", "modulepreload", "preload":
+				reference = attributes["href"]
+			}
+		}
+		resourcePath, ok := this.subresourcePath(name, reference)
+		if !ok || isHtml(resourcePath) {
+			return tag
+		}
+		integrity, ok := this.subresourceIntegrity(ctx, resourcePath)
+		if !ok {
+			return tag
+		}
+
+		injected := ` integrity="` + integrity + `"`
+		if _, ok := attributes["crossorigin"]; !ok {
+			injected += ` crossorigin="` + this.cfg.SriCrossOrigin + `"`
+		}
+		return []byte("<" + string(groups[1]) + string(groups[2]) + injected + string(groups[3]) + ">")
+	})
+}
+
+// subresourcePath resolves the reference of the document to the resource path
+// within the roots, external references are not resolved
+func (this *server) subresourcePath(document string, reference string) (string, bool) {
+	if reference == "" {
+		return "", false
+	}
+	parsed, err := url.Parse(reference)
+	if err != nil || parsed.Scheme != "" || parsed.Host != "" || parsed.Path == "" {
+		return "", false
+	}
+	if !strings.HasPrefix(parsed.Path, "/") {
+		// relative to the document
+		return path.Join(path.Dir("/"+document), parsed.Path), true
+	}
+	if stripped, ok := this.stripBaseUrl(parsed.Path); ok {
+		return stripped, true
+	}
+	return parsed.Path, true
+}
+
+// subresourceIntegrity computes the sha384 integrity of the resource
+func (this *server) subresourceIntegrity(ctx context.Context, resourcePath string) (string, bool) {
+	file, ok, err := this.findFile(ctx, resourcePath)
+	if err != nil || !ok {
+		return "", false
+	}
+	if this.transformsContent(ctx, resourcePath) {
+		// the integrity of the served content
+		if file, err = this.transformContent(ctx, resourcePath, file); err != nil {
+			return "", false
+		}
+	}
+	defer file.Close()
+
+	digest := sha512.New384()
+	if _, err := io.Copy(digest, file); err != nil {
+		return "", false
+	}
+	return "sha384-" + base64.StdEncoding.EncodeToString(digest.Sum(nil)), true
+}
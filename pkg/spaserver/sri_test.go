@@ -0,0 +1,72 @@
+package spaserver
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type SriTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestSriTestSuite(t *testing.T) {
+	suite.Run(t, new(SriTestSuite))
+}
+
+func (suite *SriTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.MkdirAll(path.Join(rootDir, "assets"), 0755))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "index.html"), []byte(
+		`<link rel="stylesheet" href="/app/assets/main.css">`+
+			`<script type="module" src="assets/main.js"></script>`+
+			`<script src="https://cdn.example.com/lib.js"></script>`+
+			`<script src="/app/assets/checked.js" integrity="sha384-x"></script>`), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "assets/main.css"), []byte("body{}"), 0644))
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "assets/main.js"), []byte("init()"), 0644))
+
+	suite.sut = &server{
+		cfg: Config{
+			RootDirs:       []string{rootDir},
+			BaseURL:        "/app/",
+			SriEnabled:     true,
+			SriCrossOrigin: "anonymous",
+		},
+		logger: zerolog.New(os.Stdout),
+	}
+}
+
+func (suite *SriTestSuite) integrity(content string) string {
+	digest := sha512.Sum384([]byte(content))
+	return "sha384-" + base64.StdEncoding.EncodeToString(digest[:])
+}
+
+func (suite *SriTestSuite) Test_Document_Then_integrity_of_local_subresources_injected() {
+
+	// given
+	req, err := http.NewRequest("GET", "/app/", nil)
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+
+	// when
+	suite.sut.handler(context.Background(), rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(
+		`<link rel="stylesheet" href="/app/assets/main.css" integrity="`+suite.integrity("body{}")+`" crossorigin="anonymous">`+
+			`<script type="module" src="assets/main.js" integrity="`+suite.integrity("init()")+`" crossorigin="anonymous"></script>`+
+			`<script src="https://cdn.example.com/lib.js"></script>`+
+			`<script src="/app/assets/checked.js" integrity="sha384-x"></script>`,
+		rr.Body.String())
+}
@@ -0,0 +1,202 @@
+package spaserver
+
+import (
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// recentWindow is the period of the recent event counts reported by the status
+const recentWindow = 15 * time.Minute
+
+// recentCounts counts the events in the per-minute buckets of the recent window
+type recentCounts struct {
+	lock    sync.Mutex
+	buckets [int(recentWindow / time.Minute)]recentBucket
+}
+
+type recentBucket struct {
+	minute int64
+	counts map[string]int64
+}
+
+func (this *recentCounts) add(event string) {
+	minute := time.Now().Unix() / 60
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	bucket := &this.buckets[minute%int64(len(this.buckets))]
+	if bucket.minute != minute || bucket.counts == nil {
+		bucket.minute = minute
+		bucket.counts = map[string]int64{}
+	}
+	bucket.counts[event]++
+}
+
+// snapshot sums the counts of the buckets within the recent window
+func (this *recentCounts) snapshot() map[string]int64 {
+	oldest := time.Now().Unix()/60 - int64(len(this.buckets)) + 1
+	counts := map[string]int64{}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for _, bucket := range this.buckets {
+		if bucket.minute < oldest {
+			continue
+		}
+		for event, count := range bucket.counts {
+			counts[event] += count
+		}
+	}
+	return counts
+}
+
+type statusResponse struct {
+	Started         time.Time        `json:"started"`
+	UptimeSeconds   float64          `json:"uptime_seconds"`
+	Build           statusBuild      `json:"build"`
+	Config          statusConfig     `json:"config"`
+	Roots           []statusRoot     `json:"roots"`
+	RootsError      string           `json:"roots_error,omitempty"`
+	ScheduledActive bool             `json:"scheduled_active,omitempty"`
+	Cache           statusCache      `json:"cache"`
+	RecentErrors    map[string]int64 `json:"recent_errors"`
+	RecentWindow    string           `json:"recent_window"`
+}
+
+type statusBuild struct {
+	GoVersion string `json:"go_version"`
+	Version   string `json:"version,omitempty"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+type statusConfig struct {
+	Port                int      `json:"port"`
+	BaseURL             string   `json:"base_url"`
+	Roots               []string `json:"roots"`
+	FallbackDisabled    bool     `json:"fallback_disabled"`
+	BrotliDisabled      bool     `json:"brotli_disabled"`
+	GzipDisabled        bool     `json:"gzip_disabled"`
+	ZstdDisabled        bool     `json:"zstd_disabled"`
+	DynamicCompression  bool     `json:"dynamic_compression"`
+	SnapshotEnabled     bool     `json:"snapshot_enabled"`
+	CoalesceMaxSize     int64    `json:"coalesce_max_size"`
+	SyncInterval        string   `json:"sync_interval"`
+	IntegrityMode       string   `json:"integrity_mode,omitempty"`
+	SignatureKeys       int      `json:"signature_keys"`
+	SignatureIdentities []string `json:"signature_identities,omitempty"`
+
+	ScheduledRoots      []string `json:"scheduled_roots,omitempty"`
+	ScheduledActivation string   `json:"scheduled_activation,omitempty"`
+	RolloutRoots        []string `json:"rollout_roots,omitempty"`
+	RolloutPercentage   int      `json:"rollout_percentage,omitempty"`
+	VersionsDir         string   `json:"versions_dir,omitempty"`
+}
+
+type statusRoot struct {
+	Name     string     `json:"name"`
+	Remote   bool       `json:"remote"`
+	Readable bool       `json:"readable"`
+	Error    string     `json:"error,omitempty"`
+	Synced   *time.Time `json:"synced,omitempty"`
+	Revision string     `json:"revision,omitempty"`
+}
+
+type statusCache struct {
+	Lookups          int64  `json:"lookups"`
+	CoalescedLookups int64  `json:"coalesced_lookups"`
+	FileStats        int64  `json:"file_stats"`
+	MemoryPressure   string `json:"memory_pressure"`
+}
+
+// serveStatus reports the operational status of the server
+func (this *server) serveStatus(w http.ResponseWriter, req *http.Request) {
+	status := statusResponse{
+		Started:       this.started,
+		UptimeSeconds: time.Since(this.started).Seconds(),
+		Build:         buildStatus(),
+		Config:        this.configStatus(),
+		Roots:         []statusRoot{},
+		Cache: statusCache{
+			Lookups:          this.coalesceStats.lookups.Load(),
+			CoalescedLookups: this.coalesceStats.coalesced.Load(),
+			FileStats:        this.fileStatsCount.Load(),
+			MemoryPressure:   this.memoryLevelName(),
+		},
+		RecentErrors: this.recent.snapshot(),
+		RecentWindow: recentWindow.String(),
+	}
+
+	roots, err := this.assetRoots()
+	if err != nil {
+		status.RootsError = err.Error()
+	}
+	status.ScheduledActive = this.scheduled.active.Load()
+	for _, root := range roots {
+		rootStatus := statusRoot{Name: rootLabel(root.name), Remote: root.refresh != nil, Readable: true}
+		if file, err := root.fsys.Open("."); err != nil {
+			rootStatus.Readable = false
+			rootStatus.Error = err.Error()
+		} else {
+			file.Close()
+		}
+		if root.refresh != nil {
+			synced := time.Unix(0, root.synced.Load())
+			rootStatus.Synced = &synced
+			rootStatus.Revision = this.rootRevision(root.name)
+		}
+		status.Roots = append(status.Roots, rootStatus)
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (this *server) configStatus() statusConfig {
+	integrityMode := ""
+	if this.cfg.IntegrityManifest != "" {
+		integrityMode = this.cfg.IntegrityMode
+	}
+	return statusConfig{
+		Port:                this.cfg.Port,
+		BaseURL:             this.cfg.BaseURL,
+		Roots:               rootLabels(this.cfg.RootDirs),
+		FallbackDisabled:    this.cfg.FallbackDisabled,
+		BrotliDisabled:      this.cfg.BrotliDisabled,
+		GzipDisabled:        this.cfg.GzipDisabled,
+		ZstdDisabled:        this.cfg.ZstdDisabled,
+		DynamicCompression:  this.cfg.DynamicCompression,
+		SnapshotEnabled:     this.cfg.SnapshotEnabled,
+		CoalesceMaxSize:     this.cfg.CoalesceMaxSize,
+		SyncInterval:        this.cfg.SyncInterval.String(),
+		IntegrityMode:       integrityMode,
+		SignatureKeys:       len(this.cfg.SignaturePublicKeys),
+		SignatureIdentities: this.cfg.SignatureIdentities,
+
+		ScheduledRoots:      rootLabels(this.cfg.ScheduledRoots),
+		ScheduledActivation: this.cfg.ScheduledActivation,
+		RolloutRoots:        rootLabels(this.cfg.RolloutRoots),
+		RolloutPercentage:   this.cfg.RolloutPercentage,
+		VersionsDir:         this.cfg.VersionsDir,
+	}
+}
+
+// buildStatus reads the build information embedded by the go toolchain
+func buildStatus() statusBuild {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return statusBuild{}
+	}
+	build := statusBuild{GoVersion: info.GoVersion, Version: info.Main.Version}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Revision = setting.Value
+		case "vcs.time":
+			build.Time = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+	return build
+}
@@ -0,0 +1,71 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type StatusTestSuite struct {
+	suite.Suite
+}
+
+func TestStatusTestSuite(t *testing.T) {
+	suite.Run(t, new(StatusTestSuite))
+}
+
+func (suite *StatusTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+}
+
+func (suite *StatusTestSuite) Test_Status_Then_roots_and_recent_errors_reported() {
+
+	// given
+	sut, err := newServer(Config{
+		RootDirs: []string{"test/data"},
+		BaseURL:  "/",
+	}, zerolog.New(os.Stdout))
+	suite.Require().Nil(err)
+	notFound, err := http.NewRequest("GET", "/missing.js", nil)
+	suite.Require().Nil(err)
+	notFound.Header.Set("Accept", "application/javascript")
+	sut.handler(notFound.Context(), httptest.NewRecorder(), notFound)
+
+	req, err := http.NewRequest("GET", "/status", nil)
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+
+	// when
+	sut.adminHandler().ServeHTTP(rr, req)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("application/json", rr.Header().Get("Content-Type"))
+	status := statusResponse{}
+	suite.Require().Nil(json.Unmarshal(rr.Body.Bytes(), &status))
+	suite.Equal([]statusRoot{{Name: "test/data", Readable: true}}, status.Roots)
+	suite.Equal(int64(1), status.RecentErrors[outcomeNotFound])
+	suite.NotEmpty(status.Build.GoVersion)
+}
+
+func (suite *StatusTestSuite) Test_Counts_older_than_window_Then_not_reported() {
+
+	// given
+	sut := recentCounts{}
+	sut.add("error")
+	// bucket of the minute preceding the window
+	stale := time.Now().Add(-recentWindow).Unix()/60 - 1
+	sut.buckets[stale%int64(len(sut.buckets))] = recentBucket{minute: stale, counts: map[string]int64{"error": 5}}
+
+	// when
+	counts := sut.snapshot()
+
+	// then
+	suite.Equal(int64(1), counts["error"])
+}
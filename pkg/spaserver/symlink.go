@@ -0,0 +1,97 @@
+package spaserver
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// symlink policies of the directory roots
+const (
+	// symlinksFollow follows the symlinks anywhere, e.g. out of the root
+	symlinksFollow = "follow"
+	// symlinksWithinRoot follows the symlinks resolving within the root
+	symlinksWithinRoot = "within-root"
+	// symlinksDeny serves no file reached through a symlink within the root
+	symlinksDeny = "deny"
+)
+
+// errSymlinkRefused reports the file refused by the symlink policy, it is
+// not found, so that the lookup continues with the next root or the fallback
+var errSymlinkRefused = fmt.Errorf("symlink refused by policy: %w", fs.ErrNotExist)
+
+// dirFS opens the directory root with the symlink policy. The root directory
+// itself may be a symlink, e.g. to the current release, the policy applies
+// to the symlinks within the root.
+func dirFS(dir string, policy string) (fs.FS, error) {
+	switch policy {
+	case "", symlinksFollow:
+		return os.DirFS(dir), nil
+	case symlinksWithinRoot, symlinksDeny:
+		return &symlinkFS{FS: os.DirFS(dir), dir: dir, policy: policy}, nil
+	default:
+		return nil, fmt.Errorf("unknown symlink policy %v", policy)
+	}
+}
+
+// symlinkFS checks the path of the opened file against the symlink policy.
+// The path is resolved on each open, since the symlinks may be swapped while
+// serving.
+type symlinkFS struct {
+	fs.FS
+	dir    string
+	policy string
+}
+
+func (this *symlinkFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	var err error
+	if this.policy == symlinksDeny {
+		err = this.checkNoSymlink(name)
+	} else {
+		err = this.checkWithinRoot(name)
+	}
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return this.FS.Open(name)
+}
+
+// checkNoSymlink checks that none of the path segments is a symlink
+func (this *symlinkFS) checkNoSymlink(name string) error {
+	if name == "." {
+		return nil
+	}
+	current := this.dir
+	for _, segment := range strings.Split(name, "/") {
+		current = filepath.Join(current, segment)
+		info, err := os.Lstat(current)
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return errSymlinkRefused
+		}
+	}
+	return nil
+}
+
+// checkWithinRoot checks that the path resolves within the resolved root
+func (this *symlinkFS) checkWithinRoot(name string) error {
+	root, err := filepath.EvalSymlinks(this.dir)
+	if err != nil {
+		return err
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(this.dir, filepath.FromSlash(name)))
+	if err != nil {
+		return err
+	}
+	if resolved != root && !strings.HasPrefix(resolved, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator)) {
+		return errSymlinkRefused
+	}
+	return nil
+}
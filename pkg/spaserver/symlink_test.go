@@ -0,0 +1,93 @@
+package spaserver
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SymlinkTestSuite struct {
+	suite.Suite
+	root string
+}
+
+func TestSymlinkTestSuite(t *testing.T) {
+	suite.Run(t, new(SymlinkTestSuite))
+}
+
+func (suite *SymlinkTestSuite) SetupTest() {
+	dir := suite.T().TempDir()
+	release := filepath.Join(dir, "releases", "42")
+	suite.Require().Nil(os.MkdirAll(filepath.Join(release, "shared"), 0755))
+	suite.Require().Nil(os.WriteFile(filepath.Join(release, "shared", "app.js"), []byte("app"), 0644))
+	suite.Require().Nil(os.WriteFile(filepath.Join(dir, "secret"), []byte("secret"), 0644))
+	suite.Require().Nil(os.Symlink(filepath.Join(release, "shared"), filepath.Join(release, "assets")))
+	suite.Require().Nil(os.Symlink(filepath.Join(dir, "secret"), filepath.Join(release, "escape")))
+	suite.root = filepath.Join(dir, "current")
+	suite.Require().Nil(os.Symlink(release, suite.root))
+}
+
+func (suite *SymlinkTestSuite) read(policy string, name string) (string, error) {
+	fsys, err := dirFS(suite.root, policy)
+	suite.Require().Nil(err)
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	return string(content), err
+}
+
+func (suite *SymlinkTestSuite) Test_Follow_Then_all_symlinks_followed() {
+
+	// when
+	assets, assetsErr := suite.read(symlinksFollow, "assets/app.js")
+	escape, escapeErr := suite.read(symlinksFollow, "escape")
+
+	// then
+	suite.Nil(assetsErr)
+	suite.Equal("app", assets)
+	suite.Nil(escapeErr)
+	suite.Equal("secret", escape)
+}
+
+func (suite *SymlinkTestSuite) Test_Within_root_Then_escaping_symlink_not_found() {
+
+	// when
+	assets, assetsErr := suite.read(symlinksWithinRoot, "assets/app.js")
+	_, escapeErr := suite.read(symlinksWithinRoot, "escape")
+
+	// then
+	suite.Nil(assetsErr)
+	suite.Equal("app", assets)
+	suite.True(errors.Is(escapeErr, fs.ErrNotExist))
+}
+
+func (suite *SymlinkTestSuite) Test_Deny_Then_symlinks_not_found() {
+
+	// when
+	shared, sharedErr := suite.read(symlinksDeny, "shared/app.js")
+	_, assetsErr := suite.read(symlinksDeny, "assets/app.js")
+	_, escapeErr := suite.read(symlinksDeny, "escape")
+
+	// then
+	suite.Nil(sharedErr)
+	suite.Equal("app", shared)
+	suite.True(errors.Is(assetsErr, fs.ErrNotExist))
+	suite.True(errors.Is(escapeErr, fs.ErrNotExist))
+}
+
+func (suite *SymlinkTestSuite) Test_Unknown_policy_Then_error() {
+
+	// when
+	_, err := dirFS(suite.root, "sometimes")
+
+	// then
+	suite.NotNil(err)
+}
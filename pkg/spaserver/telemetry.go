@@ -0,0 +1,519 @@
+package spaserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/contrib/exporters/autoexport"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	metricsdk "go.opentelemetry.io/otel/sdk/metric"
+
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type instruments struct {
+	tracer           trace.Tracer
+	meters           metric.Meter
+	resources_served metric.Int64Counter
+	fallbacks        metric.Int64Counter
+	brotli_encrypted metric.Int64Counter
+	gzip_encrypted   metric.Int64Counter
+	zstd_encrypted   metric.Int64Counter
+	not_found        metric.Int64Counter
+	responses        metric.Int64Counter
+	request_duration metric.Int64Histogram
+	response_bytes   metric.Int64Counter
+
+	original_bytes         metric.Int64Counter
+	transferred_bytes      metric.Int64Counter
+	precompressed_missing  metric.Int64Counter
+	precompressed_lookups  metric.Int64Counter
+	dynamically_compressed metric.Int64Counter
+	cache_lookups          metric.Int64Counter
+
+	root_sync_failures metric.Int64Counter
+	root_sync_age      metric.Float64ObservableGauge
+	root_revision      metric.Int64ObservableGauge
+	integrity_failures metric.Int64Counter
+	root_switches      metric.Int64Counter
+	throttled_requests metric.Int64Counter
+	blocked_requests   metric.Int64Counter
+	variant_requests   metric.Int64Counter
+	memory_pressure    metric.Int64ObservableGauge
+	requests_inflight  metric.Int64ObservableGauge
+	requests_queued    metric.Int64ObservableGauge
+}
+
+// initialize OpenTelemetry instrumentations
+func initTelemetry(ctx context.Context, cfg Config, logger *zerolog.Logger) (shutdown func(context.Context) error, metricsHandler http.Handler, err error) {
+	res, err := telemetryResource(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	views, err := metricViews(cfg.MetricViews)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metricReader, err := autoexport.NewMetricReader(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metricOptions := []metricsdk.Option{
+		metricsdk.WithResource(res),
+		metricsdk.WithReader(metricReader),
+		metricsdk.WithView(views...),
+	}
+	if cfg.PrometheusMetrics {
+		// alongside the exporter of OTEL_METRICS_EXPORTER
+		prometheusReader, handler, err := prometheusExporter()
+		if err != nil {
+			return nil, nil, err
+		}
+		metricOptions = append(metricOptions, metricsdk.WithReader(prometheusReader))
+		metricsHandler = handler
+	}
+	metricProvider := metricsdk.NewMeterProvider(metricOptions...)
+	otel.SetMeterProvider(metricProvider)
+
+	if !cfg.RuntimeMetricsDisabled {
+		if err := startRuntimeMetrics(metricProvider, cfg.RuntimeMetricsInterval); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	traceExporter, err := autoexport.NewSpanExporter(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	traceOptions := []tracesdk.TracerProviderOption{
+		tracesdk.WithResource(res),
+		tracesdk.WithBatcher(traceExporter,
+			tracesdk.WithMaxQueueSize(cfg.TraceBatchQueueSize),
+			tracesdk.WithMaxExportBatchSize(cfg.TraceBatchSize),
+			tracesdk.WithBatchTimeout(cfg.TraceBatchTimeout),
+			tracesdk.WithExportTimeout(cfg.TraceExportTimeout),
+		),
+	}
+	sampler, err := traceSampler(cfg.TraceSampler, cfg.TraceSamplerRatio)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sampler != nil {
+		traceOptions = append(traceOptions, tracesdk.WithSampler(sampler))
+	}
+
+	traceProvider := tracesdk.NewTracerProvider(traceOptions...)
+
+	otel.SetTracerProvider(traceProvider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	shutdown = func(context.Context) error {
+		errMetric := metricProvider.Shutdown(ctx)
+		errTrace := traceProvider.Shutdown(ctx)
+
+		if errMetric != nil || errTrace != nil {
+			return fmt.Errorf("error shutting down telemetry: %v, %v", errMetric, errTrace)
+		}
+		return nil
+	}
+
+	return shutdown, metricsHandler, nil
+}
+
+// traceExcludedKey marks the context of requests excluded from tracing
+type traceExcludedKey struct{}
+
+// startSpan starts a new span unless the request is excluded from tracing
+func startSpan(ctx context.Context, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if excluded, _ := ctx.Value(traceExcludedKey{}).(bool); excluded {
+		return ctx, noop.Span{}
+	}
+	return telemetry().tracer.Start(ctx, spanName, opts...)
+}
+
+// traceExcluded returns true if the path matches any of the trace exclusion regexps
+func (this *server) traceExcluded(requestPath string) bool {
+	return this.regexes().traceExclude.matches(requestPath)
+}
+
+// traceSampler creates the configured sampler, nil if the SDK default shall be used
+func traceSampler(name string, ratio float64) (tracesdk.Sampler, error) {
+	if ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("trace sampler ratio %v out of range [0, 1]", ratio)
+	}
+
+	switch strings.ToLower(name) {
+	case "":
+		return nil, nil
+	case "always_on":
+		return tracesdk.AlwaysSample(), nil
+	case "always_off":
+		return tracesdk.NeverSample(), nil
+	case "traceidratio":
+		return tracesdk.TraceIDRatioBased(ratio), nil
+	case "parentbased_always_on":
+		return tracesdk.ParentBased(tracesdk.AlwaysSample()), nil
+	case "parentbased_always_off":
+		return tracesdk.ParentBased(tracesdk.NeverSample()), nil
+	case "parentbased_traceidratio":
+		return tracesdk.ParentBased(tracesdk.TraceIDRatioBased(ratio)), nil
+	default:
+		return nil, fmt.Errorf("unknown trace sampler %v", name)
+	}
+}
+
+// metricViews creates the metric views from the configuration
+func metricViews(configured []MetricView) ([]metricsdk.View, error) {
+	views := []metricsdk.View{}
+	for _, view := range configured {
+		if view.Instrument == "" {
+			return nil, fmt.Errorf("metric view requires instrument name")
+		}
+		if view.Name != "" && strings.ContainsAny(view.Instrument, "*?") {
+			return nil, fmt.Errorf("metric view %v cannot rename instruments matched by wildcard", view.Instrument)
+		}
+
+		stream := metricsdk.Stream{
+			Name:        view.Name,
+			Description: view.Description,
+		}
+
+		if len(view.AllowedAttributes) > 0 {
+			stream.AttributeFilter = attribute.NewAllowKeysFilter(attributeKeys(view.AllowedAttributes)...)
+		}
+		if len(view.DropAttributes) > 0 {
+			deny := attribute.NewDenyKeysFilter(attributeKeys(view.DropAttributes)...)
+			if allow := stream.AttributeFilter; allow != nil {
+				stream.AttributeFilter = func(kv attribute.KeyValue) bool { return allow(kv) && deny(kv) }
+			} else {
+				stream.AttributeFilter = deny
+			}
+		}
+
+		if view.Drop {
+			stream.Aggregation = metricsdk.AggregationDrop{}
+		} else if len(view.HistogramBuckets) > 0 {
+			stream.Aggregation = metricsdk.AggregationExplicitBucketHistogram{
+				Boundaries: view.HistogramBuckets,
+			}
+		}
+
+		views = append(views, metricsdk.NewView(metricsdk.Instrument{Name: view.Instrument}, stream))
+	}
+	return views, nil
+}
+
+func attributeKeys(names []string) []attribute.Key {
+	keys := make([]attribute.Key, 0, len(names))
+	for _, name := range names {
+		keys = append(keys, attribute.Key(name))
+	}
+	return keys
+}
+
+var telemetry = sync.OnceValue[instruments](func() instruments {
+	var err error
+	instruments := instruments{}
+
+	instruments.tracer = otel.Tracer("spa_d")
+	instruments.meters = otel.Meter("spa_d")
+	instruments.resources_served, err = instruments.meters.Int64Counter(
+		"resources_served",
+		metric.WithDescription("Count of resources served with success"),
+		metric.WithUnit("{resources}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.fallbacks, err = instruments.meters.Int64Counter(
+		"fallbacks",
+		metric.WithDescription("Count of served resources as fallback to index.html"),
+		metric.WithUnit("{resources}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.brotli_encrypted, err = instruments.meters.Int64Counter(
+		"brotli",
+		metric.WithDescription("Count of served resourcesencoded with brotli encoding"),
+		metric.WithUnit("{resources}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.gzip_encrypted, err = instruments.meters.Int64Counter(
+		"gzip",
+		metric.WithDescription("Count of served resources encoded with gzip encoding"),
+		metric.WithUnit("{resources}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.zstd_encrypted, err = instruments.meters.Int64Counter(
+		"zstd",
+		metric.WithDescription("Count of served resources encoded with zstd encoding"),
+		metric.WithUnit("{resources}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.not_found, err = instruments.meters.Int64Counter(
+		"not_found",
+		metric.WithDescription("Count of requests with not found resources"),
+		metric.WithUnit("{resources}"),
+	)
+
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.responses, err = instruments.meters.Int64Counter(
+		"responses",
+		metric.WithDescription("Count of responses by status code, status class and route outcome"),
+		metric.WithUnit("{responses}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.request_duration, err = instruments.meters.Int64Histogram(
+		"request_duration",
+		metric.WithDescription("Duration of the requests by status class and content encoding"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.response_bytes, err = instruments.meters.Int64Counter(
+		"response_bytes",
+		metric.WithDescription("Count of bytes of the response bodies by status class and content encoding"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.original_bytes, err = instruments.meters.Int64Counter(
+		"original_bytes",
+		metric.WithDescription("Size of the served resources before content encoding"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.transferred_bytes, err = instruments.meters.Int64Counter(
+		"transferred_bytes",
+		metric.WithDescription("Count of bytes of the served resources written to responses"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.precompressed_missing, err = instruments.meters.Int64Counter(
+		"precompressed_missing",
+		metric.WithDescription("Count of resources served unencoded because no precompressed variant exists for the accepted encodings"),
+		metric.WithUnit("{resources}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.dynamically_compressed, err = instruments.meters.Int64Counter(
+		"dynamically_compressed",
+		metric.WithDescription("Count of resources without the precompressed variant compressed on the fly"),
+		metric.WithUnit("{resources}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.precompressed_lookups, err = instruments.meters.Int64Counter(
+		"precompressed_lookups",
+		metric.WithDescription("Count of lookups of the precompressed variants for the accepted encodings by result"),
+		metric.WithUnit("{lookups}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.cache_lookups, err = instruments.meters.Int64Counter(
+		"cache_lookups",
+		metric.WithDescription("Count of lookups of the in-memory caches by cache and result"),
+		metric.WithUnit("{lookups}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.root_sync_failures, err = instruments.meters.Int64Counter(
+		"root_sync_failures",
+		metric.WithDescription("Count of failed synchronizations of the remote roots"),
+		metric.WithUnit("{failures}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.root_sync_age, err = instruments.meters.Float64ObservableGauge(
+		"root_sync_age",
+		metric.WithDescription("Time since the last successful synchronization of the remote roots"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.root_revision, err = instruments.meters.Int64ObservableGauge(
+		"root_revision",
+		metric.WithDescription("Revision of the served content of the remote roots, 1 for the active revision"),
+		metric.WithUnit("{revision}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.integrity_failures, err = instruments.meters.Int64Counter(
+		"integrity_failures",
+		metric.WithDescription("Count of roots failing the verification against the checksums manifest"),
+		metric.WithUnit("{failures}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.root_switches, err = instruments.meters.Int64Counter(
+		"root_switches",
+		metric.WithDescription("Count of switches to the scheduled roots"),
+		metric.WithUnit("{switches}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.variant_requests, err = instruments.meters.Int64Counter(
+		"variant_requests",
+		metric.WithDescription("Count of requests served by the variant of the rollout"),
+		metric.WithUnit("{requests}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.throttled_requests, err = instruments.meters.Int64Counter(
+		"throttled_requests",
+		metric.WithDescription("Count of requests refused beyond the rate limit of the client"),
+		metric.WithUnit("{requests}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.blocked_requests, err = instruments.meters.Int64Counter(
+		"blocked_requests",
+		metric.WithDescription("Count of requests refused as path traversal attempts, or for hidden or disallowed files"),
+		metric.WithUnit("{requests}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.memory_pressure, err = instruments.meters.Int64ObservableGauge(
+		"memory_pressure",
+		metric.WithDescription("Memory pressure level degrading the caches, 0 normal, 1 elevated, 2 critical"),
+		metric.WithUnit("{level}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.requests_inflight, err = instruments.meters.Int64ObservableGauge(
+		"requests_inflight",
+		metric.WithDescription("Number of requests served concurrently"),
+		metric.WithUnit("{requests}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	instruments.requests_queued, err = instruments.meters.Int64ObservableGauge(
+		"requests_queued",
+		metric.WithDescription("Number of requests waiting for a slot of the in-flight limit"),
+		metric.WithUnit("{requests}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	return instruments
+
+})
+
+// metricPathAttributes returns the path attributes attached to metrics
+// according to the configured path label mode
+func (this *server) metricPathAttributes(requestPath string) []attribute.KeyValue {
+	requestPath = normalizePath(requestPath)
+	switch this.cfg.MetricsPathLabel {
+	case "none":
+		return nil
+	case "prefix":
+		return []attribute.KeyValue{attribute.String("path", pathPrefix(requestPath, this.cfg.MetricsPathPrefixDepth))}
+	case "template":
+		return []attribute.KeyValue{attribute.String("path", templatePath(requestPath, this.regexes().pathTemplates))}
+	default:
+		return []attribute.KeyValue{attribute.String("path", requestPath)}
+	}
+}
+
+// pathPrefix keeps only the first depth segments of the path
+func pathPrefix(requestPath string, depth int) string {
+	segments := strings.Split(strings.TrimPrefix(requestPath, "/"), "/")
+	if depth < 0 {
+		depth = 0
+	}
+	if len(segments) > depth {
+		segments = segments[:depth]
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// templatePath applies the configured templates and collapses hashed asset names,
+// e.g. `/assets/index-4f9a7c1b.js` becomes `/assets/index-*.js`
+func templatePath(requestPath string, templates []templateMatcher) string {
+	for _, template := range templates {
+		requestPath = template.regex.ReplaceAllString(requestPath, template.replacement)
+	}
+
+	dir, name := path.Split(requestPath)
+	return dir + hashedNameToken.ReplaceAllStringFunc(name, func(token string) string {
+		if !strings.ContainsAny(token, "0123456789") {
+			return token
+		}
+		return token[:1] + "*"
+	})
+}
+
+// tokens of the file name looking like content hashes inserted by bundlers
+var hashedNameToken = regexp.MustCompile(`[.\-_][A-Za-z0-9]{8,}`)
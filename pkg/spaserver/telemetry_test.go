@@ -0,0 +1,364 @@
+package spaserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	metricsdk "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type TelemetryTestSuite struct {
+	suite.Suite
+}
+
+func TestTelemetryTestSuite(t *testing.T) {
+	suite.Run(t, new(TelemetryTestSuite))
+}
+
+func (suite *TelemetryTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+}
+
+// global tracer provider can be delegated only once
+var spanRecorder = sync.OnceValue(func() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder)))
+	return recorder
+})
+
+func (suite *TelemetryTestSuite) testServer(cfg Config) *server {
+	_, filename, _, _ := runtime.Caller(0)
+	cfg.RootDirs = []string{path.Join(path.Dir(filename), "test/data")}
+	return &server{cfg: cfg, logger: zerolog.New(os.Stdout)}
+}
+
+func (suite *TelemetryTestSuite) Test_Path_label_raw_Then_path_as_is() {
+
+	// given
+	sut := &server{cfg: Config{MetricsPathLabel: "raw"}}
+
+	// when
+	attrs := sut.metricPathAttributes("/assets/index-4f9a7c1b.js")
+
+	// then
+	suite.Equal([]attribute.KeyValue{attribute.String("path", "/assets/index-4f9a7c1b.js")}, attrs)
+}
+
+func (suite *TelemetryTestSuite) Test_Path_label_none_Then_no_attributes() {
+
+	// given
+	sut := &server{cfg: Config{MetricsPathLabel: "none"}}
+
+	// when
+	attrs := sut.metricPathAttributes("/assets/index-4f9a7c1b.js")
+
+	// then
+	suite.Empty(attrs)
+}
+
+func (suite *TelemetryTestSuite) Test_Path_label_prefix_Then_leading_segments_kept() {
+
+	// given
+	sut := &server{cfg: Config{MetricsPathLabel: "prefix", MetricsPathPrefixDepth: 1}}
+
+	// when
+	attrs := sut.metricPathAttributes("/assets/fonts/roboto.woff2")
+
+	// then
+	suite.Equal([]attribute.KeyValue{attribute.String("path", "/assets")}, attrs)
+}
+
+func (suite *TelemetryTestSuite) Test_Path_label_template_Then_hashes_collapsed() {
+
+	// given
+	sut := &server{cfg: Config{
+		MetricsPathLabel: "template",
+		MetricsPathTemplates: []PathTemplate{
+			{Regexp: "^/users/[^/]+", Replacement: "/users/:id"},
+		},
+	}}
+
+	// when
+	hashed := sut.metricPathAttributes("/assets/index-4f9a7c1b.js")
+	dotted := sut.metricPathAttributes("/static/js/main.3f9a8b7c.chunk.js")
+	plain := sut.metricPathAttributes("/manifest.webmanifest")
+	route := sut.metricPathAttributes("/users/jane/profile")
+
+	// then
+	suite.Equal("/assets/index-*.js", hashed[0].Value.AsString())
+	suite.Equal("/static/js/main.*.chunk.js", dotted[0].Value.AsString())
+	suite.Equal("/manifest.webmanifest", plain[0].Value.AsString())
+	suite.Equal("/users/:id/profile", route[0].Value.AsString())
+}
+
+func (suite *TelemetryTestSuite) Test_Metric_view_Then_renamed_and_attributes_dropped() {
+
+	// given
+	views, err := metricViews([]MetricView{
+		{Instrument: "responses", Name: "spa_responses", DropAttributes: []string{"status_code"}},
+	})
+	suite.Nil(err)
+
+	reader := metricsdk.NewManualReader()
+	provider := metricsdk.NewMeterProvider(metricsdk.WithReader(reader), metricsdk.WithView(views...))
+	counter, err := provider.Meter("spa_d").Int64Counter("responses")
+	suite.Nil(err)
+
+	// when
+	counter.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.Int("status_code", 200),
+		attribute.String("status_class", "2xx"),
+	))
+	data := metricdata.ResourceMetrics{}
+	suite.Nil(reader.Collect(context.Background(), &data))
+
+	// then
+	m := data.ScopeMetrics[0].Metrics[0]
+	suite.Equal("spa_responses", m.Name)
+	point := m.Data.(metricdata.Sum[int64]).DataPoints[0]
+	suite.Equal(1, point.Attributes.Len())
+	suite.True(point.Attributes.HasValue("status_class"))
+}
+
+func (suite *TelemetryTestSuite) Test_Metric_view_rename_with_wildcard_Then_error() {
+
+	// when
+	_, err := metricViews([]MetricView{{Instrument: "resp*", Name: "spa_responses"}})
+
+	// then
+	suite.NotNil(err)
+}
+
+func (suite *TelemetryTestSuite) Test_Trace_sampler_ratio_Then_parent_based_ratio_sampler() {
+
+	// when
+	sampler, err := traceSampler("parentbased_traceidratio", 0.25)
+
+	// then
+	suite.Nil(err)
+	suite.Contains(sampler.Description(), "TraceIDRatioBased{0.25}")
+}
+
+func (suite *TelemetryTestSuite) Test_Trace_sampler_unknown_Then_error() {
+
+	// when
+	_, err := traceSampler("sometimes", 1)
+
+	// then
+	suite.NotNil(err)
+}
+
+func (suite *TelemetryTestSuite) Test_Path_excluded_from_tracing_Then_no_spans() {
+
+	// given
+	recorder := spanRecorder()
+	sut := suite.testServer(Config{TraceExcludeRegexs: []string{"^/testfile\\.json$"}})
+	req, err := http.NewRequest("GET", "/testfile.json", nil)
+	suite.Nil(err)
+	before := len(recorder.Ended())
+
+	// when
+	sut.handler(context.Background(), httptest.NewRecorder(), req)
+
+	// then
+	suite.Equal(before, len(recorder.Ended()))
+}
+
+func (suite *TelemetryTestSuite) Test_Path_not_excluded_from_tracing_Then_spans_recorded() {
+
+	// given
+	recorder := spanRecorder()
+	sut := suite.testServer(Config{TraceExcludeRegexs: []string{"^/healthz$"}})
+	req, err := http.NewRequest("GET", "/testfile.json", nil)
+	suite.Nil(err)
+	before := len(recorder.Ended())
+
+	// when
+	sut.handler(context.Background(), httptest.NewRecorder(), req)
+
+	// then
+	suite.Greater(len(recorder.Ended()), before)
+}
+
+// global meter provider can be delegated only once
+var metricReader = sync.OnceValue(func() *metricsdk.ManualReader {
+	reader := metricsdk.NewManualReader()
+	otel.SetMeterProvider(metricsdk.NewMeterProvider(metricsdk.WithReader(reader)))
+	return reader
+})
+
+// counted sums the data points of the counter with the attributes
+func (suite *TelemetryTestSuite) counted(name string, attrs ...attribute.KeyValue) int64 {
+	data := metricdata.ResourceMetrics{}
+	suite.Require().Nil(metricReader().Collect(context.Background(), &data))
+	sum := int64(0)
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name != name {
+				continue
+			}
+			for _, point := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				matches := true
+				for _, attr := range attrs {
+					value, ok := point.Attributes.Value(attr.Key)
+					matches = matches && ok && value == attr.Value
+				}
+				if matches {
+					sum += point.Value
+				}
+			}
+		}
+	}
+	return sum
+}
+
+func (suite *TelemetryTestSuite) Test_Cached_etag_Then_cache_hit_counted() {
+
+	// given
+	metricReader()
+	sut := suite.testServer(Config{Etag: "strong"})
+	hit := []attribute.KeyValue{attribute.String("cache", "etags"), attribute.String("result", "hit")}
+	miss := []attribute.KeyValue{attribute.String("cache", "etags"), attribute.String("result", "miss")}
+	hits, misses := suite.counted("cache_lookups", hit...), suite.counted("cache_lookups", miss...)
+
+	// when
+	for i := 0; i < 2; i++ {
+		sut.handler(context.Background(), httptest.NewRecorder(), httptest.NewRequest("GET", "/testfile.json", nil))
+	}
+
+	// then
+	suite.Equal(hits+1, suite.counted("cache_lookups", hit...))
+	suite.Equal(misses+1, suite.counted("cache_lookups", miss...))
+}
+
+func (suite *TelemetryTestSuite) Test_Precompressed_lookup_Then_result_counted() {
+
+	// given
+	metricReader()
+	sut := suite.testServer(Config{})
+	hit := []attribute.KeyValue{attribute.String("encoding", "br"), attribute.String("result", "hit")}
+	miss := []attribute.KeyValue{attribute.String("encoding", "br"), attribute.String("result", "miss")}
+	hits, misses := suite.counted("precompressed_lookups", hit...), suite.counted("precompressed_lookups", miss...)
+
+	// when
+	for _, target := range []string{"/prebr.js", "/testfile.json"} {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Accept-Encoding", "br")
+		sut.handler(context.Background(), httptest.NewRecorder(), req)
+	}
+
+	// then
+	suite.Equal(hits+1, suite.counted("precompressed_lookups", hit...))
+	suite.Equal(misses+1, suite.counted("precompressed_lookups", miss...))
+}
+
+func (suite *TelemetryTestSuite) Test_Prometheus_metrics_Then_counters_scraped_on_admin_port() {
+
+	// given
+	reader, handler, err := prometheusExporter()
+	suite.Require().Nil(err)
+	counter, err := metricsdk.NewMeterProvider(metricsdk.WithReader(reader)).Meter("spa_d").Int64Counter("spa_d_test_requests")
+	suite.Require().Nil(err)
+	counter.Add(context.Background(), 3)
+	sut := suite.testServer(Config{})
+	sut.metricsHandler = handler
+
+	// when
+	rr := httptest.NewRecorder()
+	sut.adminHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Regexp(`(?m)^spa_d_test_requests_total\{.*\} 3$`, rr.Body.String())
+}
+
+func (suite *TelemetryTestSuite) Test_Prometheus_metrics_disabled_Then_not_found() {
+
+	// given
+	sut := suite.testServer(Config{})
+
+	// when
+	rr := httptest.NewRecorder()
+	sut.adminHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+}
+
+// recorded sums the counts of the data points of the histogram with the attributes
+func (suite *TelemetryTestSuite) recorded(name string, attrs ...attribute.KeyValue) uint64 {
+	data := metricdata.ResourceMetrics{}
+	suite.Require().Nil(metricReader().Collect(context.Background(), &data))
+	count := uint64(0)
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name != name {
+				continue
+			}
+			for _, point := range m.Data.(metricdata.Histogram[int64]).DataPoints {
+				matches := true
+				for _, attr := range attrs {
+					value, ok := point.Attributes.Value(attr.Key)
+					matches = matches && ok && value == attr.Value
+				}
+				if matches {
+					count += point.Count
+				}
+			}
+		}
+	}
+	return count
+}
+
+func (suite *TelemetryTestSuite) Test_Request_served_Then_duration_and_bytes_recorded() {
+
+	// given
+	metricReader()
+	sut := suite.testServer(Config{})
+	brotli := []attribute.KeyValue{attribute.String("status_class", "2xx"), attribute.String("encoding", "br")}
+	identity := []attribute.KeyValue{attribute.String("status_class", "2xx"), attribute.String("encoding", "identity")}
+	durations, bytes := suite.recorded("request_duration", brotli...), suite.counted("response_bytes", identity...)
+
+	// when
+	req := httptest.NewRequest("GET", "/prebr.js", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	sut.handler(context.Background(), httptest.NewRecorder(), req)
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, httptest.NewRequest("GET", "/testfile.json", nil))
+
+	// then
+	suite.Equal(durations+1, suite.recorded("request_duration", brotli...))
+	suite.Equal(bytes+int64(rr.Body.Len()), suite.counted("response_bytes", identity...))
+}
+
+func (suite *TelemetryTestSuite) Test_Request_blocked_Then_reason_counted() {
+
+	// given
+	metricReader()
+	sut := suite.testServer(Config{DotfilesBlocked: true})
+	traversal := attribute.String("reason", blockedTraversal)
+	dotfile := attribute.String("reason", blockedDotfile)
+	traversals, dotfiles := suite.counted("blocked_requests", traversal), suite.counted("blocked_requests", dotfile)
+
+	// when
+	sut.handler(context.Background(), httptest.NewRecorder(), httptest.NewRequest("GET", "/assets/%252e%252e/secret", nil))
+	sut.handler(context.Background(), httptest.NewRecorder(), httptest.NewRequest("GET", "/.env", nil))
+
+	// then
+	suite.Equal(traversals+1, suite.counted("blocked_requests", traversal))
+	suite.Equal(dotfiles+1, suite.counted("blocked_requests", dotfile))
+}
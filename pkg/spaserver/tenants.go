@@ -0,0 +1,90 @@
+package spaserver
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// tenantPlaceholder is replaced with the tenant in the tenant root template
+	tenantPlaceholder = "{tenant}"
+	// tenantSourceHost takes the tenant from the first label of the host, e.g. `acme.example.com`
+	tenantSourceHost = "host"
+	// tenantSourcePath takes the tenant from the first path segment, e.g. `/acme/main.js`
+	tenantSourcePath = "path"
+)
+
+type tenantKey struct{}
+
+// tenantsEnabled reports whether the roots are resolved per tenant
+func (this *server) tenantsEnabled() bool {
+	return this.cfg.TenantRoot != ""
+}
+
+// splitTenant extracts the tenant from the host or the path, the resource path is returned
+// without the tenant segment, ok is false if the tenant is missing or invalid
+func (this *server) splitTenant(host string, resourcePath string) (tenant string, tenantPath string, ok bool) {
+	if this.cfg.TenantSource == tenantSourcePath {
+		tenant, tenantPath, _ = strings.Cut(strings.TrimPrefix(resourcePath, "/"), "/")
+	} else {
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+		tenant, _, _ = strings.Cut(host, ".")
+		tenantPath = resourcePath
+	}
+	tenant = strings.ToLower(tenant)
+	// the tenant becomes a part of the root path
+	if tenant == "" || tenant == "." || tenant == ".." || strings.ContainsAny(tenant, `/\`) {
+		return "", "", false
+	}
+	if regex := this.regexes().tenant; regex == nil || !regex.MatchString(tenant) {
+		return "", "", false
+	}
+	return tenant, tenantPath, true
+}
+
+// tenantRoots opens the root of the tenant, the roots are nil if the tenant does not exist
+func (this *server) tenantRoots(tenant string) ([]assetRoot, error) {
+	if roots, ok := this.tenants.Load(tenant); ok {
+		return roots.([]assetRoot), nil
+	}
+
+	dir := strings.ReplaceAll(this.cfg.TenantRoot, tenantPlaceholder, tenant)
+	info, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.IsDir()) {
+		// unknown tenants are not cached
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	roots, err := this.openRoots([]string{dir})
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := this.tenants.LoadOrStore(tenant, roots)
+	return actual.([]assetRoot), nil
+}
+
+// tenantsDir is the directory containing the roots of all the tenants
+func (this *server) tenantsDir() string {
+	prefix, _, _ := strings.Cut(this.cfg.TenantRoot, tenantPlaceholder)
+	return filepath.Dir(prefix + "x")
+}
+
+// withTenant stores the tenant of the request in the context
+func withTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// requestTenant returns the tenant of the request, empty if the tenants are disabled
+func requestTenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
@@ -0,0 +1,82 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type TenantsTestSuite struct {
+	suite.Suite
+	tenantsDir string
+}
+
+func TestTenantsTestSuite(t *testing.T) {
+	suite.Run(t, new(TenantsTestSuite))
+}
+
+func (suite *TenantsTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.tenantsDir = suite.T().TempDir()
+	for _, tenant := range []string{"acme", "globex"} {
+		dir := path.Join(suite.tenantsDir, tenant, "public")
+		suite.Require().Nil(os.MkdirAll(dir, 0755))
+		suite.Require().Nil(os.WriteFile(path.Join(dir, "index.html"), []byte(tenant), 0644))
+	}
+}
+
+func (suite *TenantsTestSuite) serve(source string, host string, target string) *httptest.ResponseRecorder {
+	sut, err := newServer(Config{
+		RootDirs:     []string{path.Join(suite.tenantsDir, "missing")},
+		BaseURL:      "/",
+		TenantRoot:   path.Join(suite.tenantsDir, "{tenant}", "public"),
+		TenantSource: source,
+		TenantRegex:  "^[a-z0-9][a-z0-9-]*$",
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	req := httptest.NewRequest("GET", target, nil)
+	req.Host = host
+	rr := httptest.NewRecorder()
+	sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *TenantsTestSuite) Test_Tenant_from_host_Then_tenant_root_served() {
+
+	// when
+	acme := suite.serve(tenantSourceHost, "acme.example.com:8080", "/some/route")
+	globex := suite.serve(tenantSourceHost, "globex.example.com", "/")
+
+	// then
+	suite.Equal(http.StatusOK, acme.Code)
+	suite.Equal("acme", acme.Body.String())
+	suite.Equal("globex", globex.Body.String())
+}
+
+func (suite *TenantsTestSuite) Test_Tenant_from_path_Then_segment_stripped() {
+
+	// when
+	rr := suite.serve(tenantSourcePath, "example.com", "/globex/index.html")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("globex", rr.Body.String())
+}
+
+func (suite *TenantsTestSuite) Test_Unknown_tenant_Then_not_found() {
+
+	// when
+	unknown := suite.serve(tenantSourceHost, "initech.example.com", "/")
+	traversal := suite.serve(tenantSourcePath, "example.com", "/../acme/index.html")
+
+	// then
+	suite.Equal(http.StatusNotFound, unknown.Code)
+	suite.Equal(http.StatusNotFound, traversal.Code)
+}
@@ -0,0 +1,164 @@
+package spaserver
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var tlsCurves = map[string]tls.CurveID{
+	"x25519": tls.X25519,
+	"p256":   tls.CurveP256,
+	"p384":   tls.CurveP384,
+	"p521":   tls.CurveP521,
+}
+
+// tlsConfig creates the TLS configuration of the server and the store of its
+// certificate, nil if TLS is not configured. The certificates of the ACME
+// manager are obtained on the first handshake of their hosts, no store is
+// created for them.
+func tlsConfig(cfg Config, manager *autocert.Manager) (*tls.Config, *certificateStore, error) {
+	var config *tls.Config
+	var certificates *certificateStore
+	switch {
+	case manager != nil:
+		// answers the TLS-ALPN-01 challenges too
+		config = manager.TLSConfig()
+	case cfg.TlsCertFile != "" || cfg.TlsKeyFile != "":
+		certificates = &certificateStore{certFile: cfg.TlsCertFile, keyFile: cfg.TlsKeyFile}
+		if _, err := certificates.reload(); err != nil {
+			return nil, nil, err
+		}
+		config = &tls.Config{GetCertificate: certificates.getCertificate}
+	default:
+		return nil, nil, nil
+	}
+
+	if cfg.TlsMinVersion != "" {
+		version, ok := tlsVersions[cfg.TlsMinVersion]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown TLS version %v", cfg.TlsMinVersion)
+		}
+		config.MinVersion = version
+	}
+	if cfg.TlsMaxVersion != "" {
+		version, ok := tlsVersions[cfg.TlsMaxVersion]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown TLS version %v", cfg.TlsMaxVersion)
+		}
+		config.MaxVersion = version
+	}
+	if config.MaxVersion != 0 && config.MaxVersion < config.MinVersion {
+		return nil, nil, fmt.Errorf("TLS max version %v is lower than min version %v", cfg.TlsMaxVersion, cfg.TlsMinVersion)
+	}
+
+	for _, name := range cfg.TlsCipherSuites {
+		id, ok := cipherSuite(name)
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown or insecure TLS cipher suite %v", name)
+		}
+		config.CipherSuites = append(config.CipherSuites, id)
+	}
+
+	for _, name := range cfg.TlsCurvePreferences {
+		curve, ok := tlsCurves[strings.ToLower(strings.ReplaceAll(name, "-", ""))]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown TLS curve %v", name)
+		}
+		config.CurvePreferences = append(config.CurvePreferences, curve)
+	}
+	return config, certificates, nil
+}
+
+// cipherSuite finds the secure cipher suite by its IANA name, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+func cipherSuite(name string) (uint16, bool) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name == name {
+			return suite.ID, true
+		}
+	}
+	return 0, false
+}
+
+// certificateStore holds the certificate of the server loaded from the files, so
+// that the renewed certificate, e.g. by cert-manager, is served without a restart
+type certificateStore struct {
+	certFile    string
+	keyFile     string
+	certificate atomic.Pointer[tls.Certificate]
+	mutex       sync.Mutex
+	// modified are the modification times of the files of the loaded certificate
+	modified [2]time.Time
+}
+
+func (this *certificateStore) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return this.certificate.Load(), nil
+}
+
+// reload loads the certificate again when any of its files changed since loaded,
+// reports whether the certificate was reloaded. The certificate not matching the
+// key, e.g. while the files are being replaced, keeps the previous one served.
+func (this *certificateStore) reload() (bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	modified := [2]time.Time{}
+	for i, name := range []string{this.certFile, this.keyFile} {
+		// the symlinks are followed, e.g. of the mounted Kubernetes secrets
+		info, err := os.Stat(name)
+		if err != nil {
+			return false, fmt.Errorf("cannot load TLS certificate: %w", err)
+		}
+		modified[i] = info.ModTime()
+	}
+	if this.certificate.Load() != nil && modified == this.modified {
+		return false, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(this.certFile, this.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("cannot load TLS certificate: %w", err)
+	}
+	this.certificate.Store(&certificate)
+	this.modified = modified
+	return true, nil
+}
+
+// check reloads the changed certificate and logs the result
+func (this *certificateStore) check(logger zerolog.Logger) {
+	reloaded, err := this.reload()
+	if err != nil {
+		logger.Warn().Err(err).Msg("Cannot reload TLS certificate, the previous one is served")
+	} else if reloaded {
+		logger.Info().Str("cert_file", this.certFile).Msg("TLS certificate reloaded")
+	}
+}
+
+// watch checks the files of the certificate periodically
+func (this *certificateStore) watch(ctx context.Context, interval time.Duration, logger zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		this.check(logger)
+	}
+}
@@ -0,0 +1,183 @@
+package spaserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TlsTestSuite struct {
+	suite.Suite
+	cfg Config
+}
+
+func TestTlsTestSuite(t *testing.T) {
+	suite.Run(t, new(TlsTestSuite))
+}
+
+func (suite *TlsTestSuite) SetupTest() {
+	dir := suite.T().TempDir()
+	suite.cfg = Config{
+		TlsCertFile:   filepath.Join(dir, "tls.crt"),
+		TlsKeyFile:    filepath.Join(dir, "tls.key"),
+		TlsMinVersion: "1.2",
+	}
+	suite.writeCertificate("localhost", time.Now().Add(-time.Minute))
+}
+
+// writeCertificate writes the self-signed certificate and its key modified at the time
+func (suite *TlsTestSuite) writeCertificate(commonName string, modified time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().Nil(err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	suite.Require().Nil(err)
+	der, err := x509.MarshalECPrivateKey(key)
+	suite.Require().Nil(err)
+
+	suite.Require().Nil(os.WriteFile(suite.cfg.TlsCertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), 0600))
+	suite.Require().Nil(os.WriteFile(suite.cfg.TlsKeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+	suite.Require().Nil(os.Chtimes(suite.cfg.TlsCertFile, modified, modified))
+	suite.Require().Nil(os.Chtimes(suite.cfg.TlsKeyFile, modified, modified))
+}
+
+// served returns the common name of the certificate served by the configuration
+func (suite *TlsTestSuite) served(config *tls.Config) string {
+	certificate, err := config.GetCertificate(&tls.ClientHelloInfo{})
+	suite.Require().Nil(err)
+	parsed, err := x509.ParseCertificate(certificate.Certificate[0])
+	suite.Require().Nil(err)
+	return parsed.Subject.CommonName
+}
+
+func (suite *TlsTestSuite) Test_No_certificate_Then_tls_disabled() {
+
+	// when
+	config, _, err := tlsConfig(Config{TlsMinVersion: "1.2"}, nil)
+
+	// then
+	suite.Nil(err)
+	suite.Nil(config)
+}
+
+func (suite *TlsTestSuite) Test_Hardening_configured_Then_applied() {
+
+	// given
+	suite.cfg.TlsMaxVersion = "1.3"
+	suite.cfg.TlsCipherSuites = []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}
+	suite.cfg.TlsCurvePreferences = []string{"X25519", "P-256"}
+
+	// when
+	config, _, err := tlsConfig(suite.cfg, nil)
+
+	// then
+	suite.Require().Nil(err)
+	suite.NotNil(config.GetCertificate)
+	suite.Equal(uint16(tls.VersionTLS12), config.MinVersion)
+	suite.Equal(uint16(tls.VersionTLS13), config.MaxVersion)
+	suite.Equal([]uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, config.CipherSuites)
+	suite.Equal([]tls.CurveID{tls.X25519, tls.CurveP256}, config.CurvePreferences)
+}
+
+func (suite *TlsTestSuite) Test_Insecure_cipher_suite_Then_error() {
+
+	// given
+	suite.cfg.TlsCipherSuites = []string{"TLS_RSA_WITH_RC4_128_SHA"}
+
+	// when
+	_, _, err := tlsConfig(suite.cfg, nil)
+
+	// then
+	suite.NotNil(err)
+}
+
+func (suite *TlsTestSuite) Test_Max_version_below_min_Then_error() {
+
+	// given
+	suite.cfg.TlsMinVersion = "1.3"
+	suite.cfg.TlsMaxVersion = "1.2"
+
+	// when
+	_, _, err := tlsConfig(suite.cfg, nil)
+
+	// then
+	suite.NotNil(err)
+}
+
+func (suite *TlsTestSuite) Test_Unknown_version_Then_error() {
+
+	// given
+	suite.cfg.TlsMinVersion = "1.4"
+
+	// when
+	_, _, err := tlsConfig(suite.cfg, nil)
+
+	// then
+	suite.NotNil(err)
+}
+
+func (suite *TlsTestSuite) Test_Certificate_renewed_Then_reloaded() {
+
+	// given
+	config, certificates, err := tlsConfig(suite.cfg, nil)
+	suite.Require().Nil(err)
+	suite.writeCertificate("renewed", time.Now())
+
+	// when
+	reloaded, err := certificates.reload()
+
+	// then
+	suite.Nil(err)
+	suite.True(reloaded)
+	suite.Equal("renewed", suite.served(config))
+}
+
+func (suite *TlsTestSuite) Test_Certificate_unchanged_Then_not_reloaded() {
+
+	// given
+	config, certificates, err := tlsConfig(suite.cfg, nil)
+	suite.Require().Nil(err)
+
+	// when
+	reloaded, err := certificates.reload()
+
+	// then
+	suite.Nil(err)
+	suite.False(reloaded)
+	suite.Equal("localhost", suite.served(config))
+}
+
+func (suite *TlsTestSuite) Test_Key_not_matching_Then_previous_served() {
+
+	// given
+	config, certificates, err := tlsConfig(suite.cfg, nil)
+	suite.Require().Nil(err)
+	key, err := os.ReadFile(suite.cfg.TlsKeyFile)
+	suite.Require().Nil(err)
+	suite.writeCertificate("renewed", time.Now())
+	suite.Require().Nil(os.WriteFile(suite.cfg.TlsKeyFile, key, 0600))
+
+	// when
+	reloaded, err := certificates.reload()
+
+	// then
+	suite.NotNil(err)
+	suite.False(reloaded)
+	suite.Equal("localhost", suite.served(config))
+}
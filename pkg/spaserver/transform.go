@@ -0,0 +1,76 @@
+package spaserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// transformsContent returns true if the content of the resource is modified when served
+func (this *server) transformsContent(ctx context.Context, resourcePath string) bool {
+	return this.rewritesUrls(ctx, resourcePath) || this.rewritesBaseHref(ctx, resourcePath) ||
+		this.injectsIntegrity(resourcePath) || this.personalizesContent(ctx, resourcePath)
+}
+
+// personalizesContent returns true if the content differs for each response,
+// e.g. with the nonce or the claims of the request
+func (this *server) personalizesContent(ctx context.Context, resourcePath string) bool {
+	return this.injectsNonce(ctx, resourcePath) || injectsClaims(ctx, resourcePath)
+}
+
+// transformContent applies the content transformations to the file, the
+// transformed content is cached until the file changes
+func (this *server) transformContent(ctx context.Context, name string, file asset) (asset, error) {
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%v|%v|%v|%v", rootSetKey(ctx), name, info.ModTime().UnixNano(), info.Size())
+	var content []byte
+	if cached, ok := this.transforms.Load(key); ok {
+		recordCacheLookup(ctx, "transforms", cacheHit)
+		content = cached.([]byte)
+	} else {
+		recordCacheLookup(ctx, "transforms", cacheMiss)
+		if content, err = io.ReadAll(file); err != nil {
+			return nil, err
+		}
+		if this.rewritesUrls(ctx, name) {
+			content = this.rewriteUrls(ctx, name, content)
+		}
+		if this.rewritesBaseHref(ctx, name) {
+			content = this.rewriteBaseHref(ctx, content)
+		}
+		if this.injectsIntegrity(name) {
+			content = this.injectIntegrity(ctx, name, content)
+		}
+		this.cache(&this.transforms, key, content, memoryElevated)
+	}
+
+	if this.injectsNonce(ctx, name) {
+		// the nonce of the response is not cached
+		content = injectNonce(content, cspNonce(ctx))
+	}
+	if injectsClaims(ctx, name) {
+		content = injectClaims(content, documentClaims(ctx))
+	}
+	return transformedAsset(content, info), nil
+}
+
+func transformedAsset(content []byte, info fs.FileInfo) asset {
+	return &tarFile{
+		SectionReader: io.NewSectionReader(bytes.NewReader(content), 0, int64(len(content))),
+		info:          &sizedInfo{FileInfo: info, size: int64(len(content))},
+	}
+}
+
+// sizedInfo overrides the size of the file info
+type sizedInfo struct {
+	fs.FileInfo
+	size int64
+}
+
+func (this *sizedInfo) Size() int64 { return this.size }
@@ -0,0 +1,165 @@
+package spaserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// upstreamResponse is the cached response of the prerender service or of the proxied upstream
+type upstreamResponse struct {
+	status  int
+	header  http.Header
+	body    []byte
+	fetched time.Time
+}
+
+// upstreamCacheKeyType carries the cache key of the proxied request to the response modifier
+type upstreamCacheKeyType struct{}
+
+// upstreamCacheable reports whether the response to the request may be served from the cache
+func (this *server) upstreamCacheable(req *http.Request) bool {
+	return this.cfg.UpstreamCacheTtl > 0 && (req.Method == http.MethodGet || req.Method == http.MethodHead)
+}
+
+// proxiedCacheKey is the key of the response of the upstream, the hosts of the sites
+// and the encodings of the response are cached separately. The responses to the
+// requests carrying the credentials are served only to the same credentials.
+func proxiedCacheKey(upstream string, req *http.Request) string {
+	key := "proxy|" + upstream + "|" + req.Host + "|" + req.URL.RequestURI() + "|" + req.Header.Get("Accept-Encoding")
+	if credentialed(req) {
+		credentials := sha256.Sum256([]byte(req.Header.Get("Authorization") + "\n" + req.Header.Get("Cookie")))
+		key += "|" + hex.EncodeToString(credentials[:])
+	}
+	return key
+}
+
+// credentialed reports whether the request carries the Authorization or Cookie header
+func credentialed(req *http.Request) bool {
+	return req.Header.Get("Authorization") != "" || req.Header.Get("Cookie") != ""
+}
+
+// sharedUpstreamResponse reports whether the successful response of the upstream may
+// be shared among the clients. The responses varying on other headers than the
+// encoding, which is a part of the cache key, are not cached, and the responses to
+// the requests carrying the credentials only if marked public.
+func sharedUpstreamResponse(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK || resp.Request.Method != http.MethodGet || resp.Header.Get("Set-Cookie") != "" {
+		return false
+	}
+	for _, value := range resp.Header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" && !strings.EqualFold(name, "Accept-Encoding") {
+				return false
+			}
+		}
+	}
+	cacheControl := strings.ToLower(resp.Header.Get("Cache-Control"))
+	for _, directive := range []string{"no-store", "no-cache", "private"} {
+		if strings.Contains(cacheControl, directive) {
+			return false
+		}
+	}
+	return !credentialed(resp.Request) || slices.ContainsFunc(strings.Split(cacheControl, ","), func(directive string) bool {
+		return strings.TrimSpace(directive) == "public"
+	})
+}
+
+// bufferUpstreamBody reads the body of the response of the known size up to the max
+// size, the body is replaced with the buffered content. The streamed responses, e.g.
+// server-sent events, and the larger bodies are not buffered.
+func bufferUpstreamBody(resp *http.Response, maxSize int64) ([]byte, bool, error) {
+	if resp.ContentLength < 0 || resp.ContentLength > maxSize {
+		return nil, false, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil || int64(len(body)) > maxSize {
+		return nil, false, err
+	}
+	return body, true, nil
+}
+
+// cachedUpstreamResponse returns the cached response, fresh within the ttl and stale
+// until the stale-if-error period passes
+func (this *server) cachedUpstreamResponse(key string, now time.Time) (*upstreamResponse, bool, bool) {
+	cached, ok := this.upstreamResponses.Load(key)
+	if !ok {
+		return nil, false, false
+	}
+	response := cached.(*upstreamResponse)
+	age := now.Sub(response.fetched)
+	if age > this.cfg.UpstreamCacheTtl+this.cfg.UpstreamCacheStaleIfError {
+		if this.upstreamResponses.CompareAndDelete(key, cached) {
+			this.upstreamResponsesCount.Add(-1)
+		}
+		return nil, false, false
+	}
+	return response, age <= this.cfg.UpstreamCacheTtl, true
+}
+
+// cacheUpstreamResponse stores the response unless the memory is under pressure, the
+// full cache is cleared like the one of the file infos
+func (this *server) cacheUpstreamResponse(key string, response *upstreamResponse) {
+	if this.memoryLevel.Load() >= memoryElevated {
+		return
+	}
+	if this.upstreamResponsesCount.Load() >= int64(this.cfg.UpstreamCacheMaxEntries) {
+		this.clearUpstreamResponses()
+	}
+	if _, replaced := this.upstreamResponses.Swap(key, response); !replaced {
+		this.upstreamResponsesCount.Add(1)
+	}
+}
+
+// clearUpstreamResponses drops all the cached upstream responses
+func (this *server) clearUpstreamResponses() {
+	clearMap(&this.upstreamResponses)
+	this.upstreamResponsesCount.Store(0)
+}
+
+// serveFreshUpstreamResponse serves the cached response within the ttl
+func (this *server) serveFreshUpstreamResponse(ctx context.Context, w http.ResponseWriter, req *http.Request, key string) bool {
+	now := time.Now()
+	if response, fresh, _ := this.cachedUpstreamResponse(key, now); fresh {
+		recordCacheLookup(ctx, "upstream_responses", cacheHit)
+		response.write(w, req, now)
+		return true
+	}
+	recordCacheLookup(ctx, "upstream_responses", cacheMiss)
+	return false
+}
+
+// serveStaleUpstreamResponse serves the cached response of the failing upstream
+// within the stale-if-error period
+func (this *server) serveStaleUpstreamResponse(ctx context.Context, w http.ResponseWriter, req *http.Request, key string) bool {
+	now := time.Now()
+	response, _, ok := this.cachedUpstreamResponse(key, now)
+	if !ok {
+		return false
+	}
+	recordCacheLookup(ctx, "upstream_responses", cacheStaleHit)
+	this.logger.Info().Str("path", req.URL.Path).Dur("age", now.Sub(response.fetched)).Msg("Stale upstream response served")
+	response.write(w, req, now)
+	return true
+}
+
+// write serves the cached response, the Age header tells the time since it was fetched
+func (this *upstreamResponse) write(w http.ResponseWriter, req *http.Request, now time.Time) {
+	for name, values := range this.header {
+		w.Header()[name] = slices.Clone(values)
+	}
+	w.Header().Set("Age", strconv.Itoa(int(now.Sub(this.fetched).Seconds())))
+	w.WriteHeader(this.status)
+	if req.Method != http.MethodHead {
+		w.Write(this.body)
+	}
+}
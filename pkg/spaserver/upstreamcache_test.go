@@ -0,0 +1,251 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type UpstreamCacheTestSuite struct {
+	suite.Suite
+	sut      *server
+	upstream *httptest.Server
+	calls    int
+	failing  bool
+	// header is set on the responses of the upstream
+	header http.Header
+}
+
+func TestUpstreamCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(UpstreamCacheTestSuite))
+}
+
+func (suite *UpstreamCacheTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	suite.calls = 0
+	suite.failing = false
+	suite.header = http.Header{}
+	suite.upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		suite.calls++
+		if suite.failing {
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		for name, values := range suite.header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<h1>"+req.URL.Path+"</h1>")
+	}))
+	suite.T().Cleanup(suite.upstream.Close)
+	rootDir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(rootDir, "index.html"), []byte("<div id=app></div>"), 0644))
+
+	var err error
+	suite.sut, err = newServer(Config{
+		RootDirs:                  []string{rootDir},
+		BaseURL:                   "/",
+		Proxies:                   []Proxy{{Prefix: "/api/", Upstream: suite.upstream.URL}},
+		PrerenderUrl:              suite.upstream.URL,
+		PrerenderUserAgentRegex:   defaultCrawlerUserAgents,
+		UpstreamCacheTtl:          time.Minute,
+		UpstreamCacheStaleIfError: time.Hour,
+		UpstreamCacheMaxEntries:   10,
+		UpstreamCacheMaxSize:      1024,
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+}
+
+func (suite *UpstreamCacheTestSuite) get(target string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+// expire ages the cached responses by the duration
+func (suite *UpstreamCacheTestSuite) expire(age time.Duration) {
+	suite.sut.upstreamResponses.Range(func(_, cached any) bool {
+		cached.(*upstreamResponse).fetched = time.Now().Add(-age)
+		return true
+	})
+}
+
+func (suite *UpstreamCacheTestSuite) Test_Proxied_within_ttl_Then_served_from_cache() {
+
+	// given
+	suite.get("/api/users", nil)
+
+	// when
+	rr := suite.get("/api/users", nil)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("<h1>/api/users</h1>", rr.Body.String())
+	suite.Equal("0", rr.Header().Get("Age"))
+	suite.Equal(1, suite.calls)
+}
+
+func (suite *UpstreamCacheTestSuite) Test_Proxied_after_ttl_Then_fetched_again() {
+
+	// given
+	suite.get("/api/users", nil)
+	suite.expire(2 * time.Minute)
+
+	// when
+	rr := suite.get("/api/users", nil)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Empty(rr.Header().Get("Age"))
+	suite.Equal(2, suite.calls)
+}
+
+func (suite *UpstreamCacheTestSuite) Test_Upstream_failing_Then_stale_response_served() {
+
+	// given
+	suite.get("/api/users", nil)
+	suite.expire(2 * time.Minute)
+	suite.failing = true
+
+	// when
+	rr := suite.get("/api/users", nil)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("<h1>/api/users</h1>", rr.Body.String())
+	suite.Equal("120", rr.Header().Get("Age"))
+}
+
+func (suite *UpstreamCacheTestSuite) Test_Upstream_down_Then_stale_response_served() {
+
+	// given
+	suite.get("/api/users", nil)
+	suite.expire(2 * time.Minute)
+	suite.upstream.Close()
+
+	// when
+	rr := suite.get("/api/users", nil)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("<h1>/api/users</h1>", rr.Body.String())
+}
+
+func (suite *UpstreamCacheTestSuite) Test_Stale_if_error_passed_Then_upstream_error_served() {
+
+	// given
+	suite.get("/api/users", nil)
+	suite.expire(2 * time.Hour)
+	suite.failing = true
+
+	// when
+	rr := suite.get("/api/users", nil)
+
+	// then
+	suite.Equal(http.StatusServiceUnavailable, rr.Code)
+}
+
+func (suite *UpstreamCacheTestSuite) Test_Credentials_Then_not_cached() {
+
+	// given
+	suite.get("/api/users", map[string]string{"Cookie": "session=alice"})
+
+	// when
+	suite.get("/api/users", map[string]string{"Cookie": "session=alice"})
+
+	// then
+	suite.Equal(2, suite.calls)
+	suite.Equal(int64(0), suite.sut.upstreamResponsesCount.Load())
+}
+
+func (suite *UpstreamCacheTestSuite) Test_Public_response_with_credentials_Then_cached_for_same_credentials() {
+
+	// given
+	suite.header.Set("Cache-Control", "max-age=60, public")
+	suite.get("/api/users", map[string]string{"Cookie": "session=alice"})
+
+	// when
+	alice := suite.get("/api/users", map[string]string{"Cookie": "session=alice"})
+	bob := suite.get("/api/users", map[string]string{"Cookie": "session=bob"})
+	anonymous := suite.get("/api/users", nil)
+
+	// then
+	suite.Equal("0", alice.Header().Get("Age"))
+	suite.Empty(bob.Header().Get("Age"))
+	suite.Empty(anonymous.Header().Get("Age"))
+	suite.Equal(3, suite.calls)
+}
+
+func (suite *UpstreamCacheTestSuite) Test_Vary_on_other_headers_Then_not_cached() {
+	for _, vary := range []string{"User-Agent", "Accept-Encoding, Accept-Language", "*"} {
+
+		// given
+		suite.SetupTest()
+		suite.header.Set("Vary", vary)
+		suite.get("/api/users", nil)
+
+		// when
+		suite.get("/api/users", nil)
+
+		// then
+		suite.Equal(2, suite.calls, vary)
+		suite.Equal(int64(0), suite.sut.upstreamResponsesCount.Load(), vary)
+	}
+}
+
+func (suite *UpstreamCacheTestSuite) Test_Vary_on_encoding_Then_cached() {
+
+	// given
+	suite.header.Set("Vary", "Accept-Encoding")
+	suite.get("/api/users", nil)
+
+	// when
+	rr := suite.get("/api/users", nil)
+
+	// then
+	suite.Equal("0", rr.Header().Get("Age"))
+	suite.Equal(1, suite.calls)
+}
+
+func (suite *UpstreamCacheTestSuite) Test_Prerender_failing_Then_stale_page_served() {
+
+	// given
+	crawler := map[string]string{"User-Agent": "Mozilla/5.0 (compatible; Googlebot/2.1)"}
+	suite.get("/about", crawler)
+	suite.expire(2 * time.Minute)
+	suite.failing = true
+
+	// when
+	rr := suite.get("/about", crawler)
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Contains(rr.Body.String(), "<h1>/http://example.com/about</h1>")
+	suite.Equal(2, suite.calls)
+}
+
+func (suite *UpstreamCacheTestSuite) Test_Memory_pressure_Then_cache_cleared() {
+
+	// given
+	suite.get("/api/users", nil)
+
+	// when
+	suite.sut.setMemoryLevel(memoryElevated, 0, 0)
+
+	// then
+	suite.Equal(int64(0), suite.sut.upstreamResponsesCount.Load())
+	_, _, ok := suite.sut.cachedUpstreamResponse(proxiedCacheKey(suite.upstream.URL, httptest.NewRequest("GET", "/api/users", nil)), time.Now())
+	suite.False(ok)
+}
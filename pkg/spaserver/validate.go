@@ -0,0 +1,353 @@
+package spaserver
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"golang.org/x/net/http/httpguts"
+)
+
+// validateConfig compiles the configured regexps and checks the configured
+// headers, so that a typo fails the startup instead of never matching. All
+// the problems are reported, each with its configuration key.
+func validateConfig(cfg Config) error {
+	var errs []error
+	regex := func(key string, value string) {
+		if _, err := regexp.Compile(value); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", key, err))
+		}
+	}
+	regexs := func(key string, values []string) {
+		for i, value := range values {
+			regex(fmt.Sprintf("%v[%v]", key, i), value)
+		}
+	}
+	headers := func(key string, values map[string]string) {
+		for name, value := range values {
+			if !httpguts.ValidHeaderFieldName(name) {
+				errs = append(errs, fmt.Errorf("%v: invalid header name %q", key, name))
+			} else if !httpguts.ValidHeaderFieldValue(value) {
+				errs = append(errs, fmt.Errorf("%v: invalid value of header %v", key, name))
+			}
+		}
+	}
+
+	headers("headers", cfg.Headers)
+	for rx, values := range cfg.HeadersPerPathRegex {
+		key := fmt.Sprintf("headers-per-regexp[%v]", rx)
+		regex(key, rx)
+		headers(key, values)
+	}
+	etag := func(key string, mode string) {
+		if !slices.Contains(etagModes, mode) {
+			errs = append(errs, fmt.Errorf("%v: unknown ETag strategy %v", key, mode))
+		}
+	}
+	if _, ok := securityHeaderPresets[cfg.SecurityHeaders]; !ok && cfg.SecurityHeaders != "" {
+		errs = append(errs, fmt.Errorf("security-headers: unknown preset %v", cfg.SecurityHeaders))
+	}
+	if cfg.Etag != "" {
+		etag("etag", cfg.Etag)
+	}
+	for rx, mode := range cfg.EtagPerPathRegex {
+		regex(fmt.Sprintf("etag-per-regexp[%v]", rx), rx)
+		etag(fmt.Sprintf("etag-per-regexp[%v]", rx), mode)
+	}
+	regexs("no-fallback-regexp", cfg.NotFoundRegexs)
+	regexs("trace-exclude-regexp", cfg.TraceExcludeRegexs)
+	regexs("log-exclude-regexp", cfg.LogExcludeRegexs)
+	regexs("signed-url-regexp", cfg.SignedUrlRegexs)
+	regex("tenant-regexp", cfg.TenantRegex)
+	regex("hashed-file-regexp", cfg.HashedFileRegex)
+	for i, asset := range cfg.PreloadAssets {
+		if asset == "" || !httpguts.ValidHeaderFieldValue(asset) {
+			errs = append(errs, fmt.Errorf("preload-assets[%v]: invalid asset %q", i, asset))
+		}
+	}
+	if cfg.FileCacheTtl > 0 && cfg.FileCacheMaxEntries < 1 {
+		errs = append(errs, fmt.Errorf("file-cache-max-entries: the cache must hold at least 1 entry"))
+	}
+	if cfg.UpstreamCacheTtl > 0 && cfg.UpstreamCacheMaxEntries < 1 {
+		errs = append(errs, fmt.Errorf("upstream-cache-max-entries: the cache must hold at least 1 entry"))
+	}
+	if cfg.UpstreamCacheStaleIfError < 0 {
+		errs = append(errs, fmt.Errorf("upstream-cache-stale-if-error: the time must not be negative"))
+	}
+	for key, timeout := range map[string]time.Duration{
+		"read-header-timeout": cfg.ReadHeaderTimeout, "read-timeout": cfg.ReadTimeout,
+		"write-timeout": cfg.WriteTimeout, "idle-timeout": cfg.IdleTimeout, "sync-timeout": cfg.SyncTimeout,
+	} {
+		if timeout < 0 {
+			errs = append(errs, fmt.Errorf("%v: the timeout must not be negative", key))
+		}
+	}
+	if cfg.MaxHeaderBytes < 0 {
+		errs = append(errs, fmt.Errorf("max-header-bytes: the size must not be negative"))
+	}
+	if cfg.BandwidthLimit < 0 {
+		errs = append(errs, fmt.Errorf("bandwidth-limit: the limit must not be negative"))
+	}
+	for rx, limit := range cfg.BandwidthLimitPerRegex {
+		regex(fmt.Sprintf("bandwidth-limit-per-regexp[%v]", rx), rx)
+		if limit < 0 {
+			errs = append(errs, fmt.Errorf("bandwidth-limit-per-regexp[%v]: the limit must not be negative", rx))
+		}
+	}
+	if cfg.BandwidthLimitBurst < 0 {
+		errs = append(errs, fmt.Errorf("bandwidth-limit-burst: the burst must not be negative"))
+	}
+	if cfg.OverloadQueueTimeout < 0 {
+		errs = append(errs, fmt.Errorf("overload-queue-timeout: the timeout must not be negative"))
+	}
+	if cfg.ShutdownDrainDelay < 0 {
+		errs = append(errs, fmt.Errorf("shutdown-drain-delay: the delay must not be negative"))
+	}
+	if cfg.RateLimit < 0 {
+		errs = append(errs, fmt.Errorf("rate-limit: the rate must not be negative"))
+	}
+	if cfg.RateLimit > 0 && cfg.RateLimitBurst < 1 {
+		errs = append(errs, fmt.Errorf("rate-limit-burst: the burst must be at least 1"))
+	}
+	if len(cfg.SignatureIdentities) > 0 {
+		if cfg.SignatureOidcIssuer == "" {
+			errs = append(errs, fmt.Errorf("signature-oidc-issuer: the issuer of the signature identities is required"))
+		}
+		if len(cfg.SignatureCertificateRoots) == 0 {
+			errs = append(errs, fmt.Errorf("signature-certificate-roots: the certificates of the CA issuing the identity certificates are required"))
+		}
+		if len(cfg.SignatureTlogPublicKeys) == 0 {
+			errs = append(errs, fmt.Errorf("signature-tlog-public-keys: the public keys of the transparency logs are required"))
+		}
+	}
+	regexs("auth-exclude-regexp", cfg.AuthExcludeRegexs)
+	regexs("directory-listing-regexp", cfg.DirectoryListingRegexs)
+	if cfg.AccessLogFormat != "" && !slices.Contains(accessLogFormats, cfg.AccessLogFormat) {
+		errs = append(errs, fmt.Errorf("access-log-format: unknown format %v", cfg.AccessLogFormat))
+	}
+	for i, sample := range cfg.AccessLogSampling {
+		regex(fmt.Sprintf("access-log-sampling[%v].regexp", i), sample.Regexp)
+		if sample.Ratio < 0 || sample.Ratio > 1 {
+			errs = append(errs, fmt.Errorf("access-log-sampling[%v].ratio: the ratio must be from 0 to 1", i))
+		}
+	}
+	if cfg.TraceResponseHeader != "" && !slices.Contains(traceResponseHeaders, cfg.TraceResponseHeader) {
+		errs = append(errs, fmt.Errorf("trace-response-header: unknown header %v", cfg.TraceResponseHeader))
+	}
+	for key, values := range map[string][]string{"allowed-cidrs": cfg.AllowedCidrs, "denied-cidrs": cfg.DeniedCidrs, "trusted-proxies": cfg.TrustedProxies} {
+		for i, value := range values {
+			if _, err := parseCidr(value); err != nil {
+				errs = append(errs, fmt.Errorf("%v[%v]: %w", key, i, err))
+			}
+		}
+	}
+	switch cfg.AuthMode {
+	case "":
+	case authBasic:
+		if len(cfg.BasicAuthUsers) == 0 && cfg.BasicAuthFile == "" {
+			errs = append(errs, fmt.Errorf("auth-mode: the basic auth requires the basic-auth-users or the basic-auth-file"))
+		}
+		for user, hash := range cfg.BasicAuthUsers {
+			if err := checkPasswordHash(hash); err != nil {
+				errs = append(errs, fmt.Errorf("basic-auth-users.%v: %w", user, err))
+			}
+		}
+	case authOidc:
+		if issuer, err := url.Parse(cfg.OidcIssuer); err != nil || issuer.Scheme == "" || issuer.Host == "" {
+			errs = append(errs, fmt.Errorf("oidc-issuer: %q is not an absolute url", cfg.OidcIssuer))
+		}
+		if cfg.OidcClientId == "" {
+			errs = append(errs, fmt.Errorf("oidc-client-id: the client id is required"))
+		}
+		if redirect, err := url.Parse(cfg.OidcRedirectUrl); err != nil || redirect.Scheme == "" || redirect.Host == "" {
+			errs = append(errs, fmt.Errorf("oidc-redirect-url: %q is not an absolute url", cfg.OidcRedirectUrl))
+		}
+		if cfg.OidcCookie == "" {
+			errs = append(errs, fmt.Errorf("oidc-cookie: the cookie name is required"))
+		}
+		if cfg.OidcSessionTtl <= 0 {
+			errs = append(errs, fmt.Errorf("oidc-session-ttl: the session ttl must be positive"))
+		}
+	case authJwt:
+		if jwks, err := url.Parse(cfg.JwtJwksUrl); err != nil || jwks.Scheme == "" || jwks.Host == "" {
+			errs = append(errs, fmt.Errorf("jwt-jwks-url: %q is not an absolute url", cfg.JwtJwksUrl))
+		}
+		for claim, name := range cfg.JwtClaimHeaders {
+			if !httpguts.ValidHeaderFieldName(name) {
+				errs = append(errs, fmt.Errorf("jwt-claim-headers.%v: invalid header name %q", claim, name))
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("auth-mode: unknown mode %v", cfg.AuthMode))
+	}
+	regex("prerender-user-agent-regexp", cfg.PrerenderUserAgentRegex)
+	for i, template := range cfg.MetricsPathTemplates {
+		regex(fmt.Sprintf("metrics-path-templates[%v].regexp", i), template.Regexp)
+	}
+	for i, variant := range cfg.ClientHintsVariants {
+		regex(fmt.Sprintf("client-hints-variants[%v].regexp", i), variant.Regexp)
+	}
+	for i, route := range cfg.FallbackRoutes {
+		if !strings.HasPrefix(route.Prefix, "/") {
+			errs = append(errs, fmt.Errorf("fallback-routes[%v].prefix: the prefix %q must be an absolute path", i, route.Prefix))
+		}
+		if route.Document == "" {
+			errs = append(errs, fmt.Errorf("fallback-routes[%v].document: the document is required", i))
+		}
+	}
+	for key, extensions := range map[string][]string{"extensions-allowed": cfg.ExtensionsAllowed, "extensions-denied": cfg.ExtensionsDenied} {
+		for i, ext := range extensions {
+			if !strings.HasPrefix(ext, ".") || strings.ContainsAny(ext[1:], "./") || len(ext) < 2 {
+				errs = append(errs, fmt.Errorf("%v[%v]: invalid extension %q, expected a dot and the last extension, e.g. .js", key, i, ext))
+			}
+		}
+	}
+	for language, document := range cfg.FallbackLanguages {
+		if !languageTagRegex.MatchString(language) {
+			errs = append(errs, fmt.Errorf("fallback-languages[%v]: invalid language tag", language))
+		}
+		if document == "" {
+			errs = append(errs, fmt.Errorf("fallback-languages[%v]: the document is required", language))
+		}
+	}
+	for i, proxy := range cfg.Proxies {
+		key := fmt.Sprintf("proxies[%v]", i)
+		if (proxy.Prefix == "") == (proxy.Regexp == "") {
+			errs = append(errs, fmt.Errorf("%v: exactly one of prefix and regexp must be set", key))
+		}
+		regex(key+".regexp", proxy.Regexp)
+		if upstream, err := url.Parse(proxy.Upstream); err != nil || upstream.Scheme == "" || upstream.Host == "" {
+			errs = append(errs, fmt.Errorf("%v.upstream: %q is not an absolute url", key, proxy.Upstream))
+		}
+	}
+	for i, redirect := range cfg.Redirects {
+		key := fmt.Sprintf("redirects[%v]", i)
+		regex(key+".regexp", redirect.Regexp)
+		if redirect.Target == "" {
+			errs = append(errs, fmt.Errorf("%v.target: the target is required", key))
+		}
+		if redirect.Status != 0 && !slices.Contains(redirectStatuses, redirect.Status) {
+			errs = append(errs, fmt.Errorf("%v.status: %v is not a redirect status", key, redirect.Status))
+		}
+	}
+	for status, page := range cfg.ErrorPages {
+		if code, err := strconv.Atoi(status); err != nil || !slices.Contains(errorPageStatuses, code) {
+			errs = append(errs, fmt.Errorf("error-pages.%v: the status must be one of %v", status, errorPageStatuses))
+		}
+		if page == "" {
+			errs = append(errs, fmt.Errorf("error-pages.%v: the page is required", status))
+		}
+	}
+	for i, rewrite := range cfg.Rewrites {
+		key := fmt.Sprintf("rewrites[%v]", i)
+		regex(key+".regexp", rewrite.Regexp)
+		if !strings.HasPrefix(rewrite.Target, "/") {
+			errs = append(errs, fmt.Errorf("%v.target: the target %q must be an absolute path", key, rewrite.Target))
+		}
+	}
+	for i, check := range cfg.ReadyChecks {
+		if !slices.Contains(readyChecks, check) {
+			errs = append(errs, fmt.Errorf("ready-checks[%v]: unknown readiness check %v", i, check))
+		}
+	}
+	for i, route := range cfg.SitemapRoutes {
+		if !strings.HasPrefix(route, "/") {
+			errs = append(errs, fmt.Errorf("sitemap-routes[%v]: the route %q must be an absolute path", i, route))
+		}
+	}
+	if cfg.SitemapOrigin != "" {
+		if origin, err := url.Parse(cfg.SitemapOrigin); err != nil || origin.Scheme == "" || origin.Host == "" {
+			errs = append(errs, fmt.Errorf("sitemap-origin: %q is not an absolute url", cfg.SitemapOrigin))
+		}
+	}
+	if len(cfg.AcmeHosts) > 0 && (cfg.TlsCertFile != "" || cfg.TlsKeyFile != "") {
+		errs = append(errs, errors.New("acme-hosts: cannot be combined with tls-cert-file and tls-key-file"))
+	}
+	if cfg.AcmeDirectoryUrl != "" {
+		if directory, err := url.Parse(cfg.AcmeDirectoryUrl); err != nil || directory.Scheme == "" || directory.Host == "" {
+			errs = append(errs, fmt.Errorf("acme-directory-url: %q is not an absolute url", cfg.AcmeDirectoryUrl))
+		}
+	}
+	if cfg.BaseURL != "" {
+		if base, err := url.Parse(cfg.BaseURL); err != nil || !strings.HasPrefix(cfg.BaseURL, "/") || base.RawQuery != "" || base.Fragment != "" {
+			errs = append(errs, fmt.Errorf("base-url: %q is not an absolute path", cfg.BaseURL))
+		}
+	}
+	if cfg.DynamicGzipLevel < 0 || cfg.DynamicGzipLevel > gzip.BestCompression {
+		errs = append(errs, fmt.Errorf("dynamic-gzip-level: %v is not between 1 and 9", cfg.DynamicGzipLevel))
+	}
+	if cfg.DynamicBrotliLevel < brotli.BestSpeed || cfg.DynamicBrotliLevel > brotli.BestCompression {
+		errs = append(errs, fmt.Errorf("dynamic-brotli-level: %v is not between 0 and 11", cfg.DynamicBrotliLevel))
+	}
+	if _, err := dirFS(".", cfg.SymlinkPolicy); err != nil {
+		errs = append(errs, fmt.Errorf("symlink-policy: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// validateCommand checks the configuration, e.g. `spa_d validate --root dist`,
+// without starting the server, and fails with all the problems found
+func validateCommand(args []string) error {
+	setDefaults(viper.GetViper())
+	if err := parseFlags(viper.GetViper(), args); err != nil {
+		return err
+	}
+	if err := checkConfig(context.Background(), loadConfiguration()); err != nil {
+		return err
+	}
+	fmt.Println("Configuration is valid")
+	return nil
+}
+
+// checkConfig validates the configuration, opens the roots and checks that the
+// local roots exist and that the fallback documents are served, for each site
+func checkConfig(ctx context.Context, cfg Config) error {
+	spa, err := newServer(cfg, zerolog.Nop())
+	if err != nil {
+		return err
+	}
+	errs := checkRoots(ctx, spa)
+	for _, site := range spa.sites {
+		for _, err := range checkRoots(ctx, site.server) {
+			errs = append(errs, fmt.Errorf("sites[%v]: %w", site.pattern, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkRoots(ctx context.Context, spa *server) []error {
+	var errs []error
+	for i, rootDir := range spa.cfg.RootDirs {
+		if strings.HasPrefix(rootDir, bundleScheme) {
+			archive, err := bundleArchive(rootDir)
+			if err == nil {
+				_, err = os.Stat(archive)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("roots[%v]: %w", i, err))
+			}
+			continue
+		}
+		if rootDir == embedScheme || strings.Contains(rootDir, "://") {
+			continue
+		}
+		if _, err := os.Stat(resolveReleasePointer(rootDir)); err != nil {
+			errs = append(errs, fmt.Errorf("roots[%v]: %w", i, err))
+		}
+	}
+	if err := spa.checkFallbackDocument(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("roots: %w", err))
+	}
+	return errs
+}
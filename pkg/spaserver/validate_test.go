@@ -0,0 +1,105 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type ValidateTestSuite struct {
+	suite.Suite
+}
+
+func TestValidateTestSuite(t *testing.T) {
+	suite.Run(t, new(ValidateTestSuite))
+}
+
+func (suite *ValidateTestSuite) Test_Valid_config_Then_no_error() {
+
+	// when
+	err := validateConfig(Config{
+		Headers:             map[string]string{"X-Frame-Options": "DENY"},
+		HeadersPerPathRegex: map[string]map[string]string{`\.json$`: {"Cache-Control": "no-cache"}},
+		NotFoundRegexs:      []string{`\.js$`},
+		TenantRegex:         "^[a-z]+$",
+	})
+
+	// then
+	suite.Nil(err)
+}
+
+func (suite *ValidateTestSuite) Test_Invalid_config_Then_all_problems_reported() {
+
+	// when
+	err := validateConfig(Config{
+		Headers:              map[string]string{"X Frame": "DENY"},
+		HeadersPerPathRegex:  map[string]map[string]string{`\.json$`: {"Cache-Control": "no-cache\r\nX-Injected: 1"}},
+		NotFoundRegexs:       []string{`\.js$`, `(`},
+		MetricsPathTemplates: []PathTemplate{{Regexp: `[`}},
+	})
+
+	// then
+	suite.Require().NotNil(err)
+	suite.ErrorContains(err, `headers: invalid header name "X Frame"`)
+	suite.ErrorContains(err, `headers-per-regexp[\.json$]: invalid value of header Cache-Control`)
+	suite.ErrorContains(err, "no-fallback-regexp[1]: error parsing regexp")
+	suite.ErrorContains(err, "metrics-path-templates[0].regexp: error parsing regexp")
+	suite.NotContains(err.Error(), "no-fallback-regexp[0]")
+}
+
+func (suite *ValidateTestSuite) Test_Invalid_config_Then_server_not_created() {
+
+	// when
+	_, err := newServer(Config{RootDirs: []string{suite.T().TempDir()}, TraceExcludeRegexs: []string{`*`}}, zerolog.New(io.Discard))
+
+	// then
+	suite.ErrorContains(err, "trace-exclude-regexp[0]")
+}
+
+func (suite *ValidateTestSuite) Test_Invalid_base_url_Then_invalid() {
+
+	// when
+	err := validateConfig(Config{BaseURL: "app/?v=1"})
+
+	// then
+	suite.ErrorContains(err, `base-url: "app/?v=1" is not an absolute path`)
+}
+
+func (suite *ValidateTestSuite) Test_Check_roots_with_index_Then_valid() {
+
+	// given
+	dir := suite.T().TempDir()
+	suite.Require().Nil(os.WriteFile(path.Join(dir, "index.html"), []byte("index"), 0644))
+
+	// when
+	err := checkConfig(context.Background(), Config{RootDirs: []string{dir}, BaseURL: "/", FallbackDocument: "index.html"})
+
+	// then
+	suite.Nil(err)
+}
+
+func (suite *ValidateTestSuite) Test_Check_missing_root_and_index_Then_all_reported() {
+
+	// given
+	dir := suite.T().TempDir()
+	missing := path.Join(dir, "missing")
+
+	// when
+	err := checkConfig(context.Background(), Config{
+		RootDirs:         []string{dir, missing},
+		BaseURL:          "/",
+		FallbackDocument: "index.html",
+		Sites:            map[string]map[string]any{"app.example.com": {"fallback-document": "app.html"}},
+	})
+
+	// then
+	suite.Require().NotNil(err)
+	suite.ErrorContains(err, "roots[1]: stat "+missing)
+	suite.ErrorContains(err, "roots: /index.html not found")
+	suite.ErrorContains(err, "sites[app.example.com]: roots: /app.html not found")
+}
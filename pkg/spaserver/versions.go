@@ -0,0 +1,67 @@
+package spaserver
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// versionsPrefix is the path prefix of the versioned requests, e.g. `/v/1.2.0/main.js`
+const versionsPrefix = "v/"
+
+type versionKey struct{}
+
+// versionedPath splits the versioned resource path to the version and the
+// path within the version, ok is false for the requests of the default roots
+func (this *server) versionedPath(resourcePath string) (version string, versionPath string, ok bool) {
+	if this.cfg.VersionsDir == "" {
+		return "", "", false
+	}
+	trimmed := strings.TrimPrefix(resourcePath, "/")
+	if !strings.HasPrefix(trimmed, versionsPrefix) {
+		return "", "", false
+	}
+	version, versionPath, _ = strings.Cut(strings.TrimPrefix(trimmed, versionsPrefix), "/")
+	if version == "" || version == "." || version == ".." {
+		return "", "", false
+	}
+	return version, versionPath, true
+}
+
+// versionRoots opens the roots of the version directory, the roots are nil
+// if the version does not exist
+func (this *server) versionRoots(version string) ([]assetRoot, error) {
+	if roots, ok := this.versions.Load(version); ok {
+		return roots.([]assetRoot), nil
+	}
+
+	dir := filepath.Join(this.cfg.VersionsDir, version)
+	info, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.IsDir()) {
+		// unknown versions are not cached
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	roots, err := this.openRoots([]string{dir})
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := this.versions.LoadOrStore(version, roots)
+	return actual.([]assetRoot), nil
+}
+
+// withVersion stores the version of the request in the context
+func withVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, versionKey{}, version)
+}
+
+// requestVersion returns the version of the request, empty for the requests of the default roots
+func requestVersion(ctx context.Context) string {
+	version, _ := ctx.Value(versionKey{}).(string)
+	return version
+}
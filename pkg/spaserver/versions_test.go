@@ -0,0 +1,100 @@
+package spaserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type VersionsTestSuite struct {
+	suite.Suite
+	sut *server
+}
+
+func TestVersionsTestSuite(t *testing.T) {
+	suite.Run(t, new(VersionsTestSuite))
+}
+
+func (suite *VersionsTestSuite) SetupTest() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	versionsDir := suite.T().TempDir()
+	for _, version := range []string{"1.0.0", "2.0.0"} {
+		suite.Require().Nil(os.MkdirAll(path.Join(versionsDir, version), 0755))
+		suite.Require().Nil(os.WriteFile(path.Join(versionsDir, version, "index.html"), []byte("index "+version), 0644))
+		suite.Require().Nil(os.WriteFile(path.Join(versionsDir, version, "chunk-"+version+".js"), []byte(version), 0644))
+	}
+	suite.Require().Nil(os.Symlink(path.Join(versionsDir, "2.0.0"), path.Join(versionsDir, "current")))
+
+	sut, err := newServer(Config{
+		RootDirs:    []string{path.Join(versionsDir, "current")},
+		BaseURL:     "/app/",
+		VersionsDir: versionsDir,
+		// missing chunks are not served by the fallback
+		NotFoundRegexs: []string{`\.js$`},
+	}, zerolog.New(io.Discard))
+	suite.Require().Nil(err)
+	suite.sut = sut
+}
+
+func (suite *VersionsTestSuite) get(requestPath string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", requestPath, nil)
+	suite.Require().Nil(err)
+	rr := httptest.NewRecorder()
+	suite.sut.handler(context.Background(), rr, req)
+	return rr
+}
+
+func (suite *VersionsTestSuite) Test_Versioned_path_Then_served_from_version() {
+
+	// when
+	rr := suite.get("/app/v/1.0.0/chunk-1.0.0.js")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("1.0.0", rr.Body.String())
+}
+
+func (suite *VersionsTestSuite) Test_Versioned_route_Then_fallback_to_version_index() {
+
+	// when
+	rr := suite.get("/app/v/1.0.0/some/route")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("index 1.0.0", rr.Body.String())
+}
+
+func (suite *VersionsTestSuite) Test_Default_route_Then_served_from_current() {
+
+	// when
+	rr := suite.get("/app/chunk-2.0.0.js")
+
+	// then
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal("2.0.0", rr.Body.String())
+}
+
+func (suite *VersionsTestSuite) Test_Unknown_version_Then_not_found() {
+
+	// when
+	rr := suite.get("/app/v/3.0.0/some/route")
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+}
+
+func (suite *VersionsTestSuite) Test_Chunk_of_other_version_Then_not_found() {
+
+	// when
+	rr := suite.get("/app/v/2.0.0/chunk-1.0.0.js")
+
+	// then
+	suite.Equal(http.StatusNotFound, rr.Code)
+}